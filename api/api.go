@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -16,6 +17,7 @@ type API struct {
 	VersionRouter  *VersionRouter
 	RateLimiter    RateLimiter
 	middlewares    []func(http.Handler) http.Handler
+	routes         *routeRegistry
 }
 
 // New creates a new API instance
@@ -34,6 +36,7 @@ func New(config *APIConfig) *API {
 	api := &API{
 		Router: chi.NewRouter(),
 		Config: config,
+		routes: &routeRegistry{},
 	}
 	
 	// Setup version router
@@ -62,6 +65,11 @@ func New(config *APIConfig) *API {
 
 // setupMiddleware configures default middleware
 func (api *API) setupMiddleware() {
+	// Problem details for all error responses, including panics
+	if api.Config.ProblemDetails {
+		api.Router.Use(ProblemJSON(api.Config.ProblemTypeBase))
+	}
+	
 	// Request ID
 	api.Router.Use(middleware.RequestID)
 	
@@ -118,6 +126,17 @@ func (api *API) Group(pattern string, middlewares ...func(http.Handler) http.Han
 	}
 }
 
+// Version returns a router for routes of one API version. Routes are
+// registered on the VersionRouter, so it must be mounted to serve them.
+func (api *API) Version(version string, middlewares ...func(http.Handler) http.Handler) *Router {
+	return &Router{
+		mux:         api.VersionRouter.RegisterVersion(version),
+		middlewares: middlewares,
+		api:         api,
+		version:     version,
+	}
+}
+
 // Mount mounts a handler at the specified pattern
 func (api *API) Mount(pattern string, handler http.Handler) {
 	api.Router.Mount(pattern, handler)
@@ -134,6 +153,7 @@ type Router struct {
 	pattern     string
 	middlewares []func(http.Handler) http.Handler
 	api         *API
+	version     string
 }
 
 // Route creates a new sub-router
@@ -142,33 +162,34 @@ func (r *Router) Route(pattern string, fn func(r chi.Router)) {
 }
 
 // Get registers a GET route
-func (r *Router) Get(pattern string, handler http.HandlerFunc) {
-	r.handle("GET", pattern, handler)
+func (r *Router) Get(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	r.handle("GET", pattern, handler, opts...)
 }
 
 // Post registers a POST route
-func (r *Router) Post(pattern string, handler http.HandlerFunc) {
-	r.handle("POST", pattern, handler)
+func (r *Router) Post(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	r.handle("POST", pattern, handler, opts...)
 }
 
 // Put registers a PUT route
-func (r *Router) Put(pattern string, handler http.HandlerFunc) {
-	r.handle("PUT", pattern, handler)
+func (r *Router) Put(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	r.handle("PUT", pattern, handler, opts...)
 }
 
 // Patch registers a PATCH route
-func (r *Router) Patch(pattern string, handler http.HandlerFunc) {
-	r.handle("PATCH", pattern, handler)
+func (r *Router) Patch(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	r.handle("PATCH", pattern, handler, opts...)
 }
 
 // Delete registers a DELETE route
-func (r *Router) Delete(pattern string, handler http.HandlerFunc) {
-	r.handle("DELETE", pattern, handler)
+func (r *Router) Delete(pattern string, handler http.HandlerFunc, opts ...RouteOption) {
+	r.handle("DELETE", pattern, handler, opts...)
 }
 
-// handle registers a route with middleware
-func (r *Router) handle(method, pattern string, handler http.HandlerFunc) {
+// handle registers a route with middleware and records it for the OpenAPI document
+func (r *Router) handle(method, pattern string, handler http.HandlerFunc, opts ...RouteOption) {
 	fullPattern := r.pattern + pattern
+	r.document(method, fullPattern, opts...)
 	
 	// Apply group middleware
 	h := http.Handler(handler)
@@ -179,14 +200,47 @@ func (r *Router) handle(method, pattern string, handler http.HandlerFunc) {
 	r.mux.Method(method, fullPattern, h)
 }
 
-// Resource creates RESTful routes for a resource
-func (r *Router) Resource(pattern string, controller ResourceController) {
+// document records a route in the API's route registry
+func (r *Router) document(method, pattern string, opts ...RouteOption) {
+	if r.api == nil || r.api.routes == nil {
+		return
+	}
+
+	info := &RouteInfo{Method: method, Pattern: pattern, Version: r.version}
+	for _, opt := range opts {
+		opt(&info.Doc)
+	}
+	r.api.routes.add(info)
+}
+
+// Resource creates RESTful routes for a resource. The options document
+// every route; routes are tagged with the resource name by default. A
+// request body set with Accepts is only documented on create and update.
+func (r *Router) Resource(pattern string, controller ResourceController, opts ...RouteOption) {
+	name := strings.Trim(pattern, "/")
+	if len(opts) == 0 {
+		opts = []RouteOption{Tags(name)}
+	}
+	base := r.pattern + pattern
+	withoutBody := append(opts[:len(opts):len(opts)], func(d *RouteDoc) { d.Request = nil })
+	r.document("GET", base, append([]RouteOption{Summary("List " + name)}, withoutBody...)...)
+	r.document("POST", base, append([]RouteOption{Summary("Create " + name)}, opts...)...)
+	r.document("GET", base+"/{id}", append([]RouteOption{Summary("Get " + name)}, withoutBody...)...)
+	r.document("PUT", base+"/{id}", append([]RouteOption{Summary("Update " + name)}, opts...)...)
+	r.document("DELETE", base+"/{id}", append([]RouteOption{Summary("Delete " + name)}, withoutBody...)...)
+
 	r.Route(pattern, func(router chi.Router) {
 		router.Get("/", controller.List)       // GET /resources
 		router.Post("/", controller.Create)    // POST /resources
-		router.Get("/{id}", controller.Get)    // GET /resources/{id}
-		router.Put("/{id}", controller.Update) // PUT /resources/{id}
-		router.Delete("/{id}", controller.Delete) // DELETE /resources/{id}
+		
+		// Conditional requests for controllers that report versions
+		item := router
+		if vr, ok := controller.(VersionedResource); ok {
+			item = router.With(Conditional(resourceVersion(vr)))
+		}
+		item.Get("/{id}", controller.Get)    // GET /resources/{id}
+		item.Put("/{id}", controller.Update) // PUT /resources/{id}
+		item.Delete("/{id}", controller.Delete) // DELETE /resources/{id}
 	})
 }
 
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ResourceVersion identifies the current state of a resource for
+// conditional requests
+type ResourceVersion struct {
+	// ETag is the entity tag without quotes, e.g. a revision number or hash
+	ETag string
+	// Weak marks the ETag as weak: equal tags mean equivalent, not
+	// byte-identical, representations
+	Weak bool
+	// LastModified is when the resource last changed
+	LastModified time.Time
+}
+
+// IsZero reports whether the version has no validators
+func (v ResourceVersion) IsZero() bool {
+	return v.ETag == "" && v.LastModified.IsZero()
+}
+
+// header returns the ETag header value
+func (v ResourceVersion) header() string {
+	if v.ETag == "" {
+		return ""
+	}
+	tag := `"` + strings.ReplaceAll(v.ETag, `"`, "") + `"`
+	if v.Weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// VersionFunc returns the current version of the resource a request is for.
+// Returning a TjoError with ErrNotFound sends a 404.
+type VersionFunc func(r *http.Request) (ResourceVersion, error)
+
+// VersionedResource is implemented by resource controllers that support
+// conditional requests. Resource then answers GET with 304 Not Modified
+// and rejects PUT and DELETE with 412 Precondition Failed when the
+// client's version is out of date.
+type VersionedResource interface {
+	Version(r *http.Request, id string) (ResourceVersion, error)
+}
+
+// NewETag returns an ETag header value for the JSON encoding of data
+func NewETag(data interface{}, weak bool) (string, error) {
+	tag, err := hashData(data)
+	if err != nil {
+		return "", err
+	}
+	return ResourceVersion{ETag: tag, Weak: weak}.header(), nil
+}
+
+func hashData(data interface{}) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// SetVersionHeaders sets the ETag and Last-Modified headers, e.g. on the
+// response to a successful update
+func SetVersionHeaders(w http.ResponseWriter, v ResourceVersion) {
+	if tag := v.header(); tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+	if !v.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// CheckPreconditions sets the version headers and evaluates the request's
+// conditional headers against v. It sends 304 Not Modified for GET and HEAD
+// requests the client has a current copy of, and 412 Precondition Failed
+// for other requests whose If-Match or If-Unmodified-Since does not hold.
+// It returns true if a response was sent and the handler should stop.
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, v ResourceVersion) bool {
+	if v.IsZero() {
+		return false
+	}
+
+	// Updates send their new version, so only describe the current one on
+	// reads and failed preconditions
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if safe {
+		SetVersionHeaders(w, v)
+	}
+	etag := v.header()
+	lastModified := v.LastModified.Truncate(time.Second)
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !etagMatches(im, etag, false) {
+			return preconditionFailed(w, v)
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !v.LastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.After(t) {
+			return preconditionFailed(w, v)
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag, true) {
+			if safe {
+				return notModified(w)
+			}
+			return preconditionFailed(w, v)
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && safe && !v.LastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			return notModified(w)
+		}
+	}
+
+	return false
+}
+
+// Conditional is middleware that checks the request's conditional headers
+// against the version returned by fn before calling the handler
+func Conditional(fn VersionFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, err := fn(r)
+			if err != nil {
+				WriteError(w, err)
+				return
+			}
+			if CheckPreconditions(w, r, v) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireIfMatch rejects PUT and PATCH requests without an If-Match or
+// If-Unmodified-Since header with 428 Precondition Required, so clients
+// cannot overwrite changes they have not seen. Use it with Conditional.
+func RequireIfMatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method == http.MethodPut || r.Method == http.MethodPatch) &&
+			r.Header.Get("If-Match") == "" && r.Header.Get("If-Unmodified-Since") == "" {
+			Error(w, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED",
+				"If-Match header is required", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CachedJSON sends data like JSON with a weak ETag computed from data,
+// or 304 Not Modified if the client's copy is current. The ETag is weak
+// because the envelope's timestamp changes between responses.
+func CachedJSON(w http.ResponseWriter, r *http.Request, data interface{}, opts ...ResponseOption) error {
+	tag, err := hashData(data)
+	if err != nil {
+		return err
+	}
+	if CheckPreconditions(w, r, ResourceVersion{ETag: tag, Weak: true}) {
+		return nil
+	}
+	return JSON(w, http.StatusOK, data, opts...)
+}
+
+// resourceVersion adapts a VersionedResource to a VersionFunc
+func resourceVersion(vr VersionedResource) VersionFunc {
+	return func(r *http.Request) (ResourceVersion, error) {
+		return vr.Version(r, chi.URLParam(r, "id"))
+	}
+}
+
+// etagMatches compares an If-Match or If-None-Match header with etag.
+// Weak comparison ignores the W/ prefix; strong comparison, used for
+// If-Match, never matches weak tags.
+func etagMatches(header, etag string, weak bool) bool {
+	// The resource exists, since we have a version
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if !strings.HasPrefix(candidate, "W/") && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func notModified(w http.ResponseWriter) bool {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+func preconditionFailed(w http.ResponseWriter, v ResourceVersion) bool {
+	SetVersionHeaders(w, v)
+	Error(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED",
+		"Resource has been modified", nil)
+	return true
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jimmitjoo/tjo"
+)
+
+type versionedController struct {
+	revision int
+	updated  time.Time
+	updates  int
+}
+
+func (c *versionedController) List(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, nil)
+}
+func (c *versionedController) Create(w http.ResponseWriter, r *http.Request) { Created(w, nil) }
+func (c *versionedController) Get(w http.ResponseWriter, r *http.Request) {
+	JSON(w, http.StatusOK, "doc")
+}
+func (c *versionedController) Delete(w http.ResponseWriter, r *http.Request) { NoContent(w) }
+
+func (c *versionedController) Update(w http.ResponseWriter, r *http.Request) {
+	c.updates++
+	c.revision++
+	SetVersionHeaders(w, ResourceVersion{ETag: "rev-" + strconv.Itoa(c.revision)})
+	JSON(w, http.StatusOK, "updated")
+}
+
+func (c *versionedController) Version(r *http.Request, id string) (ResourceVersion, error) {
+	if id != "1" {
+		return ResourceVersion{}, tjo.NewError("docs.version", "document not found", tjo.ErrNotFound)
+	}
+	return ResourceVersion{ETag: "rev-" + strconv.Itoa(c.revision), LastModified: c.updated}, nil
+}
+
+func conditionalRequest(h http.Handler, method, path string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestVersionedResource(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := &versionedController{revision: 1, updated: updated}
+	a := New(&APIConfig{Version: "v1"})
+	a.Group("/api").Resource("/docs", c)
+
+	w := conditionalRequest(a, "GET", "/api/docs/1")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"rev-1"` ||
+		w.Header().Get("Last-Modified") != "Wed, 01 May 2024 12:00:00 GMT" {
+		t.Fatalf("GET = %d, headers = %v", w.Code, w.Header())
+	}
+
+	notModified := [][]string{
+		{"If-None-Match", `"rev-1"`},
+		{"If-None-Match", `"rev-0", W/"rev-1"`},
+		{"If-Modified-Since", "Wed, 01 May 2024 12:00:00 GMT"},
+	}
+	for _, h := range notModified {
+		w := conditionalRequest(a, "GET", "/api/docs/1", h...)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%v: status = %d, body = %q", h, w.Code, w.Body.String())
+		}
+	}
+
+	modified := [][]string{
+		{"If-None-Match", `"rev-0"`},
+		{"If-Modified-Since", "Tue, 30 Apr 2024 12:00:00 GMT"},
+		{"If-None-Match", `"rev-0"`, "If-Modified-Since", "Wed, 01 May 2024 12:00:00 GMT"},
+	}
+	for _, h := range modified {
+		if w := conditionalRequest(a, "GET", "/api/docs/1", h...); w.Code != http.StatusOK {
+			t.Errorf("%v: status = %d, want 200", h, w.Code)
+		}
+	}
+
+	failed := [][]string{
+		{"If-Match", `"rev-0"`},
+		{"If-Match", `W/"rev-1"`},
+		{"If-Unmodified-Since", "Tue, 30 Apr 2024 12:00:00 GMT"},
+	}
+	for _, h := range failed {
+		w := conditionalRequest(a, "PUT", "/api/docs/1", h...)
+		if w.Code != http.StatusPreconditionFailed || w.Header().Get("ETag") != `"rev-1"` {
+			t.Errorf("%v: status = %d, headers = %v", h, w.Code, w.Header())
+		}
+	}
+	if c.updates != 0 {
+		t.Fatalf("updates = %d after failed preconditions", c.updates)
+	}
+
+	w = conditionalRequest(a, "PUT", "/api/docs/1", "If-Match", `"rev-1"`)
+	if w.Code != http.StatusOK || c.updates != 1 || w.Header().Get("ETag") != `"rev-2"` {
+		t.Errorf("PUT = %d, updates = %d, ETag = %s", w.Code, c.updates, w.Header().Get("ETag"))
+	}
+
+	// The lost update: a second client still holds rev-1
+	if w := conditionalRequest(a, "PUT", "/api/docs/1", "If-Match", `"rev-1"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale PUT = %d, want 412", w.Code)
+	}
+	if w := conditionalRequest(a, "DELETE", "/api/docs/1", "If-Match", "*"); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE If-Match * = %d, want 204", w.Code)
+	}
+	if w := conditionalRequest(a, "GET", "/api/docs/2"); w.Code != http.StatusNotFound {
+		t.Errorf("missing document = %d, want 404", w.Code)
+	}
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	h := RequireIfMatch(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NoContent(w)
+	}))
+
+	if w := conditionalRequest(h, "PATCH", "/docs/1"); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("PATCH without If-Match = %d, want 428", w.Code)
+	}
+	if w := conditionalRequest(h, "PATCH", "/docs/1", "If-Match", `"a"`); w.Code != http.StatusNoContent {
+		t.Errorf("PATCH with If-Match = %d", w.Code)
+	}
+	if w := conditionalRequest(h, "GET", "/docs/1"); w.Code != http.StatusNoContent {
+		t.Errorf("GET = %d", w.Code)
+	}
+}
+
+func TestCachedJSON(t *testing.T) {
+	data := map[string]interface{}{"id": 1, "name": "Ada"}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		CachedJSON(w, r, data)
+	})
+
+	w := conditionalRequest(h, "GET", "/users/1")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || len(etag) < 4 || etag[:3] != `W/"` {
+		t.Fatalf("status = %d, ETag = %q", w.Code, etag)
+	}
+	if want, _ := NewETag(data, true); etag != want {
+		t.Errorf("ETag = %q, want %q", etag, want)
+	}
+
+	if w := conditionalRequest(h, "GET", "/users/1", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", w.Code)
+	}
+
+	data["name"] = "Grace"
+	if w := conditionalRequest(h, "GET", "/users/1", "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("status after change = %d, want 200", w.Code)
+	}
+}
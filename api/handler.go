@@ -0,0 +1,474 @@
+package api
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jimmitjoo/tjo"
+)
+
+// MaxBodyBytes limits the size of JSON bodies decoded by typed handlers
+const MaxBodyBytes = 1 << 20 // 1MB
+
+// HandlerFunc is a typed handler. The request is bound from the path,
+// query string, headers and JSON body and validated before it is called.
+type HandlerFunc[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// NoBody as a response type sends 204 No Content
+type NoBody struct{}
+
+// StatusCoder lets a response type choose its HTTP status
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// BindError is returned when a request cannot be bound to the request type
+type BindError struct {
+	Field   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+type handlerContextKey struct{}
+
+type handlerContext struct {
+	w http.ResponseWriter
+	r *http.Request
+}
+
+// RequestFromContext returns the HTTP request of a typed handler
+func RequestFromContext(ctx context.Context) *http.Request {
+	if hc, ok := ctx.Value(handlerContextKey{}).(*handlerContext); ok {
+		return hc.r
+	}
+	return nil
+}
+
+// ResponseHeader returns the response headers of a typed handler so it
+// can set headers such as Location or Cache-Control
+func ResponseHeader(ctx context.Context) http.Header {
+	if hc, ok := ctx.Value(handlerContextKey{}).(*handlerContext); ok {
+		return hc.w.Header()
+	}
+	return http.Header{}
+}
+
+// Handle adapts a typed handler to an http.HandlerFunc. It binds the request
+// using `path`, `query` and `header` struct tags and the JSON body, runs the
+// `validate` tag rules and Validatable, calls fn and writes the result in
+// the standard envelope. Errors are mapped to statuses with
+// tjo.ErrorCode.HTTPStatus.
+//
+//	type GetUserRequest struct {
+//	    ID     int64  `path:"id" validate:"required"`
+//	    Expand string `query:"expand" validate:"oneof=posts comments"`
+//	}
+//
+//	router.Get("/users/{id}", api.Handle(func(ctx context.Context, req GetUserRequest) (*User, error) {
+//	    return users.Find(ctx, req.ID)
+//	}))
+func Handle[Req, Resp any](fn HandlerFunc[Req, Resp]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if err := Bind(r, &req); err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlerContextKey{}, &handlerContext{w: w, r: r})
+		resp, err := fn(ctx, req)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		status := defaultStatus(r.Method)
+		if sc, ok := any(resp).(StatusCoder); ok {
+			status = sc.StatusCode()
+		}
+		if _, ok := any(resp).(NoBody); ok || status == http.StatusNoContent {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		JSON(w, status, resp)
+	}
+}
+
+func defaultStatus(method string) int {
+	if method == http.MethodPost {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+// Bind decodes the JSON body and path, query and header parameters into dst
+// and validates it. dst must be a pointer to a struct. Nil pointers it
+// points to are allocated, so Handle works with pointer request types.
+func Bind(r *http.Request, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("api: Bind requires a non-nil pointer")
+	}
+
+	elem := rv.Elem()
+	for elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		dst = elem.Interface()
+		elem = elem.Elem()
+	}
+	if elem.Kind() == reflect.Struct && hasBody(r) && hasBodyFields(elem.Type()) {
+		if err := decodeBody(r, dst); err != nil {
+			return err
+		}
+	}
+
+	if elem.Kind() == reflect.Struct {
+		if err := bindParams(r, elem); err != nil {
+			return err
+		}
+	}
+
+	if errs := Validate(dst); errs != nil {
+		return errs
+	}
+	if v, ok := dst.(Validatable); ok {
+		return v.Validate()
+	}
+	if v, ok := elem.Interface().(Validatable); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+func decodeBody(r *http.Request, dst interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, MaxBodyBytes))
+	if err := dec.Decode(dst); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &BindError{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}
+		}
+		var sizeErr *http.MaxBytesError
+		if errors.As(err, &sizeErr) {
+			return &BindError{Message: "request body too large"}
+		}
+		return &BindError{Message: "invalid JSON in request body"}
+	}
+
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		var sizeErr *http.MaxBytesError
+		if errors.As(err, &sizeErr) {
+			return &BindError{Message: "request body too large"}
+		}
+		return &BindError{Message: "request body must only contain a single JSON object"}
+	}
+	return nil
+}
+
+// paramTags are the struct tags bound from the request outside the body
+var paramTags = []string{"path", "query", "header"}
+
+// isParamField reports whether a field is bound from a parameter rather than the body
+func isParamField(f reflect.StructField) bool {
+	for _, tag := range paramTags {
+		if f.Tag.Get(tag) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBodyFields reports whether any field of t is decoded from the JSON body
+func hasBodyFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && !isParamField(f) && f.Tag.Get("json") != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+func bindParams(r *http.Request, v reflect.Value) error {
+	t := v.Type()
+	query := r.URL.Query()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		var values []string
+		var name string
+		if name = f.Tag.Get("path"); name != "" {
+			if value := chi.URLParam(r, name); value != "" {
+				values = []string{value}
+			}
+		} else if name = f.Tag.Get("query"); name != "" {
+			values = query[name]
+		} else if name = f.Tag.Get("header"); name != "" {
+			values = r.Header.Values(name)
+		} else {
+			continue
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+		if err := setField(v.Field(i), values); err != nil {
+			return &BindError{Field: name, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setField converts parameter values to the field's type. Slices accept
+// repeated parameters and comma separated values.
+func setField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setField(field.Elem(), values)
+	}
+
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		var parts []string
+		for _, v := range values {
+			for _, p := range strings.Split(v, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					parts = append(parts, p)
+				}
+			}
+		}
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(slice.Index(i), p); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setValue(field, values[0])
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "true", "1", "yes", "on":
+			field.SetBool(true)
+		case "false", "0", "no", "off":
+			field.SetBool(false)
+		default:
+			return errors.New("must be a boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return errors.New("must be a duration")
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive integer")
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported parameter type %s", field.Type())
+	}
+	return nil
+}
+
+// WriteError writes err in the standard error envelope. Validation and
+// bind errors become 400 responses; a tjo.TjoError uses the status of its
+// code with the status text as message. The wrapped error and context of a
+// TjoError are for logs and are never sent to the client.
+func WriteError(w http.ResponseWriter, err error) {
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		ValidationErrors(w, fieldErrs)
+		return
+	}
+
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		var details map[string]interface{}
+		if bindErr.Field != "" {
+			details = map[string]interface{}{"field": bindErr.Field}
+		}
+		Error(w, http.StatusBadRequest, "INVALID_REQUEST", bindErr.Error(), details)
+		return
+	}
+
+	var tjoErr *tjo.TjoError
+	if errors.As(err, &tjoErr) {
+		status := tjoErr.Code.HTTPStatus()
+		Error(w, status, errorCode(status), http.StatusText(status), nil)
+		return
+	}
+
+	InternalServerError(w, "")
+}
+
+// errorCode returns the envelope error code for a status
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "VALIDATION_ERROR"
+	case http.StatusUnauthorized:
+		return "UNAUTHORIZED"
+	case http.StatusForbidden:
+		return "FORBIDDEN"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "CONFLICT"
+	case http.StatusBadGateway:
+		return "BAD_GATEWAY"
+	case http.StatusGatewayTimeout:
+		return "TIMEOUT"
+	case http.StatusInternalServerError:
+		return "INTERNAL_ERROR"
+	}
+	return strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+// Get registers a typed GET handler and documents its parameters and response
+func Get[Req, Resp any](r *Router, pattern string, fn HandlerFunc[Req, Resp], opts ...RouteOption) {
+	r.Get(pattern, Handle(fn), typedDocs[Req, Resp](http.MethodGet, opts)...)
+}
+
+// Post registers a typed POST handler and documents its body and response
+func Post[Req, Resp any](r *Router, pattern string, fn HandlerFunc[Req, Resp], opts ...RouteOption) {
+	r.Post(pattern, Handle(fn), typedDocs[Req, Resp](http.MethodPost, opts)...)
+}
+
+// Put registers a typed PUT handler and documents its body and response
+func Put[Req, Resp any](r *Router, pattern string, fn HandlerFunc[Req, Resp], opts ...RouteOption) {
+	r.Put(pattern, Handle(fn), typedDocs[Req, Resp](http.MethodPut, opts)...)
+}
+
+// Patch registers a typed PATCH handler and documents its body and response
+func Patch[Req, Resp any](r *Router, pattern string, fn HandlerFunc[Req, Resp], opts ...RouteOption) {
+	r.Patch(pattern, Handle(fn), typedDocs[Req, Resp](http.MethodPatch, opts)...)
+}
+
+// Delete registers a typed DELETE handler and documents its parameters and response
+func Delete[Req, Resp any](r *Router, pattern string, fn HandlerFunc[Req, Resp], opts ...RouteOption) {
+	r.Delete(pattern, Handle(fn), typedDocs[Req, Resp](http.MethodDelete, opts)...)
+}
+
+// typedDocs derives route documentation from the request and response
+// types. Explicit options are applied last and take precedence.
+func typedDocs[Req, Resp any](method string, opts []RouteOption) []RouteOption {
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	respType := reflect.TypeOf((*Resp)(nil)).Elem()
+
+	docs := []RouteOption{func(d *RouteDoc) {
+		d.Parameters = append(d.Parameters, paramDocs(reqType)...)
+	}}
+
+	st := reqType
+	for st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() == reflect.Struct && hasBodyFields(st) {
+		docs = append(docs, Accepts(reqType))
+	}
+
+	var respValue interface{} = respType
+	status := defaultStatus(method)
+	if respType == reflect.TypeOf(NoBody{}) {
+		status, respValue = http.StatusNoContent, nil
+	} else if sc, ok := reflect.New(respType).Elem().Interface().(StatusCoder); ok && respType.Kind() != reflect.Ptr {
+		status = sc.StatusCode()
+	}
+	docs = append(docs, Returns(status, respValue))
+
+	return append(docs, opts...)
+}
+
+// paramDocs documents the path, query and header fields of a request type
+func paramDocs(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	sr := newSchemaRegistry()
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		for _, in := range paramTags {
+			name := f.Tag.Get(in)
+			if name == "" {
+				continue
+			}
+			schema := sr.schema(f.Type)
+			applyRules(schema, f.Tag.Get("validate"))
+			params = append(params, &Parameter{
+				Name:        name,
+				In:          in,
+				Description: f.Tag.Get("description"),
+				Required:    in == "path" || hasRule(f.Tag.Get("validate"), "required"),
+				Schema:      schema,
+			})
+		}
+	}
+	return params
+}
@@ -0,0 +1,378 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jimmitjoo/tjo"
+)
+
+type updateUserRequest struct {
+	ID      int64    `path:"id"`
+	Notify  bool     `query:"notify"`
+	Fields  []string `query:"fields"`
+	Tenant  string   `header:"X-Tenant" validate:"required"`
+	Name    string   `json:"name" validate:"required,min=2"`
+	Email   string   `json:"email" validate:"email"`
+	Role    string   `json:"role" validate:"oneof=admin member"`
+	Ignored string   `json:"-"`
+}
+
+type userResponse struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type acceptedResponse struct {
+	Job string `json:"job"`
+}
+
+func (acceptedResponse) StatusCode() int { return http.StatusAccepted }
+
+func serveTyped(t *testing.T, method, pattern, target, body string, h http.HandlerFunc, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := chi.NewRouter()
+	r.Method(method, pattern, h)
+
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder) Response {
+	t.Helper()
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	return resp
+}
+
+func TestHandleBindsRequest(t *testing.T) {
+	var got updateUserRequest
+	h := Handle(func(ctx context.Context, req updateUserRequest) (*userResponse, error) {
+		got = req
+		if RequestFromContext(ctx) == nil {
+			t.Error("RequestFromContext() = nil")
+		}
+		ResponseHeader(ctx).Set("X-Handled", "yes")
+		return &userResponse{ID: req.ID, Name: req.Name, Email: req.Email}, nil
+	})
+
+	w := serveTyped(t, "PUT", "/users/{id}", "/users/42?notify=1&fields=name,email&fields=role",
+		`{"name":"Ada","email":"ada@example.com","role":"admin"}`, h, "X-Tenant", "acme")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if got.ID != 42 || !got.Notify || got.Tenant != "acme" || got.Name != "Ada" || got.Role != "admin" {
+		t.Errorf("bound request = %+v", got)
+	}
+	if strings.Join(got.Fields, "|") != "name|email|role" {
+		t.Errorf("Fields = %v", got.Fields)
+	}
+	if w.Header().Get("X-Handled") != "yes" {
+		t.Error("ResponseHeader() changes should be sent")
+	}
+
+	resp := decodeResponse(t, w)
+	data := resp.Data.(map[string]interface{})
+	if !resp.Success || data["name"] != "Ada" || data["id"].(float64) != 42 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestHandlePointerRequest(t *testing.T) {
+	var got *updateUserRequest
+	h := Handle(func(ctx context.Context, req *updateUserRequest) (*userResponse, error) {
+		got = req
+		return &userResponse{ID: req.ID, Name: req.Name}, nil
+	})
+
+	w := serveTyped(t, "PUT", "/users/{id}", "/users/7", `{"name":"Ada","role":"member"}`, h, "X-Tenant", "acme")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if got == nil || got.ID != 7 || got.Name != "Ada" || got.Tenant != "acme" {
+		t.Errorf("bound request = %+v", got)
+	}
+
+	w = serveTyped(t, "PUT", "/users/{id}", "/users/7", `{"name":"A"}`, h)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("pointer requests should be validated, status = %d", w.Code)
+	}
+}
+
+func TestHandleValidation(t *testing.T) {
+	called := false
+	h := Handle(func(ctx context.Context, req updateUserRequest) (*userResponse, error) {
+		called = true
+		return nil, nil
+	})
+
+	w := serveTyped(t, "PUT", "/users/{id}", "/users/1", `{"name":"A","email":"nope","role":"owner"}`, h)
+
+	if called {
+		t.Error("handler should not be called for invalid requests")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	resp := decodeResponse(t, w)
+	if resp.Error.Code != "VALIDATION_ERROR" {
+		t.Errorf("code = %s", resp.Error.Code)
+	}
+	fields := map[string]bool{}
+	for _, e := range resp.Error.Details["validation_errors"].([]interface{}) {
+		fields[e.(map[string]interface{})["field"].(string)] = true
+	}
+	for _, f := range []string{"X-Tenant", "name", "email", "role"} {
+		if !fields[f] {
+			t.Errorf("missing validation error for %s: %v", f, fields)
+		}
+	}
+}
+
+func TestHandleBindErrors(t *testing.T) {
+	h := Handle(func(ctx context.Context, req updateUserRequest) (*userResponse, error) {
+		return &userResponse{}, nil
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+	}{
+		{"bad path param", "/users/abc", `{"name":"Ada"}`},
+		{"bad query param", "/users/1?notify=maybe", `{"name":"Ada"}`},
+		{"malformed JSON", "/users/1", `{"name":`},
+		{"wrong JSON type", "/users/1", `{"name":42}`},
+		{"multiple objects", "/users/1", `{"name":"Ada"}{"name":"Bob"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveTyped(t, "PUT", "/users/{id}", tt.target, tt.body, h, "X-Tenant", "acme")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+			if resp := decodeResponse(t, w); resp.Error.Code != "INVALID_REQUEST" {
+				t.Errorf("code = %s", resp.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandleStatuses(t *testing.T) {
+	type empty struct{}
+
+	created := Handle(func(ctx context.Context, req empty) (userResponse, error) {
+		return userResponse{ID: 1}, nil
+	})
+	if w := serveTyped(t, "POST", "/users", "/users", "", created); w.Code != http.StatusCreated {
+		t.Errorf("POST status = %d, want 201", w.Code)
+	}
+
+	accepted := Handle(func(ctx context.Context, req empty) (acceptedResponse, error) {
+		return acceptedResponse{Job: "j1"}, nil
+	})
+	if w := serveTyped(t, "POST", "/jobs", "/jobs", "", accepted); w.Code != http.StatusAccepted {
+		t.Errorf("StatusCoder status = %d, want 202", w.Code)
+	}
+
+	deleted := Handle(func(ctx context.Context, req empty) (NoBody, error) {
+		return NoBody{}, nil
+	})
+	w := serveTyped(t, "DELETE", "/users/1", "/users/1", "", deleted)
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("NoBody status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleErrorMapping(t *testing.T) {
+	tests := []struct {
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{tjo.NewError("users.find", "user not found", tjo.ErrNotFound), http.StatusNotFound, "NOT_FOUND", "Not Found"},
+		{tjo.NewError("auth", "token expired", tjo.ErrUnauthorized), http.StatusUnauthorized, "UNAUTHORIZED", "Unauthorized"},
+		{tjo.WrapError("db.query", errors.New("connection refused"), tjo.ErrDatabase), http.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error"},
+		{FieldErrors{{Field: "name", Message: "taken"}}, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed"},
+		{errors.New("boom"), http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		h := Handle(func(ctx context.Context, req struct{}) (*userResponse, error) {
+			return nil, tt.err
+		})
+
+		w := serveTyped(t, "GET", "/", "/", "", h)
+		if w.Code != tt.status {
+			t.Errorf("%v: status = %d, want %d", tt.err, w.Code, tt.status)
+			continue
+		}
+		resp := decodeResponse(t, w)
+		if resp.Error.Code != tt.code || resp.Error.Message != tt.message {
+			t.Errorf("%v: error = %+v", tt.err, resp.Error)
+		}
+	}
+}
+
+func TestHandleBodyTooLarge(t *testing.T) {
+	h := Handle(func(ctx context.Context, req updateUserRequest) (*userResponse, error) {
+		return &userResponse{}, nil
+	})
+	body := `{"name":"` + strings.Repeat("a", MaxBodyBytes) + `"}`
+
+	for _, chunked := range []bool{false, true} {
+		req := httptest.NewRequest("PUT", "/users/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", "acme")
+		if chunked {
+			// Chunked requests have no Content-Length
+			req.ContentLength = -1
+		}
+
+		r := chi.NewRouter()
+		r.Put("/users/{id}", h)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		resp := decodeResponse(t, w)
+		if w.Code != http.StatusBadRequest || resp.Error.Message != "request body too large" {
+			t.Errorf("chunked=%v: status = %d, error = %+v", chunked, w.Code, resp.Error)
+		}
+	}
+}
+
+func TestHandleErrorHidesCause(t *testing.T) {
+	h := Handle(func(ctx context.Context, req struct{}) (*userResponse, error) {
+		err := tjo.WrapError("users.find", sql.ErrNoRows, tjo.ErrNotFound)
+		return nil, err.WithContext("query", "SELECT * FROM users WHERE id = 1")
+	})
+
+	w := serveTyped(t, "GET", "/", "/", "", h)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+	for _, leaked := range []string{sql.ErrNoRows.Error(), "SELECT", "query"} {
+		if strings.Contains(w.Body.String(), leaked) {
+			t.Errorf("body %s exposes %q", w.Body.String(), leaked)
+		}
+	}
+}
+
+type signupRequest struct {
+	Password string `json:"password" validate:"required"`
+	Confirm  string `json:"confirm"`
+}
+
+func (r signupRequest) Validate() error {
+	if r.Password != r.Confirm {
+		return FieldErrors{{Field: "confirm", Message: "Passwords do not match"}}
+	}
+	return nil
+}
+
+func TestHandleValidatable(t *testing.T) {
+	h := Handle(func(ctx context.Context, req signupRequest) (NoBody, error) {
+		return NoBody{}, nil
+	})
+
+	w := serveTyped(t, "POST", "/signup", "/signup", `{"password":"a","confirm":"b"}`, h)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Passwords do not match") {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = serveTyped(t, "POST", "/signup", "/signup", `{"password":"a","confirm":"a"}`, h)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTypedRoutesDocumented(t *testing.T) {
+	a := New(nil)
+	users := a.Group("/users")
+
+	Put(users, "/{id}", func(ctx context.Context, req updateUserRequest) (*userResponse, error) {
+		return &userResponse{}, nil
+	}, Summary("Update a user"))
+	Delete(users, "/{id}", func(ctx context.Context, req struct {
+		ID int64 `path:"id"`
+	}) (NoBody, error) {
+		return NoBody{}, nil
+	})
+
+	doc := a.OpenAPI(nil, "")
+
+	put := doc.Paths["/users/{id}"]["put"]
+	if put == nil {
+		t.Fatalf("missing PUT /users/{id}: %v", doc.Paths)
+	}
+	if put.Summary != "Update a user" {
+		t.Errorf("summary = %q", put.Summary)
+	}
+
+	params := map[string]*Parameter{}
+	for _, p := range put.Parameters {
+		params[p.In+":"+p.Name] = p
+	}
+	if len(put.Parameters) != 4 {
+		t.Errorf("parameters = %d, want 4 (no duplicate path param)", len(put.Parameters))
+	}
+	if p := params["path:id"]; p == nil || p.Schema.Type != "integer" || !p.Required {
+		t.Errorf("path id = %+v", p)
+	}
+	if p := params["header:X-Tenant"]; p == nil || !p.Required {
+		t.Errorf("header X-Tenant = %+v", p)
+	}
+	if p := params["query:fields"]; p == nil || p.Schema.Type != "array" {
+		t.Errorf("query fields = %+v", p)
+	}
+
+	body := doc.Components.Schemas["updateUserRequest"]
+	if body == nil {
+		t.Fatal("missing request body schema")
+	}
+	if _, ok := body.Properties["id"]; ok {
+		t.Error("path parameters should not be part of the body schema")
+	}
+	if len(body.Required) != 1 || body.Required[0] != "name" {
+		t.Errorf("required = %v", body.Required)
+	}
+	if body.Properties["email"].Format != "email" || len(body.Properties["role"].Enum) != 2 {
+		t.Errorf("validate rules not documented: %+v", body.Properties)
+	}
+	if put.Responses["200"] == nil {
+		t.Errorf("responses = %v", put.Responses)
+	}
+
+	del := doc.Paths["/users/{id}"]["delete"]
+	if del == nil || del.RequestBody != nil || del.Responses["204"] == nil {
+		t.Errorf("DELETE operation = %+v", del)
+	}
+}
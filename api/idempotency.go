@@ -0,0 +1,363 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimmitjoo/tjo/cache"
+)
+
+// IdempotencyHeader is the request header carrying the idempotency key
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyConfig configures IdempotencyMiddleware
+type IdempotencyConfig struct {
+	// Cache stores the responses. Required.
+	Cache cache.Cache
+
+	// Header is the request header with the key (default Idempotency-Key)
+	Header string
+
+	// Methods are the methods the middleware applies to (default POST and PATCH)
+	Methods []string
+
+	// TTL is how long responses are replayed (default 24 hours)
+	TTL time.Duration
+
+	// LockTTL is how long a request is considered in flight if it never
+	// completes, e.g. because the instance died (default 1 minute)
+	LockTTL time.Duration
+
+	// ScopeFunc returns the scope keys are unique within, usually the
+	// user ID. The default scopes keys by the Authorization and X-API-Key
+	// headers so clients cannot replay each other's responses.
+	ScopeFunc func(*http.Request) string
+
+	// Required rejects requests without a key
+	Required bool
+
+	// OnError is called when the cache fails. The request is then handled
+	// without idempotency rather than failing.
+	OnError func(error)
+}
+
+// DefaultIdempotencyConfig returns the default configuration for a cache
+func DefaultIdempotencyConfig(c cache.Cache) *IdempotencyConfig {
+	return &IdempotencyConfig{
+		Cache:     c,
+		Header:    IdempotencyHeader,
+		Methods:   []string{http.MethodPost, http.MethodPatch},
+		TTL:       24 * time.Hour,
+		LockTTL:   time.Minute,
+		ScopeFunc: credentialScope,
+	}
+}
+
+// idempotencyRecord is the cached state of a key
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// atomicAdder is implemented by caches that can set a key only if it does
+// not exist, like cache.RedisCache
+type atomicAdder interface {
+	Add(key string, value interface{}, ttl int) (bool, error)
+}
+
+// IdempotencyMiddleware makes unsafe requests safe to retry. The response
+// to a request with an Idempotency-Key header is stored, and retries with
+// the same key get the stored response with an Idempotent-Replayed header
+// instead of running the handler again.
+//
+// A retry while the original request is still running gets 409
+// IDEMPOTENCY_CONFLICT. Reusing a key with a different method, path or body
+// gets 422 IDEMPOTENCY_KEY_REUSED. Server errors (5xx) are not stored so
+// the request can be retried.
+func IdempotencyMiddleware(config *IdempotencyConfig) func(http.Handler) http.Handler {
+	cfg := DefaultIdempotencyConfig(nil)
+	if config != nil {
+		cfg.Cache = config.Cache
+		cfg.Required = config.Required
+		cfg.OnError = config.OnError
+		if config.Header != "" {
+			cfg.Header = config.Header
+		}
+		if len(config.Methods) > 0 {
+			cfg.Methods = config.Methods
+		}
+		if config.TTL > 0 {
+			cfg.TTL = config.TTL
+		}
+		if config.LockTTL > 0 {
+			cfg.LockTTL = config.LockTTL
+		}
+		if config.ScopeFunc != nil {
+			cfg.ScopeFunc = config.ScopeFunc
+		}
+	}
+	if cfg.Cache == nil {
+		panic("api: IdempotencyMiddleware requires a cache")
+	}
+
+	methods := make(map[string]bool, len(cfg.Methods))
+	for _, m := range cfg.Methods {
+		methods[m] = true
+	}
+
+	// Caches without Add can't claim keys atomically, so claims of the same
+	// key are serialized on this instance
+	var locks *keyLocks
+	if _, ok := cfg.Cache.(atomicAdder); !ok {
+		locks = &keyLocks{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !methods[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(cfg.Header)
+			if key == "" {
+				if cfg.Required {
+					Error(w, http.StatusBadRequest, "IDEMPOTENCY_KEY_REQUIRED",
+						cfg.Header+" header is required", nil)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > 255 {
+				Error(w, http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY",
+					cfg.Header+" must be at most 255 characters", nil)
+				return
+			}
+
+			var body []byte
+			if r.Body != nil {
+				var err error
+				body, err = io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+				if err != nil {
+					Error(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to read request body", nil)
+					return
+				}
+				if len(body) > MaxBodyBytes {
+					Error(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request body is too large", nil)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			cacheKey := "idempotency:" + hashParts(cfg.ScopeFunc(r), key)
+			fingerprint := hashParts(r.Method, r.URL.Path, string(body))
+
+			unlock := locks.lock(cacheKey)
+			record, err := loadRecord(cfg.Cache, cacheKey)
+			claimed := false
+			if err == nil && record == nil {
+				claimed, err = claimKey(cfg.Cache, cacheKey, fingerprint, cfg.LockTTL)
+				if err == nil && !claimed {
+					record, err = loadRecord(cfg.Cache, cacheKey)
+				}
+			}
+			unlock()
+
+			if err != nil {
+				if cfg.OnError != nil {
+					cfg.OnError(err)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !claimed {
+				switch {
+				case record == nil:
+					// Claimed by another request and already released
+					Error(w, http.StatusConflict, "IDEMPOTENCY_CONFLICT",
+						"A request with this idempotency key is being processed", nil)
+				case record.Fingerprint != fingerprint:
+					Error(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+						"Idempotency key was already used for a different request", nil)
+				case !record.Done:
+					w.Header().Set("Retry-After", "1")
+					Error(w, http.StatusConflict, "IDEMPOTENCY_CONFLICT",
+						"A request with this idempotency key is being processed", nil)
+				default:
+					replayResponse(w, record)
+				}
+				return
+			}
+
+			before := w.Header().Clone()
+			rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					// Release the key so the request can be retried
+					cfg.Cache.Forget(cacheKey)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= 500 {
+				return
+			}
+
+			completed = true
+			stored := idempotencyRecord{
+				Fingerprint: fingerprint,
+				Done:        true,
+				Status:      rec.status,
+				Header:      addedHeaders(before, w.Header()),
+				Body:        rec.body.Bytes(),
+			}
+			if err := saveRecord(cfg.Cache, cacheKey, stored, cfg.TTL); err != nil && cfg.OnError != nil {
+				cfg.OnError(err)
+			}
+		})
+	}
+}
+
+// recordingWriter captures the response while writing it
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the underlying writer
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// addedHeaders returns the headers set by the handler, leaving out those
+// of earlier middleware like rate limit headers
+func addedHeaders(before, after http.Header) http.Header {
+	added := http.Header{}
+	for name, values := range after {
+		if old, ok := before[name]; !ok || strings.Join(old, "\x00") != strings.Join(values, "\x00") {
+			added[name] = values
+		}
+	}
+	return added
+}
+
+func replayResponse(w http.ResponseWriter, record *idempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// loadRecord returns the record for a key, or nil if there is none.
+// Records are stored as JSON strings so any cache.Cache can hold them.
+func loadRecord(c cache.Cache, key string) (*idempotencyRecord, error) {
+	value, err := c.Get(key)
+	if err != nil {
+		// Caches report missing keys as errors
+		if exists, herr := c.Has(key); herr == nil && !exists {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s, _ := value.(string)
+	var record idempotencyRecord
+	if err := json.Unmarshal([]byte(s), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func saveRecord(c cache.Cache, key string, record idempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return c.Set(key, string(data), ttlSeconds(ttl))
+}
+
+// claimKey marks a key as in flight. It returns false if another request
+// claimed it first.
+func claimKey(c cache.Cache, key, fingerprint string, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return false, err
+	}
+
+	if adder, ok := c.(atomicAdder); ok {
+		return adder.Add(key, string(data), ttlSeconds(ttl))
+	}
+	return true, c.Set(key, string(data), ttlSeconds(ttl))
+}
+
+// keyLocks serializes work on the same key. Keys are spread over a fixed
+// set of mutexes, so unrelated keys rarely wait for each other.
+type keyLocks [64]sync.Mutex
+
+// lock locks the mutex of key and returns its unlock function. A nil
+// keyLocks does not lock.
+func (l *keyLocks) lock(key string) func() {
+	if l == nil {
+		return func() {}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	mu := &l[h.Sum32()%uint32(len(l))]
+	mu.Lock()
+	return mu.Unlock
+}
+
+func ttlSeconds(d time.Duration) int {
+	if s := int(d / time.Second); s > 0 {
+		return s
+	}
+	return 1
+}
+
+// credentialScope scopes keys by the request's credentials
+func credentialScope(r *http.Request) string {
+	return r.Header.Get("Authorization") + "\x00" + r.Header.Get("X-API-Key")
+}
+
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
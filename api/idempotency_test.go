@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jimmitjoo/tjo/cache"
+)
+
+// memoryCache is a minimal cache.Cache for tests
+type memoryCache struct {
+	mu    sync.Mutex
+	items map[string]interface{}
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{items: map[string]interface{}{}}
+}
+
+func (c *memoryCache) Has(key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[key]
+	return ok, nil
+}
+
+func (c *memoryCache) Get(key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.items[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return v, nil
+}
+
+func (c *memoryCache) Set(key string, value interface{}, ttl ...int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = value
+	return nil
+}
+
+func (c *memoryCache) Forget(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+	return nil
+}
+
+func (c *memoryCache) EmptyByMatch(string) error { return nil }
+func (c *memoryCache) Flush() error              { return nil }
+
+func idempotentRequest(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/orders", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer user-1")
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	caches := map[string]func(t *testing.T) cache.Cache{
+		"memory": func(t *testing.T) cache.Cache { return newMemoryCache() },
+		"redis": func(t *testing.T) cache.Cache {
+			_, pool := newTestRedisPool(t)
+			return &cache.RedisCache{Conn: pool, Prefix: "test:"}
+		},
+	}
+
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				body, _ := io.ReadAll(r.Body)
+				w.Header().Set("Location", "/orders/1")
+				Created(w, map[string]interface{}{"call": n, "body": string(body)})
+			})
+			h := IdempotencyMiddleware(DefaultIdempotencyConfig(newCache(t)))(handler)
+
+			first := idempotentRequest(h, "order-1", `{"sku":"a"}`)
+			if first.Code != http.StatusCreated {
+				t.Fatalf("status = %d, body = %s", first.Code, first.Body.String())
+			}
+
+			retry := idempotentRequest(h, "order-1", `{"sku":"a"}`)
+			if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+				t.Errorf("replay = %d %s, want %s", retry.Code, retry.Body.String(), first.Body.String())
+			}
+			if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Header().Get("Location") != "/orders/1" {
+				t.Errorf("replay headers = %v", retry.Header())
+			}
+			if calls != 1 {
+				t.Errorf("handler called %d times, want 1", calls)
+			}
+
+			reused := idempotentRequest(h, "order-1", `{"sku":"b"}`)
+			if reused.Code != http.StatusUnprocessableEntity {
+				t.Errorf("reused key status = %d, want 422", reused.Code)
+			}
+			if resp := decodeResponse(t, reused); resp.Error.Code != "IDEMPOTENCY_KEY_REUSED" {
+				t.Errorf("code = %s", resp.Error.Code)
+			}
+
+			idempotentRequest(h, "order-2", `{"sku":"a"}`)
+			idempotentRequest(h, "", `{"sku":"a"}`)
+			if calls != 3 {
+				t.Errorf("handler called %d times, want 3", calls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		JSON(w, http.StatusOK, "done")
+	})
+	h := IdempotencyMiddleware(DefaultIdempotencyConfig(newMemoryCache()))(handler)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- idempotentRequest(h, "slow", "{}") }()
+	<-started
+
+	w := idempotentRequest(h, "slow", "{}")
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("in flight status = %d, headers = %v", w.Code, w.Header())
+	}
+	if resp := decodeResponse(t, w); resp.Error.Code != "IDEMPOTENCY_CONFLICT" {
+		t.Errorf("code = %s", resp.Error.Code)
+	}
+
+	close(release)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Errorf("original status = %d", w.Code)
+	}
+}
+
+func TestIdempotencyConcurrentClaims(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		JSON(w, http.StatusCreated, "created")
+	})
+	// memoryCache has no Add, so claims rely on the per-key locks
+	h := IdempotencyMiddleware(DefaultIdempotencyConfig(newMemoryCache()))(handler)
+
+	var wg sync.WaitGroup
+	conflicts := make(chan int, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := idempotentRequest(h, "same", "{}"); w.Code == http.StatusConflict {
+				conflicts <- w.Code
+			}
+		}()
+	}
+
+	// All but the request holding the key are turned away while it runs
+	for i := 0; i < 19; i++ {
+		<-conflicts
+	}
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyServerErrorsNotStored(t *testing.T) {
+	var calls int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			InternalServerError(w, "try again")
+			return
+		}
+		JSON(w, http.StatusOK, "ok")
+	})
+	h := IdempotencyMiddleware(DefaultIdempotencyConfig(newMemoryCache()))(handler)
+
+	if w := idempotentRequest(h, "k", "{}"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if w := idempotentRequest(h, "k", "{}"); w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after 5xx = %d, headers = %v", w.Code, w.Header())
+	}
+}
+
+func TestIdempotencyScopesAndRequired(t *testing.T) {
+	var calls int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		JSON(w, http.StatusOK, calls)
+	})
+	config := DefaultIdempotencyConfig(newMemoryCache())
+	config.Required = true
+	h := IdempotencyMiddleware(config)(handler)
+
+	if w := idempotentRequest(h, "", "{}"); w.Code != http.StatusBadRequest {
+		t.Errorf("missing key status = %d, want 400", w.Code)
+	}
+
+	idempotentRequest(h, "k", "{}")
+
+	// The same key from another user is a different request
+	req := httptest.NewRequest("POST", "/orders", strings.NewReader("{}"))
+	req.Header.Set("Authorization", "Bearer user-2")
+	req.Header.Set(IdempotencyHeader, "k")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if calls != 2 || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("calls = %d, want separate scopes", calls)
+	}
+
+	// Safe methods are not affected
+	get := httptest.NewRequest("GET", "/orders", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, get)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET status = %d", w.Code)
+	}
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jimmitjoo/tjo/database"
+)
+
+// Filter operators accepted in filter[field][op]=value. A filter without an
+// operator, filter[field]=value, uses eq.
+const (
+	OpEq   = "eq"
+	OpNe   = "ne"
+	OpGt   = "gt"
+	OpGte  = "gte"
+	OpLt   = "lt"
+	OpLte  = "lte"
+	OpLike = "like"
+	OpIn   = "in"   // Comma separated values
+	OpNull = "null" // true for IS NULL, false for IS NOT NULL
+)
+
+// filterSQL maps filter operators to query builder operators
+var filterSQL = map[string]string{
+	OpEq:   "=",
+	OpNe:   "!=",
+	OpGt:   ">",
+	OpGte:  ">=",
+	OpLt:   "<",
+	OpLte:  "<=",
+	OpLike: "LIKE",
+}
+
+// ListSpec is the allowlist of a list endpoint. Anything not listed is
+// rejected by ParseListQuery.
+type ListSpec struct {
+	// Filters maps filterable fields to their allowed operators
+	Filters map[string][]string
+
+	// Sorts are the fields that can be sorted by
+	Sorts []string
+
+	// Fields are the fields that can be selected with fields=
+	Fields []string
+
+	// DefaultSort is used without a sort parameter, e.g. "-created_at,id"
+	DefaultSort string
+
+	// Columns maps field names to database columns when they differ,
+	// e.g. "author": "users.name". Fields map to the column of the same
+	// name by default.
+	Columns map[string]string
+}
+
+// ListQuery is a parsed and validated list request
+type ListQuery struct {
+	Filters []Filter
+	Sort    []SortField
+	Fields  []string
+}
+
+// Filter is a condition on a field
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+	Values   []string // Values of the in operator
+}
+
+// SortField is a field to sort by
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseListQuery parses the filter, sort and fields query parameters:
+//
+//	?filter[status]=active&filter[created_at][gte]=2024-01-01&sort=-created_at,id&fields=id,name
+//
+// Fields and operators not allowed by spec are returned as validation
+// errors, which can be sent with ValidationErrors or returned from a
+// typed handler.
+func ParseListQuery(r *http.Request, spec *ListSpec) (*ListQuery, FieldErrors) {
+	q := &ListQuery{}
+	var errs FieldErrors
+	values := r.URL.Query()
+
+	// Sorted for stable filter and error order
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+
+		field, op, ok := parseFilterKey(key)
+		if !ok {
+			errs = append(errs, ValidationError{Field: key, Message: "Invalid filter"})
+			continue
+		}
+
+		allowed, filterable := spec.Filters[field]
+		if !filterable {
+			errs = append(errs, ValidationError{Field: key, Message: "Filtering by " + field + " is not allowed"})
+			continue
+		}
+		if _, known := filterSQL[op]; !known && op != OpIn && op != OpNull {
+			errs = append(errs, ValidationError{Field: key, Message: "Unknown operator " + op})
+			continue
+		}
+		if !contains(allowed, op) {
+			errs = append(errs, ValidationError{Field: key, Message: fmt.Sprintf("Operator %s is not allowed for %s", op, field)})
+			continue
+		}
+
+		value := values.Get(key)
+		f := Filter{Field: field, Operator: op, Value: value}
+		switch op {
+		case OpIn:
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					f.Values = append(f.Values, v)
+				}
+			}
+			if len(f.Values) == 0 {
+				errs = append(errs, ValidationError{Field: key, Message: "At least one value is required"})
+				continue
+			}
+		case OpNull:
+			if value != "true" && value != "false" {
+				errs = append(errs, ValidationError{Field: key, Message: "Must be true or false", Value: value})
+				continue
+			}
+		}
+		q.Filters = append(q.Filters, f)
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	for _, s := range splitList(sortParam) {
+		field := strings.TrimPrefix(s, "-")
+		if !contains(spec.Sorts, field) {
+			errs = append(errs, ValidationError{Field: "sort", Message: "Sorting by " + field + " is not allowed", Value: s})
+			continue
+		}
+		q.Sort = append(q.Sort, SortField{Field: field, Desc: strings.HasPrefix(s, "-")})
+	}
+
+	for _, field := range splitList(values.Get("fields")) {
+		if !contains(spec.Fields, field) {
+			errs = append(errs, ValidationError{Field: "fields", Message: "Unknown field " + field, Value: field})
+			continue
+		}
+		q.Fields = append(q.Fields, field)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return q, nil
+}
+
+// Apply adds the filters, sort and field selection to a query using the
+// spec's column mapping
+func (q *ListQuery) Apply(qb *database.QueryBuilder, spec *ListSpec) *database.QueryBuilder {
+	for _, f := range q.Filters {
+		column := spec.column(f.Field)
+		switch f.Operator {
+		case OpIn:
+			values := make([]interface{}, len(f.Values))
+			for i, v := range f.Values {
+				values[i] = v
+			}
+			qb = qb.WhereIn(column, values)
+		case OpNull:
+			if f.Value == "true" {
+				qb = qb.WhereNull(column)
+			} else {
+				qb = qb.WhereNotNull(column)
+			}
+		default:
+			qb = qb.Where(column, filterSQL[f.Operator], f.Value)
+		}
+	}
+
+	for _, s := range q.Sort {
+		dir := "ASC"
+		if s.Desc {
+			dir = "DESC"
+		}
+		qb = qb.OrderBy(spec.column(s.Field), dir)
+	}
+
+	if len(q.Fields) > 0 {
+		columns := make([]string, len(q.Fields))
+		for i, field := range q.Fields {
+			columns[i] = spec.column(field)
+		}
+		qb = qb.Select(columns...)
+	}
+
+	return qb
+}
+
+func (spec *ListSpec) column(field string) string {
+	if column, ok := spec.Columns[field]; ok {
+		return column
+	}
+	return field
+}
+
+// ListParams documents the filter, sort and fields parameters of a spec
+func ListParams(spec *ListSpec) RouteOption {
+	return func(d *RouteDoc) {
+		fields := make([]string, 0, len(spec.Filters))
+		for field := range spec.Filters {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			for _, op := range spec.Filters[field] {
+				name := "filter[" + field + "][" + op + "]"
+				if op == OpEq {
+					name = "filter[" + field + "]"
+				}
+				d.Parameters = append(d.Parameters, &Parameter{
+					Name:        name,
+					In:          "query",
+					Description: "Filter " + field + " (" + op + ")",
+					Schema:      &Schema{Type: "string"},
+				})
+			}
+		}
+
+		if len(spec.Sorts) > 0 {
+			d.Parameters = append(d.Parameters, &Parameter{
+				Name:        "sort",
+				In:          "query",
+				Description: "Comma separated fields to sort by, prefixed with - for descending: " + strings.Join(spec.Sorts, ", "),
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+		if len(spec.Fields) > 0 {
+			d.Parameters = append(d.Parameters, &Parameter{
+				Name:        "fields",
+				In:          "query",
+				Description: "Comma separated fields to return: " + strings.Join(spec.Fields, ", "),
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+	}
+}
+
+// parseFilterKey parses filter[field] and filter[field][op]
+func parseFilterKey(key string) (field, op string, ok bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+	field, rest, ok = strings.Cut(rest, "]")
+	if !ok || field == "" {
+		return "", "", false
+	}
+	if rest == "" {
+		return field, OpEq, true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") {
+		return "", "", false
+	}
+	op = rest[1 : len(rest)-1]
+	return field, op, op != ""
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jimmitjoo/tjo/database"
+)
+
+var postListSpec = &ListSpec{
+	Filters: map[string][]string{
+		"status":     {OpEq, OpIn},
+		"created_at": {OpGte, OpLt},
+		"deleted_at": {OpNull},
+		"author":     {OpEq},
+	},
+	Sorts:       []string{"created_at", "id"},
+	Fields:      []string{"id", "title", "status"},
+	DefaultSort: "-id",
+	Columns:     map[string]string{"author": "users.name"},
+}
+
+func TestParseListQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/posts?filter[status][in]=active,draft&filter[created_at][gte]=2024-01-01"+
+		"&filter[deleted_at][null]=true&filter[author]=ada&sort=-created_at,id&fields=id,title&page=2", nil)
+
+	q, errs := ParseListQuery(r, postListSpec)
+	if errs != nil {
+		t.Fatalf("errors = %v", errs)
+	}
+
+	wantFilters := []Filter{
+		{Field: "author", Operator: OpEq, Value: "ada"},
+		{Field: "created_at", Operator: OpGte, Value: "2024-01-01"},
+		{Field: "deleted_at", Operator: OpNull, Value: "true"},
+		{Field: "status", Operator: OpIn, Value: "active,draft", Values: []string{"active", "draft"}},
+	}
+	if !reflect.DeepEqual(q.Filters, wantFilters) {
+		t.Errorf("filters = %+v", q.Filters)
+	}
+	if !reflect.DeepEqual(q.Sort, []SortField{{"created_at", true}, {"id", false}}) {
+		t.Errorf("sort = %+v", q.Sort)
+	}
+	if !reflect.DeepEqual(q.Fields, []string{"id", "title"}) {
+		t.Errorf("fields = %v", q.Fields)
+	}
+
+	sql, params, err := q.Apply(database.NewQueryBuilder(nil).Table("posts"), postListSpec).ToSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSQL := "SELECT id, title FROM posts WHERE users.name = ? AND created_at >= ? AND deleted_at IS NULL" +
+		" AND status IN (?, ?) ORDER BY created_at DESC, id ASC"
+	if sql != wantSQL {
+		t.Errorf("sql = %s", sql)
+	}
+	if !reflect.DeepEqual(params, []interface{}{"ada", "2024-01-01", "active", "draft"}) {
+		t.Errorf("params = %v", params)
+	}
+}
+
+func TestParseListQueryDefaultSort(t *testing.T) {
+	q, errs := ParseListQuery(httptest.NewRequest("GET", "/posts", nil), postListSpec)
+	if errs != nil {
+		t.Fatalf("errors = %v", errs)
+	}
+	if !reflect.DeepEqual(q.Sort, []SortField{{"id", true}}) || q.Filters != nil || q.Fields != nil {
+		t.Errorf("query = %+v", q)
+	}
+}
+
+func TestParseListQueryRejects(t *testing.T) {
+	r := httptest.NewRequest("GET", "/posts?filter[password]=x&filter[status][gt]=a&filter[status][regexp]=a"+
+		"&filter[deleted_at][null]=maybe&filter[status=x&sort=-secret&fields=id,password", nil)
+
+	q, errs := ParseListQuery(r, postListSpec)
+	if q != nil {
+		t.Errorf("query = %+v, want nil", q)
+	}
+
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Field+" "+e.Message] = e.Value
+	}
+	want := []string{
+		"filter[password] Filtering by password is not allowed",
+		"filter[status][gt] Operator gt is not allowed for status",
+		"filter[status][regexp] Unknown operator regexp",
+		"filter[deleted_at][null] Must be true or false",
+		"filter[status Invalid filter",
+		"sort Sorting by secret is not allowed",
+		"fields Unknown field password",
+	}
+	for _, w := range want {
+		if _, ok := got[w]; !ok {
+			t.Errorf("missing error %q in %v", w, errs)
+		}
+	}
+	if len(errs) != len(want) {
+		t.Errorf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+
+	w := httptest.NewRecorder()
+	ValidationErrors(w, errs)
+	if resp := decodeResponse(t, w); w.Code != 400 || resp.Error.Code != "VALIDATION_ERROR" {
+		t.Errorf("response = %d %+v", w.Code, resp.Error)
+	}
+}
+
+func TestListParamsDocumented(t *testing.T) {
+	a := New(nil)
+	a.Group("/api").Get("/posts", func(w http.ResponseWriter, r *http.Request) {}, ListParams(postListSpec))
+
+	op := a.OpenAPI(nil, "").Paths["/api/posts"]["get"]
+	names := map[string]bool{}
+	for _, p := range op.Parameters {
+		names[p.Name] = true
+	}
+	for _, n := range []string{"filter[status]", "filter[status][in]", "filter[created_at][gte]", "sort", "fields"} {
+		if !names[n] {
+			t.Errorf("missing parameter %s in %v", n, names)
+		}
+	}
+}
@@ -32,6 +32,13 @@ type APIConfig struct {
 	AllowedOrigins []string
 	EnableMetrics  bool
 	Debug          bool
+
+	// ProblemDetails sends errors as RFC 7807 application/problem+json
+	// documents instead of the response envelope
+	ProblemDetails bool
+	// ProblemTypeBase prefixes the error code in the problem type,
+	// e.g. "https://example.com/problems/". Defaults to about:blank.
+	ProblemTypeBase string
 }
 
 // ErrorHandler handles panics and errors in API routes
@@ -0,0 +1,583 @@
+package api
+
+import (
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPIVersion is the OpenAPI specification version of generated documents
+const OpenAPIVersion = "3.1.0"
+
+// RouteDoc describes a route for the generated OpenAPI document
+type RouteDoc struct {
+	Summary     string
+	Description string
+	OperationID string
+	Tags        []string
+	Deprecated  bool
+	Hidden      bool
+
+	// Request is a value of the request body type, e.g. CreateUserRequest{}
+	Request interface{}
+
+	// Responses maps status codes to a value of the response data type.
+	// The data is documented inside the standard Response envelope;
+	// a nil value documents a response without a body.
+	Responses map[int]interface{}
+
+	Parameters []*Parameter
+}
+
+// RouteOption documents a route
+type RouteOption func(*RouteDoc)
+
+// Summary sets a short summary of what the route does
+func Summary(summary string) RouteOption {
+	return func(d *RouteDoc) {
+		d.Summary = summary
+	}
+}
+
+// Description sets a longer, Markdown description of the route
+func Description(description string) RouteOption {
+	return func(d *RouteDoc) {
+		d.Description = description
+	}
+}
+
+// Tags groups the route in the docs UI
+func Tags(tags ...string) RouteOption {
+	return func(d *RouteDoc) {
+		d.Tags = append(d.Tags, tags...)
+	}
+}
+
+// OperationID sets a unique operation identifier, used by client generators
+func OperationID(id string) RouteOption {
+	return func(d *RouteDoc) {
+		d.OperationID = id
+	}
+}
+
+// Deprecated marks the route as deprecated
+func Deprecated() RouteOption {
+	return func(d *RouteDoc) {
+		d.Deprecated = true
+	}
+}
+
+// Hidden leaves the route out of the generated document
+func Hidden() RouteOption {
+	return func(d *RouteDoc) {
+		d.Hidden = true
+	}
+}
+
+// Accepts documents the JSON request body type
+func Accepts(body interface{}) RouteOption {
+	return func(d *RouteDoc) {
+		d.Request = body
+	}
+}
+
+// Returns documents a response status and the type of its data
+func Returns(status int, data interface{}) RouteOption {
+	return func(d *RouteDoc) {
+		if d.Responses == nil {
+			d.Responses = make(map[int]interface{})
+		}
+		d.Responses[status] = data
+	}
+}
+
+// QueryParam documents a query parameter
+func QueryParam(name, description string, required bool) RouteOption {
+	return func(d *RouteDoc) {
+		d.Parameters = append(d.Parameters, &Parameter{
+			Name:        name,
+			In:          "query",
+			Description: description,
+			Required:    required,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+}
+
+// HeaderParam documents a request header
+func HeaderParam(name, description string, required bool) RouteOption {
+	return func(d *RouteDoc) {
+		d.Parameters = append(d.Parameters, &Parameter{
+			Name:        name,
+			In:          "header",
+			Description: description,
+			Required:    required,
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+}
+
+// RouteInfo is a registered route
+type RouteInfo struct {
+	Method  string
+	Pattern string
+	Version string
+	Doc     RouteDoc
+}
+
+// routeRegistry records routes registered through Router
+type routeRegistry struct {
+	mu     sync.RWMutex
+	routes []*RouteInfo
+}
+
+func (rr *routeRegistry) add(info *RouteInfo) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.routes = append(rr.routes, info)
+}
+
+func (rr *routeRegistry) list() []*RouteInfo {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	return append([]*RouteInfo(nil), rr.routes...)
+}
+
+// Routes returns the routes registered through the API's routers
+func (api *API) Routes() []*RouteInfo {
+	return api.routes.list()
+}
+
+// OpenAPIConfig configures the generated document and where it is served
+type OpenAPIConfig struct {
+	Title       string
+	Description string
+	Version     string // Document version; defaults to APIConfig.Version
+	Servers     []string
+
+	// VersionPrefix is where the VersionRouter is mounted, e.g. "/api".
+	// Versioned routes are documented as <prefix>/<version><pattern>.
+	VersionPrefix string
+
+	Path     string // Path of the JSON document (default: /api/openapi.json)
+	DocsPath string // Path of the docs UI (default: /api/docs); "-" disables it
+
+	// DocsAssets are the Swagger UI files of the docs UI (default: the
+	// embedded copy served under DocsPath)
+	DocsAssets *DocsAssets
+}
+
+// SwaggerUIVersion is the Swagger UI release embedded for the docs UI
+const SwaggerUIVersion = "5.18.2"
+
+// swaggerUI holds swagger-ui.css and swagger-ui-bundle.js of
+// swagger-ui-dist SwaggerUIVersion (Apache License 2.0)
+//
+//go:embed swaggerui
+var swaggerUI embed.FS
+
+// DocsAssets locates the Swagger UI stylesheet and bundle. Set them to
+// load Swagger UI from elsewhere, e.g. a CDN; the integrity hashes have
+// browsers verify the files (subresource integrity).
+type DocsAssets struct {
+	CSS          string
+	JS           string
+	CSSIntegrity string // e.g. "sha384-..."
+	JSIntegrity  string
+}
+
+// DefaultDocsAssets returns the embedded Swagger UI files as ServeOpenAPI
+// serves them for a docs UI at docsPath, with their integrity hashes
+func DefaultDocsAssets(docsPath string) *DocsAssets {
+	base := strings.TrimSuffix(docsPath, "/") + "/assets/"
+	return &DocsAssets{
+		CSS:          base + "swagger-ui.css",
+		JS:           base + "swagger-ui-bundle.js",
+		CSSIntegrity: swaggerUIIntegrity("swagger-ui.css"),
+		JSIntegrity:  swaggerUIIntegrity("swagger-ui-bundle.js"),
+	}
+}
+
+// swaggerUIIntegrity returns the subresource integrity hash of an
+// embedded Swagger UI file
+func swaggerUIIntegrity(name string) string {
+	data, err := swaggerUI.ReadFile("swaggerui/" + name)
+	if err != nil {
+		panic("api: missing embedded Swagger UI file " + name)
+	}
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// DefaultOpenAPIConfig returns the default OpenAPI configuration
+func DefaultOpenAPIConfig() *OpenAPIConfig {
+	return &OpenAPIConfig{
+		Title:    "Tjo API",
+		Path:     "/api/openapi.json",
+		DocsPath: "/api/docs",
+	}
+}
+
+// OpenAPI is an OpenAPI 3.1 document
+type OpenAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+	Tags       []Tag                            `json:"tags,omitempty"`
+}
+
+// Info is the document metadata
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Components holds reusable schemas
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation documents a single method on a path
+type Operation struct {
+	Tags        []string                    `json:"tags,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	OperationID string                      `json:"operationId,omitempty"`
+	Parameters  []*Parameter                `json:"parameters,omitempty"`
+	RequestBody *RequestBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*OperationResult `json:"responses"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+}
+
+// Parameter documents a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody documents a request body
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// OperationResult documents a response of an operation
+type OperationResult struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// OpenAPI generates the document from the registered routes. If version is
+// not empty, only unversioned routes and routes of that version are included.
+func (api *API) OpenAPI(cfg *OpenAPIConfig, version string) *OpenAPI {
+	if cfg == nil {
+		cfg = DefaultOpenAPIConfig()
+	}
+
+	docVersion := cfg.Version
+	if docVersion == "" {
+		docVersion = version
+	}
+	if docVersion == "" {
+		docVersion = api.Config.Version
+	}
+
+	doc := &OpenAPI{
+		OpenAPI: OpenAPIVersion,
+		Info: Info{
+			Title:       cfg.Title,
+			Description: cfg.Description,
+			Version:     docVersion,
+		},
+		Paths: make(map[string]map[string]*Operation),
+	}
+	for _, url := range cfg.Servers {
+		doc.Servers = append(doc.Servers, Server{URL: url})
+	}
+
+	sr := newSchemaRegistry()
+	sr.schemas["ErrorResponse"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success":   {Type: "boolean"},
+			"error":     sr.schemaOf(ErrorInfo{}),
+			"meta":      sr.schemaOf(Meta{}),
+			"timestamp": {Type: "integer", Format: "int64"},
+		},
+		Required: []string{"success", "error", "timestamp"},
+	}
+	sr.schemaOf(Response{})
+
+	tags := make(map[string]bool)
+	for _, route := range api.routes.list() {
+		if route.Doc.Hidden {
+			continue
+		}
+		if version != "" && route.Version != "" && route.Version != version {
+			continue
+		}
+
+		path, params := openAPIPath(route.Pattern)
+		if route.Version != "" {
+			path = cfg.VersionPrefix + "/" + route.Version + path
+		}
+
+		op := api.operation(sr, route, params)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*Operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+
+		for _, tag := range op.Tags {
+			tags[tag] = true
+		}
+	}
+
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.Tags = append(doc.Tags, Tag{Name: name})
+	}
+
+	doc.Components.Schemas = sr.schemas
+	return doc
+}
+
+// operation builds the operation for a route
+func (api *API) operation(sr *schemaRegistry, route *RouteInfo, pathParams []string) *Operation {
+	d := route.Doc
+
+	op := &Operation{
+		Tags:        d.Tags,
+		Summary:     d.Summary,
+		Description: d.Description,
+		OperationID: d.OperationID,
+		Deprecated:  d.Deprecated,
+		Responses:   make(map[string]*OperationResult),
+	}
+
+	if route.Version != "" && api.VersionRouter != nil {
+		if _, deprecated := api.VersionRouter.config.Deprecated[route.Version]; deprecated {
+			op.Deprecated = true
+		}
+	}
+
+	documented := make(map[string]bool)
+	for _, p := range d.Parameters {
+		if p.In == "path" {
+			documented[p.Name] = true
+		}
+	}
+	for _, name := range pathParams {
+		if documented[name] {
+			continue
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	op.Parameters = append(op.Parameters, d.Parameters...)
+
+	if d.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: sr.schemaOf(d.Request)},
+			},
+		}
+	}
+
+	if len(d.Responses) == 0 {
+		op.Responses["200"] = &OperationResult{
+			Description: http.StatusText(http.StatusOK),
+			Content:     envelope(&Schema{}),
+		}
+	}
+	for status, data := range d.Responses {
+		result := &OperationResult{Description: http.StatusText(status)}
+		if data != nil && status != http.StatusNoContent {
+			result.Content = envelope(sr.schemaOf(data))
+		}
+		op.Responses[strconv.Itoa(status)] = result
+	}
+
+	op.Responses["default"] = &OperationResult{
+		Description: "Error",
+		Content: map[string]MediaType{
+			"application/json": {Schema: &Schema{Ref: "#/components/schemas/ErrorResponse"}},
+		},
+	}
+	if api.Config.ProblemDetails {
+		op.Responses["default"].Content = map[string]MediaType{
+			ProblemContentType: {Schema: sr.schemaOf(Problem{})},
+		}
+	}
+
+	return op
+}
+
+// envelope wraps a data schema in the standard Response envelope
+func envelope(data *Schema) map[string]MediaType {
+	return map[string]MediaType{
+		"application/json": {Schema: &Schema{
+			AllOf: []*Schema{
+				{Ref: "#/components/schemas/Response"},
+				{Type: "object", Properties: map[string]*Schema{"data": data}},
+			},
+		}},
+	}
+}
+
+// openAPIPath converts a chi pattern to an OpenAPI path and returns the
+// names of its parameters, e.g. "/users/{id:[0-9]+}" becomes "/users/{id}".
+func openAPIPath(pattern string) (string, []string) {
+	var b strings.Builder
+	var params []string
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '{' {
+			b.WriteByte(pattern[i])
+			continue
+		}
+
+		// Find the matching brace; regexps may contain braces themselves
+		depth, end := 0, -1
+		for j := i; j < len(pattern); j++ {
+			if pattern[j] == '{' {
+				depth++
+			} else if pattern[j] == '}' {
+				depth--
+				if depth == 0 {
+					end = j
+					break
+				}
+			}
+		}
+		if end < 0 {
+			b.WriteString(pattern[i:])
+			break
+		}
+
+		name, _, _ := strings.Cut(pattern[i+1:end], ":")
+		params = append(params, name)
+		b.WriteString("{" + name + "}")
+		i = end
+	}
+
+	path := strings.TrimSuffix(b.String(), "/")
+	if path == "" {
+		path = "/"
+	}
+	return path, params
+}
+
+// ServeOpenAPI serves the generated document and the docs UI on the API router.
+// The document is generated on each request so routes registered later are included.
+// Pass ?version=v2 to the document URL to restrict it to one API version.
+func (api *API) ServeOpenAPI(cfg *OpenAPIConfig) {
+	if cfg == nil {
+		cfg = DefaultOpenAPIConfig()
+	}
+	if cfg.Path == "" {
+		cfg.Path = DefaultOpenAPIConfig().Path
+	}
+
+	api.Router.Get(cfg.Path, func(w http.ResponseWriter, r *http.Request) {
+		doc := api.OpenAPI(cfg, normalizeVersionParam(r.URL.Query().Get("version")))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(doc)
+	})
+
+	if cfg.DocsPath == "-" {
+		return
+	}
+	if cfg.DocsPath == "" {
+		cfg.DocsPath = DefaultOpenAPIConfig().DocsPath
+	}
+
+	assets := cfg.DocsAssets
+	if assets == nil {
+		assets = DefaultDocsAssets(cfg.DocsPath)
+	}
+
+	files, _ := fs.Sub(swaggerUI, "swaggerui")
+	assetsPath := strings.TrimSuffix(cfg.DocsPath, "/") + "/assets/"
+	fileServer := http.StripPrefix(assetsPath, http.FileServer(http.FS(files)))
+	api.Router.Get(assetsPath+"*", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		fileServer.ServeHTTP(w, r)
+	})
+
+	api.Router.Get(cfg.DocsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		docsTemplate.Execute(w, map[string]interface{}{
+			"Title":  cfg.Title,
+			"URL":    cfg.Path,
+			"Assets": assets,
+		})
+	})
+}
+
+func normalizeVersionParam(version string) string {
+	if version == "" {
+		return ""
+	}
+	return normalizeVersion(version)
+}
+
+// docsTemplate renders Swagger UI for the document
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.Assets.CSS}}"{{with .Assets.CSSIntegrity}} integrity="{{.}}"{{end}} crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets.JS}}"{{with .Assets.JSIntegrity}} integrity="{{.}}"{{end}} crossorigin="anonymous"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({ url: {{.URL}}, dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`))
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type docUser struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name" description:"Full name"`
+	Email     string    `json:"email,omitempty"`
+	Tags      []string  `json:"tags"`
+	Manager   *docUser  `json:"manager,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	password  string
+	Internal  string `json:"-"`
+}
+
+type docCreateUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		params  []string
+	}{
+		{"/users", "/users", nil},
+		{"/users/", "/users", nil},
+		{"/", "/", nil},
+		{"/users/{id}", "/users/{id}", []string{"id"}},
+		{"/users/{id:[0-9]+}/posts/{slug}", "/users/{id}/posts/{slug}", []string{"id", "slug"}},
+		{"/codes/{code:[A-Z]{3}}", "/codes/{code}", []string{"code"}},
+	}
+
+	for _, tt := range tests {
+		path, params := openAPIPath(tt.pattern)
+		if path != tt.path {
+			t.Errorf("openAPIPath(%q) path = %q, want %q", tt.pattern, path, tt.path)
+		}
+		if strings.Join(params, ",") != strings.Join(tt.params, ",") {
+			t.Errorf("openAPIPath(%q) params = %v, want %v", tt.pattern, params, tt.params)
+		}
+	}
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	sr := newSchemaRegistry()
+
+	ref := sr.schemaOf(&docUser{})
+	if ref.Ref != "#/components/schemas/docUser" {
+		t.Fatalf("schemaOf() ref = %q", ref.Ref)
+	}
+
+	s := sr.schemas["docUser"]
+	if s == nil || s.Type != "object" {
+		t.Fatalf("docUser component = %+v", s)
+	}
+
+	expected := map[string]string{
+		"id":         "integer",
+		"name":       "string",
+		"email":      "string",
+		"tags":       "array",
+		"created_at": "string",
+	}
+	for name, typ := range expected {
+		if s.Properties[name] == nil || s.Properties[name].Type != typ {
+			t.Errorf("property %s = %+v, want type %s", name, s.Properties[name], typ)
+		}
+	}
+	if s.Properties["name"].Description != "Full name" {
+		t.Errorf("name description = %q", s.Properties["name"].Description)
+	}
+	if s.Properties["created_at"].Format != "date-time" {
+		t.Errorf("created_at format = %q", s.Properties["created_at"].Format)
+	}
+	if s.Properties["manager"].Ref != ref.Ref {
+		t.Errorf("recursive manager property = %+v", s.Properties["manager"])
+	}
+	if _, ok := s.Properties["password"]; ok {
+		t.Error("unexported fields should not be documented")
+	}
+	if _, ok := s.Properties["Internal"]; ok {
+		t.Error(`fields tagged json:"-" should not be documented`)
+	}
+}
+
+func TestAPIOpenAPI(t *testing.T) {
+	a := New(nil)
+	a.VersionRouter.DeprecateVersion("v1", "2030-01-01")
+
+	users := a.Group("/admin")
+	users.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {},
+		Summary("Get a user"),
+		Tags("users"),
+		Returns(http.StatusOK, docUser{}),
+	)
+	users.Post("/users", func(w http.ResponseWriter, r *http.Request) {},
+		Tags("users"),
+		Accepts(docCreateUser{}),
+		Returns(http.StatusCreated, docUser{}),
+	)
+	users.Delete("/users/{id}", func(w http.ResponseWriter, r *http.Request) {},
+		Returns(http.StatusNoContent, nil),
+	)
+	users.Get("/internal", func(w http.ResponseWriter, r *http.Request) {}, Hidden())
+
+	a.Version("v2").Get("/users", func(w http.ResponseWriter, r *http.Request) {},
+		Returns(http.StatusOK, []docUser{}),
+	)
+	a.Version("v1").Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	doc := a.OpenAPI(&OpenAPIConfig{Title: "Test", VersionPrefix: "/api"}, "")
+
+	if doc.OpenAPI != OpenAPIVersion {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+	if doc.Info.Version != "v1" {
+		t.Errorf("info.version = %q, want v1", doc.Info.Version)
+	}
+
+	get := doc.Paths["/admin/users/{id}"]["get"]
+	if get == nil {
+		t.Fatalf("missing GET /admin/users/{id}: %v", doc.Paths)
+	}
+	if get.Summary != "Get a user" || len(get.Tags) != 1 || get.Tags[0] != "users" {
+		t.Errorf("GET operation = %+v", get)
+	}
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].In != "path" {
+		t.Errorf("GET parameters = %+v", get.Parameters)
+	}
+	if get.Responses["200"] == nil || get.Responses["default"] == nil {
+		t.Errorf("GET responses = %+v", get.Responses)
+	}
+
+	post := doc.Paths["/admin/users"]["post"]
+	if post == nil || post.RequestBody == nil {
+		t.Fatalf("POST /admin/users missing request body: %+v", post)
+	}
+	if ref := post.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/docCreateUser" {
+		t.Errorf("request body ref = %q", ref)
+	}
+
+	del := doc.Paths["/admin/users/{id}"]["delete"]
+	if del == nil || del.Responses["204"] == nil || del.Responses["204"].Content != nil {
+		t.Errorf("DELETE 204 should have no content: %+v", del)
+	}
+
+	if _, ok := doc.Paths["/admin/internal"]; ok {
+		t.Error("hidden routes should not be documented")
+	}
+
+	if doc.Paths["/api/v2/users"]["get"] == nil {
+		t.Errorf("missing versioned path /api/v2/users: %v", doc.Paths)
+	}
+	if v1 := doc.Paths["/api/v1/users"]["get"]; v1 == nil || !v1.Deprecated {
+		t.Errorf("routes of deprecated versions should be deprecated: %+v", v1)
+	}
+
+	for _, name := range []string{"Response", "ErrorInfo", "Meta", "ErrorResponse", "docUser", "docCreateUser"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("missing component schema %s", name)
+		}
+	}
+
+	// Filtering by version keeps unversioned routes
+	v2 := a.OpenAPI(&OpenAPIConfig{VersionPrefix: "/api"}, "v2")
+	if _, ok := v2.Paths["/api/v1/users"]; ok {
+		t.Error("v2 document should not contain v1 routes")
+	}
+	if _, ok := v2.Paths["/admin/users"]; !ok {
+		t.Error("v2 document should contain unversioned routes")
+	}
+	if v2.Info.Version != "v2" {
+		t.Errorf("v2 info.version = %q", v2.Info.Version)
+	}
+}
+
+func TestRouterResourceDocumented(t *testing.T) {
+	a := New(nil)
+	a.Group("/api").Resource("/posts", &testResourceController{})
+
+	doc := a.OpenAPI(nil, "")
+	for path, methods := range map[string][]string{
+		"/api/posts":      {"get", "post"},
+		"/api/posts/{id}": {"get", "put", "delete"},
+	} {
+		for _, m := range methods {
+			op := doc.Paths[path][m]
+			if op == nil {
+				t.Errorf("missing %s %s", m, path)
+				continue
+			}
+			if len(op.Tags) != 1 || op.Tags[0] != "posts" {
+				t.Errorf("%s %s tags = %v", m, path, op.Tags)
+			}
+		}
+	}
+}
+
+func TestRouterResourceBodyDocumented(t *testing.T) {
+	a := New(nil)
+	a.Group("/api").Resource("/posts", &testResourceController{}, Tags("posts"), Accepts(docCreateUser{}))
+
+	doc := a.OpenAPI(nil, "")
+	for _, tt := range []struct {
+		path, method string
+		body         bool
+	}{
+		{"/api/posts", "get", false},
+		{"/api/posts", "post", true},
+		{"/api/posts/{id}", "get", false},
+		{"/api/posts/{id}", "put", true},
+		{"/api/posts/{id}", "delete", false},
+	} {
+		if got := doc.Paths[tt.path][tt.method].RequestBody != nil; got != tt.body {
+			t.Errorf("%s %s documents a body = %v, want %v", tt.method, tt.path, got, tt.body)
+		}
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	a := New(nil)
+	a.ServeOpenAPI(nil)
+	a.Group("").Get("/ping", func(w http.ResponseWriter, r *http.Request) {}, Summary("Ping"))
+
+	req := httptest.NewRequest("GET", "/api/openapi.json", nil)
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc["openapi"] != OpenAPIVersion {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+	if _, ok := doc["paths"].(map[string]interface{})["/ping"]; !ok {
+		t.Error("routes registered after ServeOpenAPI should be documented")
+	}
+
+	req = httptest.NewRequest("GET", "/api/docs", nil)
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/api/openapi.json") {
+		t.Errorf("docs UI status = %d, body = %s", w.Code, w.Body.String())
+	}
+	assets := DefaultDocsAssets("/api/docs")
+	page := html.UnescapeString(w.Body.String())
+	for _, want := range []string{
+		`href="/api/docs/assets/swagger-ui.css" integrity="` + assets.CSSIntegrity + `"`,
+		`src="/api/docs/assets/swagger-ui-bundle.js" integrity="` + assets.JSIntegrity + `"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("docs UI should load the embedded Swagger UI with %s, body = %s", want, w.Body.String())
+		}
+	}
+	if strings.Contains(w.Body.String(), "https://") {
+		t.Errorf("docs UI should not load third-party files, body = %s", w.Body.String())
+	}
+
+	for _, tt := range []struct{ path, contentType, integrity string }{
+		{"/api/docs/assets/swagger-ui.css", "text/css", assets.CSSIntegrity},
+		{"/api/docs/assets/swagger-ui-bundle.js", "javascript", assets.JSIntegrity},
+	} {
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Type"), tt.contentType) {
+			t.Errorf("%s: status = %d, Content-Type = %q", tt.path, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		sum := sha512.Sum384(w.Body.Bytes())
+		if got := "sha384-" + base64.StdEncoding.EncodeToString(sum[:]); got != tt.integrity {
+			t.Errorf("%s: integrity = %s, want %s", tt.path, got, tt.integrity)
+		}
+	}
+}
+
+func TestServeOpenAPIDocsAssets(t *testing.T) {
+	a := New(nil)
+	a.ServeOpenAPI(&OpenAPIConfig{
+		Path:     "/api/openapi.json",
+		DocsPath: "/api/docs",
+		DocsAssets: &DocsAssets{
+			CSS:          "/static/swagger-ui.css",
+			JS:           "/static/swagger-ui-bundle.js",
+			CSSIntegrity: "sha384-css",
+			JSIntegrity:  "sha384-js",
+		},
+	})
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`href="/static/swagger-ui.css" integrity="sha384-css"`,
+		`src="/static/swagger-ui-bundle.js" integrity="sha384-js"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("docs UI should contain %s, body = %s", want, body)
+		}
+	}
+}
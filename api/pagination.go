@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CursorParam is the query parameter carrying the pagination cursor
+const CursorParam = "cursor"
+
+// CursorPaginated sends a 200 response for a page of a cursor paginated
+// list. The cursors are added to the metadata and as Link headers with
+// rel="next" and rel="prev" pointing at the current URL with the cursor
+// parameter replaced. Empty cursors are left out.
+//
+//	page, err := query.CursorPaginate(api.Query(r, api.CursorParam), 20)
+//	...
+//	api.CursorPaginated(w, r, page.Rows, page.NextCursor, page.PrevCursor)
+func CursorPaginated(w http.ResponseWriter, r *http.Request, data interface{}, next, prev string, opts ...ResponseOption) error {
+	SetCursorLinks(w, r, next, prev)
+	opts = append(opts, WithCursors(next, prev))
+	return JSON(w, http.StatusOK, data, opts...)
+}
+
+// SetCursorLinks adds Link headers for the next and previous pages
+func SetCursorLinks(w http.ResponseWriter, r *http.Request, next, prev string) {
+	var links []string
+	if next != "" {
+		links = append(links, "<"+cursorURL(r, next)+`>; rel="next"`)
+	}
+	if prev != "" {
+		links = append(links, "<"+cursorURL(r, prev)+`>; rel="prev"`)
+	}
+	if len(links) > 0 {
+		w.Header().Add("Link", strings.Join(links, ", "))
+	}
+}
+
+// cursorURL returns the request URL with the cursor parameter replaced
+func cursorURL(r *http.Request, cursor string) string {
+	u := *r.URL
+	q := u.Query()
+	q.Set(CursorParam, cursor)
+	u.RawQuery = q.Encode()
+	u.Scheme, u.Host, u.User = "", "", nil
+	return u.String()
+}
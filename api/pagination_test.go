@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCursorPaginated(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/api/posts?status=active&cursor=abc", nil)
+	w := httptest.NewRecorder()
+
+	CursorPaginated(w, r, []string{"a", "b"}, "next+1", "prev")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	link := w.Header().Get("Link")
+	want := []string{
+		`</api/posts?cursor=next%2B1&status=active>; rel="next"`,
+		`</api/posts?cursor=prev&status=active>; rel="prev"`,
+	}
+	for _, l := range want {
+		if !strings.Contains(link, l) {
+			t.Errorf("Link = %q, want %q", link, l)
+		}
+	}
+
+	resp := decodeResponse(t, w)
+	if resp.Meta == nil || resp.Meta.NextCursor != "next+1" || resp.Meta.PrevCursor != "prev" {
+		t.Errorf("meta = %+v", resp.Meta)
+	}
+}
+
+func TestCursorPaginatedLastPage(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/posts", nil)
+	w := httptest.NewRecorder()
+
+	CursorPaginated(w, r, []string{}, "", "")
+
+	if link := w.Header().Get("Link"); link != "" {
+		t.Errorf("Link = %q, want none", link)
+	}
+	if resp := decodeResponse(t, w); resp.Meta != nil && resp.Meta.NextCursor != "" {
+		t.Errorf("meta = %+v", resp.Meta)
+	}
+}
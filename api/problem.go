@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document. Extensions are added
+// as top-level members.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    []ValidationError `json:"errors,omitempty"`
+
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON adds the extension members to the document
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	data, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	members := make(map[string]interface{}, len(p.Extensions)+8)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	// Standard members win over extensions with the same name
+	var std map[string]interface{}
+	if err := json.Unmarshal(data, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		members[k] = v
+	}
+	return json.Marshal(members)
+}
+
+// WriteProblem sends a problem document
+func WriteProblem(w http.ResponseWriter, p *Problem) error {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// ProblemJSON makes the error helpers (Error, ValidationErrors, NotFound,
+// WriteError, ...) send RFC 7807 problem documents instead of the
+// envelope for requests passing through it. It is installed by New when
+// APIConfig.ProblemDetails is set.
+//
+// The problem type is typeBase followed by the error code in lower case,
+// e.g. https://example.com/problems/not-found, or about:blank if typeBase
+// is empty. The error code is also sent in the code member.
+func ProblemJSON(typeBase string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&problemWriter{
+				ResponseWriter: w,
+				typeBase:       typeBase,
+				instance:       r.URL.Path,
+			}, r)
+		})
+	}
+}
+
+// problemWriter marks a response as using problem documents
+type problemWriter struct {
+	http.ResponseWriter
+	typeBase string
+	instance string
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (pw *problemWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// Flush implements http.Flusher for streaming responses
+func (pw *problemWriter) Flush() {
+	if f, ok := pw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for WebSocket upgrades
+func (pw *problemWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := pw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("api: response writer does not support hijacking")
+}
+
+// problemMode finds the problemWriter wrapping w, if any
+func problemMode(w http.ResponseWriter) *problemWriter {
+	for w != nil {
+		if pw, ok := w.(*problemWriter); ok {
+			return pw
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
+
+// newProblem converts an error response to a problem document. Validation
+// errors become the errors member and other details extension members.
+func (pw *problemWriter) newProblem(status int, response *Response) *Problem {
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: pw.instance,
+	}
+	if response.Meta != nil {
+		p.RequestID = response.Meta.RequestID
+	}
+
+	info := response.Error
+	if info == nil {
+		return p
+	}
+
+	p.Code = info.Code
+	p.Detail = info.Message
+	if pw.typeBase != "" && info.Code != "" {
+		p.Type = pw.typeBase + strings.ToLower(strings.ReplaceAll(info.Code, "_", "-"))
+	}
+
+	for k, v := range info.Details {
+		if errs, ok := v.([]ValidationError); ok && k == "validation_errors" {
+			p.Errors = errs
+			continue
+		}
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]interface{})
+		}
+		p.Extensions[k] = v
+	}
+
+	return p
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jimmitjoo/tjo"
+)
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ProblemContentType)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid problem %q: %v", w.Body.String(), err)
+	}
+	return doc
+}
+
+func TestProblemDetailsMode(t *testing.T) {
+	a := New(&APIConfig{Version: "v1", ProblemDetails: true, ProblemTypeBase: "https://example.com/problems/"})
+	a.SetupRoutes()
+
+	users := a.Group("/api/users")
+	Get(users, "/{id}", func(ctx context.Context, req struct {
+		ID int64 `path:"id"`
+	}) (*userResponse, error) {
+		return nil, tjo.NewError("users.find", "user not found", tjo.ErrNotFound)
+	})
+	Post(users, "/", func(ctx context.Context, req struct {
+		Name string `json:"name" validate:"required"`
+	}) (*userResponse, error) {
+		return &userResponse{}, nil
+	})
+	users.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	t.Run("TjoError", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest("GET", "/api/users/7", nil))
+
+		doc := decodeProblem(t, w)
+		if w.Code != http.StatusNotFound || doc["status"].(float64) != 404 {
+			t.Errorf("status = %d, doc = %v", w.Code, doc)
+		}
+		if doc["type"] != "https://example.com/problems/not-found" || doc["title"] != "Not Found" ||
+			doc["detail"] != "Not Found" || doc["instance"] != "/api/users/7" || doc["code"] != "NOT_FOUND" {
+			t.Errorf("doc = %v", doc)
+		}
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/api/users/", nil)
+		r.Header.Set("Content-Type", "application/json")
+		a.ServeHTTP(w, r)
+
+		doc := decodeProblem(t, w)
+		errs, _ := doc["errors"].([]interface{})
+		if w.Code != http.StatusBadRequest || len(errs) != 1 || errs[0].(map[string]interface{})["field"] != "name" {
+			t.Errorf("status = %d, doc = %v", w.Code, doc)
+		}
+		if _, ok := doc["validation_errors"]; ok {
+			t.Error("validation errors should only be in errors")
+		}
+	})
+
+	t.Run("router errors and panics", func(t *testing.T) {
+		for _, tt := range []struct {
+			method, path string
+			status       int
+		}{
+			{"GET", "/api/missing", http.StatusNotFound},
+			{"DELETE", "/api/health", http.StatusMethodNotAllowed},
+			{"GET", "/api/users/panic", http.StatusInternalServerError},
+		} {
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			doc := decodeProblem(t, w)
+			if w.Code != tt.status || doc["status"].(float64) != float64(tt.status) {
+				t.Errorf("%s %s: status = %d, doc = %v", tt.method, tt.path, w.Code, doc)
+			}
+		}
+	})
+
+	t.Run("success responses keep the envelope", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest("GET", "/api/health", nil))
+		if resp := decodeResponse(t, w); !resp.Success {
+			t.Errorf("response = %+v", resp)
+		}
+	})
+
+	t.Run("documented", func(t *testing.T) {
+		op := a.OpenAPI(nil, "").Paths["/api/users/{id}"]["get"]
+		if _, ok := op.Responses["default"].Content[ProblemContentType]; !ok {
+			t.Errorf("default response = %+v", op.Responses["default"])
+		}
+	})
+}
+
+func TestProblemExtensions(t *testing.T) {
+	h := ProblemJSON("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, http.StatusConflict, "CONFLICT", "Version mismatch",
+			map[string]interface{}{"current_version": 3, "status": "ignored"}, WithRequestID("req-1"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/api/docs/1", nil))
+
+	doc := decodeProblem(t, w)
+	if doc["type"] != "about:blank" || doc["title"] != "Conflict" || doc["request_id"] != "req-1" {
+		t.Errorf("doc = %v", doc)
+	}
+	if doc["current_version"].(float64) != 3 || doc["status"].(float64) != 409 {
+		t.Errorf("extensions = %v", doc)
+	}
+}
+
+func TestErrorWithoutProblemMode(t *testing.T) {
+	w := httptest.NewRecorder()
+	NotFound(w, "")
+	if resp := decodeResponse(t, w); resp.Error == nil || resp.Error.Code != "NOT_FOUND" {
+		t.Errorf("response = %+v", resp)
+	}
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// DefaultRateLimitPrefix is the Redis key prefix of the Redis rate limiters
+const DefaultRateLimitPrefix = "ratelimit:"
+
+// redisNowMs reads the Redis server clock in milliseconds, so instances
+// with skewed clocks share one timeline. Scripts reading it replicate
+// their effects, which Redis 5 and later do by default.
+const redisNowMs = `
+redis.replicate_commands()
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+`
+
+// tokenBucketScript refills and takes a token atomically. Tokens refill
+// continuously at ARGV[2] tokens per millisecond up to ARGV[1].
+// Returns {allowed, remaining, ms until full, ms until next token}.
+var tokenBucketScript = redis.NewScript(1, redisNowMs+`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+
+local full = math.ceil((capacity - tokens) / rate)
+redis.call('PEXPIRE', KEYS[1], full + 1000)
+
+local wait = 0
+if allowed == 0 then
+  wait = math.ceil((1 - tokens) / rate)
+end
+
+return {allowed, math.floor(tokens), full, wait}
+`)
+
+// slidingWindowScript keeps a log of request timestamps in a sorted set.
+// Returns {allowed, remaining, ms until reset}.
+var slidingWindowScript = redis.NewScript(1, redisNowMs+`
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[3])
+  count = count + 1
+  allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+
+local reset = now + window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] ~= nil then
+  reset = tonumber(oldest[2]) + window
+end
+
+return {allowed, limit - count, reset - now}
+`)
+
+// RedisTokenBucket is a token bucket shared by all instances through Redis.
+// Tokens refill continuously at rate per interval up to capacity.
+// When Redis is unavailable it falls back to a local TokenBucket.
+type RedisTokenBucket struct {
+	Pool     *redis.Pool
+	Prefix   string
+	Fallback RateLimiter
+	OnError  func(error) // Called when Redis fails and the fallback is used
+
+	rate     int
+	capacity int
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewRedisTokenBucket creates a Redis token bucket rate limiter. It panics
+// unless rate and interval are positive.
+func NewRedisTokenBucket(pool *redis.Pool, rate, capacity int, interval time.Duration) *RedisTokenBucket {
+	if rate <= 0 || interval <= 0 {
+		panic("api: NewRedisTokenBucket requires a positive rate and interval")
+	}
+	return &RedisTokenBucket{
+		Pool:     pool,
+		Prefix:   DefaultRateLimitPrefix + "tb:",
+		Fallback: NewTokenBucket(rate, capacity, interval),
+		rate:     rate,
+		capacity: capacity,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Allow checks if request is allowed
+func (tb *RedisTokenBucket) Allow(key string) (bool, *RateLimitInfo) {
+	now := tb.now()
+	perMs := float64(tb.rate) / (float64(tb.interval) / float64(time.Millisecond))
+
+	conn := tb.Pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Int64s(tokenBucketScript.Do(conn,
+		tb.Prefix+key,
+		tb.capacity,
+		strconv.FormatFloat(perMs, 'g', -1, 64),
+	))
+	if err != nil || len(values) != 4 {
+		return fallbackAllow(tb.Fallback, tb.OnError, key, err)
+	}
+
+	allowed := values[0] == 1
+	info := &RateLimitInfo{
+		Limit:     tb.capacity,
+		Remaining: int(values[1]),
+		Reset:     now.Add(time.Duration(values[2]) * time.Millisecond),
+	}
+	if !allowed {
+		info.RetryAfter = retryAfterSeconds(time.Duration(values[3]) * time.Millisecond)
+	}
+
+	return allowed, info
+}
+
+// Reset resets the bucket for a key
+func (tb *RedisTokenBucket) Reset(key string) {
+	resetKey(tb.Pool, tb.Prefix+key)
+	if tb.Fallback != nil {
+		tb.Fallback.Reset(key)
+	}
+}
+
+// Stop stops the fallback limiter's cleanup routine
+func (tb *RedisTokenBucket) Stop() {
+	stopLimiter(tb.Fallback)
+}
+
+// RedisSlidingWindow is a sliding window log limiter shared by all instances
+// through Redis: at most limit requests in any window of duration.
+// When Redis is unavailable it falls back to a local SlidingWindow.
+type RedisSlidingWindow struct {
+	Pool     *redis.Pool
+	Prefix   string
+	Fallback RateLimiter
+	OnError  func(error) // Called when Redis fails and the fallback is used
+
+	limit    int
+	duration time.Duration
+	now      func() time.Time
+}
+
+// NewRedisSlidingWindow creates a Redis sliding window rate limiter. It
+// panics unless duration is at least a millisecond, the resolution of the
+// window in Redis.
+func NewRedisSlidingWindow(pool *redis.Pool, limit int, duration time.Duration) *RedisSlidingWindow {
+	if duration < time.Millisecond {
+		panic("api: NewRedisSlidingWindow requires a duration of at least 1ms")
+	}
+	return &RedisSlidingWindow{
+		Pool:     pool,
+		Prefix:   DefaultRateLimitPrefix + "sw:",
+		Fallback: NewSlidingWindow(limit, duration),
+		limit:    limit,
+		duration: duration,
+		now:      time.Now,
+	}
+}
+
+// Allow checks if request is allowed
+func (sw *RedisSlidingWindow) Allow(key string) (bool, *RateLimitInfo) {
+	now := sw.now()
+
+	conn := sw.Pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Int64s(slidingWindowScript.Do(conn,
+		sw.Prefix+key,
+		sw.duration.Milliseconds(),
+		sw.limit,
+		requestMember(now),
+	))
+	if err != nil || len(values) != 3 {
+		return fallbackAllow(sw.Fallback, sw.OnError, key, err)
+	}
+
+	allowed := values[0] == 1
+	untilReset := time.Duration(values[2]) * time.Millisecond
+	info := &RateLimitInfo{
+		Limit:     sw.limit,
+		Remaining: int(values[1]),
+		Reset:     now.Add(untilReset),
+	}
+	if !allowed {
+		info.RetryAfter = retryAfterSeconds(untilReset)
+	}
+
+	return allowed, info
+}
+
+// Reset resets the window for a key
+func (sw *RedisSlidingWindow) Reset(key string) {
+	resetKey(sw.Pool, sw.Prefix+key)
+	if sw.Fallback != nil {
+		sw.Fallback.Reset(key)
+	}
+}
+
+// Stop stops the fallback limiter's cleanup routine
+func (sw *RedisSlidingWindow) Stop() {
+	stopLimiter(sw.Fallback)
+}
+
+// fallbackAllow uses the local limiter when Redis fails. Without a
+// fallback the request is allowed rather than failing the API.
+func fallbackAllow(fallback RateLimiter, onError func(error), key string, err error) (bool, *RateLimitInfo) {
+	if err == nil {
+		err = redis.ErrNil
+	}
+	if onError != nil {
+		onError(err)
+	}
+
+	if fallback != nil {
+		return fallback.Allow(key)
+	}
+	return true, &RateLimitInfo{Reset: time.Now()}
+}
+
+func resetKey(pool *redis.Pool, key string) {
+	conn := pool.Get()
+	defer conn.Close()
+	conn.Do("DEL", key)
+}
+
+func stopLimiter(limiter RateLimiter) {
+	if s, ok := limiter.(interface{ Stop() }); ok {
+		s.Stop()
+	}
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// requestMember returns a unique sorted set member for a request
+func requestMember(now time.Time) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return strconv.FormatInt(now.UnixNano(), 10) + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func newTestRedisPool(t *testing.T) (*miniredis.Miniredis, *redis.Pool) {
+	t.Helper()
+
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	addr := s.Addr()
+	pool := &redis.Pool{
+		MaxIdle: 2,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	return s, pool
+}
+
+// fakeClock drives both the limiter's clock and the Redis server's, which
+// the scripts read
+type fakeClock struct {
+	t      time.Time
+	server *miniredis.Miniredis
+}
+
+func newFakeClock(server *miniredis.Miniredis) *fakeClock {
+	c := &fakeClock{t: time.Unix(1700000000, 0), server: server}
+	server.SetTime(c.t)
+	return c
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+	c.server.SetTime(c.t)
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	s, pool := newTestRedisPool(t)
+	clock := newFakeClock(s)
+
+	tb := NewRedisTokenBucket(pool, 2, 3, time.Second)
+	defer tb.Stop()
+	tb.now = clock.now
+
+	for i := 0; i < 3; i++ {
+		allowed, info := tb.Allow("client")
+		if !allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+		if info.Remaining != 2-i {
+			t.Errorf("request %d remaining = %d, want %d", i+1, info.Remaining, 2-i)
+		}
+	}
+
+	allowed, info := tb.Allow("client")
+	if allowed {
+		t.Fatal("request over capacity should be denied")
+	}
+	if info.Limit != 3 || info.RetryAfter != 1 {
+		t.Errorf("info = %+v", info)
+	}
+
+	// Other keys have their own bucket
+	if allowed, _ := tb.Allow("other"); !allowed {
+		t.Error("other key should be allowed")
+	}
+
+	// 2 tokens per second refill continuously
+	clock.advance(500 * time.Millisecond)
+	if allowed, _ := tb.Allow("client"); !allowed {
+		t.Error("one token should have been refilled after 500ms")
+	}
+	if allowed, _ := tb.Allow("client"); allowed {
+		t.Error("bucket should be empty again")
+	}
+
+	tb.Reset("client")
+	if allowed, info := tb.Allow("client"); !allowed || info.Remaining != 2 {
+		t.Errorf("after reset allowed = %v, info = %+v", allowed, info)
+	}
+}
+
+func TestRedisTokenBucketShared(t *testing.T) {
+	_, pool := newTestRedisPool(t)
+
+	// Two replicas share the same limit
+	a := NewRedisTokenBucket(pool, 1, 2, time.Minute)
+	b := NewRedisTokenBucket(pool, 1, 2, time.Minute)
+	defer a.Stop()
+	defer b.Stop()
+
+	if ok, _ := a.Allow("k"); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	if ok, _ := b.Allow("k"); !ok {
+		t.Fatal("second request should be allowed")
+	}
+	if ok, _ := a.Allow("k"); ok {
+		t.Error("third request should be denied across instances")
+	}
+}
+
+func TestRedisTokenBucketClockSkew(t *testing.T) {
+	s, pool := newTestRedisPool(t)
+	newFakeClock(s)
+
+	a := NewRedisTokenBucket(pool, 1, 1, time.Minute)
+	b := NewRedisTokenBucket(pool, 1, 1, time.Minute)
+	defer a.Stop()
+	defer b.Stop()
+
+	// b's clock runs an hour ahead, which must not refill the bucket
+	b.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	if ok, _ := a.Allow("k"); !ok {
+		t.Fatal("first request should be allowed")
+	}
+	if ok, _ := b.Allow("k"); ok {
+		t.Error("a skewed instance clock should not refill the bucket")
+	}
+}
+
+func TestNewRedisTokenBucketInvalidRate(t *testing.T) {
+	for _, tt := range []struct {
+		rate     int
+		interval time.Duration
+	}{
+		{0, time.Second},
+		{-1, time.Second},
+		{1, 0},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRedisTokenBucket(%d, %v) should panic", tt.rate, tt.interval)
+				}
+			}()
+			NewRedisTokenBucket(nil, tt.rate, 1, tt.interval)
+		}()
+	}
+}
+
+func TestNewRedisSlidingWindowInvalidDuration(t *testing.T) {
+	for _, duration := range []time.Duration{0, -time.Second, time.Microsecond} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRedisSlidingWindow(%v) should panic", duration)
+				}
+			}()
+			NewRedisSlidingWindow(nil, 1, duration)
+		}()
+	}
+}
+
+func TestRedisSlidingWindow(t *testing.T) {
+	s, pool := newTestRedisPool(t)
+	clock := newFakeClock(s)
+
+	sw := NewRedisSlidingWindow(pool, 2, time.Minute)
+	defer sw.Stop()
+	sw.now = clock.now
+
+	if ok, info := sw.Allow("client"); !ok || info.Remaining != 1 {
+		t.Fatalf("first request: allowed = %v, info = %+v", ok, info)
+	}
+
+	clock.advance(30 * time.Second)
+	if ok, _ := sw.Allow("client"); !ok {
+		t.Fatal("second request should be allowed")
+	}
+
+	ok, info := sw.Allow("client")
+	if ok {
+		t.Fatal("third request should be denied")
+	}
+	if info.RetryAfter != 30 {
+		t.Errorf("RetryAfter = %d, want 30", info.RetryAfter)
+	}
+
+	// The first request leaves the window
+	clock.advance(31 * time.Second)
+	if ok, info := sw.Allow("client"); !ok || info.Remaining != 0 {
+		t.Errorf("after window: allowed = %v, info = %+v", ok, info)
+	}
+
+	sw.Reset("client")
+	if ok, _ := sw.Allow("client"); !ok {
+		t.Error("request after reset should be allowed")
+	}
+}
+
+func TestRedisRateLimiterFallback(t *testing.T) {
+	s, pool := newTestRedisPool(t)
+	s.Close()
+
+	var redisErr error
+	sw := NewRedisSlidingWindow(pool, 1, time.Minute)
+	defer sw.Stop()
+	sw.OnError = func(err error) { redisErr = err }
+
+	if ok, _ := sw.Allow("client"); !ok {
+		t.Fatal("fallback should allow the first request")
+	}
+	if ok, _ := sw.Allow("client"); ok {
+		t.Error("fallback should enforce the limit locally")
+	}
+	if redisErr == nil {
+		t.Error("OnError should be called when Redis is unavailable")
+	}
+
+	tb := NewRedisTokenBucket(pool, 1, 1, time.Minute)
+	defer tb.Stop()
+	tb.Fallback = nil
+
+	if ok, _ := tb.Allow("client"); !ok {
+		t.Error("without a fallback requests should be allowed")
+	}
+}
+
+func TestFallbackAllowNilError(t *testing.T) {
+	var got error
+	fallbackAllow(nil, func(err error) { got = err }, "k", nil)
+	if !errors.Is(got, redis.ErrNil) {
+		t.Errorf("error = %v", got)
+	}
+}
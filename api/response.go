@@ -29,6 +29,8 @@ type Meta struct {
 	PerPage    int    `json:"per_page,omitempty" xml:"per_page,omitempty"`
 	Total      int    `json:"total,omitempty" xml:"total,omitempty"`
 	TotalPages int    `json:"total_pages,omitempty" xml:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty" xml:"prev_cursor,omitempty"`
 	Version    string `json:"version,omitempty" xml:"version,omitempty"`
 	RequestID  string `json:"request_id,omitempty" xml:"request_id,omitempty"`
 }
@@ -65,6 +67,18 @@ func WithPagination(page, perPage, total int) ResponseOption {
 	}
 }
 
+// WithCursors adds cursor pagination metadata. Use CursorPaginated to
+// also send the matching Link header.
+func WithCursors(next, prev string) ResponseOption {
+	return func(r *Response) {
+		if r.Meta == nil {
+			r.Meta = &Meta{}
+		}
+		r.Meta.NextCursor = next
+		r.Meta.PrevCursor = prev
+	}
+}
+
 // WithRequestID adds request ID to metadata
 func WithRequestID(requestID string) ResponseOption {
 	return func(r *Response) {
@@ -135,6 +149,11 @@ func Error(w http.ResponseWriter, status int, code, message string, details map[
 		opt(response)
 	}
 	
+	// RFC 7807 problem documents when enabled with ProblemJSON
+	if pw := problemMode(w); pw != nil {
+		return WriteProblem(w, pw.newProblem(status, response))
+	}
+	
 	// Check Accept header for response format
 	accept := w.Header().Get("Accept")
 	if accept == "application/xml" {
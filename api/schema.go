@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	byteSliceType = reflect.TypeOf([]byte{})
+)
+
+// schemaRegistry converts Go types to schemas. Named struct types are
+// stored once under components/schemas and referenced with $ref.
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema for the type of v. v may be a value,
+// a pointer or a reflect.Type.
+func (sr *schemaRegistry) schemaOf(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	if t, ok := v.(reflect.Type); ok {
+		return sr.schema(t)
+	}
+	return sr.schema(reflect.TypeOf(v))
+}
+
+func (sr *schemaRegistry) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawJSONType:
+		return &Schema{}
+	case byteSliceType:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: sr.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: sr.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return sr.structSchema(t)
+		}
+		return sr.ref(t)
+	default:
+		// interface{} and anything else accepts any value
+		return &Schema{}
+	}
+}
+
+// ref registers a named struct type as a component and returns a reference to it
+func (sr *schemaRegistry) ref(t reflect.Type) *Schema {
+	name, ok := sr.names[t]
+	if !ok {
+		name = sr.componentName(t)
+		sr.names[t] = name
+		// Reserve the name before building so recursive types terminate
+		sr.schemas[name] = &Schema{}
+		sr.schemas[name] = sr.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName picks a unique component name for a type, qualifying it
+// with the package name when two packages use the same type name.
+func (sr *schemaRegistry) componentName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		// Generic instantiation, e.g. Page[main.User]
+		name = name[:i]
+	}
+	if _, taken := sr.schemas[name]; !taken {
+		return name
+	}
+
+	pkg := t.PkgPath()
+	if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	for base, i := name, 2; ; i++ {
+		if _, taken := sr.schemas[name]; !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+func (sr *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	sr.addFields(s, t)
+	return s
+}
+
+// addFields adds the JSON-visible fields of t to s, following the
+// encoding/json rules for tags and embedded structs.
+func (sr *schemaRegistry) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			sr.addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() || isParamField(f) {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := sr.schema(f.Type)
+		if desc := f.Tag.Get("description"); desc != "" {
+			if prop.Ref != "" {
+				// Siblings of $ref are allowed in 3.1 but wrap for older tooling
+				prop = &Schema{AllOf: []*Schema{prop}}
+			}
+			prop.Description = desc
+		}
+		if example := f.Tag.Get("example"); example != "" {
+			prop.Example = example
+		}
+		if strings.Contains(opts, "string") && prop.Type != "string" {
+			prop = &Schema{Type: "string", Description: prop.Description}
+		}
+
+		rules := f.Tag.Get("validate")
+		if prop.Ref == "" {
+			applyRules(prop, rules)
+		}
+		if hasRule(rules, "required") {
+			s.Required = append(s.Required, name)
+		}
+
+		s.Properties[name] = prop
+	}
+}
+
+// hasRule reports whether a validate tag contains the named rule
+func hasRule(tag, name string) bool {
+	for _, rule := range splitRules(tag) {
+		if rule == name || strings.HasPrefix(rule, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules documents the validate tag rules of a field on its schema
+func applyRules(s *Schema, tag string) {
+	for _, rule := range splitRules(tag) {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "uuid":
+			s.Format = "uuid"
+		case "pattern":
+			s.Pattern = arg
+		case "oneof":
+			for _, o := range strings.Fields(arg) {
+				s.Enum = append(s.Enum, o)
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			i := int(n)
+			switch s.Type {
+			case "string":
+				if name == "min" {
+					s.MinLength = &i
+				} else {
+					s.MaxLength = &i
+				}
+			case "array":
+				if name == "min" {
+					s.MinItems = &i
+				} else {
+					s.MaxItems = &i
+				}
+			case "integer", "number":
+				if name == "min" {
+					s.Minimum = &n
+				} else {
+					s.Maximum = &n
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jimmitjoo/tjo"
+)
+
+// FieldErrors is a list of validation errors. It is returned by Validate
+// and can be returned from a typed handler or a Validate method to send a
+// VALIDATION_ERROR response.
+type FieldErrors []ValidationError
+
+// Error implements the error interface
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, len(fe))
+	for i, e := range fe {
+		msgs[i] = e.Field + ": " + e.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validatable is implemented by request types with validation that struct
+// tags cannot express. Validate is called after the tag rules pass.
+type Validatable interface {
+	Validate() error
+}
+
+// Validate checks the `validate` struct tags of v and returns the failures,
+// or nil if v is valid. Rules are comma separated, and those with a
+// tjo.Validation method use it:
+//
+//	required      value must not be the zero value
+//	email         valid email address (IsEmail)
+//	url           valid URL (IsURL)
+//	phone         valid phone number in international format (IsPhone)
+//	uuid          valid UUID (IsUUID)
+//	alphanum      letters and digits only (IsAlphanumeric)
+//	min=N, max=N  string length (MinLength, MaxLength), number value or
+//	              slice length
+//	oneof=a b c   value must be one of the space separated options
+//	pattern=re    string must match the regular expression
+//
+// Empty values are only checked by required. Nested structs are validated
+// and their fields reported as parent.child.
+func Validate(v interface{}) FieldErrors {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs FieldErrors
+	validateStruct(rv, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *FieldErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+		name := prefix + fieldName(f)
+
+		if rules := f.Tag.Get("validate"); rules != "" && rules != "-" {
+			for _, rule := range splitRules(rules) {
+				if msg := checkRule(fv, rule); msg != "" {
+					*errs = append(*errs, ValidationError{Field: name, Message: msg})
+					break
+				}
+			}
+		}
+
+		// Descend into nested structs
+		nested := fv
+		for nested.Kind() == reflect.Ptr && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && nested.Type() != timeType {
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				validateStruct(nested, prefix, errs)
+			} else {
+				validateStruct(nested, name+".", errs)
+			}
+		}
+	}
+}
+
+// fieldName returns the name a field is reported under: its parameter
+// or JSON name, falling back to the Go field name.
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"path", "query", "header", "json"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// splitRules splits a validate tag on commas, keeping pattern= rules intact
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "pattern=") {
+			rules = append(rules, tag)
+			break
+		}
+		rule, rest, _ := strings.Cut(tag, ",")
+		rules = append(rules, strings.TrimSpace(rule))
+		tag = rest
+	}
+	return rules
+}
+
+// patterns caches compiled pattern= rules
+var patterns sync.Map
+
+// checkRule returns an error message if the value fails the rule. Rules
+// with a tjo.Validation counterpart are checked by it, so tags and form
+// validation agree.
+func checkRule(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	check := &tjo.Validation{Errors: map[string]string{}}
+
+	if name == "required" {
+		check.Check(!isEmpty(v), name, "This field cannot be blank")
+		return check.Errors[name]
+	}
+
+	if isEmpty(v) {
+		return ""
+	}
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch name {
+	case "email":
+		check.IsEmail(name, stringValue(v))
+	case "url":
+		check.IsURL(name, stringValue(v))
+	case "phone":
+		check.IsPhone(name, stringValue(v), "")
+	case "uuid":
+		check.IsUUID(name, stringValue(v))
+	case "alphanum":
+		check.IsAlphanumeric(name, stringValue(v))
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return ""
+		}
+		if v.Kind() == reflect.String {
+			if name == "min" {
+				check.MinLength(name, v.String(), int(limit))
+			} else {
+				check.MaxLength(name, v.String(), int(limit))
+			}
+			break
+		}
+		size, unit := measure(v)
+		check.Check(name != "min" || size >= limit, name, fmt.Sprintf("This field must be at least %s%s", arg, unit))
+		check.Check(name != "max" || size <= limit, name, fmt.Sprintf("This field must not exceed %s%s", arg, unit))
+	case "oneof":
+		options := strings.Fields(arg)
+		s := fmt.Sprint(v.Interface())
+		found := false
+		for _, o := range options {
+			if o == s {
+				found = true
+				break
+			}
+		}
+		check.Check(found, name, "This field must be one of: "+strings.Join(options, ", "))
+	case "pattern":
+		cached, ok := patterns.Load(arg)
+		if !ok {
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return ""
+			}
+			cached, _ = patterns.LoadOrStore(arg, re)
+		}
+		check.Check(cached.(*regexp.Regexp).MatchString(stringValue(v)), name, "This field has an invalid format")
+	}
+
+	return check.Errors[name]
+}
+
+// measure returns the size used by min and max on values other than
+// strings: the length of collections, or the value of numbers
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	return 0, ""
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	}
+	return v.IsZero()
+}
+
+func stringValue(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
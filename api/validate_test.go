@@ -0,0 +1,79 @@
+package api
+
+import (
+	"testing"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateInput struct {
+	Name     string           `json:"name" validate:"required,max=5"`
+	Age      int              `json:"age" validate:"min=18,max=130"`
+	Tags     []string         `json:"tags" validate:"max=2"`
+	Website  string           `json:"website" validate:"url"`
+	Phone    string           `json:"phone" validate:"phone"`
+	Code     string           `json:"code" validate:"pattern=^[A-Z]{2},[0-9]+$"`
+	Optional *string          `json:"optional" validate:"email"`
+	Address  validateAddress  `json:"address"`
+	Billing  *validateAddress `json:"billing"`
+}
+
+func TestValidate(t *testing.T) {
+	valid := validateInput{
+		Name:    "Ada",
+		Age:     36,
+		Tags:    []string{"a"},
+		Website: "https://example.com",
+		Phone:   "+46701234567",
+		Code:    "SE,123",
+		Address: validateAddress{City: "Lund"},
+	}
+	if errs := Validate(&valid); errs != nil {
+		t.Fatalf("Validate(valid) = %v", errs)
+	}
+
+	invalid := validateInput{
+		Name:    "Adalovelace",
+		Age:     12,
+		Tags:    []string{"a", "b", "c"},
+		Website: "not a url",
+		Phone:   "12",
+		Code:    "se,1",
+		Billing: &validateAddress{},
+	}
+	errs := Validate(invalid)
+
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Field] = e.Message
+	}
+	expected := map[string]string{
+		"name":         "This field must not exceed 5 characters",
+		"age":          "This field must be at least 18",
+		"tags":         "This field must not exceed 2 items",
+		"website":      "This field must be a valid URL",
+		"phone":        "This field must be a valid phone number",
+		"code":         "This field has an invalid format",
+		"address.city": "This field cannot be blank",
+		"billing.city": "This field cannot be blank",
+	}
+	for field, msg := range expected {
+		if got[field] != msg {
+			t.Errorf("%s: got %q, want %q", field, got[field], msg)
+		}
+	}
+	if len(errs) != len(expected) {
+		t.Errorf("got %d errors, want %d: %v", len(errs), len(expected), errs)
+	}
+}
+
+func TestValidateNonStruct(t *testing.T) {
+	if errs := Validate("string"); errs != nil {
+		t.Errorf("Validate(string) = %v", errs)
+	}
+	if errs := Validate((*validateInput)(nil)); errs != nil {
+		t.Errorf("Validate(nil) = %v", errs)
+	}
+}
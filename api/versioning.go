@@ -77,6 +77,9 @@ func (vr *VersionRouter) RegisterVersion(version string) *chi.Mux {
 
 // DeprecateVersion marks a version as deprecated
 func (vr *VersionRouter) DeprecateVersion(version, sunsetDate string) {
+	if vr.config.Deprecated == nil {
+		vr.config.Deprecated = make(map[string]string)
+	}
 	vr.config.Deprecated[version] = sunsetDate
 }
 
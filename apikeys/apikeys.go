@@ -0,0 +1,355 @@
+// Package apikeys issues and verifies API keys. Keys are shown once when
+// created; only a SHA-256 hash is stored, together with the key's scopes,
+// expiry and last use.
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jimmitjoo/tjo/database"
+)
+
+// DefaultTable is the table keys are stored in when none is set
+const DefaultTable = "api_keys"
+
+// DefaultPrefix starts every key, so leaked keys are easy to recognize
+// with secret scanners
+const DefaultPrefix = "tjo"
+
+var (
+	// ErrInvalidKey is returned for keys that are malformed or unknown
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrKeyExpired is returned for keys past their expiry
+	ErrKeyExpired = errors.New("API key has expired")
+	// ErrKeyRevoked is returned for revoked keys
+	ErrKeyRevoked = errors.New("API key has been revoked")
+	// ErrNotFound is returned when a key ID does not exist
+	ErrNotFound = errors.New("API key not found")
+)
+
+var validPrefix = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*$`)
+
+// Key is a stored API key. The plain text key is only available when the
+// key is created.
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsExpired reports whether the key is past its expiry
+func (k *Key) IsExpired() bool {
+	return k.ExpiresAt != nil && !time.Now().Before(*k.ExpiresAt)
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *Key) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// HasScope reports whether the key grants scope. A key with the scope "*"
+// grants every scope, and "posts:*" grants "posts:read" and "posts:write".
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == "*" || s == scope {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(s, ":*"); ok && strings.HasPrefix(scope, prefix+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScopes reports whether the key grants all of scopes
+func (k *Key) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !k.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Store creates, verifies and revokes keys in a database table. The table
+// is created by the migrations from `tjo make apikey`.
+//
+// Keys look like tjo_1a2b3c4d5e6f7a8b_<64 hex characters>. The middle part
+// is the key ID, which is not secret and is used to look the key up.
+type Store struct {
+	DB     *sql.DB
+	Table  string
+	Prefix string
+
+	// LastUsedInterval is how often the last used time of a key is updated,
+	// so busy keys do not cause a write on every request (default 1 minute)
+	LastUsedInterval time.Duration
+}
+
+// NewStore creates a store using the default table and prefix
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		DB:               db,
+		Table:            DefaultTable,
+		Prefix:           DefaultPrefix,
+		LastUsedInterval: time.Minute,
+	}
+}
+
+// Create issues a key with the given scopes. A zero expiresAt creates a
+// key that does not expire. The returned plain text key cannot be
+// recovered later, so it must be shown to the user now.
+func (s *Store) Create(name string, scopes []string, expiresAt time.Time) (string, *Key, error) {
+	if name == "" {
+		return "", nil, errors.New("apikeys: name is required")
+	}
+	prefix := s.prefix()
+	if !validPrefix.MatchString(prefix) {
+		return "", nil, fmt.Errorf("apikeys: invalid prefix %q", prefix)
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	plain := prefix + "_" + id + "_" + secret
+
+	key := &Key{
+		ID:        id,
+		Name:      name,
+		Scopes:    normalizeScopes(scopes),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	data := map[string]interface{}{
+		"id":         key.ID,
+		"name":       key.Name,
+		"key_hash":   hashKey(plain),
+		"scopes":     strings.Join(key.Scopes, " "),
+		"created_at": key.CreatedAt,
+	}
+	if !expiresAt.IsZero() {
+		t := expiresAt.UTC()
+		key.ExpiresAt = &t
+		data["expires_at"] = t
+	}
+
+	if _, err := s.query().Insert(data); err != nil {
+		return "", nil, err
+	}
+	return plain, key, nil
+}
+
+// Verify returns the key for a plain text key. It returns ErrInvalidKey
+// for unknown keys, ErrKeyRevoked and ErrKeyExpired for keys that can no
+// longer be used, and records when valid keys were last used.
+func (s *Store) Verify(plain string) (*Key, error) {
+	id, ok := s.parse(plain)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	key, hash, err := s.find(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashKey(plain))) != 1 {
+		return nil, ErrInvalidKey
+	}
+
+	if key.IsRevoked() {
+		return nil, ErrKeyRevoked
+	}
+	if key.IsExpired() {
+		return nil, ErrKeyExpired
+	}
+
+	if err := s.touch(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Find returns a key by ID
+func (s *Store) Find(id string) (*Key, error) {
+	key, _, err := s.find(id)
+	return key, err
+}
+
+// List returns all keys, newest first, including revoked and expired ones
+func (s *Store) List() ([]*Key, error) {
+	rows, err := s.query().
+		Select("id", "name", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at").
+		OrderBy("created_at", "DESC").
+		Get()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*Key
+	for rows.Next() {
+		key, _, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke revokes a key. Revoked keys are kept so they show up in List.
+func (s *Store) Revoke(id string) error {
+	res, err := s.query().
+		Where("id", "=", id).
+		WhereNull("revoked_at").
+		Update(map[string]interface{}{"revoked_at": time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := s.Find(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a key
+func (s *Store) Delete(id string) error {
+	_, err := s.query().Where("id", "=", id).Delete()
+	return err
+}
+
+// parse returns the key ID of a plain text key with the store's prefix
+func (s *Store) parse(plain string) (string, bool) {
+	rest, ok := strings.CutPrefix(plain, s.prefix()+"_")
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 16 || len(secret) != 64 {
+		return "", false
+	}
+	return id, true
+}
+
+func (s *Store) find(id string) (*Key, string, error) {
+	row := s.query().
+		Select("id", "name", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at").
+		Where("id", "=", id).
+		First()
+
+	key, hash, err := scanKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrNotFound
+	}
+	return key, hash, err
+}
+
+// touch records that the key was used, at most once per LastUsedInterval
+func (s *Store) touch(key *Key) error {
+	now := time.Now().UTC()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < s.LastUsedInterval {
+		return nil
+	}
+
+	if _, err := s.query().Where("id", "=", key.ID).Update(map[string]interface{}{"last_used_at": now}); err != nil {
+		return err
+	}
+	key.LastUsedAt = &now
+	return nil
+}
+
+func (s *Store) table() string {
+	if s.Table == "" {
+		return DefaultTable
+	}
+	return s.Table
+}
+
+func (s *Store) prefix() string {
+	if s.Prefix == "" {
+		return DefaultPrefix
+	}
+	return s.Prefix
+}
+
+func (s *Store) query() *database.QueryBuilder {
+	return database.NewQueryBuilder(s.DB).Table(s.table())
+}
+
+// scanKey reads a key and its hash from a row
+func scanKey(row interface{ Scan(...interface{}) error }) (*Key, string, error) {
+	var key Key
+	var hash, scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	if err := row.Scan(&key.ID, &key.Name, &hash, &scopes, &expiresAt, &lastUsedAt, &revokedAt, &key.CreatedAt); err != nil {
+		return nil, "", err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	key.ExpiresAt = nullTime(expiresAt)
+	key.LastUsedAt = nullTime(lastUsedAt)
+	key.RevokedAt = nullTime(revokedAt)
+	return &key, hash, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+// normalizeScopes trims and deduplicates scopes. Scopes are stored space
+// separated, so they cannot contain whitespace.
+func normalizeScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := []string{}
+	for _, scope := range scopes {
+		for _, s := range strings.Fields(scope) {
+			if !seen[s] {
+				seen[s] = true
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
+
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("apikeys: failed to generate key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
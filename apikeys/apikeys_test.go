@@ -0,0 +1,181 @@
+package apikeys
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore creates a store on an in-memory database using the SQLite
+// migration from the CLI templates
+func newTestStore(t *testing.T) *Store {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile("../cmd/tjo/templates/migrations/api_keys.sqlite.up.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(schema))
+	require.NoError(t, err)
+
+	return NewStore(db)
+}
+
+func TestStore_CreateAndVerify(t *testing.T) {
+	s := newTestStore(t)
+
+	plain, key, err := s.Create("ci", []string{"posts:read", "posts:write posts:read"}, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, "tjo_"+key.ID+"_"))
+	assert.Len(t, plain, len("tjo_")+16+1+64)
+	assert.Equal(t, []string{"posts:read", "posts:write"}, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+
+	// Only the hash is stored
+	var hash string
+	require.NoError(t, s.DB.QueryRow("SELECT key_hash FROM api_keys WHERE id = ?", key.ID).Scan(&hash))
+	assert.NotContains(t, hash, plain[len(plain)-64:])
+	assert.Equal(t, hashKey(plain), hash)
+
+	verified, err := s.Verify(plain)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, verified.ID)
+	assert.Equal(t, "ci", verified.Name)
+	assert.Equal(t, key.Scopes, verified.Scopes)
+	require.NotNil(t, verified.LastUsedAt)
+
+	found, err := s.Find(key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.LastUsedAt)
+}
+
+func TestStore_VerifyRejectsInvalidKeys(t *testing.T) {
+	s := newTestStore(t)
+	plain, key, err := s.Create("ci", nil, time.Time{})
+	require.NoError(t, err)
+
+	tampered := plain[:len(plain)-1] + "0"
+	if tampered == plain {
+		tampered = plain[:len(plain)-1] + "1"
+	}
+
+	for _, k := range []string{
+		"",
+		"garbage",
+		tampered,
+		"other_" + strings.TrimPrefix(plain, "tjo_"),
+		"tjo_" + strings.Repeat("0", 16) + "_" + strings.Repeat("0", 64),
+	} {
+		_, err := s.Verify(k)
+		assert.ErrorIs(t, err, ErrInvalidKey, k)
+	}
+
+	// A failed verification does not count as use
+	found, err := s.Find(key.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found.LastUsedAt)
+}
+
+func TestStore_Expiry(t *testing.T) {
+	s := newTestStore(t)
+
+	plain, key, err := s.Create("old", nil, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, key.ExpiresAt)
+	assert.True(t, key.IsExpired())
+
+	_, err = s.Verify(plain)
+	assert.ErrorIs(t, err, ErrKeyExpired)
+
+	plain, _, err = s.Create("new", nil, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = s.Verify(plain)
+	assert.NoError(t, err)
+}
+
+func TestStore_RevokeAndList(t *testing.T) {
+	s := newTestStore(t)
+
+	plain, key, err := s.Create("first", []string{"*"}, time.Time{})
+	require.NoError(t, err)
+	_, _, err = s.Create("second", nil, time.Time{})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Revoke(key.ID))
+	require.NoError(t, s.Revoke(key.ID), "revoking twice is not an error")
+	assert.ErrorIs(t, s.Revoke("missing"), ErrNotFound)
+
+	_, err = s.Verify(plain)
+	assert.ErrorIs(t, err, ErrKeyRevoked)
+
+	keys, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	names := []string{keys[0].Name, keys[1].Name}
+	assert.ElementsMatch(t, []string{"first", "second"}, names)
+	for _, k := range keys {
+		assert.Equal(t, k.Name == "first", k.IsRevoked())
+	}
+
+	require.NoError(t, s.Delete(key.ID))
+	_, err = s.Find(key.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_LastUsedInterval(t *testing.T) {
+	s := newTestStore(t)
+	plain, _, err := s.Create("ci", nil, time.Time{})
+	require.NoError(t, err)
+
+	first, err := s.Verify(plain)
+	require.NoError(t, err)
+	second, err := s.Verify(plain)
+	require.NoError(t, err)
+	assert.True(t, first.LastUsedAt.Equal(*second.LastUsedAt), "not updated within the interval")
+
+	s.LastUsedInterval = 0
+	third, err := s.Verify(plain)
+	require.NoError(t, err)
+	assert.False(t, third.LastUsedAt.Before(*second.LastUsedAt))
+}
+
+func TestStore_Prefix(t *testing.T) {
+	s := newTestStore(t)
+	s.Prefix = "acme"
+
+	plain, _, err := s.Create("ci", nil, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, "acme_"))
+	_, err = s.Verify(plain)
+	assert.NoError(t, err)
+
+	s.Prefix = "bad_prefix"
+	_, _, err = s.Create("ci", nil, time.Time{})
+	assert.Error(t, err)
+
+	_, _, err = s.Create("", nil, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestKey_HasScope(t *testing.T) {
+	k := &Key{Scopes: []string{"posts:*", "users:read"}}
+
+	assert.True(t, k.HasScope("posts:read"))
+	assert.True(t, k.HasScope("posts:write"))
+	assert.True(t, k.HasScope("users:read"))
+	assert.False(t, k.HasScope("users:write"))
+	assert.False(t, k.HasScope("postsx:read"))
+	assert.True(t, k.HasScopes())
+	assert.True(t, k.HasScopes("posts:read", "users:read"))
+	assert.False(t, k.HasScopes("posts:read", "users:write"))
+
+	assert.True(t, (&Key{Scopes: []string{"*"}}).HasScope("anything"))
+	assert.False(t, (&Key{}).HasScope("posts:read"))
+}
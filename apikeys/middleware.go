@@ -0,0 +1,115 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jimmitjoo/tjo/api"
+)
+
+// Header is the request header keys are read from. Keys are also accepted
+// as bearer tokens in the Authorization header.
+const Header = "X-API-Key"
+
+type contextKey struct{}
+
+// FromContext returns the key that authenticated the request
+func FromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(*Key)
+	return key, ok
+}
+
+// WithKey returns a context carrying key, e.g. for testing handlers
+func WithKey(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// Middleware authenticates requests by API key and requires the key to
+// grant all of scopes. Requests without a valid key get 401 Unauthorized
+// and keys without the scopes 403 Forbidden. The key is available to
+// handlers through FromContext.
+//
+//	r.Use(store.Middleware("posts:read"))
+func (s *Store) Middleware(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain := s.extract(r)
+			if plain == "" {
+				api.Unauthorized(w, "API key is required")
+				return
+			}
+
+			key, err := s.Verify(plain)
+			switch {
+			case errors.Is(err, ErrKeyExpired):
+				api.Unauthorized(w, "API key has expired")
+				return
+			case errors.Is(err, ErrKeyRevoked), errors.Is(err, ErrInvalidKey):
+				api.Unauthorized(w, "Invalid API key")
+				return
+			case err != nil:
+				api.InternalServerError(w, "")
+				return
+			}
+
+			if !key.HasScopes(scopes...) {
+				forbidden(w, scopes)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithKey(r.Context(), key)))
+		})
+	}
+}
+
+// RequireScopes requires the key authenticated by Middleware to grant all
+// of scopes, for routes that need more than the rest of their group
+//
+//	r.Use(store.Middleware())
+//	r.With(apikeys.RequireScopes("posts:write")).Post("/posts", createPost)
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := FromContext(r.Context())
+			if !ok {
+				api.Unauthorized(w, "API key is required")
+				return
+			}
+			if !key.HasScopes(scopes...) {
+				forbidden(w, scopes)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitKey returns the ID of the request's key as rate limit key, so
+// limits follow the key rather than the client's IP. Use it after
+// Middleware; unauthenticated requests fall back to api.IPKeyFunc.
+func RateLimitKey(r *http.Request) string {
+	if key, ok := FromContext(r.Context()); ok {
+		return "apikey:" + key.ID
+	}
+	return api.IPKeyFunc(r)
+}
+
+// extract reads the key from the X-API-Key header or a bearer token with
+// the store's prefix, leaving other bearer tokens to other middleware
+func (s *Store) extract(r *http.Request) string {
+	if key := r.Header.Get(Header); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok && strings.HasPrefix(token, s.prefix()+"_") {
+		return token
+	}
+	return ""
+}
+
+func forbidden(w http.ResponseWriter, scopes []string) {
+	api.Error(w, http.StatusForbidden, "INSUFFICIENT_SCOPE", "API key does not grant the required scopes",
+		map[string]interface{}{"required_scopes": scopes})
+}
@@ -0,0 +1,102 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	s := newTestStore(t)
+	reader, _, err := s.Create("reader", []string{"posts:read"}, time.Time{})
+	require.NoError(t, err)
+	writer, _, err := s.Create("writer", []string{"posts:*"}, time.Time{})
+	require.NoError(t, err)
+	expired, _, err := s.Create("expired", []string{"posts:*"}, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	handler := s.Middleware("posts:read")(RequireScopes("posts:write")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := FromContext(r.Context())
+			require.True(t, ok)
+			w.Write([]byte(key.Name))
+		})))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+		code   string
+	}{
+		{"missing key", "", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"invalid key", Header, "tjo_nope", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"expired key", Header, expired, http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"missing scope", Header, reader, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"header", Header, writer, http.StatusOK, ""},
+		{"bearer token", "Authorization", "Bearer " + writer, http.StatusOK, ""},
+		{"other bearer token", "Authorization", "Bearer eyJhbGciOi", http.StatusUnauthorized, "UNAUTHORIZED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.code == "" {
+				assert.Equal(t, "writer", rec.Body.String())
+				return
+			}
+
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Error.Code)
+		})
+	}
+}
+
+func TestRequireScopesWithoutKey(t *testing.T) {
+	handler := RequireScopes("posts:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not be called")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestRateLimitKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.NotContains(t, RateLimitKey(req), "apikey:")
+
+	req = req.WithContext(WithKey(req.Context(), &Key{ID: "abc"}))
+	assert.Equal(t, "apikey:abc", RateLimitKey(req))
+}
+
+func TestModule_Initialize(t *testing.T) {
+	m := NewModule()
+	assert.Equal(t, "apikeys", m.Name())
+	assert.Error(t, m.Initialize(nil))
+
+	s := newTestStore(t)
+	m = NewModule(WithDatabase(s.DB), WithPrefix("acme"), WithTable("api_keys"))
+	require.NoError(t, m.Initialize(nil))
+
+	plain, _, err := m.Store.Create("ci", nil, time.Time{})
+	require.NoError(t, err)
+	assert.Contains(t, plain, "acme_")
+}
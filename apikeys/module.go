@@ -0,0 +1,102 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/jimmitjoo/tjo"
+)
+
+// Module implements the tjo.Module interface for API keys. The api_keys
+// table is created by the migrations from `tjo make apikey`.
+//
+// Example:
+//
+//	app := tjo.Tjo{}
+//	app.New(rootPath, apikeys.NewModule(apikeys.WithPrefix("acme")))
+//
+//	// Later:
+//	keys := app.GetModule("apikeys").(*apikeys.Module)
+//	r.Use(keys.Middleware("posts:read"))
+type Module struct {
+	Store *Store
+
+	db     *sql.DB
+	table  string
+	prefix string
+}
+
+// Option is a function that configures the API keys module
+type Option func(*Module)
+
+// NewModule creates an API keys module
+func NewModule(opts ...Option) *Module {
+	m := &Module{}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// WithDatabase sets the database keys are stored in.
+// Defaults to the application's database.
+func WithDatabase(db *sql.DB) Option {
+	return func(m *Module) {
+		m.db = db
+	}
+}
+
+// WithTable sets the table keys are stored in (default api_keys)
+func WithTable(table string) Option {
+	return func(m *Module) {
+		m.table = table
+	}
+}
+
+// WithPrefix sets the prefix of issued keys (default tjo). Keys with
+// another prefix are rejected, so changing it invalidates existing keys.
+func WithPrefix(prefix string) Option {
+	return func(m *Module) {
+		m.prefix = prefix
+	}
+}
+
+// Name returns the module identifier
+func (m *Module) Name() string {
+	return "apikeys"
+}
+
+// Initialize creates the key store on the configured or application database
+func (m *Module) Initialize(g *tjo.Tjo) error {
+	db := m.db
+	if db == nil {
+		if g == nil || g.Data == nil || g.Data.DB.Pool == nil {
+			return errors.New("apikeys: a database connection is required")
+		}
+		db = g.Data.DB.Pool
+	}
+
+	m.Store = NewStore(db)
+	if m.table != "" {
+		m.Store.Table = m.table
+	}
+	if m.prefix != "" {
+		m.Store.Prefix = m.prefix
+	}
+	return nil
+}
+
+// Shutdown is a no-op; the database connection belongs to the application
+func (m *Module) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Middleware authenticates requests by API key and requires scopes.
+// See Store.Middleware.
+func (m *Module) Middleware(scopes ...string) func(http.Handler) http.Handler {
+	return m.Store.Middleware(scopes...)
+}
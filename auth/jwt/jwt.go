@@ -0,0 +1,226 @@
+// Package jwt signs and verifies JSON Web Tokens and issues access and
+// refresh tokens. Tokens are signed with HS256, RS256 or EdDSA keys from a
+// KeySet, so keys can be rotated without invalidating tokens in flight.
+// Refresh tokens are opaque, stored hashed in the database and rotated on
+// every use; presenting a used refresh token again revokes its family.
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or have an
+	// invalid signature, issuer or audience
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned for tokens past their expiry
+	ErrTokenExpired = errors.New("token has expired")
+	// ErrUnknownKey is returned for tokens signed with a key not in the key set
+	ErrUnknownKey = errors.New("token is signed with an unknown key")
+	// ErrTokenRevoked is returned for revoked refresh tokens
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrTokenReused is returned when a refresh token is used twice. The
+	// token's family is revoked, since one of the uses was not legitimate.
+	ErrTokenReused = errors.New("refresh token has already been used")
+)
+
+// Claims are the claims of a token. Registered claims have their own
+// fields and Extra holds the rest. Scopes are encoded as the space
+// separated scope claim.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	Scopes    []string
+	Extra     map[string]interface{}
+}
+
+// registered are the claim names with a Claims field
+var registered = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true, "scope": true,
+}
+
+// HasScope reports whether the claims grant scope. The scope "*" grants
+// every scope, and "posts:*" grants "posts:read" and "posts:write".
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == "*" || s == scope {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(s, ":*"); ok && strings.HasPrefix(scope, prefix+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScopes reports whether the claims grant all of scopes
+func (c *Claims) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !c.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAudience reports whether aud is one of the token's audiences
+func (c *Claims) HasAudience(aud string) bool {
+	for _, a := range c.Audience {
+		if a == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON encodes the claims as a JWT claims set
+func (c Claims) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(c.Extra)+8)
+	for k, v := range c.Extra {
+		if !registered[k] {
+			m[k] = v
+		}
+	}
+
+	setString(m, "iss", c.Issuer)
+	setString(m, "sub", c.Subject)
+	setString(m, "jti", c.ID)
+	setString(m, "scope", strings.Join(c.Scopes, " "))
+	switch len(c.Audience) {
+	case 0:
+	case 1:
+		m["aud"] = c.Audience[0]
+	default:
+		m["aud"] = c.Audience
+	}
+	setTime(m, "exp", c.ExpiresAt)
+	setTime(m, "nbf", c.NotBefore)
+	setTime(m, "iat", c.IssuedAt)
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes a JWT claims set
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+
+	*c = Claims{}
+	var err error
+	for k, v := range m {
+		switch k {
+		case "iss":
+			c.Issuer, err = claimString(k, v)
+		case "sub":
+			c.Subject, err = claimString(k, v)
+		case "jti":
+			c.ID, err = claimString(k, v)
+		case "scope":
+			var scope string
+			scope, err = claimString(k, v)
+			c.Scopes = strings.Fields(scope)
+		case "aud":
+			c.Audience, err = claimStrings(k, v)
+		case "exp":
+			c.ExpiresAt, err = claimTime(k, v)
+		case "nbf":
+			c.NotBefore, err = claimTime(k, v)
+		case "iat":
+			c.IssuedAt, err = claimTime(k, v)
+		default:
+			if c.Extra == nil {
+				c.Extra = make(map[string]interface{})
+			}
+			c.Extra[k] = v
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the time based claims, allowing leeway for clock skew
+func (c *Claims) validate(now time.Time, leeway time.Duration) error {
+	if !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt.Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if !c.NotBefore.IsZero() && now.Add(leeway).Before(c.NotBefore) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func setString(m map[string]interface{}, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+func setTime(m map[string]interface{}, key string, t time.Time) {
+	if !t.IsZero() {
+		m[key] = t.Unix()
+	}
+}
+
+func claimString(key string, v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("jwt: claim %q must be a string", key)
+	}
+	return s, nil
+}
+
+// claimStrings decodes a claim that is either a string or an array of them
+func claimStrings(key string, v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := claimString(key, item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("jwt: claim %q must be a string or an array of strings", key)
+}
+
+// claimTime decodes a NumericDate, seconds since the Unix epoch
+func claimTime(key string, v interface{}) (time.Time, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, fmt.Errorf("jwt: claim %q must be a number", key)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("jwt: claim %q must be a number", key)
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), nil
+}
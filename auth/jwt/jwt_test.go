@@ -0,0 +1,141 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndParse(t *testing.T) {
+	for _, alg := range []string{HS256, RS256, EdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := GenerateKey(alg)
+			require.NoError(t, err)
+			keys := NewKeySet(key)
+
+			token, err := keys.Sign(&Claims{
+				Subject:   "42",
+				Scopes:    []string{"posts:read"},
+				ExpiresAt: time.Now().Add(time.Minute),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 2, strings.Count(token, "."))
+
+			var h header
+			require.NoError(t, decodeJSON(strings.Split(token, ".")[0], &h))
+			assert.Equal(t, header{Algorithm: alg, Type: "JWT", KeyID: key.ID}, h)
+
+			claims, err := keys.Parse(token)
+			require.NoError(t, err)
+			assert.Equal(t, "42", claims.Subject)
+			assert.Equal(t, []string{"posts:read"}, claims.Scopes)
+		})
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	key, err := GenerateKey(HS256)
+	require.NoError(t, err)
+	keys := NewKeySet(key)
+
+	expired, err := keys.Sign(&Claims{Subject: "42", ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = keys.Parse(expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	// Within the leeway an expired token is still accepted
+	_, err = keys.parse(expired, time.Now(), 2*time.Minute)
+	assert.NoError(t, err)
+
+	early, err := keys.Sign(&Claims{Subject: "42", NotBefore: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = keys.Parse(early)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	valid, err := keys.Sign(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	parts := strings.Split(valid, ".")
+
+	forged, err := json.Marshal(Claims{Subject: "1"})
+	require.NoError(t, err)
+	_, err = keys.Parse(parts[0] + "." + encode(forged) + "." + parts[2])
+	assert.ErrorIs(t, err, ErrInvalidToken, "tampered claims")
+
+	_, err = keys.Parse("not.a.token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = keys.Parse("nope")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	other, err := GenerateKey(HS256)
+	require.NoError(t, err)
+	foreign, err := NewKeySet(other).Sign(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	_, err = keys.Parse(foreign)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestParse_RejectsAlgorithmConfusion(t *testing.T) {
+	key, err := GenerateKey(EdDSA)
+	require.NoError(t, err)
+	keys := NewKeySet(key)
+
+	// An HS256 token using the public key as secret must not verify
+	hmacKey, err := NewHMACKey(key.ID, key.Public().(ed25519.PublicKey))
+	require.NoError(t, err)
+	forged, err := NewKeySet(hmacKey).Sign(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	_, err = keys.Parse(forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	h, err := json.Marshal(header{Algorithm: "none", KeyID: key.ID})
+	require.NoError(t, err)
+	c, err := json.Marshal(Claims{Subject: "42"})
+	require.NoError(t, err)
+	_, err = keys.Parse(encode(h) + "." + encode(c) + ".")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestClaims_JSON(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	data, err := json.Marshal(Claims{
+		Issuer:    "https://example.com",
+		Subject:   "42",
+		Audience:  []string{"api"},
+		ExpiresAt: exp,
+		Scopes:    []string{"posts:read", "posts:write"},
+		Extra:     map[string]interface{}{"role": "admin", "sub": "ignored"},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"iss": "https://example.com",
+		"sub": "42",
+		"aud": "api",
+		"exp": 1700000000,
+		"scope": "posts:read posts:write",
+		"role": "admin"
+	}`, string(data))
+
+	var claims Claims
+	require.NoError(t, json.Unmarshal([]byte(`{"sub":"42","aud":["a","b"],"exp":1700000000,"role":"admin"}`), &claims))
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, []string{"a", "b"}, claims.Audience)
+	assert.True(t, claims.ExpiresAt.Equal(exp))
+	assert.Equal(t, "admin", claims.Extra["role"])
+	assert.True(t, claims.HasAudience("b"))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"exp":"tomorrow"}`), &claims))
+}
+
+func TestClaims_HasScope(t *testing.T) {
+	c := &Claims{Scopes: []string{"posts:*", "users:read"}}
+	assert.True(t, c.HasScope("posts:write"))
+	assert.True(t, c.HasScopes("posts:read", "users:read"))
+	assert.False(t, c.HasScope("users:write"))
+	assert.False(t, c.HasScope("postsx:read"))
+	assert.True(t, (&Claims{Scopes: []string{"*"}}).HasScope("anything"))
+}
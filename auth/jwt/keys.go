@@ -0,0 +1,426 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinHMACKeySize is the minimum length of HS256 secrets, the size of the hash
+const MinHMACKeySize = 32
+
+// MinRSAKeySize is the minimum size of RS256 keys in bits
+const MinRSAKeySize = 2048
+
+// Key is a signing or verification key. Its ID is sent as the kid header,
+// so tokens are verified with the key that signed them.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	secret  []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// NewHMACKey creates an HS256 key. The secret must be at least
+// MinHMACKeySize bytes.
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) < MinHMACKeySize {
+		return nil, fmt.Errorf("jwt: HS256 secrets must be at least %d bytes", MinHMACKeySize)
+	}
+	return &Key{ID: id, Algorithm: HS256, secret: secret}, nil
+}
+
+// NewRSAKey creates an RS256 signing key
+func NewRSAKey(id string, key *rsa.PrivateKey) (*Key, error) {
+	if key == nil || key.N.BitLen() < MinRSAKeySize {
+		return nil, fmt.Errorf("jwt: RS256 keys must be at least %d bits", MinRSAKeySize)
+	}
+	return &Key{ID: id, Algorithm: RS256, private: key, public: &key.PublicKey}, nil
+}
+
+// NewEd25519Key creates an EdDSA signing key
+func NewEd25519Key(id string, key ed25519.PrivateKey) (*Key, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("jwt: invalid Ed25519 private key")
+	}
+	return &Key{ID: id, Algorithm: EdDSA, private: key, public: key.Public()}, nil
+}
+
+// NewPublicKey creates a verification key from an *rsa.PublicKey or an
+// ed25519.PublicKey, e.g. for tokens issued by another service
+func NewPublicKey(id string, key crypto.PublicKey) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < MinRSAKeySize {
+			return nil, fmt.Errorf("jwt: RS256 keys must be at least %d bits", MinRSAKeySize)
+		}
+		return &Key{ID: id, Algorithm: RS256, public: k}, nil
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return nil, errors.New("jwt: invalid Ed25519 public key")
+		}
+		return &Key{ID: id, Algorithm: EdDSA, public: k}, nil
+	}
+	return nil, fmt.Errorf("jwt: unsupported public key type %T", key)
+}
+
+// GenerateKey creates a key with a random ID and key material for the
+// algorithm
+func GenerateKey(algorithm string) (*Key, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case HS256:
+		secret := make([]byte, MinHMACKeySize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return NewHMACKey(id, secret)
+	case RS256:
+		key, err := rsa.GenerateKey(rand.Reader, MinRSAKeySize)
+		if err != nil {
+			return nil, err
+		}
+		return NewRSAKey(id, key)
+	case EdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewEd25519Key(id, key)
+	}
+	return nil, fmt.Errorf("jwt: unsupported algorithm %q", algorithm)
+}
+
+// CanSign reports whether the key can sign tokens, rather than only verify them
+func (k *Key) CanSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+// Public returns the public key of an RS256 or EdDSA key, or nil for HS256
+func (k *Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+func (k *Key) sign(input []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case HS256:
+		if k.secret == nil {
+			break
+		}
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case RS256:
+		if k.private == nil {
+			break
+		}
+		sum := sha256.Sum256(input)
+		return k.private.Sign(rand.Reader, sum[:], crypto.SHA256)
+	case EdDSA:
+		if k.private == nil {
+			break
+		}
+		return k.private.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+	return nil, fmt.Errorf("jwt: key %q cannot sign", k.ID)
+}
+
+func (k *Key) verify(input, signature []byte) bool {
+	switch k.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return hmac.Equal(signature, mac.Sum(nil))
+	case RS256:
+		sum := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(k.public.(*rsa.PublicKey), crypto.SHA256, sum[:], signature) == nil
+	case EdDSA:
+		return ed25519.Verify(k.public.(ed25519.PublicKey), input, signature)
+	}
+	return false
+}
+
+// KeySet holds the key tokens are signed with and the keys they are
+// verified with. Rotate to a new signing key while keeping the old one
+// for verification until the tokens it signed have expired, then Remove it.
+// A KeySet is safe for concurrent use.
+type KeySet struct {
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	signing *Key
+}
+
+// NewKeySet creates a key set signing with signing and verifying with it
+// and keys. signing may be nil for a set that only verifies tokens.
+func NewKeySet(signing *Key, keys ...*Key) *KeySet {
+	s := &KeySet{keys: make(map[string]*Key)}
+	for _, k := range keys {
+		s.keys[k.ID] = k
+	}
+	if signing != nil {
+		s.keys[signing.ID] = signing
+		s.signing = signing
+	}
+	return s
+}
+
+// Add adds a verification key, replacing any key with the same ID
+func (s *KeySet) Add(key *Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.ID] = key
+}
+
+// Rotate makes key the signing key. The previous signing key is kept to
+// verify the tokens it signed.
+func (s *KeySet) Rotate(key *Key) error {
+	if !key.CanSign() {
+		return fmt.Errorf("jwt: key %q cannot sign", key.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.ID] = key
+	s.signing = key
+	return nil
+}
+
+// Remove removes a verification key. The signing key cannot be removed;
+// Rotate away from it first.
+func (s *KeySet) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.signing != nil && s.signing.ID == id {
+		return fmt.Errorf("jwt: key %q is the signing key", id)
+	}
+	delete(s.keys, id)
+	return nil
+}
+
+// Key returns a key by ID
+func (s *KeySet) Key(id string) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[id]
+	return k, ok
+}
+
+// SigningKey returns the key new tokens are signed with, or nil
+func (s *KeySet) SigningKey() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.signing
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// Sign signs claims with the signing key
+func (s *KeySet) Sign(claims *Claims) (string, error) {
+	key := s.SigningKey()
+	if key == nil {
+		return "", errors.New("jwt: key set has no signing key")
+	}
+
+	h, err := json.Marshal(header{Algorithm: key.Algorithm, Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := encode(h) + "." + encode(c)
+	sig, err := key.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + encode(sig), nil
+}
+
+// Parse verifies the signature and expiry of token and returns its claims.
+// Tokens without a kid header are verified with the signing key.
+func (s *KeySet) Parse(token string) (*Claims, error) {
+	return s.parse(token, time.Now(), 0)
+}
+
+func (s *KeySet) parse(token string, now time.Time, leeway time.Duration) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var key *Key
+	if h.KeyID != "" {
+		var ok bool
+		if key, ok = s.Key(h.KeyID); !ok {
+			return nil, ErrUnknownKey
+		}
+	} else if key = s.SigningKey(); key == nil {
+		return nil, ErrUnknownKey
+	}
+
+	// The key decides the algorithm, so a token cannot pick a weaker one
+	// or verify an RS256 public key as an HS256 secret
+	if h.Algorithm != key.Algorithm {
+		return nil, ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !key.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeJSON(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := claims.validate(now, leeway); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// jwk is a JSON Web Key (RFC 7517) for an RSA or Ed25519 public key
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// JWKS returns the public keys of the set as a JSON Web Key Set, sorted
+// by ID. HS256 keys are secret and never included.
+func (s *KeySet) JWKS() ([]byte, error) {
+	s.mu.RLock()
+	set := jwks{Keys: []jwk{}}
+	for _, k := range s.keys {
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{
+				KeyType: "RSA", KeyID: k.ID, Use: "sig", Algorithm: RS256,
+				N: encode(pub.N.Bytes()),
+				E: encode(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, jwk{
+				KeyType: "OKP", KeyID: k.ID, Use: "sig", Algorithm: EdDSA, Curve: "Ed25519",
+				X: encode(pub),
+			})
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return json.Marshal(set)
+}
+
+// JWKSHandler serves the public keys of the set, conventionally at
+// /.well-known/jwks.json, so other services can verify its tokens
+func (s *KeySet) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := s.JWKS()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Write(data)
+	})
+}
+
+// ParseJWKS creates a verification key set from a JSON Web Key Set, such
+// as another service's JWKSHandler. Keys of other types are skipped.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwt: invalid JWKS: %w", err)
+	}
+
+	s := NewKeySet(nil)
+	for _, k := range set.Keys {
+		var pub crypto.PublicKey
+		switch {
+		case k.KeyType == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				return nil, fmt.Errorf("jwt: invalid RSA key %q", k.KeyID)
+			}
+			pub = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.KeyType == "OKP" && k.Curve == "Ed25519":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("jwt: invalid Ed25519 key %q", k.KeyID)
+			}
+			pub = ed25519.PublicKey(x)
+		default:
+			continue
+		}
+
+		key, err := NewPublicKey(k.KeyID, pub)
+		if err != nil {
+			return nil, err
+		}
+		s.Add(key)
+	}
+	return s, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeJSON(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("jwt: failed to generate random data: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKey_RejectsWeakKeys(t *testing.T) {
+	_, err := NewHMACKey("k", []byte("short"))
+	assert.Error(t, err)
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = NewRSAKey("k", small)
+	assert.Error(t, err)
+	_, err = NewPublicKey("k", &small.PublicKey)
+	assert.Error(t, err)
+
+	_, err = GenerateKey("HS512")
+	assert.Error(t, err)
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	old, err := GenerateKey(EdDSA)
+	require.NoError(t, err)
+	keys := NewKeySet(old)
+
+	before, err := keys.Sign(&Claims{Subject: "42"})
+	require.NoError(t, err)
+
+	next, err := GenerateKey(RS256)
+	require.NoError(t, err)
+	require.NoError(t, keys.Rotate(next))
+	assert.Equal(t, next, keys.SigningKey())
+
+	after, err := keys.Sign(&Claims{Subject: "42"})
+	require.NoError(t, err)
+
+	// Tokens signed with the previous key verify until it is removed
+	_, err = keys.Parse(before)
+	assert.NoError(t, err)
+	_, err = keys.Parse(after)
+	assert.NoError(t, err)
+
+	assert.Error(t, keys.Remove(next.ID), "signing key cannot be removed")
+	require.NoError(t, keys.Remove(old.ID))
+	_, err = keys.Parse(before)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	public, err := NewPublicKey("public", next.Public())
+	require.NoError(t, err)
+	assert.False(t, public.CanSign())
+	assert.Error(t, keys.Rotate(public))
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKey, err := GenerateKey(RS256)
+	require.NoError(t, err)
+	edKey, err := GenerateKey(EdDSA)
+	require.NoError(t, err)
+	hmacKey, err := GenerateKey(HS256)
+	require.NoError(t, err)
+	keys := NewKeySet(rsaKey, edKey, hmacKey)
+
+	rec := httptest.NewRecorder()
+	keys.JWKSHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var set jwks
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+	require.Len(t, set.Keys, 2, "HS256 secrets are never published")
+	assert.NotContains(t, rec.Body.String(), hmacKey.ID)
+
+	// A service verifying with the published keys accepts tokens of both
+	verifier, err := ParseJWKS(rec.Body.Bytes())
+	require.NoError(t, err)
+	assert.Nil(t, verifier.SigningKey())
+
+	for _, k := range []*Key{rsaKey, edKey} {
+		token, err := NewKeySet(k).Sign(&Claims{Subject: "42"})
+		require.NoError(t, err)
+		claims, err := verifier.Parse(token)
+		require.NoError(t, err, k.Algorithm)
+		assert.Equal(t, "42", claims.Subject)
+	}
+
+	_, err = ParseJWKS([]byte("{"))
+	assert.Error(t, err)
+}
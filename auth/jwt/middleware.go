@@ -0,0 +1,95 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jimmitjoo/tjo/api"
+)
+
+type contextKey struct{}
+
+// FromContext returns the claims of the token that authenticated the request
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
+
+// WithClaims returns a context carrying claims, e.g. for testing handlers
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// Middleware verifies bearer tokens and puts their claims on the request
+// context. Requests without a bearer JWT are passed on unauthenticated, so
+// public and protected routes can share it; combine it with
+// api.RequireAuth to require a token. Invalid and expired tokens get 401
+// Unauthorized.
+//
+//	r.Use(tokens.Middleware())
+//	r.With(api.RequireAuth(jwt.Authenticated)).Get("/me", me)
+func (t *Tokens) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := extract(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := t.Verify(token)
+			switch {
+			case errors.Is(err, ErrTokenExpired):
+				api.Unauthorized(w, "Token has expired")
+				return
+			case err != nil:
+				api.Unauthorized(w, "Invalid token")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// Authenticated reports whether Middleware authenticated the request. It
+// is an api.RequireAuth check:
+//
+//	r.Use(tokens.Middleware(), api.RequireAuth(jwt.Authenticated))
+func Authenticated(r *http.Request) (bool, error) {
+	_, ok := FromContext(r.Context())
+	return ok, nil
+}
+
+// RequireScopes requires the token authenticated by Middleware to grant
+// all of scopes. Requests without a token get 401 Unauthorized and tokens
+// without the scopes 403 Forbidden.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := FromContext(r.Context())
+			if !ok {
+				api.Unauthorized(w, "Authentication required")
+				return
+			}
+			if !claims.HasScopes(scopes...) {
+				api.Error(w, http.StatusForbidden, "INSUFFICIENT_SCOPE", "Token does not grant the required scopes",
+					map[string]interface{}{"required_scopes": scopes})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// extract returns the bearer token of the request if it looks like a JWT,
+// leaving other bearer tokens, such as API keys, to other middleware
+func extract(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.Count(token, ".") != 2 {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,82 @@
+package jwt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jimmitjoo/tjo/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tokens := newTestTokens(t)
+	reader, err := tokens.IssueAccess(&Claims{Subject: "reader", Scopes: []string{"posts:read"}})
+	require.NoError(t, err)
+	writer, err := tokens.IssueAccess(&Claims{Subject: "writer", Scopes: []string{"posts:*"}})
+	require.NoError(t, err)
+	expired, err := tokens.IssueAccess(&Claims{Subject: "writer", ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+
+	handler := tokens.Middleware()(api.RequireAuth(Authenticated)(RequireScopes("posts:write")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := FromContext(r.Context())
+			require.True(t, ok)
+			w.Write([]byte(claims.Subject))
+		}))))
+
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+		code   string
+	}{
+		{"missing token", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"not a JWT", "Bearer tjo_0123_abcd", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"invalid token", "Bearer a.b.c", http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"expired token", "Bearer " + expired, http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"missing scope", "Bearer " + reader, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"valid token", "Bearer " + writer, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.code == "" {
+				assert.Equal(t, "writer", rec.Body.String())
+				return
+			}
+
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Error.Code)
+		})
+	}
+}
+
+func TestMiddleware_PassesThroughWithoutToken(t *testing.T) {
+	tokens := newTestTokens(t)
+	handler := tokens.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := FromContext(r.Context())
+		assert.False(t, ok)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -0,0 +1,152 @@
+package jwt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jimmitjoo/tjo"
+)
+
+// Module implements the tjo.Module interface for JWT authentication.
+// Refresh tokens are stored in the refresh_tokens table created by the
+// migrations from `tjo make jwt`; without a database only access tokens
+// can be issued.
+//
+// Example:
+//
+//	app := tjo.Tjo{}
+//	app.New(rootPath, jwt.NewModule(jwt.WithIssuer("https://example.com")))
+//
+//	// Later:
+//	tokens := app.GetModule("jwt").(*jwt.Module).Tokens
+//	r.Use(tokens.Middleware(), api.RequireAuth(jwt.Authenticated))
+type Module struct {
+	Tokens *Tokens
+
+	keys       *KeySet
+	db         *sql.DB
+	table      string
+	issuer     string
+	audience   string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// Option is a function that configures the JWT module
+type Option func(*Module)
+
+// NewModule creates a JWT module
+func NewModule(opts ...Option) *Module {
+	m := &Module{}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// WithKeySet sets the keys tokens are signed and verified with. Defaults
+// to an HS256 key with the JWT_SECRET environment variable as secret.
+func WithKeySet(keys *KeySet) Option {
+	return func(m *Module) {
+		m.keys = keys
+	}
+}
+
+// WithDatabase sets the database refresh tokens are stored in.
+// Defaults to the application's database.
+func WithDatabase(db *sql.DB) Option {
+	return func(m *Module) {
+		m.db = db
+	}
+}
+
+// WithTable sets the table refresh tokens are stored in (default refresh_tokens)
+func WithTable(table string) Option {
+	return func(m *Module) {
+		m.table = table
+	}
+}
+
+// WithIssuer sets the iss claim of issued tokens and requires it on verified ones
+func WithIssuer(issuer string) Option {
+	return func(m *Module) {
+		m.issuer = issuer
+	}
+}
+
+// WithAudience sets the aud claim of issued tokens and requires it on verified ones
+func WithAudience(audience string) Option {
+	return func(m *Module) {
+		m.audience = audience
+	}
+}
+
+// WithTTL sets the lifetime of access and refresh tokens
+// (default 15 minutes and 30 days)
+func WithTTL(access, refresh time.Duration) Option {
+	return func(m *Module) {
+		m.accessTTL = access
+		m.refreshTTL = refresh
+	}
+}
+
+// Name returns the module identifier
+func (m *Module) Name() string {
+	return "jwt"
+}
+
+// Initialize creates the token issuer with the configured keys, and a
+// refresh token store on the configured or application database
+func (m *Module) Initialize(g *tjo.Tjo) error {
+	keys := m.keys
+	if keys == nil {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return errors.New("jwt: a key set or the JWT_SECRET environment variable is required")
+		}
+		key, err := NewHMACKey("default", []byte(secret))
+		if err != nil {
+			return err
+		}
+		keys = NewKeySet(key)
+	}
+
+	db := m.db
+	if db == nil && g != nil && g.Data != nil {
+		db = g.Data.DB.Pool
+	}
+	var store *RefreshStore
+	if db != nil {
+		store = NewRefreshStore(db)
+		if m.table != "" {
+			store.Table = m.table
+		}
+	}
+
+	m.Tokens = NewTokens(keys, store)
+	m.Tokens.Issuer = m.issuer
+	m.Tokens.Audience = m.audience
+	if m.accessTTL > 0 {
+		m.Tokens.AccessTTL = m.accessTTL
+	}
+	if m.refreshTTL > 0 {
+		m.Tokens.RefreshTTL = m.refreshTTL
+	}
+	return nil
+}
+
+// Shutdown is a no-op; the database connection belongs to the application
+func (m *Module) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Middleware verifies bearer tokens. See Tokens.Middleware.
+func (m *Module) Middleware() func(http.Handler) http.Handler {
+	return m.Tokens.Middleware()
+}
@@ -0,0 +1,272 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jimmitjoo/tjo/database"
+)
+
+// DefaultTable is the table refresh tokens are stored in when none is set
+const DefaultTable = "refresh_tokens"
+
+// refreshPrefix starts every refresh token, so leaked tokens are easy to
+// recognize with secret scanners
+const refreshPrefix = "rt_"
+
+// RefreshToken is a stored refresh token. Each rotation creates a new
+// token in the same family, and reuse of a rotated token revokes the
+// family.
+type RefreshToken struct {
+	ID        string
+	FamilyID  string
+	Subject   string
+	Scopes    []string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RefreshStore stores refresh tokens in a database table. The table is
+// created by the migrations from `tjo make jwt`. Only a SHA-256 hash of
+// each token is stored.
+type RefreshStore struct {
+	DB    *sql.DB
+	Table string
+}
+
+// NewRefreshStore creates a store using the default table
+func NewRefreshStore(db *sql.DB) *RefreshStore {
+	return &RefreshStore{DB: db, Table: DefaultTable}
+}
+
+// Create issues a refresh token starting a new family
+func (s *RefreshStore) Create(subject string, scopes []string, expiresAt time.Time) (string, *RefreshToken, error) {
+	family, err := randomHex(16)
+	if err != nil {
+		return "", nil, err
+	}
+	return s.create(family, subject, scopes, expiresAt)
+}
+
+// Rotate exchanges a refresh token for a new one in the same family. It
+// returns ErrInvalidToken for unknown tokens and ErrTokenRevoked and
+// ErrTokenExpired for tokens that can no longer be used. A token that
+// was already rotated returns ErrTokenReused and revokes its family.
+func (s *RefreshStore) Rotate(plain string, expiresAt time.Time) (string, *RefreshToken, error) {
+	token, err := s.verify(plain)
+	if errors.Is(err, ErrTokenReused) {
+		if err := s.revokeFamily(token.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrTokenReused
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Only one of two concurrent rotations can mark the token used
+	res, err := s.query().
+		Where("id", "=", token.ID).
+		WhereNull("used_at").
+		WhereNull("revoked_at").
+		Update(map[string]interface{}{"used_at": time.Now().UTC()})
+	if err != nil {
+		return "", nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return "", nil, err
+	} else if n == 0 {
+		if err := s.revokeFamily(token.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrTokenReused
+	}
+
+	return s.create(token.FamilyID, token.Subject, token.Scopes, expiresAt)
+}
+
+// Verify returns the stored token for a plain text refresh token without
+// using it. It returns the same errors as Rotate, but does not revoke the
+// family of a reused token.
+func (s *RefreshStore) Verify(plain string) (*RefreshToken, error) {
+	token, err := s.verify(plain)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// verify is Verify, but also returns the token of ErrTokenReused so its
+// family can be revoked
+func (s *RefreshStore) verify(plain string) (*RefreshToken, error) {
+	token, err := s.lookup(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case token.RevokedAt != nil:
+		return nil, ErrTokenRevoked
+	case token.UsedAt != nil:
+		return token, ErrTokenReused
+	case !time.Now().Before(token.ExpiresAt):
+		return nil, ErrTokenExpired
+	}
+	return token, nil
+}
+
+// Revoke revokes the family of a refresh token, e.g. on logout. Unknown
+// tokens return ErrInvalidToken.
+func (s *RefreshStore) Revoke(plain string) error {
+	token, err := s.lookup(plain)
+	if err != nil {
+		return err
+	}
+	return s.revokeFamily(token.FamilyID)
+}
+
+// RevokeSubject revokes every refresh token of a subject, e.g. after a
+// password change
+func (s *RefreshStore) RevokeSubject(subject string) error {
+	_, err := s.query().
+		Where("subject", "=", subject).
+		WhereNull("revoked_at").
+		Update(map[string]interface{}{"revoked_at": time.Now().UTC()})
+	return err
+}
+
+// DeleteExpired deletes tokens that expired before cutoff and returns
+// how many were deleted. Keep expired tokens for a while so reuse of a
+// recently rotated token is still detected.
+func (s *RefreshStore) DeleteExpired(cutoff time.Time) (int64, error) {
+	res, err := s.query().Where("expires_at", "<", cutoff.UTC()).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *RefreshStore) create(family, subject string, scopes []string, expiresAt time.Time) (string, *RefreshToken, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	plain := refreshPrefix + id + "_" + secret
+
+	token := &RefreshToken{
+		ID:        id,
+		FamilyID:  family,
+		Subject:   subject,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt.UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err = s.query().Insert(map[string]interface{}{
+		"id":         token.ID,
+		"family_id":  token.FamilyID,
+		"subject":    token.Subject,
+		"token_hash": hashToken(plain),
+		"scopes":     strings.Join(token.Scopes, " "),
+		"expires_at": token.ExpiresAt,
+		"created_at": token.CreatedAt,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+func (s *RefreshStore) revokeFamily(family string) error {
+	_, err := s.query().
+		Where("family_id", "=", family).
+		WhereNull("revoked_at").
+		Update(map[string]interface{}{"revoked_at": time.Now().UTC()})
+	return err
+}
+
+// lookup returns the stored token for a plain text token, whatever its state
+func (s *RefreshStore) lookup(plain string) (*RefreshToken, error) {
+	id, ok := parseRefreshToken(plain)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	token, hash, err := s.find(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(plain))) != 1 {
+		return nil, ErrInvalidToken
+	}
+	return token, nil
+}
+
+func (s *RefreshStore) find(id string) (*RefreshToken, string, error) {
+	row := s.query().
+		Select("id", "family_id", "subject", "token_hash", "scopes", "expires_at", "used_at", "revoked_at", "created_at").
+		Where("id", "=", id).
+		First()
+
+	var token RefreshToken
+	var hash, scopes string
+	var usedAt, revokedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.FamilyID, &token.Subject, &hash, &scopes,
+		&token.ExpiresAt, &usedAt, &revokedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	token.UsedAt = nullTime(usedAt)
+	token.RevokedAt = nullTime(revokedAt)
+	return &token, hash, nil
+}
+
+func (s *RefreshStore) query() *database.QueryBuilder {
+	table := s.Table
+	if table == "" {
+		table = DefaultTable
+	}
+	return database.NewQueryBuilder(s.DB).Table(table)
+}
+
+// parseRefreshToken returns the ID of a plain text refresh token
+func parseRefreshToken(plain string) (string, bool) {
+	rest, ok := strings.CutPrefix(plain, refreshPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 16 || len(secret) != 64 {
+		return "", false
+	}
+	return id, true
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
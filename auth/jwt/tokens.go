@@ -0,0 +1,185 @@
+package jwt
+
+import (
+	"errors"
+	"time"
+)
+
+// Default token lifetimes
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// ErrNoRefreshStore is returned when refresh tokens are used without a store
+var ErrNoRefreshStore = errors.New("jwt: refresh tokens require a refresh store")
+
+// Pair is an access token with its refresh token, encoded like an OAuth 2
+// token response
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// Tokens issues and verifies access tokens signed with Keys, and refresh
+// tokens stored in Store.
+type Tokens struct {
+	Keys  *KeySet
+	Store *RefreshStore
+
+	// Issuer and Audience are set on issued tokens and required on
+	// verified ones when not empty
+	Issuer   string
+	Audience string
+
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+
+	// Leeway is the clock skew allowed when checking expiry
+	Leeway time.Duration
+
+	// RefreshClaims builds the access token claims when a refresh token is
+	// used, e.g. to reload the user's roles. By default the new token has
+	// the subject and scopes of the refresh token.
+	RefreshClaims func(token *RefreshToken) (*Claims, error)
+}
+
+// NewTokens creates a token issuer with the default lifetimes. store may
+// be nil if refresh tokens are not used.
+func NewTokens(keys *KeySet, store *RefreshStore) *Tokens {
+	return &Tokens{
+		Keys:       keys,
+		Store:      store,
+		AccessTTL:  DefaultAccessTTL,
+		RefreshTTL: DefaultRefreshTTL,
+	}
+}
+
+// IssueAccess signs an access token. The issuer, audience, issue time,
+// expiry and ID are filled in unless claims sets them.
+func (t *Tokens) IssueAccess(claims *Claims) (string, error) {
+	token, _, err := t.issueAccess(claims)
+	return token, err
+}
+
+// issueAccess is IssueAccess, also returning the claims that were signed
+func (t *Tokens) issueAccess(claims *Claims) (string, *Claims, error) {
+	c := *claims
+	now := time.Now()
+
+	if c.Issuer == "" {
+		c.Issuer = t.Issuer
+	}
+	if len(c.Audience) == 0 && t.Audience != "" {
+		c.Audience = []string{t.Audience}
+	}
+	if c.IssuedAt.IsZero() {
+		c.IssuedAt = now
+	}
+	if c.ExpiresAt.IsZero() {
+		c.ExpiresAt = now.Add(t.accessTTL())
+	}
+	if c.ID == "" {
+		id, err := randomHex(16)
+		if err != nil {
+			return "", nil, err
+		}
+		c.ID = id
+	}
+
+	token, err := t.Keys.Sign(&c)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &c, nil
+}
+
+// Issue signs an access token and creates a refresh token for its
+// subject and scopes, e.g. after a login
+func (t *Tokens) Issue(claims *Claims) (*Pair, error) {
+	if t.Store == nil {
+		return nil, ErrNoRefreshStore
+	}
+
+	refresh, _, err := t.Store.Create(claims.Subject, claims.Scopes, time.Now().Add(t.refreshTTL()))
+	if err != nil {
+		return nil, err
+	}
+	return t.pair(claims, refresh)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token.
+// The refresh token is rotated, so it cannot be used again; using it
+// again returns ErrTokenReused and revokes every token issued from it.
+func (t *Tokens) Refresh(refreshToken string) (*Pair, error) {
+	if t.Store == nil {
+		return nil, ErrNoRefreshStore
+	}
+
+	refresh, token, err := t.Store.Rotate(refreshToken, time.Now().Add(t.refreshTTL()))
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{Subject: token.Subject, Scopes: token.Scopes}
+	if t.RefreshClaims != nil {
+		if claims, err = t.RefreshClaims(token); err != nil {
+			return nil, err
+		}
+	}
+	return t.pair(claims, refresh)
+}
+
+// Revoke revokes a refresh token and every token rotated from the same
+// login, e.g. on logout
+func (t *Tokens) Revoke(refreshToken string) error {
+	if t.Store == nil {
+		return ErrNoRefreshStore
+	}
+	return t.Store.Revoke(refreshToken)
+}
+
+// Verify verifies an access token and returns its claims. Tokens from
+// another issuer or for another audience return ErrInvalidToken.
+func (t *Tokens) Verify(token string) (*Claims, error) {
+	claims, err := t.Keys.parse(token, time.Now(), t.Leeway)
+	if err != nil {
+		return nil, err
+	}
+	if t.Issuer != "" && claims.Issuer != t.Issuer {
+		return nil, ErrInvalidToken
+	}
+	if t.Audience != "" && !claims.HasAudience(t.Audience) {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (t *Tokens) pair(claims *Claims, refresh string) (*Pair, error) {
+	access, signed, err := t.issueAccess(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &Pair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(signed.ExpiresAt).Round(time.Second).Seconds()),
+		RefreshToken: refresh,
+	}, nil
+}
+
+func (t *Tokens) accessTTL() time.Duration {
+	if t.AccessTTL <= 0 {
+		return DefaultAccessTTL
+	}
+	return t.AccessTTL
+}
+
+func (t *Tokens) refreshTTL() time.Duration {
+	if t.RefreshTTL <= 0 {
+		return DefaultRefreshTTL
+	}
+	return t.RefreshTTL
+}
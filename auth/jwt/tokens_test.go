@@ -0,0 +1,185 @@
+package jwt
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTokens creates a token issuer with a refresh store on an
+// in-memory database using the SQLite migration from the CLI templates
+func newTestTokens(t *testing.T) *Tokens {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile("../../cmd/tjo/templates/migrations/refresh_tokens.sqlite.up.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(schema))
+	require.NoError(t, err)
+
+	key, err := GenerateKey(HS256)
+	require.NoError(t, err)
+	return NewTokens(NewKeySet(key), NewRefreshStore(db))
+}
+
+func TestTokens_IssueAndRefresh(t *testing.T) {
+	tokens := newTestTokens(t)
+	tokens.Issuer = "https://example.com"
+	tokens.Audience = "api"
+
+	pair, err := tokens.Issue(&Claims{Subject: "42", Scopes: []string{"posts:read"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.Equal(t, int(DefaultAccessTTL.Seconds()), pair.ExpiresIn)
+	assert.True(t, strings.HasPrefix(pair.RefreshToken, refreshPrefix))
+
+	claims, err := tokens.Verify(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, "https://example.com", claims.Issuer)
+	assert.Equal(t, []string{"api"}, claims.Audience)
+	assert.NotEmpty(t, claims.ID)
+
+	// Only the hash of the refresh token is stored
+	var hash string
+	id, _ := parseRefreshToken(pair.RefreshToken)
+	require.NoError(t, tokens.Store.DB.QueryRow("SELECT token_hash FROM refresh_tokens WHERE id = ?", id).Scan(&hash))
+	assert.Equal(t, hashToken(pair.RefreshToken), hash)
+
+	refreshed, err := tokens.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, pair.RefreshToken, refreshed.RefreshToken)
+
+	claims, err = tokens.Verify(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "42", claims.Subject)
+	assert.Equal(t, []string{"posts:read"}, claims.Scopes)
+}
+
+func TestTokens_RefreshReuseRevokesFamily(t *testing.T) {
+	tokens := newTestTokens(t)
+
+	first, err := tokens.Issue(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	second, err := tokens.Refresh(first.RefreshToken)
+	require.NoError(t, err)
+
+	// Another login is a separate family and stays valid
+	other, err := tokens.Issue(&Claims{Subject: "42"})
+	require.NoError(t, err)
+
+	_, err = tokens.Refresh(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenReused)
+
+	_, err = tokens.Refresh(second.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked, "the whole family is revoked")
+
+	_, err = tokens.Store.Verify(other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestTokens_RefreshRejects(t *testing.T) {
+	tokens := newTestTokens(t)
+
+	_, err := tokens.Refresh("rt_nope")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	pair, err := tokens.Issue(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	_, err = tokens.Refresh(pair.RefreshToken[:len(pair.RefreshToken)-1] + "0")
+	assert.ErrorIs(t, err, ErrInvalidToken, "wrong secret")
+
+	require.NoError(t, tokens.Revoke(pair.RefreshToken))
+	_, err = tokens.Refresh(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	expired, _, err := tokens.Store.Create("42", nil, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	_, err = tokens.Refresh(expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	n, err := tokens.Store.DeleteExpired(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, err = NewTokens(tokens.Keys, nil).Refresh(pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrNoRefreshStore)
+}
+
+func TestTokens_RevokeSubject(t *testing.T) {
+	tokens := newTestTokens(t)
+
+	a, err := tokens.Issue(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	b, err := tokens.Issue(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	c, err := tokens.Issue(&Claims{Subject: "7"})
+	require.NoError(t, err)
+
+	require.NoError(t, tokens.Store.RevokeSubject("42"))
+
+	_, err = tokens.Refresh(a.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = tokens.Refresh(b.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	_, err = tokens.Refresh(c.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestTokens_RefreshClaims(t *testing.T) {
+	tokens := newTestTokens(t)
+	tokens.RefreshClaims = func(token *RefreshToken) (*Claims, error) {
+		if token.Subject == "deleted" {
+			return nil, errors.New("user deleted")
+		}
+		return &Claims{Subject: token.Subject, Extra: map[string]interface{}{"role": "admin"}}, nil
+	}
+
+	pair, err := tokens.Issue(&Claims{Subject: "42"})
+	require.NoError(t, err)
+	refreshed, err := tokens.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+
+	claims, err := tokens.Verify(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", claims.Extra["role"])
+
+	deleted, err := tokens.Issue(&Claims{Subject: "deleted"})
+	require.NoError(t, err)
+	_, err = tokens.Refresh(deleted.RefreshToken)
+	assert.EqualError(t, err, "user deleted")
+}
+
+func TestTokens_VerifyIssuerAndAudience(t *testing.T) {
+	tokens := newTestTokens(t)
+	tokens.Issuer = "https://example.com"
+	tokens.Audience = "api"
+
+	for name, claims := range map[string]*Claims{
+		"issuer":   {Subject: "42", Issuer: "https://evil.example"},
+		"audience": {Subject: "42", Audience: []string{"admin"}},
+	} {
+		token, err := tokens.IssueAccess(claims)
+		require.NoError(t, err)
+		_, err = tokens.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+
+	token, err := tokens.IssueAccess(&Claims{Subject: "42", ExpiresAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	_, err = tokens.Verify(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	tokens.Leeway = time.Minute
+	_, err = tokens.Verify(token)
+	assert.NoError(t, err)
+}
@@ -13,8 +13,7 @@ type BadgerCache struct {
 }
 
 func (b *BadgerCache) Has(str string) (bool, error) {
-	key := b.Prefix + str
-	_, err := b.Get(key)
+	_, err := b.Get(str)
 	if err != nil {
 		return false, nil
 	}
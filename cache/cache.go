@@ -102,6 +102,29 @@ func (c *RedisCache) Set(str string, value interface{}, ttl ...int) error {
 	return nil
 }
 
+// Add sets the value only if the key does not exist, and reports whether
+// it was set. The ttl is in seconds.
+func (c *RedisCache) Add(str string, value interface{}, ttl int) (bool, error) {
+	key := c.Prefix + str
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	encoded, err := encode(Entry{key: value})
+	if err != nil {
+		return false, err
+	}
+
+	reply, err := conn.Do("SET", key, string(encoded), "EX", ttl, "NX")
+	if err == redis.ErrNil || (err == nil && reply == nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 func (c *RedisCache) Forget(str string) error {
 	key := c.Prefix + str
 	conn := c.Conn.Get()
@@ -58,6 +58,38 @@ func TestRedisCache_Get(t *testing.T) {
 	}
 }
 
+func TestRedisCache_Add(t *testing.T) {
+	err := testRedisCache.Forget("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	added, err := testRedisCache.Add("foo", "bar", 60)
+	if err != nil {
+		t.Error(err)
+	}
+	if !added {
+		t.Error("foo should be added")
+	}
+
+	added, err = testRedisCache.Add("foo", "baz", 60)
+	if err != nil {
+		t.Error(err)
+	}
+	if added {
+		t.Error("foo should not be added twice")
+	}
+
+	val, err := testRedisCache.Get("foo")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if val != "bar" {
+		t.Error("foo should be bar")
+	}
+}
+
 func TestRedisCache_Set(t *testing.T) {
 	err := testRedisCache.Forget("foo")
 	if err != nil {
@@ -36,18 +36,21 @@ func TestMain(m *testing.M) {
 
 	defer testRedisCache.Conn.Close()
 
-	_ = os.RemoveAll("./testdata/tmp/badger")
-
-	if _, err := os.Stat("./testdata/tmp"); os.IsNotExist(err) {
-		err := os.MkdirAll("./testdata/tmp", 0755)
-		if err != nil {
-			log.Fatal(err)
-		}
+	// create a badger database outside the source tree
+	dir, err := os.MkdirTemp("", "tjo-badger-")
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// create a badger database
-	db, _ := badger.Open(badger.DefaultOptions("./testdata/tmp/badger"))
+	db, err := badger.Open(badger.DefaultOptions(dir))
+	if err != nil {
+		log.Fatal(err)
+	}
 	testBadgerCache.Conn = db
 
-	os.Exit(m.Run())
+	code := m.Run()
+
+	_ = db.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
 }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jimmitjoo/tjo"
+	"github.com/jimmitjoo/tjo/apikeys"
+)
+
+// doAPIKey handles tjo apikey create|revoke|list
+func doAPIKey(arg2, arg3 string) error {
+	switch arg2 {
+	case "create":
+		if arg3 == "" || strings.HasPrefix(arg3, "-") {
+			return errors.New("apikey create requires a name")
+		}
+		expiresAt, err := parseExpires(parseFlag("--expires", "-e"))
+		if err != nil {
+			return err
+		}
+		return apiKeyCreate(arg3, splitScopes(parseFlag("--scopes", "-s")), expiresAt)
+
+	case "revoke":
+		if arg3 == "" {
+			return errors.New("apikey revoke requires a key ID")
+		}
+		return apiKeyRevoke(arg3)
+
+	case "list":
+		return apiKeyList()
+
+	default:
+		return errors.New("apikey requires a subcommand: create, revoke or list")
+	}
+}
+
+func apiKeyCreate(name string, scopes []string, expiresAt time.Time) error {
+	store, err := openAPIKeyStore()
+	if err != nil {
+		return err
+	}
+	defer store.DB.Close()
+
+	plain, key, err := store.Create(name, scopes, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	color.Green("API key %s created for %q", key.ID, key.Name)
+	color.Yellow("Store it now, it cannot be shown again:")
+	fmt.Println(plain)
+	return nil
+}
+
+func apiKeyRevoke(id string) error {
+	store, err := openAPIKeyStore()
+	if err != nil {
+		return err
+	}
+	defer store.DB.Close()
+
+	if err := store.Revoke(id); err != nil {
+		return err
+	}
+
+	color.Green("API key %s revoked", id)
+	return nil
+}
+
+func apiKeyList() error {
+	store, err := openAPIKeyStore()
+	if err != nil {
+		return err
+	}
+	defer store.DB.Close()
+
+	keys, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		color.Yellow("No API keys")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSCOPES\tSTATUS\tEXPIRES\tLAST USED\tCREATED")
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, strings.Join(k.Scopes, ","), apiKeyStatus(k),
+			formatTime(k.ExpiresAt), formatTime(k.LastUsedAt), k.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+// openAPIKeyStore connects to the application's database
+func openAPIKeyStore() (*apikeys.Store, error) {
+	if cfg == nil || cfg.Config == nil || !cfg.Config.Database.IsEnabled() {
+		return nil, errors.New("no database configured, set DATABASE_TYPE in .env")
+	}
+
+	var app tjo.Tjo
+	db, err := app.OpenDB(cfg.Config.Database.Type, cfg.Config.Database.DSN(cfg.RootPath))
+	if err != nil {
+		return nil, err
+	}
+	return apikeys.NewStore(db), nil
+}
+
+func apiKeyStatus(k *apikeys.Key) string {
+	switch {
+	case k.IsRevoked():
+		return "revoked"
+	case k.IsExpired():
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// parseExpires parses an expiry like 90d or 720h. Empty means never.
+func parseExpires(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return time.Time{}, fmt.Errorf("invalid expiry %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid expiry %q, use e.g. 90d or 720h", s)
+		}
+	}
+
+	return time.Now().Add(d), nil
+}
+
+func splitScopes(s string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// parseFlag returns the value of a flag given as --name value, -n value
+// or --name=value
+func parseFlag(long, short string) string {
+	for i, arg := range os.Args {
+		if arg == long || arg == short {
+			if i+1 < len(os.Args) {
+				return os.Args[i+1]
+			}
+		}
+		if v, ok := strings.CutPrefix(arg, long+"="); ok {
+			return v
+		}
+		if v, ok := strings.CutPrefix(arg, short+"="); ok {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jimmitjoo/tjo/config"
+	"github.com/jimmitjoo/tjo/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyCommands(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "data"), 0755))
+
+	originalCfg := cfg
+	defer func() { cfg = originalCfg }()
+	cfg = &core.CLIConfig{
+		RootPath: tempDir,
+		DBType:   "sqlite",
+		Config: &config.Config{
+			Database: config.DatabaseConfig{Type: "sqlite", Name: "app.db"},
+		},
+	}
+
+	require.NoError(t, doMake("apikey", ""))
+
+	matches, err := filepath.Glob(filepath.Join(tempDir, "migrations", "*_create_api_keys_table.sqlite.*.sql"))
+	require.NoError(t, err)
+	assert.Len(t, matches, 2)
+
+	require.NoError(t, apiKeyCreate("ci", []string{"posts:read"}, time.Time{}))
+
+	store, err := openAPIKeyStore()
+	require.NoError(t, err)
+	defer store.DB.Close()
+
+	keys, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "ci", keys[0].Name)
+	assert.Equal(t, []string{"posts:read"}, keys[0].Scopes)
+
+	require.NoError(t, apiKeyList())
+	require.NoError(t, doAPIKey("revoke", keys[0].ID))
+
+	key, err := store.Find(keys[0].ID)
+	require.NoError(t, err)
+	assert.True(t, key.IsRevoked())
+
+	assert.Error(t, doAPIKey("create", ""))
+	assert.Error(t, doAPIKey("revoke", ""))
+	assert.Error(t, doAPIKey("unknown", ""))
+}
+
+func TestParseExpires(t *testing.T) {
+	never, err := parseExpires("")
+	require.NoError(t, err)
+	assert.True(t, never.IsZero())
+
+	days, err := parseExpires("90d")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(90*24*time.Hour), days, time.Minute)
+
+	hours, err := parseExpires("12h")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(12*time.Hour), hours, time.Minute)
+
+	for _, s := range []string{"soon", "-1d", "0d", "-5h"} {
+		_, err := parseExpires(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSplitScopes(t *testing.T) {
+	assert.Equal(t, []string{"posts:read", "posts:write"}, splitScopes("posts:read, posts:write,"))
+	assert.Nil(t, splitScopes(""))
+}
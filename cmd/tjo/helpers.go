@@ -71,6 +71,10 @@ func getDSN() string {
 		return dsn
 	}
 
+	if dbType == "sqlite" || dbType == "sqlite3" {
+		return "sqlite3://" + cfg.Config.Database.DSN(cfg.RootPath)
+	}
+
 	// Build MySQL DSN from config
 	return "mysql://" + cfg.Config.Database.DSN(cfg.RootPath)
 }
@@ -94,6 +98,14 @@ func showHelp() {
 	make migration <name>    - creates up/down migrations
 	make model <name>        - creates a new model
 	make session             - creates session table
+	make apikey              - creates api_keys table
+	apikey create <name>     - issues an API key
+	  -s, --scopes <list>    - comma separated scopes, e.g. posts:read,posts:write
+	  -e, --expires <time>   - expiry, e.g. 90d or 720h (default never)
+	apikey revoke <id>       - revokes an API key
+	apikey list              - lists API keys
+	make notifications       - creates notifications table
+	make jwt                 - creates refresh_tokens table
 	make mail <name>         - creates email template
 
 Examples:
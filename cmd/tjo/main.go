@@ -63,6 +63,12 @@ func main() {
 
 		message = "Migrations completed"
 
+	case "apikey":
+		err = doAPIKey(arg2, arg3)
+		if err != nil {
+			exitGracefully(err)
+		}
+
 	case "run":
 		watch := arg2 == "--watch" || arg2 == "-w"
 		err = doRun(watch)
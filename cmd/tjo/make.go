@@ -135,6 +135,15 @@ func doMake(arg2, arg3 string) error {
 	case "session":
 		return doSession()
 
+	case "apikey":
+		return makeTableMigration("api_keys")
+
+	case "notifications":
+		return makeTableMigration("notifications")
+
+	case "jwt":
+		return makeTableMigration("refresh_tokens")
+
 	case "api-controller":
 		return doAPIController(arg3)
 
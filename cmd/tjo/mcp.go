@@ -57,7 +57,7 @@ Environment variables:
 
 Usage:
   if m := app.GetModule("sms"); m != nil {
-      m.(*sms.Module).Send("+46701234567", "Hello!", false)
+      result, err := m.(*sms.Module).Send("+46701234567", "Hello!")
   }`
 
 	case "email":
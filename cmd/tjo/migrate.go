@@ -1,6 +1,12 @@
 package main
 
-import "github.com/jimmitjoo/tjo/core"
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jimmitjoo/tjo/core"
+)
 
 func doMigrate(arg2, arg3 string) error {
 	dsn := getDSN()
@@ -41,3 +47,42 @@ func doMigrate(arg2, arg3 string) error {
 	}
 	return nil
 }
+
+// makeTableMigration creates and runs the migration for a framework table,
+// using the templates/migrations/<table>.<db>.up.sql template
+func makeTableMigration(table string) error {
+	rootPath := getRootPath()
+
+	dbType, err := promptForDatabase()
+	if err != nil {
+		return err
+	}
+	switch dbType {
+	case "pgx", "postgresql":
+		dbType = "postgres"
+	case "mariadb":
+		dbType = "mysql"
+	case "sqlite3":
+		dbType = "sqlite"
+	}
+
+	if err := os.MkdirAll(rootPath+"/migrations", 0755); err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("%d_create_%s_table", time.Now().UnixMicro(), table)
+	upFile := rootPath + "/migrations/" + fileName + "." + dbType + ".up.sql"
+	downFile := rootPath + "/migrations/" + fileName + "." + dbType + ".down.sql"
+
+	err = copyFileFromTemplate("templates/migrations/"+table+"."+dbType+".up.sql", upFile)
+	if err != nil {
+		return err
+	}
+
+	err = copyDataToFile([]byte("DROP TABLE IF EXISTS "+table+";"), downFile)
+	if err != nil {
+		return err
+	}
+
+	return doMigrate("up", "")
+}
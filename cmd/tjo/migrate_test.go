@@ -486,4 +486,37 @@ func TestMigrationRollback(t *testing.T) {
 	for i := 0; i < len(downFiles)-1; i++ {
 		assert.True(t, downFiles[i] > downFiles[i+1], "Rollback files should be in reverse chronological order")
 	}
-}
\ No newline at end of file
+}
+
+func TestTableMigrations(t *testing.T) {
+	tests := []struct {
+		make  string
+		table string
+	}{
+		{"notifications", "notifications"},
+		{"jwt", "refresh_tokens"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.make, func(t *testing.T) {
+			tempDir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "data"), 0755))
+
+			originalCfg := cfg
+			defer func() { cfg = originalCfg }()
+			cfg = &core.CLIConfig{
+				RootPath: tempDir,
+				DBType:   "sqlite",
+				Config: &config.Config{
+					Database: config.DatabaseConfig{Type: "sqlite", Name: "app.db"},
+				},
+			}
+
+			require.NoError(t, doMake(tt.make, ""))
+
+			matches, err := filepath.Glob(filepath.Join(tempDir, "migrations", "*_create_"+tt.table+"_table.sqlite.*.sql"))
+			require.NoError(t, err)
+			assert.Len(t, matches, 2)
+		})
+	}
+}
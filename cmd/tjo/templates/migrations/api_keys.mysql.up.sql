@@ -0,0 +1,12 @@
+CREATE TABLE `api_keys` (
+    `id` varchar(16) NOT NULL,
+    `name` varchar(255) NOT NULL,
+    `key_hash` char(64) NOT NULL,
+    `scopes` text NOT NULL,
+    `expires_at` timestamp NULL DEFAULT NULL,
+    `last_used_at` timestamp NULL DEFAULT NULL,
+    `revoked_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`id`),
+    KEY `api_keys_created_at_index` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,10 @@
+CREATE TABLE `notifications` (
+    `id` varchar(64) NOT NULL,
+    `user_id` varchar(255) NOT NULL,
+    `type` varchar(255) NOT NULL,
+    `data` text NOT NULL,
+    `read_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`id`),
+    KEY `notifications_user_id_read_at_index` (`user_id`, `read_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
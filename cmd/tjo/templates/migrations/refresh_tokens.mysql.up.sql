@@ -0,0 +1,14 @@
+CREATE TABLE `refresh_tokens` (
+    `id` varchar(16) NOT NULL,
+    `family_id` varchar(32) NOT NULL,
+    `subject` varchar(255) NOT NULL,
+    `token_hash` char(64) NOT NULL,
+    `scopes` text NOT NULL,
+    `expires_at` timestamp NOT NULL,
+    `used_at` timestamp NULL DEFAULT NULL,
+    `revoked_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`id`),
+    KEY `refresh_tokens_family_id_index` (`family_id`),
+    KEY `refresh_tokens_subject_index` (`subject`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,14 @@
+CREATE TABLE refresh_tokens (
+    id VARCHAR(16) PRIMARY KEY,
+    family_id VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_subject_idx ON refresh_tokens (subject);
@@ -0,0 +1,14 @@
+CREATE TABLE refresh_tokens (
+    id VARCHAR(16) PRIMARY KEY,
+    family_id VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_subject_idx ON refresh_tokens (subject);
@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all application configuration grouped by domain.
@@ -43,6 +44,11 @@ type ServerConfig struct {
 	ServerName string
 	URL        string
 	Secure     bool
+
+	// PreStopDelay is how long to keep serving after a shutdown signal
+	// while readiness checks fail, so load balancers stop sending requests
+	// before the server stops accepting them
+	PreStopDelay time.Duration
 }
 
 // DatabaseConfig holds database connection settings
@@ -166,6 +172,7 @@ func Load() (*Config, error) {
 	cfg.Server.ServerName = os.Getenv("SERVER_NAME")
 	cfg.Server.URL = os.Getenv("APP_URL")
 	cfg.Server.Secure = envBool("SECURE", true)
+	cfg.Server.PreStopDelay = time.Duration(envInt("SERVER_PRESTOP_DELAY", 0)) * time.Second
 
 	// Database config
 	cfg.Database.Type = os.Getenv("DATABASE_TYPE")
@@ -261,6 +268,9 @@ func (c *Config) Validate() error {
 	if c.Server.Port < 1 || c.Server.Port > 65535 {
 		errs = append(errs, fmt.Sprintf("invalid PORT: %d (must be 1-65535)", c.Server.Port))
 	}
+	if c.Server.PreStopDelay < 0 {
+		errs = append(errs, fmt.Sprintf("invalid SERVER_PRESTOP_DELAY: %s (must not be negative)", c.Server.PreStopDelay))
+	}
 
 	// Database validation (only if type is set)
 	if c.Database.Type != "" {
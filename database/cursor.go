@@ -0,0 +1,427 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed, were signed
+// with another key or do not match the query's ORDER BY columns
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrNoCursorKey is returned when cursors can't be signed because
+// SetCursorKey was called with an empty key
+var ErrNoCursorKey = errors.New("no cursor key set")
+
+// cursorKeyContext separates the cursor signing key from other uses of the
+// application's encryption key
+const cursorKeyContext = "tjo-cursor-v1"
+
+var (
+	cursorKeyMu sync.RWMutex
+	cursorKey   = randomCursorKey()
+)
+
+func randomCursorKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// SetCursorKey sets the secret cursors are signed with. The application
+// sets it to its encryption key; the signing key is derived from it with
+// HMAC-SHA256, so the encryption key itself never signs cursors. Until
+// then a random per-process key is used, so cursors are not valid across
+// restarts or instances. With an empty key cursors are not signed and
+// CursorPaginate returns ErrNoCursorKey.
+func SetCursorKey(key []byte) {
+	cursorKeyMu.Lock()
+	defer cursorKeyMu.Unlock()
+
+	if len(key) == 0 {
+		cursorKey = nil
+		return
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(cursorKeyContext))
+	cursorKey = h.Sum(nil)
+}
+
+// CursorPage is a page of results from CursorPaginate
+type CursorPage struct {
+	Rows       []map[string]interface{}
+	NextCursor string // Empty on the last page
+	PrevCursor string // Empty on the first page
+}
+
+// cursorColumn is a parsed ORDER BY column
+type cursorColumn struct {
+	name string // Column as written in the query, e.g. posts.id
+	key  string // Key in the result row, e.g. id
+	desc bool
+}
+
+// cursorPayload is the signed content of a cursor
+type cursorPayload struct {
+	Columns  []string      `json:"c"`
+	Values   []cursorValue `json:"v"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+// cursorValue keeps the type of a column value through JSON
+type cursorValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+// CursorPaginate returns the page of up to perPage rows after cursor, using
+// keyset pagination over the query's ORDER BY columns instead of OFFSET.
+// Pass an empty cursor for the first page and NextCursor or PrevCursor of
+// a page for the following or preceding one.
+//
+// The ORDER BY columns must together be unique, so end them with the
+// primary key, must be selected and must not be NULL:
+//
+//	page, err := db.Table("posts").
+//	    Where("published", "=", true).
+//	    OrderBy("created_at", "DESC").
+//	    OrderBy("id", "DESC").
+//	    CursorPaginate(r.URL.Query().Get("cursor"), 20)
+//
+// Cursors are opaque and signed (see SetCursorKey), so clients cannot
+// change them to read other rows. ErrInvalidCursor is returned for
+// cursors that fail verification.
+func (qb *QueryBuilder) CursorPaginate(cursor string, perPage int) (*CursorPage, error) {
+	if qb.err != nil {
+		return nil, qb.err
+	}
+	if perPage < 1 {
+		perPage = 15
+	}
+
+	columns, err := qb.cursorColumns()
+	if err != nil {
+		return nil, err
+	}
+
+	var payload *cursorPayload
+	if cursor != "" {
+		payload, err = decodeCursor(cursor, columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+	backward := payload != nil && payload.Backward
+
+	pageQB := qb.keysetQuery(columns, payload, perPage+1)
+	rows, err := pageQB.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results, err := scanRowMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(results) > perPage
+	if hasMore {
+		results = results[:perPage]
+	}
+	if backward {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+	}
+
+	page := &CursorPage{Rows: results}
+	if len(results) == 0 {
+		return page, nil
+	}
+
+	// Going forward there is a previous page if we came from a cursor and
+	// a next page if there were more rows; backward it is the other way round
+	hasNext, hasPrev := hasMore, payload != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if hasNext {
+		if page.NextCursor, err = encodeCursor(columns, results[len(results)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = encodeCursor(columns, results[0], true); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// cursorColumns parses the ORDER BY clause
+func (qb *QueryBuilder) cursorColumns() ([]cursorColumn, error) {
+	if len(qb.orderBy) == 0 {
+		return nil, fmt.Errorf("cursor pagination requires an ORDER BY clause")
+	}
+
+	columns := make([]cursorColumn, len(qb.orderBy))
+	for i, order := range qb.orderBy {
+		name, dir, _ := strings.Cut(order, " ")
+		key := name
+		if _, after, ok := strings.Cut(name, "."); ok {
+			key = after
+		}
+		columns[i] = cursorColumn{
+			name: name,
+			key:  strings.Trim(key, "`\""),
+			desc: dir == "DESC",
+		}
+	}
+	return columns, nil
+}
+
+// keysetQuery copies the query with the keyset condition, ORDER BY and
+// LIMIT of a page. Backward pages are fetched in reverse order.
+func (qb *QueryBuilder) keysetQuery(columns []cursorColumn, payload *cursorPayload, limit int) *QueryBuilder {
+	backward := payload != nil && payload.Backward
+
+	page := &QueryBuilder{
+		db:             qb.db,
+		rawDB:          qb.rawDB,
+		table:          qb.table,
+		selectCols:     qb.selectCols,
+		whereConds:     qb.whereConds,
+		groupBy:        qb.groupBy,
+		having:         qb.having,
+		joins:          qb.joins,
+		limitCount:     limit,
+		includeTrashed: qb.includeTrashed,
+	}
+
+	for _, col := range columns {
+		dir := "ASC"
+		if col.desc != backward {
+			dir = "DESC"
+		}
+		page.orderBy = append(page.orderBy, col.name+" "+dir)
+	}
+
+	if payload == nil {
+		return page
+	}
+
+	values, _ := payload.decodeValues()
+
+	// (a > ?) OR (a = ? AND b > ?) OR ... for a, b ordered ascending
+	var clauses []string
+	var params []interface{}
+	for i, col := range columns {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, columns[j].name+" = ?")
+			params = append(params, values[j])
+		}
+		op := ">"
+		if col.desc != backward {
+			op = "<"
+		}
+		parts = append(parts, col.name+" "+op+" ?")
+		params = append(params, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	page.keyset = &whereCondition{
+		value:  strings.Join(clauses, " OR "),
+		params: params,
+	}
+	return page
+}
+
+// scanRowMaps reads all rows into maps keyed by column name
+func scanRowMaps(rows *sql.Rows) ([]map[string]interface{}, error) {
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(names))
+		ptrs := make([]interface{}, len(names))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[name] = values[i]
+		}
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
+
+// encodeCursor signs a cursor pointing at row
+func encodeCursor(columns []cursorColumn, row map[string]interface{}, backward bool) (string, error) {
+	payload := cursorPayload{Backward: backward}
+	for _, col := range columns {
+		v, ok := row[col.key]
+		if !ok {
+			return "", fmt.Errorf("cursor column %q is not selected", col.name)
+		}
+		if v == nil {
+			return "", fmt.Errorf("cursor column %q is NULL", col.name)
+		}
+
+		cv, err := newCursorValue(v)
+		if err != nil {
+			return "", fmt.Errorf("cursor column %q: %w", col.name, err)
+		}
+		payload.Columns = append(payload.Columns, col.name)
+		payload.Values = append(payload.Values, cv)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := signCursor(data)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." +
+		base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// decodeCursor verifies a cursor and checks it matches the columns
+func decodeCursor(cursor string, columns []cursorColumn) (*cursorPayload, error) {
+	encoded, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	expected, err := signCursor(data)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, expected) {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if len(payload.Columns) != len(columns) || len(payload.Values) != len(columns) {
+		return nil, ErrInvalidCursor
+	}
+	for i, col := range columns {
+		if payload.Columns[i] != col.name {
+			return nil, ErrInvalidCursor
+		}
+	}
+	if _, err := payload.decodeValues(); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &payload, nil
+}
+
+func signCursor(data []byte) ([]byte, error) {
+	cursorKeyMu.RLock()
+	defer cursorKeyMu.RUnlock()
+
+	if len(cursorKey) == 0 {
+		return nil, ErrNoCursorKey
+	}
+	h := hmac.New(sha256.New, cursorKey)
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+func newCursorValue(v interface{}) (cursorValue, error) {
+	var typ string
+	switch t := v.(type) {
+	case time.Time:
+		typ = "time"
+		v = t.Format(time.RFC3339Nano)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		typ = "int"
+	case float32, float64:
+		typ = "float"
+	case bool:
+		typ = "bool"
+	case string:
+		typ = "string"
+	default:
+		return cursorValue{}, fmt.Errorf("unsupported type %T", v)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return cursorValue{}, err
+	}
+	return cursorValue{Type: typ, Value: data}, nil
+}
+
+// decodeValues returns the typed values of the cursor
+func (p *cursorPayload) decodeValues() ([]interface{}, error) {
+	values := make([]interface{}, len(p.Values))
+	for i, cv := range p.Values {
+		var err error
+		switch cv.Type {
+		case "time":
+			var s string
+			if err = json.Unmarshal(cv.Value, &s); err == nil {
+				values[i], err = time.Parse(time.RFC3339Nano, s)
+			}
+		case "int":
+			var n int64
+			err = json.Unmarshal(cv.Value, &n)
+			values[i] = n
+		case "float":
+			var f float64
+			err = json.Unmarshal(cv.Value, &f)
+			values[i] = f
+		case "bool":
+			var b bool
+			err = json.Unmarshal(cv.Value, &b)
+			values[i] = b
+		case "string":
+			var s string
+			err = json.Unmarshal(cv.Value, &s)
+			values[i] = s
+		default:
+			err = fmt.Errorf("unknown cursor value type %q", cv.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCursorDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT, score INTEGER, published BOOLEAN)`)
+	require.NoError(t, err)
+
+	// Scores repeat so the id decides the order within a score
+	for i := 1; i <= 7; i++ {
+		_, err = db.Exec(`INSERT INTO posts (id, title, score, published) VALUES (?, ?, ?, ?)`,
+			i, fmt.Sprintf("post %d", i), i/3, i != 4)
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func pageIDs(page *CursorPage) []int64 {
+	ids := make([]int64, len(page.Rows))
+	for i, row := range page.Rows {
+		ids[i] = row["id"].(int64)
+	}
+	return ids
+}
+
+func TestCursorPaginate(t *testing.T) {
+	db := setupCursorDB(t)
+	query := func() *QueryBuilder {
+		return NewQueryBuilder(db).Table("posts").OrderBy("score", "DESC").OrderBy("id", "ASC")
+	}
+
+	first, err := query().CursorPaginate("", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{6, 7, 3}, pageIDs(first))
+	assert.NotEmpty(t, first.NextCursor)
+	assert.Empty(t, first.PrevCursor)
+
+	second, err := query().CursorPaginate(first.NextCursor, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4, 5, 1}, pageIDs(second))
+	assert.NotEmpty(t, second.PrevCursor)
+
+	last, err := query().CursorPaginate(second.NextCursor, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, pageIDs(last))
+	assert.Empty(t, last.NextCursor)
+
+	back, err := query().CursorPaginate(last.PrevCursor, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4, 5, 1}, pageIDs(back))
+	assert.NotEmpty(t, back.NextCursor)
+	assert.NotEmpty(t, back.PrevCursor)
+
+	start, err := query().CursorPaginate(back.PrevCursor, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{6, 7, 3}, pageIDs(start))
+	assert.Empty(t, start.PrevCursor, "first page reached going back")
+	assert.NotEmpty(t, start.NextCursor)
+}
+
+func TestCursorPaginateWithConditions(t *testing.T) {
+	db := setupCursorDB(t)
+	query := func() *QueryBuilder {
+		return NewQueryBuilder(db).Table("posts").
+			Where("published", "=", true).
+			OrWhere("id", "=", 4).
+			OrderBy("id", "DESC")
+	}
+
+	first, err := query().CursorPaginate("", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{7, 6, 5, 4}, pageIDs(first))
+
+	second, err := query().CursorPaginate(first.NextCursor, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 2, 1}, pageIDs(second))
+	assert.Empty(t, second.NextCursor)
+
+	payload, err := decodeCursor(first.NextCursor, []cursorColumn{{name: "id", key: "id", desc: true}})
+	require.NoError(t, err)
+	sqlStr, params, err := query().keysetQuery([]cursorColumn{{name: "id", key: "id", desc: true}}, payload, 5).ToSQL()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM posts WHERE (published = ? OR id = ?) AND ((id < ?)) ORDER BY id DESC LIMIT 5", sqlStr)
+	assert.Equal(t, []interface{}{true, 4, int64(4)}, params)
+}
+
+func TestCursorPaginateInvalidCursor(t *testing.T) {
+	db := setupCursorDB(t)
+	byID := NewQueryBuilder(db).Table("posts").OrderBy("id", "ASC")
+
+	page, err := byID.CursorPaginate("", 2)
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"garbage":      "not-a-cursor",
+		"tampered":     "x" + page.NextCursor,
+		"no signature": page.NextCursor[:len(page.NextCursor)-3],
+	}
+	for name, cursor := range tests {
+		_, err := byID.CursorPaginate(cursor, 2)
+		assert.ErrorIs(t, err, ErrInvalidCursor, name)
+	}
+
+	// A cursor from another ordering is rejected
+	_, err = NewQueryBuilder(db).Table("posts").OrderBy("score", "ASC").OrderBy("id", "ASC").CursorPaginate(page.NextCursor, 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Cursors signed with another key are rejected
+	SetCursorKey([]byte("other key"))
+	defer SetCursorKey(randomCursorKey())
+	_, err = byID.CursorPaginate(page.NextCursor, 2)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = NewQueryBuilder(db).Table("posts").CursorPaginate("", 2)
+	assert.Error(t, err, "ORDER BY is required")
+}
+
+func TestCursorKey(t *testing.T) {
+	db := setupCursorDB(t)
+	byID := NewQueryBuilder(db).Table("posts").OrderBy("id", "ASC")
+	defer SetCursorKey(randomCursorKey())
+
+	// The signing key is derived from the secret rather than being the secret
+	SetCursorKey([]byte("encryption key"))
+	page, err := byID.CursorPaginate("", 2)
+	require.NoError(t, err)
+
+	encoded, sig, _ := strings.Cut(page.NextCursor, ".")
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	h := hmac.New(sha256.New, []byte("encryption key"))
+	h.Write(data)
+	assert.NotEqual(t, base64.RawURLEncoding.EncodeToString(h.Sum(nil)), sig)
+
+	// Without a key cursors are neither signed nor accepted
+	SetCursorKey(nil)
+	_, err = byID.CursorPaginate("", 2)
+	assert.ErrorIs(t, err, ErrNoCursorKey)
+	_, err = byID.CursorPaginate(page.NextCursor, 2)
+	assert.ErrorIs(t, err, ErrNoCursorKey)
+}
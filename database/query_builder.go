@@ -87,8 +87,9 @@ type QueryBuilder struct {
 	offsetCount    int
 	unionQuery     *QueryBuilder
 	unionAll       bool
-	err            error // Stores validation errors
-	includeTrashed bool  // For soft delete support
+	err            error           // Stores validation errors
+	includeTrashed bool            // For soft delete support
+	keyset         *whereCondition // Cursor condition set by CursorPaginate
 }
 
 type whereCondition struct {
@@ -478,8 +479,14 @@ func (qb *QueryBuilder) ToSQL() (string, []interface{}, error) {
 	}
 
 	// WHERE clauses
-	if len(qb.whereConds) > 0 {
+	if len(qb.whereConds) > 0 || qb.keyset != nil {
 		query.WriteString(" WHERE ")
+	}
+	if len(qb.whereConds) > 0 {
+		// Group the conditions so OR WHERE cannot bypass the cursor
+		if qb.keyset != nil {
+			query.WriteString("(")
+		}
 		for i, cond := range qb.whereConds {
 			if i > 0 {
 				query.WriteString(fmt.Sprintf(" %s ", cond.logic))
@@ -499,6 +506,13 @@ func (qb *QueryBuilder) ToSQL() (string, []interface{}, error) {
 				params = append(params, cond.value)
 			}
 		}
+		if qb.keyset != nil {
+			query.WriteString(") AND ")
+		}
+	}
+	if qb.keyset != nil {
+		query.WriteString(fmt.Sprintf("(%s)", qb.keyset.value))
+		params = append(params, qb.keyset.params...)
 	}
 
 	// GROUP BY clause
@@ -0,0 +1,184 @@
+package email
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	sp "github.com/SparkPost/gosparkpost"
+	"github.com/mailgun/mailgun-go/v4"
+	"github.com/sendgrid/rest"
+	"github.com/sendgrid/sendgrid-go"
+	sgmail "github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+// apiMessage is a message rendered for an API provider
+type apiMessage struct {
+	Message
+	HTML        string
+	PlainText   string
+	Attachments []apiAttachment
+}
+
+type apiAttachment struct {
+	Filename string
+	Content  []byte
+}
+
+func (a apiAttachment) mime() string {
+	return http.DetectContentType(a.Content)
+}
+
+func (a apiAttachment) base64() string {
+	return base64.StdEncoding.EncodeToString(a.Content)
+}
+
+// ChooseAPI sends msg through the API provider set in m.API
+func (m *Mail) ChooseAPI(msg Message) error {
+	return m.chooseAPI(context.Background(), msg)
+}
+
+func (m *Mail) chooseAPI(ctx context.Context, msg Message) error {
+	switch m.API {
+	case "mailgun", "sparkpost", "sendgrid":
+		return m.sendUsingAPI(ctx, msg, m.API)
+	default:
+		return fmt.Errorf("API %s is not supported", m.API)
+	}
+}
+
+// SendUsingAPI sends msg through the mailgun, sparkpost or sendgrid API
+func (m *Mail) SendUsingAPI(msg Message, transport string) error {
+	return m.sendUsingAPI(context.Background(), msg, transport)
+}
+
+func (m *Mail) sendUsingAPI(ctx context.Context, msg Message, transport string) error {
+	if msg.From == "" {
+		msg.From = m.From
+	}
+	if msg.FromName == "" {
+		msg.FromName = m.FromName
+	}
+
+	var send func(context.Context, *apiMessage) error
+	switch transport {
+	case "mailgun":
+		send = m.sendMailgun
+	case "sparkpost":
+		send = m.sendSparkPost
+	case "sendgrid":
+		send = m.sendSendGrid
+	default:
+		return fmt.Errorf("API %s is not supported", transport)
+	}
+
+	formattedMessage, err := m.buildHTMLMessage(msg)
+	if err != nil {
+		return err
+	}
+	plainTextMessage, err := m.buildPlainTextMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	am := &apiMessage{Message: msg, HTML: formattedMessage, PlainText: plainTextMessage}
+	for _, attachment := range msg.Attachments {
+		content, err := os.ReadFile(attachment)
+		if err != nil {
+			return err
+		}
+		am.Attachments = append(am.Attachments, apiAttachment{Filename: filepath.Base(attachment), Content: content})
+	}
+
+	return send(ctx, am)
+}
+
+func (m *Mail) httpClient() *http.Client {
+	if m.HTTPClient != nil {
+		return m.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (m *Mail) sendMailgun(ctx context.Context, msg *apiMessage) error {
+	mg := mailgun.NewMailgun(m.Domain, m.APIKey)
+	mg.SetAPIBase(m.APIUrl)
+	mg.SetClient(m.httpClient())
+
+	message := mg.NewMessage(msg.From, msg.Subject, msg.PlainText, msg.To)
+	message.SetHtml(msg.HTML)
+	for _, a := range msg.Attachments {
+		message.AddBufferAttachment(a.Filename, a.Content)
+	}
+
+	_, _, err := mg.Send(ctx, message)
+	return err
+}
+
+func (m *Mail) sendSparkPost(ctx context.Context, msg *apiMessage) error {
+	client := sp.Client{Client: m.httpClient()}
+	err := client.Init(&sp.Config{BaseUrl: m.APIUrl, ApiKey: m.APIKey, ApiVersion: 1})
+	if err != nil {
+		return err
+	}
+
+	content := sp.Content{
+		HTML:    msg.HTML,
+		Text:    msg.PlainText,
+		From:    sp.From{Email: msg.From, Name: msg.FromName},
+		Subject: msg.Subject,
+	}
+	for _, a := range msg.Attachments {
+		content.Attachments = append(content.Attachments, sp.Attachment{MIMEType: a.mime(), Filename: a.Filename, B64Data: a.base64()})
+	}
+
+	_, res, err := client.SendContext(ctx, &sp.Transmission{Recipients: []string{msg.To}, Content: content})
+	if err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		return res.Errors
+	}
+	return nil
+}
+
+func (m *Mail) sendSendGrid(ctx context.Context, msg *apiMessage) error {
+	message := sgmail.NewV3Mail()
+	message.SetFrom(sgmail.NewEmail(msg.FromName, msg.From))
+	message.Subject = msg.Subject
+
+	p := sgmail.NewPersonalization()
+	p.AddTos(sgmail.NewEmail("", msg.To))
+	message.AddPersonalizations(p)
+
+	if msg.PlainText != "" {
+		message.AddContent(sgmail.NewContent("text/plain", msg.PlainText))
+	}
+	message.AddContent(sgmail.NewContent("text/html", msg.HTML))
+
+	for _, a := range msg.Attachments {
+		attachment := sgmail.NewAttachment()
+		attachment.SetContent(a.base64())
+		attachment.SetType(a.mime())
+		attachment.SetFilename(a.Filename)
+		attachment.SetDisposition("attachment")
+		message.AddAttachment(attachment)
+	}
+
+	request := sendgrid.GetRequest(m.APIKey, "/v3/mail/send", "")
+	request.Method = rest.Post
+	request.Body = sgmail.GetRequestBody(message)
+
+	client := rest.Client{HTTPClient: m.httpClient()}
+	res, err := client.SendWithContext(ctx, request)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("sendgrid: status %d: %s", res.StatusCode, res.Body)
+	}
+	return nil
+}
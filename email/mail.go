@@ -2,15 +2,19 @@ package email
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"net/http"
+	"net/url"
 	"text/template"
 	"time"
 
-	apimail "github.com/ainsleyclark/go-mail"
 	"github.com/vanng822/go-premailer/premailer"
 	mail "github.com/xhit/go-simple-mail/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Mail struct {
@@ -28,6 +32,10 @@ type Mail struct {
 	API        string
 	APIKey     string
 	APIUrl     string
+
+	// HTTPClient sends API requests, http.DefaultClient if nil. Use
+	// otel.NewHTTPClient(nil) to trace them as child spans of the send.
+	HTTPClient *http.Client
 }
 
 type Message struct {
@@ -45,6 +53,8 @@ type Result struct {
 	Error   error
 }
 
+// ListenForMail sends the messages queued on Jobs. Queued messages carry
+// no context, so each send starts a new trace.
 func (m *Mail) ListenForMail() {
 	for {
 		msg := <-m.Jobs
@@ -57,99 +67,54 @@ func (m *Mail) ListenForMail() {
 	}
 }
 
+// Send sends msg through the configured API or SMTP server
 func (m *Mail) Send(msg Message) error {
-	var err error
-	if m.API != "" && m.APIKey != "" && m.APIUrl != "" && m.API != "smtp" {
-		// TODO: err = m.SendAPI(msg)
-		return m.ChooseAPI(msg)
-	} else {
-		err = m.SendSMTPMessage(msg)
-	}
-	return err
+	return m.SendContext(context.Background(), msg)
 }
 
-func (m *Mail) ChooseAPI(msg Message) error {
-	switch m.API {
-	case "mailgun", "sparkpost", "sendgrid":
-		return m.SendUsingAPI(msg, m.API)
-	default:
-		return fmt.Errorf("API %s is not supported", m.API)
-	}
-}
-
-func (m *Mail) SendUsingAPI(msg Message, transport string) error {
-	if msg.From == "" {
-		msg.From = m.From
-	}
-	if msg.FromName == "" {
-		msg.FromName = m.FromName
-	}
-
-	cfg := apimail.Config{
-		URL:         m.APIUrl,
-		APIKey:      m.APIKey,
-		Domain:      m.Domain,
-		FromAddress: msg.From,
-		FromName:    msg.FromName,
-	}
+// SendContext sends msg as part of the request in ctx, in a client span
+// of its trace. API requests are made with ctx.
+func (m *Mail) SendContext(ctx context.Context, msg Message) error {
+	ctx, span := otel.Tracer("tjo/email").Start(ctx, "email send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(m.spanAttributes()...),
+	)
+	defer span.End()
 
-	driver, err := apimail.NewClient(transport, cfg)
+	err := m.send(ctx, msg)
 	if err != nil {
-		return err
-	}
-
-	formattedMessage, err := m.buildHTMLMessage(msg)
-	if err != nil {
-		return err
-	}
-	plainTextMessage, err := m.buildPlainTextMessage(msg)
-	if err != nil {
-		return err
-	}
-
-	tx := &apimail.Transmission{
-		Recipients: []string{msg.To},
-		Subject:    msg.Subject,
-		HTML:       formattedMessage,
-		PlainText:  plainTextMessage,
-	}
-
-	// add attachments
-	err = m.addAPIAttachments(msg, *tx)
-	if err != nil {
-		return err
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
+	return err
+}
 
-	_, err = driver.Send(tx)
-	if err != nil {
-		return err
+func (m *Mail) send(ctx context.Context, msg Message) error {
+	if m.usesAPI() {
+		return m.chooseAPI(ctx, msg)
 	}
-
-	return nil
+	return m.SendSMTPMessage(msg)
 }
 
-func (m *Mail) addAPIAttachments(msg Message, tx apimail.Transmission) error {
-	if len(msg.Attachments) > 0 {
-		var attachments []apimail.Attachment
-
-		for _, attachment := range msg.Attachments {
-			var attach apimail.Attachment
-			content, err := ioutil.ReadFile(attachment)
-			if err != nil {
-				return err
-			}
-
-			fileName := filepath.Base(attachment)
-			attach.Bytes = content
-			attach.Filename = fileName
-			attachments = append(attachments, attach)
+func (m *Mail) usesAPI() bool {
+	return m.API != "" && m.APIKey != "" && m.APIUrl != "" && m.API != "smtp"
+}
 
+// spanAttributes describes where messages are sent
+func (m *Mail) spanAttributes() []attribute.KeyValue {
+	if m.usesAPI() {
+		attrs := []attribute.KeyValue{attribute.String("email.transport", m.API)}
+		if u, err := url.Parse(m.APIUrl); err == nil && u.Hostname() != "" {
+			attrs = append(attrs, attribute.String("server.address", u.Hostname()))
 		}
-
-		tx.Attachments = attachments
+		return attrs
 	}
 
-	return nil
+	return []attribute.KeyValue{
+		attribute.String("email.transport", "smtp"),
+		attribute.String("server.address", m.Host),
+		attribute.Int("server.port", m.Port),
+	}
 }
 
 func (m *Mail) SendSMTPMessage(msg Message) error {
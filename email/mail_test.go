@@ -1,6 +1,17 @@
 package email
 
-import "testing"
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// roundTripFunc is an http.RoundTripper for faking API providers
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
 
 func TestMail_SendSMTPMessage(t *testing.T) {
 	msg := Message{
@@ -142,3 +153,38 @@ func TestMail_ChooseAPI(t *testing.T) {
 		t.Error("no error received with invalid API")
 	}
 }
+
+func TestMail_SendContextAPI(t *testing.T) {
+	type key struct{}
+	var got *http.Request
+	m := Mail{
+		Templates: "./testdata/email",
+		From:      "test@localhost.com",
+		FromName:  "Test",
+		API:       "sparkpost",
+		APIKey:    "key",
+		APIUrl:    "https://api.sparkpost.test",
+		HTTPClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			got = r
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"results":{"id":"1"}}`)),
+				Request:    r,
+			}, nil
+		})},
+	}
+
+	ctx := context.WithValue(context.Background(), key{}, "request")
+	err := m.SendContext(ctx, Message{To: "to@test.com", Subject: "Test", Template: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got == nil {
+		t.Fatal("the API request was not sent through HTTPClient")
+	}
+	if got.Context().Value(key{}) != "request" {
+		t.Error("the API request should be made with the SendContext context")
+	}
+}
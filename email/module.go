@@ -2,6 +2,7 @@ package email
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"strconv"
 )
@@ -44,6 +45,9 @@ type Config struct {
 	API    string
 	APIKey string
 	APIURL string
+
+	// HTTPClient sends API requests, see Mail.HTTPClient
+	HTTPClient *http.Client
 }
 
 // Option is a function that configures the email module
@@ -120,6 +124,14 @@ func WithAPI(provider, apiKey, apiURL, domain string) Option {
 	}
 }
 
+// WithHTTPClient sends API provider requests through client.
+// Pass otel.NewHTTPClient(nil) to trace them as child spans of SendContext.
+func WithHTTPClient(client *http.Client) Option {
+	return func(m *Module) {
+		m.config.HTTPClient = client
+	}
+}
+
 // Name returns the module identifier
 func (m *Module) Name() string {
 	return "email"
@@ -141,6 +153,7 @@ func (m *Module) Initialize(g interface{}) error {
 		API:        m.config.API,
 		APIKey:     m.config.APIKey,
 		APIUrl:     m.config.APIURL,
+		HTTPClient: m.config.HTTPClient,
 		Jobs:       make(chan Message, 20),
 		Results:    make(chan Result, 20),
 	}
@@ -165,7 +178,13 @@ func (m *Module) Send(msg Message) error {
 	return m.Mail.Send(msg)
 }
 
+// SendContext sends an email message as part of the request in ctx.
+func (m *Module) SendContext(ctx context.Context, msg Message) error {
+	return m.Mail.SendContext(ctx, msg)
+}
+
 // Queue adds an email message to the job queue for async sending.
+// Queued messages are sent in a new trace, not the caller's.
 func (m *Module) Queue(msg Message) {
 	if m.Mail != nil && m.Mail.Jobs != nil {
 		m.Mail.Jobs <- msg
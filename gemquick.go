@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,7 +18,9 @@ import (
 	"github.com/gomodule/redigo/redis"
 	"github.com/jimmitjoo/tjo/cache"
 	"github.com/jimmitjoo/tjo/config"
+	"github.com/jimmitjoo/tjo/database"
 	"github.com/jimmitjoo/tjo/email"
+	"github.com/jimmitjoo/tjo/filesystems"
 	"github.com/jimmitjoo/tjo/filesystems/miniofilesystem"
 	"github.com/jimmitjoo/tjo/filesystems/s3filesystem"
 	"github.com/jimmitjoo/tjo/jobs"
@@ -33,6 +36,9 @@ import (
 // version is injected at build time via ldflags
 var version = "dev"
 
+// healthCheckTimeout bounds the default health checks that support a context
+const healthCheckTimeout = 2 * time.Second
+
 // Tjo is the main framework struct that orchestrates all components.
 // It uses composition to organize functionality into focused services:
 // - Logging: structured logging, metrics, and health monitoring
@@ -213,6 +219,9 @@ func (g *Tjo) New(rootPath string, modules ...Module) error {
 
 	g.HTTP.Session = sess.InitSession()
 	g.EncryptionKey = g.Config.App.EncryptionKey
+	if g.EncryptionKey != "" {
+		database.SetCursorKey([]byte(g.EncryptionKey))
+	}
 
 	// Setup Jet template engine
 	var views *jet.Set
@@ -233,6 +242,8 @@ func (g *Tjo) New(rootPath string, modules ...Module) error {
 	// Setup file systems
 	g.createFileSystems()
 
+	g.registerHealthChecks()
+
 	// Setup SMS provider (will be removed when SMS module is used)
 	g.Background.SMS = sms.CreateSMSProvider(g.Config.App.SMSProvider)
 
@@ -338,6 +349,7 @@ func (g *Tjo) ListenAndServe() error {
 		} else {
 			g.Logging.Info.Printf("Received shutdown signal: %v", sig)
 		}
+		g.drain(quit)
 	}
 
 	// Begin graceful shutdown
@@ -382,6 +394,33 @@ func (g *Tjo) ListenAndServe() error {
 	return serverFailed
 }
 
+// drain fails readiness checks and keeps serving for the pre-stop delay, so
+// load balancers stop sending requests before the server stops accepting
+// them. Another signal ends the delay early.
+func (g *Tjo) drain(quit <-chan os.Signal) {
+	if g.Logging.Health != nil {
+		g.Logging.Health.Drain()
+	}
+
+	delay := g.Config.Server.PreStopDelay
+	if delay <= 0 {
+		return
+	}
+
+	if g.Logging.Logger != nil {
+		g.Logging.Logger.Info("Draining before shutdown", map[string]interface{}{
+			"delay": delay.String(),
+		})
+	} else {
+		g.Logging.Info.Printf("Draining for %s before shutdown", delay)
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-quit:
+	}
+}
+
 func (g *Tjo) checkDotEnv(path string) error {
 	err := g.CreateFileIfNotExists(fmt.Sprintf("%s/.env", path))
 
@@ -411,11 +450,15 @@ func (g *Tjo) setupStructuredLogging() {
 
 	// Create structured logger
 	g.Logging.Logger = logging.New(logging.Config{
-		Level:      logLevel,
-		Service:    g.AppName,
-		EnableJSON: enableJSON,
+		Level:         logLevel,
+		Service:       g.AppName,
+		EnableJSON:    enableJSON,
+		ContextFields: []logging.ContextFieldsFunc{otel.TraceFields},
 	})
 
+	// Route libraries logging through log/slog to the structured logger
+	slog.SetDefault(g.Logging.Logger.Slog())
+
 	// Create metric registry and application metrics
 	g.Logging.Metrics = logging.NewMetricRegistry()
 
@@ -425,22 +468,6 @@ func (g *Tjo) setupStructuredLogging() {
 	// Create health monitor with version
 	g.Logging.Health = logging.NewHealthMonitor(g.Version)
 
-	// Add default health checks
-	if g.Data.DB.Pool != nil {
-		g.Logging.Health.AddCheck("database", logging.DatabaseHealthChecker(func() error {
-			return g.Data.DB.Pool.Ping()
-		}))
-	}
-
-	if g.Data.redisCache != nil {
-		g.Logging.Health.AddCheck("redis", logging.RedisHealthChecker(func() error {
-			conn := g.Data.redisCache.Conn.Get()
-			defer conn.Close()
-			_, err := conn.Do("PING")
-			return err
-		}))
-	}
-
 	// Log startup message
 	g.Logging.Logger.Info("Structured logging initialized", map[string]interface{}{
 		"version":    g.Version,
@@ -456,6 +483,63 @@ func (g *Tjo) setupStructuredLogging() {
 	}
 }
 
+// AppLogger returns a slog.Logger writing through the application's
+// structured logger, or nil before logging is set up. Modules that can't
+// import tjo use it to log through the application, e.g. the sms log
+// provider.
+func (g *Tjo) AppLogger() *slog.Logger {
+	if g.Logging == nil || g.Logging.Logger == nil {
+		return nil
+	}
+	return g.Logging.Logger.Slog()
+}
+
+// registerHealthChecks adds the default health checks for the configured
+// services. The job manager and file stores are not critical: the
+// application can serve requests while they are unavailable.
+func (g *Tjo) registerHealthChecks() {
+	health := g.Logging.Health
+
+	if g.Data.DB.Pool != nil {
+		health.AddCheck("database", logging.DatabaseHealthChecker(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			defer cancel()
+			return g.Data.DB.Pool.PingContext(ctx)
+		}))
+	}
+
+	if g.Data.redisCache != nil {
+		health.AddCheck("redis", logging.RedisHealthChecker(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			defer cancel()
+			conn, err := g.Data.redisCache.Conn.GetContext(ctx)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			_, err = redis.DoContext(conn, ctx, "PING")
+			return err
+		}))
+	}
+
+	if g.Data.Cache != nil {
+		health.AddCheck("cache", logging.CacheHealthChecker(func() error {
+			return g.Data.Cache.Set("tjo_health", time.Now().Unix(), 60)
+		}), logging.CacheFor(10*time.Second))
+	}
+
+	if g.Background.Jobs != nil {
+		health.AddCheck("jobs", logging.JobManagerHealthChecker(g.Background.Jobs.IsRunning), logging.NonCritical())
+	}
+
+	g.Data.Files.forEach(func(name string, fs filesystems.FS) {
+		health.AddCheck("filesystem_"+name, logging.StorageHealthChecker(name, func() error {
+			_, err := fs.List("tjo-health")
+			return err
+		}), logging.NonCritical(), logging.CacheFor(30*time.Second))
+	})
+}
+
 // setupOpenTelemetry initializes the OpenTelemetry provider for distributed tracing.
 func (g *Tjo) setupOpenTelemetry() {
 	cfg := otel.Config{
@@ -505,6 +589,9 @@ func (g *Tjo) setupOpenTelemetry() {
 
 	g.Logging.OTel = provider
 
+	// Export the application metrics through the same pipeline
+	provider.BridgeRegistry(g.Logging.Metrics)
+
 	g.Logging.Logger.Info("OpenTelemetry initialized", map[string]interface{}{
 		"service":  cfg.ServiceName,
 		"exporter": string(cfg.Exporter),
@@ -542,9 +629,10 @@ func (g *Tjo) createMailer() email.Mail {
 		Jobs:    make(chan email.Message, 20),
 		Results: make(chan email.Result, 20),
 
-		API:    g.Config.Mail.API,
-		APIKey: g.Config.Mail.APIKey,
-		APIUrl: g.Config.Mail.APIURL,
+		API:        g.Config.Mail.API,
+		APIKey:     g.Config.Mail.APIKey,
+		APIUrl:     g.Config.Mail.APIURL,
+		HTTPClient: otel.NewHTTPClient(nil),
 	}
 	return m
 }
@@ -715,7 +803,7 @@ func (g *Tjo) Shutdown(ctx context.Context) error {
 //
 //	if m := app.GetModule("sms"); m != nil {
 //	    smsModule := m.(*sms.Module)
-//	    smsModule.Send("+1234567890", "Hello!", false)
+//	    smsModule.Send("+1234567890", "Hello!")
 //	}
 func (g *Tjo) GetModule(name string) Module {
 	if g.Modules == nil {
@@ -0,0 +1,92 @@
+package tjo
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jimmitjoo/tjo/config"
+	"github.com/jimmitjoo/tjo/filesystems"
+	"github.com/jimmitjoo/tjo/logging"
+)
+
+// failingFS is a file system whose listing always fails
+type failingFS struct{}
+
+func (failingFS) Put(fileName, folder string) error             { return nil }
+func (failingFS) Get(destination string, items ...string) error { return nil }
+func (failingFS) List(prefix string) ([]filesystems.Listing, error) {
+	return nil, errors.New("storage unavailable")
+}
+func (failingFS) Delete(items []string) bool { return true }
+
+func newHealthTestTjo() *Tjo {
+	return &Tjo{
+		Config: &config.Config{},
+		Logging: &LoggingService{
+			Info:   log.New(os.Stdout, "", 0),
+			Health: logging.NewHealthMonitor("test"),
+		},
+		Data:       NewDataService(),
+		Background: &BackgroundService{},
+	}
+}
+
+func TestRegisterHealthChecks_FileSystems(t *testing.T) {
+	g := newHealthTestTjo()
+	g.Data.Files.Register("before", failingFS{})
+	g.registerHealthChecks()
+	g.Data.Files.Register("after", failingFS{})
+
+	status := g.Logging.Health.CheckHealth()
+	for _, name := range []string{"filesystem_before", "filesystem_after"} {
+		check, ok := status.Checks[name]
+		if !ok {
+			t.Fatalf("Expected check %s to be registered", name)
+		}
+		if check.Status != "unhealthy" {
+			t.Errorf("Expected %s to be unhealthy, got %s", name, check.Status)
+		}
+	}
+
+	// File system checks are non-critical
+	if status.Status != "degraded" {
+		t.Errorf("Expected status degraded, got %s", status.Status)
+	}
+	if ready := g.Logging.Health.CheckReadiness(); ready.Status != "ready" {
+		t.Errorf("Expected readiness ready, got %s", ready.Status)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	t.Run("marks the application as draining", func(t *testing.T) {
+		g := newHealthTestTjo()
+		g.drain(make(chan os.Signal))
+
+		if ready := g.Logging.Health.CheckReadiness(); ready.Status != "draining" {
+			t.Errorf("Expected readiness draining, got %s", ready.Status)
+		}
+	})
+
+	t.Run("a second signal ends the pre-stop delay", func(t *testing.T) {
+		g := newHealthTestTjo()
+		g.Config.Server.PreStopDelay = time.Minute
+
+		quit := make(chan os.Signal, 1)
+		quit <- os.Interrupt
+
+		done := make(chan struct{})
+		go func() {
+			g.drain(quit)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected drain to return after the second signal")
+		}
+	})
+}
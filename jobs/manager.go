@@ -128,6 +128,13 @@ func (jm *JobManager) Stop() error {
 	return nil
 }
 
+// IsRunning reports whether the job manager has been started and not stopped
+func (jm *JobManager) IsRunning() bool {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	return jm.running
+}
+
 func (jm *JobManager) SetPersistence(db *sql.DB) error {
 	jm.persistence = &JobPersistence{
 		db:       db,
@@ -169,10 +176,23 @@ func (jm *JobManager) initializePersistenceSchema() error {
 }
 
 func (jm *JobManager) Enqueue(job *Job) error {
+	return jm.EnqueueContext(context.Background(), job)
+}
+
+// EnqueueContext enqueues a job as part of the trace in ctx. The trace
+// context is stored in the job metadata, so the job runs in the same trace.
+func (jm *JobManager) EnqueueContext(ctx context.Context, job *Job) error {
 	if job.Queue == "" {
 		job.Queue = jm.config.DefaultQueue
 	}
 
+	_, span := startPublishSpan(ctx, job)
+	err := jm.push(job)
+	endSpan(span, err)
+	return err
+}
+
+func (jm *JobManager) push(job *Job) error {
 	queue := jm.queueManager.GetOrCreateQueue(job.Queue)
 
 	if jm.config.MaxQueueSize > 0 && queue.Size() >= jm.config.MaxQueueSize {
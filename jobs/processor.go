@@ -97,6 +97,8 @@ func (jp *JobProcessor) SetDeadLetterQueue(queue Queue) {
 }
 
 func (jp *JobProcessor) ProcessJob(ctx context.Context, job *Job) error {
+	ctx, span := startProcessSpan(ctx, job)
+	
 	startTime := time.Now()
 	
 	jp.emitEvent(EventJobStarted, job, nil, nil)
@@ -105,6 +107,7 @@ func (jp *JobProcessor) ProcessJob(ctx context.Context, job *Job) error {
 	job.MarkRunning()
 	
 	err := jp.executeJob(ctx, job)
+	endSpan(span, err)
 	
 	duration := time.Since(startTime)
 	jp.metrics.updateDuration(duration)
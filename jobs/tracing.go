@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+
+	tjootel "github.com/jimmitjoo/tjo/otel"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceContextKey is the metadata key holding the trace context of the
+// span that enqueued a job
+const TraceContextKey = "trace_context"
+
+// messagingSystem identifies the job queue in messaging span attributes
+const messagingSystem = "tjo"
+
+// tracer returns the jobs tracer of the global tracer provider
+func tracer() trace.Tracer {
+	return otel.Tracer("tjo/jobs")
+}
+
+// startPublishSpan starts the producer span of an enqueue and stores its
+// trace context in the job metadata
+func startPublishSpan(ctx context.Context, job *Job) (context.Context, trace.Span) {
+	ctx, span := tracer().Start(ctx, "publish "+job.Queue,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(jobAttributes(job, "publish")...),
+	)
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		job.WithMetadata(TraceContextKey, map[string]string(carrier))
+	}
+
+	return ctx, span
+}
+
+// startProcessSpan starts the consumer span of a job run. It continues the
+// trace of the enqueuing span and links to it, so retries and delayed jobs
+// can be found from the span that enqueued them.
+func startProcessSpan(ctx context.Context, job *Job) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(jobAttributes(job, "process")...),
+		trace.WithAttributes(attribute.Int("messaging.tjo.job.attempt", job.Attempts+1)),
+	}
+
+	if carrier := traceCarrier(job); carrier != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+		if producer := trace.SpanContextFromContext(ctx); producer.IsValid() {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: producer}))
+		}
+	}
+
+	return tracer().Start(ctx, "process "+job.Queue, opts...)
+}
+
+// endSpan records err on the span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func jobAttributes(job *Job, operation string) []attribute.KeyValue {
+	return append(tjootel.MessagingAttributes(messagingSystem, job.Queue, operation),
+		attribute.String("messaging.message.id", job.ID),
+		attribute.String("messaging.tjo.job.type", job.Type),
+	)
+}
+
+// traceCarrier returns the trace context stored in the job metadata. Jobs
+// decoded from JSON hold it as a map[string]interface{}.
+func traceCarrier(job *Job) propagation.MapCarrier {
+	switch value := job.Metadata[TraceContextKey].(type) {
+	case map[string]string:
+		return propagation.MapCarrier(value)
+	case map[string]interface{}:
+		carrier := make(propagation.MapCarrier, len(value))
+		for k, v := range value {
+			if s, ok := v.(string); ok {
+				carrier[k] = s
+			}
+		}
+		return carrier
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	return recorder
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[string]string {
+	attrs := make(map[string]string)
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	return attrs
+}
+
+func TestTracePropagation(t *testing.T) {
+	recorder := newSpanRecorder(t)
+
+	manager := NewJobManager(nil)
+	processor := NewJobProcessor(DefaultRetryConfig())
+
+	var handlerSpan trace.SpanContext
+	processor.RegisterHandlerFunc("email", func(ctx context.Context, job *Job) error {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return nil
+	})
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+	job := NewJob("email", "mail", nil)
+	require.NoError(t, manager.EnqueueContext(ctx, job))
+	request.End()
+
+	assert.Contains(t, job.Metadata, TraceContextKey)
+
+	// Jobs stored as JSON carry the trace context as map[string]interface{}
+	data, err := json.Marshal(job)
+	require.NoError(t, err)
+	var decoded Job
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	require.NoError(t, processor.ProcessJob(context.Background(), &decoded))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	publish, process := spans[0], spans[2]
+
+	assert.Equal(t, "publish mail", publish.Name())
+	assert.Equal(t, trace.SpanKindProducer, publish.SpanKind())
+	assert.Equal(t, request.SpanContext().SpanID(), publish.Parent().SpanID())
+
+	assert.Equal(t, "process mail", process.Name())
+	assert.Equal(t, trace.SpanKindConsumer, process.SpanKind())
+	assert.Equal(t, publish.SpanContext().TraceID(), process.SpanContext().TraceID())
+	assert.Equal(t, publish.SpanContext().SpanID(), process.Parent().SpanID())
+	require.Len(t, process.Links(), 1)
+	assert.Equal(t, publish.SpanContext().SpanID(), process.Links()[0].SpanContext.SpanID())
+	assert.Equal(t, process.SpanContext().SpanID(), handlerSpan.SpanID())
+
+	attrs := spanAttributes(process)
+	assert.Equal(t, "tjo", attrs["messaging.system"])
+	assert.Equal(t, "mail", attrs["messaging.destination.name"])
+	assert.Equal(t, "process", attrs["messaging.operation"])
+	assert.Equal(t, job.ID, attrs["messaging.message.id"])
+	assert.Equal(t, "email", attrs["messaging.tjo.job.type"])
+	assert.Equal(t, "1", attrs["messaging.tjo.job.attempt"])
+}
+
+func TestTracePropagationRecordsErrors(t *testing.T) {
+	recorder := newSpanRecorder(t)
+
+	processor := NewJobProcessor(DefaultRetryConfig())
+	processor.RegisterHandlerFunc("failing", func(ctx context.Context, job *Job) error {
+		return errors.New("boom")
+	})
+
+	job := NewJob("failing", "default", nil)
+	require.NoError(t, processor.ProcessJob(context.Background(), job))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "boom", spans[0].Status().Description)
+}
+
+func TestEnqueueWithoutTracing(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	manager := NewJobManager(nil)
+	job := NewJob("email", "", nil)
+	require.NoError(t, manager.Enqueue(job))
+
+	assert.NotContains(t, job.Metadata, TraceContextKey)
+}
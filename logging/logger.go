@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"runtime"
 	"strings"
@@ -78,12 +79,14 @@ type LogEntry struct {
 
 // Logger represents a structured logger
 type Logger struct {
-	level      LogLevel
-	writer     io.Writer
-	service    string
-	enableJSON bool
-	mu         sync.RWMutex
-	fields     map[string]interface{}
+	level         LogLevel
+	writer        io.Writer
+	handler       slog.Handler
+	service       string
+	enableJSON    bool
+	contextFields []ContextFieldsFunc
+	mu            sync.RWMutex
+	fields        map[string]interface{}
 }
 
 // Config holds logger configuration
@@ -92,6 +95,12 @@ type Config struct {
 	Writer     io.Writer
 	Service    string
 	EnableJSON bool
+	// Handler, when set, receives every entry as a slog record instead of
+	// it being written to Writer, e.g. slog.NewJSONHandler(os.Stderr, nil)
+	Handler slog.Handler
+	// ContextFields add fields from the context to records logged through
+	// the slog handler, e.g. otel.TraceFields
+	ContextFields []ContextFieldsFunc
 }
 
 // New creates a new Logger instance
@@ -101,11 +110,13 @@ func New(config Config) *Logger {
 	}
 
 	return &Logger{
-		level:      config.Level,
-		writer:     config.Writer,
-		service:    config.Service,
-		enableJSON: config.EnableJSON,
-		fields:     make(map[string]interface{}),
+		level:         config.Level,
+		writer:        config.Writer,
+		handler:       config.Handler,
+		service:       config.Service,
+		enableJSON:    config.EnableJSON,
+		contextFields: config.ContextFields,
+		fields:        make(map[string]interface{}),
 	}
 }
 
@@ -133,11 +144,13 @@ func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	}
 
 	return &Logger{
-		level:      l.level,
-		writer:     l.writer,
-		service:    l.service,
-		enableJSON: l.enableJSON,
-		fields:     newFields,
+		level:         l.level,
+		writer:        l.writer,
+		handler:       l.handler,
+		service:       l.service,
+		enableJSON:    l.enableJSON,
+		contextFields: l.contextFields,
+		fields:        newFields,
 	}
 }
 
@@ -172,6 +185,14 @@ func (l *Logger) GetLevel() LogLevel {
 
 // log writes a log entry
 func (l *Logger) log(level LogLevel, message string, fields map[string]interface{}) {
+	// Skip runtime.Callers, log and the logging method
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	l.logAt(context.Background(), time.Now(), level, message, fields, pcs[0])
+}
+
+// logAt writes a log entry logged at t by the caller at pc
+func (l *Logger) logAt(ctx context.Context, t time.Time, level LogLevel, message string, fields map[string]interface{}, pc uintptr) {
 	l.mu.RLock()
 	if level < l.level {
 		l.mu.RUnlock()
@@ -180,7 +201,7 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 	l.mu.RUnlock()
 
 	entry := LogEntry{
-		Timestamp: time.Now().UTC(),
+		Timestamp: t.UTC(),
 		Level:     level.String(),
 		Message:   message,
 		Service:   l.service,
@@ -212,15 +233,18 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 	l.mu.RUnlock()
 
 	// Add caller information for debug and above
-	if level >= DebugLevel {
-		if pc, file, line, ok := runtime.Caller(2); ok {
-			if fn := runtime.FuncForPC(pc); fn != nil {
-				entry.Caller = fmt.Sprintf("%s:%d", getShortFile(file), line)
-			}
+	if level >= DebugLevel && pc != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if frame.File != "" {
+			entry.Caller = fmt.Sprintf("%s:%d", getShortFile(frame.File), frame.Line)
 		}
 	}
 
-	l.writeEntry(entry)
+	if l.handler != nil {
+		l.handleEntry(ctx, level, entry, pc)
+	} else {
+		l.writeEntry(entry)
+	}
 
 	// Exit for fatal logs
 	if level == FatalLevel {
@@ -3,9 +3,11 @@ package logging
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -83,21 +85,24 @@ func (g *Gauge) Add(delta int64)           { atomic.AddInt64(&g.value, delta) }
 func (g *Gauge) Sub(delta int64)           { atomic.AddInt64(&g.value, -delta) }
 func (g *Gauge) Get() int64                { return atomic.LoadInt64(&g.value) }
 
+// DefaultBuckets are the histogram buckets used when none are given,
+// suited to request durations in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
 // Histogram represents a histogram metric
 type Histogram struct {
 	name    string
 	labels  map[string]string
 	buckets map[float64]*Counter
 	sum     int64
+	sumBits uint64 // float64 sum for the Prometheus format
 	count   int64
 	mu      sync.RWMutex
 }
 
 // NewHistogram creates a new histogram with default buckets
 func NewHistogram(name string, labels map[string]string) *Histogram {
-	return NewHistogramWithBuckets(name, labels, []float64{
-		0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
-	})
+	return NewHistogramWithBuckets(name, labels, DefaultBuckets)
 }
 
 // NewHistogramWithBuckets creates a new histogram with custom buckets
@@ -147,6 +152,12 @@ func (h *Histogram) Value() interface{} {
 func (h *Histogram) Observe(value float64) {
 	atomic.AddInt64(&h.count, 1)
 	atomic.AddInt64(&h.sum, int64(value*1000)) // Store as milliseconds
+	for {
+		old := atomic.LoadUint64(&h.sumBits)
+		if atomic.CompareAndSwapUint64(&h.sumBits, old, math.Float64bits(math.Float64frombits(old)+value)) {
+			break
+		}
+	}
 
 	h.mu.RLock()
 	for bucket, counter := range h.buckets {
@@ -157,6 +168,25 @@ func (h *Histogram) Observe(value float64) {
 	h.mu.RUnlock()
 }
 
+// snapshot returns the bucket bounds in ascending order with their
+// cumulative counts, and the count and sum of the observations
+func (h *Histogram) snapshot() (bounds []float64, counts []int64, count int64, sum float64) {
+	h.mu.RLock()
+	bounds = make([]float64, 0, len(h.buckets))
+	for bound := range h.buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+
+	counts = make([]int64, len(bounds))
+	for i, bound := range bounds {
+		counts[i] = h.buckets[bound].Get()
+	}
+	h.mu.RUnlock()
+
+	return bounds, counts, atomic.LoadInt64(&h.count), math.Float64frombits(atomic.LoadUint64(&h.sumBits))
+}
+
 // formatFloat formats a float64 as a string
 func formatFloat(f float64) string {
 	return fmt.Sprintf("%.3f", f)
@@ -221,9 +251,14 @@ func GetDefaultRegistry() *MetricRegistry {
 type ApplicationMetrics struct {
 	RequestsTotal       *Counter
 	RequestDuration     *Histogram
-	ResponseStatusCodes *Counter
+	ResponseStatusCodes *CounterVec // by "code", e.g. "404"
 	ActiveConnections   *Gauge
 	ErrorsTotal         *Counter
+
+	// Requests and RequestDurations are labelled by "method", chi "route"
+	// pattern and "status" class, e.g. "GET", "/users/{id}" and "2xx"
+	Requests         *CounterVec
+	RequestDurations *HistogramVec
 }
 
 // NewApplicationMetrics creates application metrics
@@ -231,9 +266,11 @@ func NewApplicationMetrics() *ApplicationMetrics {
 	return &ApplicationMetrics{
 		RequestsTotal:       NewCounter("http_requests_total", nil),
 		RequestDuration:     NewHistogram("http_request_duration_seconds", nil),
-		ResponseStatusCodes: NewCounter("http_response_status_codes", nil),
+		ResponseStatusCodes: NewCounterVec("http_response_status_codes", "code"),
 		ActiveConnections:   NewGauge("http_active_connections", nil),
 		ErrorsTotal:         NewCounter("application_errors_total", nil),
+		Requests:            NewCounterVec("http_server_requests_total", "method", "route", "status"),
+		RequestDurations:    NewHistogramVec("http_server_request_duration_seconds", "method", "route", "status"),
 	}
 }
 
@@ -244,6 +281,8 @@ func (am *ApplicationMetrics) Register(registry *MetricRegistry) {
 	registry.Register(am.ResponseStatusCodes)
 	registry.Register(am.ActiveConnections)
 	registry.Register(am.ErrorsTotal)
+	registry.Register(am.Requests)
+	registry.Register(am.RequestDurations)
 }
 
 // HealthStatus represents the health status of the application
@@ -267,26 +306,121 @@ type HealthChecker func() HealthCheck
 
 // HealthMonitor manages health checks
 type HealthMonitor struct {
-	checkers  map[string]HealthChecker
+	checkers  map[string]*registeredCheck
 	startTime time.Time
 	version   string
+	draining  atomic.Bool
 	mu        sync.RWMutex
 }
 
+// DefaultCheckTimeout is how long CheckHealth waits for a check before
+// reporting it unhealthy
+const DefaultCheckTimeout = 5 * time.Second
+
+// registeredCheck is a health check with its options and cached result
+type registeredCheck struct {
+	checker  HealthChecker
+	critical bool
+	cacheTTL time.Duration
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	result    HealthCheck
+	checkedAt time.Time
+}
+
+// CheckOption configures a health check
+type CheckOption func(*registeredCheck)
+
+// NonCritical marks a check whose failure degrades the application without
+// making it unhealthy or not ready, e.g. an optional file store
+func NonCritical() CheckOption {
+	return func(c *registeredCheck) {
+		c.critical = false
+	}
+}
+
+// CacheFor reuses the result of a check for the given duration, for checks
+// too slow or expensive to run on every probe
+func CacheFor(ttl time.Duration) CheckOption {
+	return func(c *registeredCheck) {
+		c.cacheTTL = ttl
+	}
+}
+
+// Timeout sets how long CheckHealth waits for the check (default
+// DefaultCheckTimeout)
+func Timeout(d time.Duration) CheckOption {
+	return func(c *registeredCheck) {
+		c.timeout = d
+	}
+}
+
+// runWithTimeout runs the check, reporting it unhealthy if it does not
+// finish in time. A check that hangs keeps running in the background, but
+// the probe no longer waits for it.
+func (c *registeredCheck) runWithTimeout() HealthCheck {
+	done := make(chan HealthCheck, 1)
+	go func() {
+		done <- c.run()
+	}()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		return result
+	case <-timer.C:
+		return HealthCheck{
+			Status:  "unhealthy",
+			Message: "Health check timed out",
+			Details: map[string]interface{}{
+				"timeout": c.timeout.String(),
+			},
+		}
+	}
+}
+
+// run runs the check, or returns its cached result. Concurrent probes wait
+// for a single run instead of each running the check.
+func (c *registeredCheck) run() HealthCheck {
+	if c.cacheTTL <= 0 {
+		return c.checker()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.cacheTTL {
+		return c.result
+	}
+	c.result = c.checker()
+	c.checkedAt = time.Now()
+	return c.result
+}
+
 // NewHealthMonitor creates a new health monitor
 func NewHealthMonitor(version string) *HealthMonitor {
 	return &HealthMonitor{
-		checkers:  make(map[string]HealthChecker),
+		checkers:  make(map[string]*registeredCheck),
 		startTime: time.Now(),
 		version:   version,
 	}
 }
 
-// AddCheck adds a health check
-func (hm *HealthMonitor) AddCheck(name string, checker HealthChecker) {
+// AddCheck adds a health check. Checks are critical unless NonCritical is
+// given: a failing critical check makes the application unhealthy and not
+// ready. A check that takes longer than its Timeout counts as failed.
+func (hm *HealthMonitor) AddCheck(name string, checker HealthChecker, opts ...CheckOption) {
+	check := &registeredCheck{checker: checker, critical: true, timeout: DefaultCheckTimeout}
+	for _, opt := range opts {
+		opt(check)
+	}
+
 	hm.mu.Lock()
 	defer hm.mu.Unlock()
-	hm.checkers[name] = checker
+	hm.checkers[name] = check
 }
 
 // RemoveCheck removes a health check
@@ -296,10 +430,16 @@ func (hm *HealthMonitor) RemoveCheck(name string) {
 	delete(hm.checkers, name)
 }
 
-// CheckHealth performs all health checks
+// CheckHealth performs all health checks concurrently and waits at most
+// each check's timeout for it. The status is "unhealthy" if a critical
+// check fails and "degraded" if only non-critical checks fail.
 func (hm *HealthMonitor) CheckHealth() HealthStatus {
 	hm.mu.RLock()
-	defer hm.mu.RUnlock()
+	checkers := make(map[string]*registeredCheck, len(hm.checkers))
+	for name, check := range hm.checkers {
+		checkers[name] = check
+	}
+	hm.mu.RUnlock()
 
 	status := HealthStatus{
 		Status:    "healthy",
@@ -309,19 +449,65 @@ func (hm *HealthMonitor) CheckHealth() HealthStatus {
 		Checks:    make(map[string]HealthCheck),
 	}
 
-	for name, checker := range hm.checkers {
-		check := checker()
-		status.Checks[name] = check
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checkers {
+		wg.Add(1)
+		go func(name string, check *registeredCheck) {
+			defer wg.Done()
+			result := check.runWithTimeout()
 
-		// If any check fails, mark overall status as unhealthy
-		if check.Status != "healthy" {
-			status.Status = "unhealthy"
+			mu.Lock()
+			defer mu.Unlock()
+			status.Checks[name] = result
+
+			if result.Status == "healthy" {
+				return
+			}
+			if check.critical {
+				status.Status = "unhealthy"
+			} else if status.Status == "healthy" {
+				status.Status = "degraded"
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return status
+}
+
+// CheckReadiness reports whether the application should receive traffic:
+// "ready" unless it is draining or a critical check fails
+func (hm *HealthMonitor) CheckReadiness() HealthStatus {
+	if hm.Draining() {
+		return HealthStatus{
+			Status:    "draining",
+			Timestamp: time.Now().UTC(),
+			Version:   hm.version,
+			Uptime:    time.Since(hm.startTime).String(),
 		}
 	}
 
+	status := hm.CheckHealth()
+	if status.Status == "unhealthy" {
+		status.Status = "not_ready"
+	} else {
+		status.Status = "ready"
+	}
 	return status
 }
 
+// Drain marks the application as shutting down, so readiness checks fail
+// and load balancers stop sending it new requests
+func (hm *HealthMonitor) Drain() {
+	hm.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called
+func (hm *HealthMonitor) Draining() bool {
+	return hm.draining.Load()
+}
+
 // Default health monitor
 var defaultHealthMonitor = NewHealthMonitor("1.0.0")
 
@@ -363,14 +549,16 @@ func MetricsHandler(registry *MetricRegistry) http.HandlerFunc {
 	}
 }
 
-// HealthHandler returns an HTTP handler for health endpoint
+// HealthHandler returns an HTTP handler for health endpoint. It responds
+// 503 when the application is unhealthy and 200 when it is healthy or
+// degraded.
 func HealthHandler(monitor *HealthMonitor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		status := monitor.CheckHealth()
 
 		w.Header().Set("Content-Type", "application/json")
 
-		if status.Status == "healthy" {
+		if status.Status != "unhealthy" {
 			w.WriteHeader(http.StatusOK)
 		} else {
 			w.WriteHeader(http.StatusServiceUnavailable)
@@ -380,18 +568,36 @@ func HealthHandler(monitor *HealthMonitor) http.HandlerFunc {
 	}
 }
 
-// ReadinessHandler returns an HTTP handler for readiness endpoint
+// ReadinessHandler returns an HTTP handler for readiness endpoint that
+// always reports ready. Use ReadinessHandlerFor to run health checks.
 func ReadinessHandler() http.HandlerFunc {
+	return ReadinessHandlerFor(nil)
+}
+
+// ReadinessHandlerFor returns an HTTP handler for readiness endpoint. It
+// responds 503 while the monitor is draining or a critical check fails.
+// Without a monitor it always reports ready.
+func ReadinessHandlerFor(monitor *HealthMonitor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
 
-		response := map[string]interface{}{
-			"status":    "ready",
-			"timestamp": time.Now().UTC(),
+		if monitor == nil {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(HealthStatus{
+				Status:    "ready",
+				Timestamp: time.Now().UTC(),
+			})
+			return
 		}
 
-		json.NewEncoder(w).Encode(response)
+		status := monitor.CheckReadiness()
+		if status.Status == "ready" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(status)
 	}
 }
 
@@ -452,6 +658,69 @@ func RedisHealthChecker(pingFunc func() error) HealthChecker {
 	}
 }
 
+// CacheHealthChecker creates a health checker for the application cache
+func CacheHealthChecker(pingFunc func() error) HealthChecker {
+	return func() HealthCheck {
+		if err := pingFunc(); err != nil {
+			return HealthCheck{
+				Status:  "unhealthy",
+				Message: "Cache unavailable",
+				Details: map[string]interface{}{
+					"error": err.Error(),
+				},
+			}
+		}
+
+		return HealthCheck{
+			Status:  "healthy",
+			Message: "Cache available",
+		}
+	}
+}
+
+// JobManagerHealthChecker creates a health checker for the background job
+// manager
+func JobManagerHealthChecker(runningFunc func() bool) HealthChecker {
+	return func() HealthCheck {
+		if !runningFunc() {
+			return HealthCheck{
+				Status:  "unhealthy",
+				Message: "Job manager is not running",
+			}
+		}
+
+		return HealthCheck{
+			Status:  "healthy",
+			Message: "Job manager running",
+		}
+	}
+}
+
+// StorageHealthChecker creates a health checker for a remote file store,
+// e.g. S3, that is reachable when listFunc succeeds
+func StorageHealthChecker(name string, listFunc func() error) HealthChecker {
+	return func() HealthCheck {
+		if err := listFunc(); err != nil {
+			return HealthCheck{
+				Status:  "unhealthy",
+				Message: "File store unreachable",
+				Details: map[string]interface{}{
+					"name":  name,
+					"error": err.Error(),
+				},
+			}
+		}
+
+		return HealthCheck{
+			Status:  "healthy",
+			Message: "File store reachable",
+			Details: map[string]interface{}{
+				"name": name,
+			},
+		}
+	}
+}
+
 // FileSystemHealthChecker creates a health checker for file system access
 func FileSystemHealthChecker(path string) HealthChecker {
 	return func() HealthCheck {
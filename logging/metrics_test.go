@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -358,7 +359,12 @@ func TestHealthHandler(t *testing.T) {
 }
 
 func TestReadinessHandler(t *testing.T) {
-	handler := ReadinessHandler()
+	monitor := NewHealthMonitor("1.0.0")
+	monitor.AddCheck("database", func() HealthCheck {
+		return HealthCheck{Status: "healthy"}
+	})
+
+	handler := ReadinessHandlerFor(monitor)
 	req := httptest.NewRequest("GET", "/ready", nil)
 	w := httptest.NewRecorder()
 
@@ -373,6 +379,105 @@ func TestReadinessHandler(t *testing.T) {
 
 	assert.Equal(t, "ready", response["status"])
 	assert.Contains(t, response, "timestamp")
+	assert.Contains(t, response["checks"], "database")
+
+	t.Run("failing critical check", func(t *testing.T) {
+		monitor.AddCheck("cache", func() HealthCheck {
+			return HealthCheck{Status: "unhealthy"}
+		})
+		defer monitor.RemoveCheck("cache")
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"not_ready"`)
+	})
+
+	t.Run("failing non-critical check", func(t *testing.T) {
+		monitor.AddCheck("files", func() HealthCheck {
+			return HealthCheck{Status: "unhealthy"}
+		}, NonCritical())
+		defer monitor.RemoveCheck("files")
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		monitor.Drain()
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"draining"`)
+	})
+}
+
+func TestReadinessHandlerWithoutMonitor(t *testing.T) {
+	for _, handler := range []http.HandlerFunc{ReadinessHandler(), ReadinessHandlerFor(nil)} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/ready", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"ready"`)
+	}
+}
+
+func TestHealthMonitorTimeout(t *testing.T) {
+	monitor := NewHealthMonitor("1.0.0")
+	release := make(chan struct{})
+	defer close(release)
+
+	monitor.AddCheck("redis", func() HealthCheck {
+		<-release
+		return HealthCheck{Status: "healthy"}
+	}, Timeout(20*time.Millisecond))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	ReadinessHandlerFor(monitor)(w, httptest.NewRequest("GET", "/ready", nil))
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Health check timed out")
+}
+
+func TestHealthMonitorNonCritical(t *testing.T) {
+	monitor := NewHealthMonitor("1.0.0")
+	monitor.AddCheck("files", func() HealthCheck {
+		return HealthCheck{Status: "unhealthy"}
+	}, NonCritical())
+
+	assert.Equal(t, "degraded", monitor.CheckHealth().Status)
+
+	w := httptest.NewRecorder()
+	HealthHandler(monitor)(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHealthMonitorCacheFor(t *testing.T) {
+	monitor := NewHealthMonitor("1.0.0")
+
+	var mu sync.Mutex
+	runs := 0
+	monitor.AddCheck("slow", func() HealthCheck {
+		mu.Lock()
+		defer mu.Unlock()
+		runs++
+		return HealthCheck{Status: "healthy"}
+	}, CacheFor(time.Hour))
+
+	monitor.CheckHealth()
+	monitor.CheckHealth()
+	monitor.CheckReadiness()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, runs)
 }
 
 func TestLivenessHandler(t *testing.T) {
@@ -3,8 +3,10 @@ package logging
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
@@ -27,15 +29,19 @@ func MetricsMiddleware(metrics *ApplicationMetrics, logger *Logger) func(next ht
 			// Calculate metrics
 			duration := time.Since(start)
 			statusCode := ww.Status()
+			if statusCode == 0 {
+				// Handlers that write nothing respond 200 OK
+				statusCode = http.StatusOK
+			}
 
 			// Update metrics
 			metrics.RequestsTotal.Inc()
 			metrics.RequestDuration.Observe(duration.Seconds())
+			metrics.ResponseStatusCodes.WithLabelValues(strconv.Itoa(statusCode)).Inc()
 
-			// Track status codes - update the existing counter
-			if statusCode == 200 {
-				metrics.ResponseStatusCodes.Add(1)
-			}
+			method, route, status := requestLabels(r, statusCode)
+			metrics.Requests.WithLabelValues(method, route, status).Inc()
+			metrics.RequestDurations.WithLabelValues(method, route, status).Observe(duration.Seconds())
 
 			// Track errors (4xx and 5xx status codes)
 			if statusCode >= 400 {
@@ -69,6 +75,33 @@ func MetricsMiddleware(metrics *ApplicationMetrics, logger *Logger) func(next ht
 	}
 }
 
+// standardMethods are the methods used as method label; others are
+// labelled "OTHER" so clients cannot create arbitrary series
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodConnect: true, http.MethodOptions: true,
+	http.MethodTrace: true,
+}
+
+// requestLabels returns the method, route pattern and status class labels
+// of a request. Requests no chi route matched are labelled "unmatched"
+// instead of by path, which would create a series per URL.
+func requestLabels(r *http.Request, statusCode int) (method, route, status string) {
+	method = r.Method
+	if !standardMethods[method] {
+		method = "OTHER"
+	}
+
+	route = "unmatched"
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			route = pattern
+		}
+	}
+
+	return method, route, strconv.Itoa(statusCode/100) + "xx"
+}
+
 // StructuredLoggingMiddleware creates middleware that adds structured logger to request context
 func StructuredLoggingMiddleware(logger *Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
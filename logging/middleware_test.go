@@ -122,6 +122,46 @@ func TestMetricsMiddlewareWithErrors(t *testing.T) {
 	}
 }
 
+func TestMetricsMiddlewareLabels(t *testing.T) {
+	metrics := NewApplicationMetrics()
+
+	r := chi.NewRouter()
+	r.Use(MetricsMiddleware(metrics, NewDefault()))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("OK"))
+	})
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/users/1", nil),
+		httptest.NewRequest("GET", "/users/2", nil),
+		httptest.NewRequest("GET", "/users/0", nil),
+		httptest.NewRequest("POST", "/users", nil),
+		httptest.NewRequest("GET", "/missing/1", nil),
+		httptest.NewRequest("BREW", "/users/1", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, int64(2), metrics.Requests.WithLabelValues("GET", "/users/{id}", "2xx").Get())
+	assert.Equal(t, int64(1), metrics.Requests.WithLabelValues("GET", "/users/{id}", "4xx").Get())
+	assert.Equal(t, int64(1), metrics.Requests.WithLabelValues("POST", "/users", "2xx").Get(), "handlers writing nothing respond 200")
+	assert.Equal(t, int64(1), metrics.Requests.WithLabelValues("GET", "unmatched", "4xx").Get())
+	assert.Equal(t, int64(1), metrics.Requests.WithLabelValues("OTHER", "unmatched", "4xx").Get())
+	assert.Len(t, metrics.Requests.Collect(), 5)
+
+	_, _, count, _ := metrics.RequestDurations.WithLabelValues("GET", "/users/{id}", "2xx").snapshot()
+	assert.Equal(t, int64(2), count)
+
+	assert.Equal(t, int64(3), metrics.ResponseStatusCodes.WithLabelValues("200").Get())
+	assert.Equal(t, int64(2), metrics.ResponseStatusCodes.WithLabelValues("404").Get())
+	assert.Equal(t, int64(1), metrics.ResponseStatusCodes.WithLabelValues("405").Get())
+}
+
 func TestMetricsMiddlewareWithRequestID(t *testing.T) {
 	var buf bytes.Buffer
 	logger := New(Config{
@@ -0,0 +1,199 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of the Prometheus text
+// exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusHandler returns an HTTP handler serving the metrics of a
+// registry and Go runtime metrics in the Prometheus text exposition format
+func PrometheusHandler(registry *MetricRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := WritePrometheus(&buf, registry); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		writeRuntimeMetrics(&buf)
+
+		w.Header().Set("Content-Type", PrometheusContentType)
+		w.Write(buf.Bytes())
+	}
+}
+
+// WritePrometheus writes the metrics of a registry in the Prometheus text
+// exposition format, sorted by name. Characters that are not valid in
+// Prometheus metric and label names are replaced with underscores.
+func WritePrometheus(w io.Writer, registry *MetricRegistry) error {
+	all := registry.GetAll()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		metric := all[name]
+		metrics := []Metric{metric}
+		if c, ok := metric.(Collector); ok {
+			metrics = c.Collect()
+		}
+
+		promType := "untyped"
+		switch metric.Type() {
+		case CounterType, GaugeType, HistogramType:
+			promType = string(metric.Type())
+		}
+
+		name := promName(metric.Name(), true)
+		bw.WriteString("# TYPE " + name + " " + promType + "\n")
+		for _, m := range metrics {
+			writeSamples(bw, name, m)
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSamples writes the samples of a single metric
+func writeSamples(w *bufio.Writer, name string, m Metric) {
+	switch m := m.(type) {
+	case *Counter:
+		writeSample(w, name, m.Labels(), "", float64(m.Get()))
+	case *Gauge:
+		writeSample(w, name, m.Labels(), "", float64(m.Get()))
+	case *Histogram:
+		bounds, counts, count, sum := m.snapshot()
+		for i, bound := range bounds {
+			writeSample(w, name+"_bucket", m.Labels(), formatValue(bound), float64(counts[i]))
+		}
+		// Observations are counted before their buckets, so a concurrent
+		// observation can leave the count behind the last bucket
+		if n := len(counts); n > 0 && counts[n-1] > count {
+			count = counts[n-1]
+		}
+		writeSample(w, name+"_bucket", m.Labels(), "+Inf", float64(count))
+		writeSample(w, name+"_sum", m.Labels(), "", sum)
+		writeSample(w, name+"_count", m.Labels(), "", float64(count))
+	default:
+		if v, ok := numericValue(m.Value()); ok {
+			writeSample(w, name, m.Labels(), "", v)
+		}
+	}
+}
+
+// writeSample writes a sample line with the labels sorted by name and the
+// le label of histogram buckets last
+func writeSample(w *bufio.Writer, name string, labels map[string]string, le string, value float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || le != "" {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(promName(k, false) + `="` + escapeLabelValue(labels[k]) + `"`)
+		}
+		if le != "" {
+			if len(keys) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(`le="` + le + `"`)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteString(" " + formatValue(value) + "\n")
+}
+
+// writeRuntimeMetrics writes Go runtime metrics, named like those of the
+// Prometheus Go client
+func writeRuntimeMetrics(w io.Writer) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	bw := bufio.NewWriter(w)
+	for _, s := range []struct {
+		name, typ string
+		value     float64
+	}{
+		{"go_goroutines", "gauge", float64(runtime.NumGoroutine())},
+		{"go_memstats_alloc_bytes", "gauge", float64(m.Alloc)},
+		{"go_memstats_alloc_bytes_total", "counter", float64(m.TotalAlloc)},
+		{"go_memstats_sys_bytes", "gauge", float64(m.Sys)},
+		{"go_memstats_gc_count_total", "counter", float64(m.NumGC)},
+		{"go_memstats_gc_pause_seconds_total", "counter", float64(m.PauseTotalNs) / 1e9},
+	} {
+		bw.WriteString("# TYPE " + s.name + " " + s.typ + "\n")
+		writeSample(bw, s.name, nil, "", s.value)
+	}
+	bw.Flush()
+}
+
+// promName replaces characters that are not valid in a Prometheus metric
+// name, or label name when metric is false
+func promName(name string, metric bool) string {
+	valid := func(i int, r rune) bool {
+		return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			(r == ':' && metric) || (r >= '0' && r <= '9' && i > 0)
+	}
+
+	var b strings.Builder
+	for i, r := range name {
+		if valid(i, r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// numericValue converts the value of a custom metric to a sample value
+func numericValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	registry := NewMetricRegistry()
+
+	counter := NewCounter("jobs_processed_total", map[string]string{"queue": "mail"})
+	counter.Add(5)
+	gauge := NewGauge("jobs.waiting", nil)
+	gauge.Set(7)
+	histogram := NewHistogramWithBuckets("job_duration_seconds", nil, []float64{0.1, 1})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(2)
+	requests := NewCounterVec("http_server_requests_total", "route", "status")
+	requests.WithLabelValues("/users/{id}", "2xx").Add(3)
+	requests.WithLabelValues(`say "hi"`+"\n", "4xx").Inc()
+
+	registry.Register(counter)
+	registry.Register(gauge)
+	registry.Register(histogram)
+	registry.Register(requests)
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePrometheus(&buf, registry))
+
+	assert.Equal(t, `# TYPE http_server_requests_total counter
+http_server_requests_total{route="/users/{id}",status="2xx"} 3
+http_server_requests_total{route="say \"hi\"\n",status="4xx"} 1
+# TYPE job_duration_seconds histogram
+job_duration_seconds_bucket{le="0.1"} 1
+job_duration_seconds_bucket{le="1"} 2
+job_duration_seconds_bucket{le="+Inf"} 3
+job_duration_seconds_sum 2.55
+job_duration_seconds_count 3
+# TYPE jobs_waiting gauge
+jobs_waiting 7
+# TYPE jobs_processed_total counter
+jobs_processed_total{queue="mail"} 5
+`, buf.String())
+}
+
+func TestWritePrometheusHistogramVec(t *testing.T) {
+	registry := NewMetricRegistry()
+	durations := NewHistogramVecWithBuckets("request_duration_seconds", []float64{0.5}, "method")
+	durations.WithLabelValues("GET").Observe(0.25)
+	registry.Register(durations)
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePrometheus(&buf, registry))
+
+	assert.Equal(t, `# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{method="GET",le="0.5"} 1
+request_duration_seconds_bucket{method="GET",le="+Inf"} 1
+request_duration_seconds_sum{method="GET"} 0.25
+request_duration_seconds_count{method="GET"} 1
+`, buf.String())
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	registry := NewMetricRegistry()
+	NewApplicationMetrics().Register(registry)
+
+	w := httptest.NewRecorder()
+	PrometheusHandler(registry)(w, httptest.NewRequest("GET", "/metrics/prometheus", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, PrometheusContentType, w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE http_requests_total counter\nhttp_requests_total 0\n")
+	assert.Contains(t, body, "# TYPE http_server_request_duration_seconds histogram\n")
+	assert.Contains(t, body, "# TYPE go_goroutines gauge\ngo_goroutines ")
+}
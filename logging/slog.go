@@ -0,0 +1,182 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// ContextFieldsFunc returns log fields carried by a context, such as the
+// trace and span IDs of otel.TraceFields
+type ContextFieldsFunc func(ctx context.Context) map[string]interface{}
+
+// Slog levels of the levels slog does not define
+const (
+	SlogLevelTrace = slog.LevelDebug - 4
+	SlogLevelFatal = slog.LevelError + 4
+)
+
+// SlogLevel returns the slog level of the log level
+func (l LogLevel) SlogLevel() slog.Level {
+	switch l {
+	case TraceLevel:
+		return SlogLevelTrace
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	default:
+		return SlogLevelFatal
+	}
+}
+
+// levelFromSlog returns the log level of a slog level. Levels above
+// error are logged as errors, so slog records never exit the process.
+func levelFromSlog(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+// Handler returns a slog.Handler writing records through the logger, with
+// its level, format, service and fields. Records also get the fields of
+// the logger in their context (see ToContext) and of the configured
+// ContextFields, so slog.InfoContext(r.Context(), ...) includes the
+// request ID set by StructuredLoggingMiddleware.
+func (l *Logger) Handler() slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+// Slog returns a slog.Logger writing through the logger
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(l.Handler())
+}
+
+// slogHandler adapts a Logger to slog.Handler. Attributes in groups are
+// flattened to dotted field names.
+type slogHandler struct {
+	logger *Logger
+	prefix string
+}
+
+// Enabled implements slog.Handler.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelFromSlog(level) >= h.logger.GetLevel()
+}
+
+// Handle implements slog.Handler.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(map[string]interface{})
+
+	if ctxLogger, ok := ctx.Value(loggerContextKey).(*Logger); ok && ctxLogger != h.logger {
+		ctxLogger.mu.RLock()
+		for k, v := range ctxLogger.fields {
+			fields[k] = v
+		}
+		ctxLogger.mu.RUnlock()
+	}
+	for _, fn := range h.logger.contextFields {
+		for k, v := range fn(ctx) {
+			fields[k] = v
+		}
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.prefix, a)
+		return true
+	})
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	h.logger.logAt(ctx, t, levelFromSlog(r.Level), r.Message, fields, r.PC)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(map[string]interface{})
+	for _, a := range attrs {
+		addAttr(fields, h.prefix, a)
+	}
+	return &slogHandler{logger: h.logger.WithFields(fields), prefix: h.prefix}
+}
+
+// WithGroup implements slog.Handler.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+// addAttr adds an attribute to fields, flattening groups
+func addAttr(fields map[string]interface{}, prefix string, a slog.Attr) {
+	value := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range value.Group() {
+			addAttr(fields, groupPrefix, ga)
+		}
+		return
+	}
+
+	v := value.Any()
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	fields[prefix+a.Key] = v
+}
+
+// handleEntry passes an entry to the slog backend of the logger
+func (l *Logger) handleEntry(ctx context.Context, level LogLevel, entry LogEntry, pc uintptr) {
+	slogLevel := level.SlogLevel()
+	if !l.handler.Enabled(ctx, slogLevel) {
+		return
+	}
+
+	r := slog.NewRecord(entry.Timestamp, slogLevel, entry.Message, pc)
+	if entry.Service != "" {
+		r.AddAttrs(slog.String("service", entry.Service))
+	}
+	if entry.RequestID != "" {
+		r.AddAttrs(slog.String("request_id", entry.RequestID))
+	}
+	if entry.UserID != "" {
+		r.AddAttrs(slog.String("user_id", entry.UserID))
+	}
+
+	keys := make([]string, 0, len(entry.Fields))
+	for k := range entry.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.AddAttrs(slog.Any(k, entry.Fields[k]))
+	}
+
+	_ = l.handler.Handle(ctx, r)
+}
@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJSONLogger(buf *bytes.Buffer, level LogLevel, contextFields ...ContextFieldsFunc) *Logger {
+	return New(Config{
+		Level:         level,
+		Writer:        buf,
+		Service:       "test",
+		EnableJSON:    true,
+		ContextFields: contextFields,
+	})
+}
+
+func decodeEntry(t *testing.T, buf *bytes.Buffer) LogEntry {
+	t.Helper()
+
+	var entry LogEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestSlogLevels(t *testing.T) {
+	tests := []struct {
+		slogLevel slog.Level
+		expected  LogLevel
+	}{
+		{SlogLevelTrace, TraceLevel},
+		{slog.LevelDebug, DebugLevel},
+		{slog.LevelInfo, InfoLevel},
+		{slog.LevelWarn, WarnLevel},
+		{slog.LevelError, ErrorLevel},
+		{SlogLevelFatal, ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slogLevel.String(), func(t *testing.T) {
+			assert.Equal(t, tt.expected, levelFromSlog(tt.slogLevel))
+		})
+	}
+
+	for _, level := range []LogLevel{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel} {
+		assert.Equal(t, level, levelFromSlog(level.SlogLevel()))
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newJSONLogger(&buf, DebugLevel).WithField("component", "billing")
+
+	logger.Slog().Info("invoice sent", "invoice", 42, "err", errors.New("retried"))
+
+	entry := decodeEntry(t, &buf)
+	assert.Equal(t, "INFO", entry.Level)
+	assert.Equal(t, "invoice sent", entry.Message)
+	assert.Equal(t, "test", entry.Service)
+	assert.Equal(t, "billing", entry.Fields["component"])
+	assert.Equal(t, float64(42), entry.Fields["invoice"])
+	assert.Equal(t, "retried", entry.Fields["err"])
+	assert.Contains(t, entry.Caller, "slog_test.go")
+}
+
+func TestSlogHandlerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	log := newJSONLogger(&buf, WarnLevel).Slog()
+
+	assert.False(t, log.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, log.Enabled(context.Background(), slog.LevelWarn))
+
+	log.Info("skipped")
+	assert.Empty(t, buf.String())
+
+	log.Log(context.Background(), SlogLevelFatal, "does not exit")
+	assert.Equal(t, "ERROR", decodeEntry(t, &buf).Level)
+}
+
+func TestSlogHandlerAttrsAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+	log := newJSONLogger(&buf, InfoLevel).Slog()
+
+	log.With("tenant", "acme").WithGroup("http").With("method", "GET").Info("request",
+		"status", 200,
+		slog.Group("client", "ip", "10.0.0.1"),
+		slog.Group("", "inline", true),
+	)
+
+	entry := decodeEntry(t, &buf)
+	assert.Equal(t, map[string]interface{}{
+		"tenant":         "acme",
+		"http.method":    "GET",
+		"http.status":    float64(200),
+		"http.client.ip": "10.0.0.1",
+		"http.inline":    true,
+	}, entry.Fields)
+}
+
+func TestSlogHandlerContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	traceFields := func(ctx context.Context) map[string]interface{} {
+		return map[string]interface{}{"trace_id": "abc123"}
+	}
+	logger := newJSONLogger(&buf, InfoLevel, traceFields)
+
+	ctx := ToContext(context.Background(), logger.WithRequestID("req-1"))
+	logger.Slog().InfoContext(ctx, "handled", "path", "/orders")
+
+	entry := decodeEntry(t, &buf)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "abc123", entry.Fields["trace_id"])
+	assert.Equal(t, "/orders", entry.Fields["path"])
+}
+
+func TestLoggerWithSlogBackend(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(Config{
+		Level:   DebugLevel,
+		Service: "test",
+		Handler: slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: SlogLevelTrace, AddSource: true}),
+	})
+
+	logger.WithRequestID("req-1").Warn("disk almost full", map[string]interface{}{"free": 5, "mount": "/"})
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "disk almost full", record["msg"])
+	assert.Equal(t, "test", record["service"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, float64(5), record["free"])
+	assert.Equal(t, "/", record["mount"])
+
+	source, ok := record["source"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Contains(t, source["file"], "slog_test.go")
+
+	t.Run("respects the backend level", func(t *testing.T) {
+		buf.Reset()
+		logger := New(Config{
+			Level:   TraceLevel,
+			Handler: slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		})
+
+		logger.Debug("skipped")
+		assert.Empty(t, buf.String())
+	})
+}
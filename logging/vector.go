@@ -0,0 +1,212 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Collector is a metric made up of labelled metrics, such as a vector.
+// Collect returns them sorted by their label values.
+type Collector interface {
+	Metric
+	Collect() []Metric
+}
+
+// vec holds the metrics of a vector, one per combination of label values
+type vec[M Metric] struct {
+	name       string
+	labelNames []string
+	newMetric  func(labels map[string]string) M
+
+	mu       sync.RWMutex
+	children map[string]M
+}
+
+func newVec[M Metric](name string, labelNames []string, newMetric func(labels map[string]string) M) vec[M] {
+	return vec[M]{
+		name:       name,
+		labelNames: labelNames,
+		newMetric:  newMetric,
+		children:   make(map[string]M),
+	}
+}
+
+func (v *vec[M]) Name() string              { return v.name }
+func (v *vec[M]) Labels() map[string]string { return map[string]string{} }
+
+// LabelNames returns the names of the vector's labels
+func (v *vec[M]) LabelNames() []string {
+	return append([]string(nil), v.labelNames...)
+}
+
+// Value returns the labels and value of every metric in the vector
+func (v *vec[M]) Value() interface{} {
+	metrics := v.Collect()
+	values := make([]map[string]interface{}, 0, len(metrics))
+	for _, m := range metrics {
+		values = append(values, map[string]interface{}{
+			"labels": m.Labels(),
+			"value":  m.Value(),
+		})
+	}
+	return values
+}
+
+// Collect returns the metrics of the vector sorted by their label values
+func (v *vec[M]) Collect() []Metric {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	for key := range v.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metrics := make([]Metric, 0, len(keys))
+	for _, key := range keys {
+		metrics = append(metrics, v.children[key])
+	}
+	v.mu.RUnlock()
+	return metrics
+}
+
+// Reset removes every metric from the vector
+func (v *vec[M]) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.children = make(map[string]M)
+}
+
+// withLabelValues returns the metric for the label values, in the order
+// of the label names, creating it on first use
+func (v *vec[M]) withLabelValues(values []string) M {
+	if len(values) != len(v.labelNames) {
+		panic(fmt.Sprintf("logging: %s has %d labels, got %d values", v.name, len(v.labelNames), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	m, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return m
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if m, ok := v.children[key]; ok {
+		return m
+	}
+
+	labels := make(map[string]string, len(values))
+	for i, name := range v.labelNames {
+		labels[name] = values[i]
+	}
+	m = v.newMetric(labels)
+	v.children[key] = m
+	return m
+}
+
+// with returns the metric for the labels, which must have exactly the
+// vector's label names
+func (v *vec[M]) with(labels map[string]string) M {
+	values := make([]string, len(v.labelNames))
+	for i, name := range v.labelNames {
+		value, ok := labels[name]
+		if !ok {
+			panic(fmt.Sprintf("logging: %s is missing label %q", v.name, name))
+		}
+		values[i] = value
+	}
+	if len(labels) != len(v.labelNames) {
+		panic(fmt.Sprintf("logging: %s has %d labels, got %d", v.name, len(v.labelNames), len(labels)))
+	}
+	return v.withLabelValues(values)
+}
+
+// CounterVec is a set of counters with the same name, one per combination
+// of label values
+type CounterVec struct {
+	vec[*Counter]
+}
+
+// NewCounterVec creates a counter vector with the given label names
+func NewCounterVec(name string, labelNames ...string) *CounterVec {
+	return &CounterVec{newVec(name, labelNames, func(labels map[string]string) *Counter {
+		return NewCounter(name, labels)
+	})}
+}
+
+func (v *CounterVec) Type() MetricType { return CounterType }
+
+// WithLabelValues returns the counter for the label values, given in the
+// order of the label names. It panics if the number of values is wrong.
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return v.withLabelValues(values)
+}
+
+// With returns the counter for the labels. It panics unless labels has
+// exactly the vector's label names.
+func (v *CounterVec) With(labels map[string]string) *Counter {
+	return v.with(labels)
+}
+
+// GaugeVec is a set of gauges with the same name, one per combination of
+// label values
+type GaugeVec struct {
+	vec[*Gauge]
+}
+
+// NewGaugeVec creates a gauge vector with the given label names
+func NewGaugeVec(name string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{newVec(name, labelNames, func(labels map[string]string) *Gauge {
+		return NewGauge(name, labels)
+	})}
+}
+
+func (v *GaugeVec) Type() MetricType { return GaugeType }
+
+// WithLabelValues returns the gauge for the label values, given in the
+// order of the label names. It panics if the number of values is wrong.
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return v.withLabelValues(values)
+}
+
+// With returns the gauge for the labels. It panics unless labels has
+// exactly the vector's label names.
+func (v *GaugeVec) With(labels map[string]string) *Gauge {
+	return v.with(labels)
+}
+
+// HistogramVec is a set of histograms with the same name and buckets,
+// one per combination of label values
+type HistogramVec struct {
+	vec[*Histogram]
+}
+
+// NewHistogramVec creates a histogram vector with the default buckets
+func NewHistogramVec(name string, labelNames ...string) *HistogramVec {
+	return NewHistogramVecWithBuckets(name, DefaultBuckets, labelNames...)
+}
+
+// NewHistogramVecWithBuckets creates a histogram vector with custom buckets
+func NewHistogramVecWithBuckets(name string, buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{newVec(name, labelNames, func(labels map[string]string) *Histogram {
+		return NewHistogramWithBuckets(name, labels, buckets)
+	})}
+}
+
+func (v *HistogramVec) Type() MetricType { return HistogramType }
+
+// WithLabelValues returns the histogram for the label values, given in the
+// order of the label names. It panics if the number of values is wrong.
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.withLabelValues(values)
+}
+
+// With returns the histogram for the labels. It panics unless labels has
+// exactly the vector's label names.
+func (v *HistogramVec) With(labels map[string]string) *Histogram {
+	return v.with(labels)
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterVec(t *testing.T) {
+	v := NewCounterVec("requests_total", "method", "status")
+	assert.Equal(t, CounterType, v.Type())
+	assert.Equal(t, "requests_total", v.Name())
+	assert.Equal(t, []string{"method", "status"}, v.LabelNames())
+
+	v.WithLabelValues("GET", "2xx").Inc()
+	v.WithLabelValues("GET", "2xx").Add(2)
+	v.With(map[string]string{"method": "POST", "status": "5xx"}).Inc()
+
+	assert.Equal(t, int64(3), v.WithLabelValues("GET", "2xx").Get())
+
+	metrics := v.Collect()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]string{"method": "GET", "status": "2xx"}, metrics[0].Labels())
+	assert.Equal(t, int64(3), metrics[0].Value())
+	assert.Equal(t, map[string]string{"method": "POST", "status": "5xx"}, metrics[1].Labels())
+
+	v.Reset()
+	assert.Empty(t, v.Collect())
+}
+
+func TestCounterVecPanicsOnWrongLabels(t *testing.T) {
+	v := NewCounterVec("requests_total", "method", "status")
+
+	assert.Panics(t, func() { v.WithLabelValues("GET") })
+	assert.Panics(t, func() { v.With(map[string]string{"method": "GET"}) })
+	assert.Panics(t, func() { v.With(map[string]string{"method": "GET", "status": "2xx", "path": "/"}) })
+}
+
+func TestGaugeVec(t *testing.T) {
+	v := NewGaugeVec("queue_size", "queue")
+	assert.Equal(t, GaugeType, v.Type())
+
+	v.WithLabelValues("mail").Set(5)
+	v.WithLabelValues("mail").Dec()
+	v.WithLabelValues("sms").Inc()
+
+	assert.Equal(t, int64(4), v.WithLabelValues("mail").Get())
+	assert.Equal(t, int64(1), v.WithLabelValues("sms").Get())
+}
+
+func TestHistogramVec(t *testing.T) {
+	v := NewHistogramVecWithBuckets("duration_seconds", []float64{0.1, 1}, "route")
+	assert.Equal(t, HistogramType, v.Type())
+
+	v.WithLabelValues("/users").Observe(0.05)
+	v.WithLabelValues("/users").Observe(0.5)
+	v.WithLabelValues("/posts").Observe(2)
+
+	bounds, counts, count, sum := v.WithLabelValues("/users").snapshot()
+	assert.Equal(t, []float64{0.1, 1}, bounds)
+	assert.Equal(t, []int64{1, 2}, counts)
+	assert.Equal(t, int64(2), count)
+	assert.InDelta(t, 0.55, sum, 1e-9)
+
+	assert.Len(t, NewHistogramVec("duration_seconds", "route").WithLabelValues("/").buckets, len(DefaultBuckets))
+}
+
+func TestVecValueJSON(t *testing.T) {
+	v := NewCounterVec("requests_total", "status")
+	v.WithLabelValues("2xx").Add(3)
+
+	data, err := json.Marshal(v.Value())
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"labels": {"status": "2xx"}, "value": 3}]`, string(data))
+}
+
+func TestVecConcurrency(t *testing.T) {
+	v := NewCounterVec("requests_total", "worker")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				v.WithLabelValues([]string{"a", "b"}[i%2]).Inc()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(2500), v.WithLabelValues("a").Get())
+	assert.Equal(t, int64(2500), v.WithLabelValues("b").Get())
+}
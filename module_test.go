@@ -1,10 +1,15 @@
 package tjo
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jimmitjoo/tjo/logging"
+	"github.com/jimmitjoo/tjo/sms"
 )
 
 // testModule is a mock module for testing
@@ -216,3 +221,21 @@ func TestModuleRegistry_ShutdownAll(t *testing.T) {
 		// Just verify it doesn't hang
 	})
 }
+
+func TestSMSModule_UsesAppLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(logging.Config{Level: logging.InfoLevel, Writer: &buf, EnableJSON: true})
+	app := &Tjo{Logging: &LoggingService{Logger: logger}}
+
+	m := sms.NewModule(sms.WithProvider("log"))
+	if err := m.Initialize(app); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Send("+46701234567", "Hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, "SMS message (not sent)") || !strings.Contains(out, `"to":"+46701234567"`) {
+		t.Errorf("The sms log provider should write to g.Logging.Logger, got %q", out)
+	}
+}
@@ -0,0 +1,176 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jimmitjoo/tjo/email"
+	"github.com/jimmitjoo/tjo/sms"
+)
+
+// Channel delivers rendered notifications
+type Channel interface {
+	// Name returns the channel name used in Notification.Via
+	Name() string
+
+	// Render prepares a delivery for the recipient. Return ErrSkip if the
+	// recipient cannot be reached on this channel.
+	Render(n Notification, to Notifiable) (*Delivery, error)
+
+	// Send delivers a rendered notification
+	Send(ctx context.Context, d *Delivery) error
+}
+
+// ErrSkip is returned by Channel.Render when a recipient has no address for the channel
+var ErrSkip = errors.New("notification skipped for channel")
+
+// Mailer sends email. *email.Module and *email.Mail satisfy it.
+type Mailer interface {
+	Send(msg email.Message) error
+}
+
+// contextMailer is a Mailer that can send as part of a trace
+type contextMailer interface {
+	SendContext(ctx context.Context, msg email.Message) error
+}
+
+// SMSSender sends SMS. *sms.Module satisfies it.
+type SMSSender interface {
+	Send(to, message string) (*sms.SendResult, error)
+}
+
+// BroadcastFunc pushes a message to a websocket room, e.g.
+//
+//	func(room string, msg []byte) { wsModule.BroadcastToRoom(room, msg, nil) }
+type BroadcastFunc func(room string, message []byte)
+
+// MailChannel sends notifications implementing MailNotification
+type MailChannel struct {
+	Mailer Mailer
+}
+
+// Name returns the channel name
+func (c *MailChannel) Name() string {
+	return ChannelMail
+}
+
+// Render builds the email message
+func (c *MailChannel) Render(n Notification, to Notifiable) (*Delivery, error) {
+	mn, ok := n.(MailNotification)
+	if !ok {
+		return nil, fmt.Errorf("notification %q does not implement ToMail", n.Type())
+	}
+
+	msg := mn.ToMail(to)
+	if msg.To == "" {
+		msg.To = to.NotificationEmail()
+	}
+	if msg.To == "" {
+		return nil, ErrSkip
+	}
+
+	return &Delivery{Mail: &msg, Email: msg.To}, nil
+}
+
+// Send sends the email
+func (c *MailChannel) Send(ctx context.Context, d *Delivery) error {
+	if c.Mailer == nil {
+		return errors.New("mail channel has no mailer")
+	}
+	if d.Mail == nil {
+		return errors.New("mail delivery has no message")
+	}
+	if cm, ok := c.Mailer.(contextMailer); ok {
+		return cm.SendContext(ctx, *d.Mail)
+	}
+	return c.Mailer.Send(*d.Mail)
+}
+
+// SMSChannel sends notifications implementing SMSNotification
+type SMSChannel struct {
+	Sender SMSSender
+}
+
+// Name returns the channel name
+func (c *SMSChannel) Name() string {
+	return ChannelSMS
+}
+
+// Render builds the SMS text
+func (c *SMSChannel) Render(n Notification, to Notifiable) (*Delivery, error) {
+	sn, ok := n.(SMSNotification)
+	if !ok {
+		return nil, fmt.Errorf("notification %q does not implement ToSMS", n.Type())
+	}
+
+	phone := to.NotificationPhone()
+	if phone == "" {
+		return nil, ErrSkip
+	}
+
+	return &Delivery{Phone: phone, SMS: sn.ToSMS(to)}, nil
+}
+
+// Send sends the SMS
+func (c *SMSChannel) Send(ctx context.Context, d *Delivery) error {
+	if c.Sender == nil {
+		return errors.New("sms channel has no sender")
+	}
+	_, err := c.Sender.Send(d.Phone, d.SMS)
+	return err
+}
+
+// BroadcastChannel pushes notifications implementing BroadcastNotification to websocket clients
+type BroadcastChannel struct {
+	Broadcast BroadcastFunc
+}
+
+// Name returns the channel name
+func (c *BroadcastChannel) Name() string {
+	return ChannelBroadcast
+}
+
+// Render builds the websocket message
+func (c *BroadcastChannel) Render(n Notification, to Notifiable) (*Delivery, error) {
+	bn, ok := n.(BroadcastNotification)
+	if !ok {
+		return nil, fmt.Errorf("notification %q does not implement ToBroadcast", n.Type())
+	}
+
+	msg := bn.ToBroadcast(to)
+	if msg.Room == "" {
+		if to.NotificationUserID() == "" {
+			return nil, ErrSkip
+		}
+		msg.Room = "user:" + to.NotificationUserID()
+	}
+
+	return &Delivery{Broadcast: &msg}, nil
+}
+
+// Send broadcasts the message in the websocket.Message format with type "notification"
+func (c *BroadcastChannel) Send(ctx context.Context, d *Delivery) error {
+	if c.Broadcast == nil {
+		return errors.New("broadcast channel has no broadcaster")
+	}
+	if d.Broadcast == nil {
+		return errors.New("broadcast delivery has no message")
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":      "notification",
+		"room":      d.Broadcast.Room,
+		"data":      d.Broadcast.Data,
+		"timestamp": time.Now(),
+		"metadata":  map[string]interface{}{"notification_type": d.Type},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Broadcast(d.Broadcast.Room, payload)
+	return nil
+}
@@ -0,0 +1,224 @@
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jimmitjoo/tjo/database"
+)
+
+// DefaultTable is the table used by DatabaseChannel when none is set
+const DefaultTable = "notifications"
+
+// ErrNotFound is returned when a stored notification does not exist
+var ErrNotFound = errors.New("notification not found")
+
+// StoredNotification is an in-app notification read from the database
+type StoredNotification struct {
+	ID        string                 `json:"id"`
+	UserID    string                 `json:"user_id"`
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data"`
+	ReadAt    *time.Time             `json:"read_at,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// IsRead reports whether the notification has been marked as read
+func (n *StoredNotification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// DatabaseChannel stores notifications implementing DatabaseNotification
+// so they can be listed in the application as an inbox with read/unread state.
+type DatabaseChannel struct {
+	DB    *sql.DB
+	Table string
+}
+
+// NewDatabaseChannel creates a database channel using the default table
+func NewDatabaseChannel(db *sql.DB) *DatabaseChannel {
+	return &DatabaseChannel{DB: db, Table: DefaultTable}
+}
+
+// Name returns the channel name
+func (c *DatabaseChannel) Name() string {
+	return ChannelDatabase
+}
+
+// Render builds the stored data
+func (c *DatabaseChannel) Render(n Notification, to Notifiable) (*Delivery, error) {
+	dn, ok := n.(DatabaseNotification)
+	if !ok {
+		return nil, fmt.Errorf("notification %q does not implement ToDatabase", n.Type())
+	}
+
+	if to.NotificationUserID() == "" {
+		return nil, ErrSkip
+	}
+
+	return &Delivery{Data: dn.ToDatabase(to)}, nil
+}
+
+// Send stores the notification as unread
+func (c *DatabaseChannel) Send(ctx context.Context, d *Delivery) error {
+	data, err := json.Marshal(d.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification data: %w", err)
+	}
+
+	_, err = c.query().Insert(map[string]interface{}{
+		"id":         newNotificationID(),
+		"user_id":    d.UserID,
+		"type":       d.Type,
+		"data":       string(data),
+		"created_at": time.Now().UTC(),
+	})
+	return err
+}
+
+// List returns the newest notifications for a user, read and unread
+func (c *DatabaseChannel) List(userID string, limit int) ([]*StoredNotification, error) {
+	qb := c.query().Where("user_id", "=", userID).OrderBy("created_at", "DESC")
+	if limit > 0 {
+		qb = qb.Limit(limit)
+	}
+	return c.scan(qb)
+}
+
+// Unread returns a user's unread notifications, newest first
+func (c *DatabaseChannel) Unread(userID string) ([]*StoredNotification, error) {
+	qb := c.query().Where("user_id", "=", userID).WhereNull("read_at").OrderBy("created_at", "DESC")
+	return c.scan(qb)
+}
+
+// UnreadCount returns the number of unread notifications for a user
+func (c *DatabaseChannel) UnreadCount(userID string) (int64, error) {
+	return c.query().Where("user_id", "=", userID).WhereNull("read_at").Count()
+}
+
+// Find returns a single notification by ID
+func (c *DatabaseChannel) Find(id string) (*StoredNotification, error) {
+	list, err := c.scan(c.query().Where("id", "=", id).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return list[0], nil
+}
+
+// MarkAsRead marks one of a user's notifications as read.
+// The user ID is checked so users cannot mark each other's notifications.
+func (c *DatabaseChannel) MarkAsRead(userID, id string) error {
+	res, err := c.query().
+		Where("id", "=", id).
+		Where("user_id", "=", userID).
+		Update(map[string]interface{}{"read_at": time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAsUnread clears the read state of one of a user's notifications
+func (c *DatabaseChannel) MarkAsUnread(userID, id string) error {
+	res, err := c.query().
+		Where("id", "=", id).
+		Where("user_id", "=", userID).
+		Update(map[string]interface{}{"read_at": nil})
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkAllAsRead marks every unread notification of a user as read
+func (c *DatabaseChannel) MarkAllAsRead(userID string) error {
+	_, err := c.query().
+		Where("user_id", "=", userID).
+		WhereNull("read_at").
+		Update(map[string]interface{}{"read_at": time.Now().UTC()})
+	return err
+}
+
+// Delete removes one of a user's notifications
+func (c *DatabaseChannel) Delete(userID, id string) error {
+	_, err := c.query().Where("id", "=", id).Where("user_id", "=", userID).Delete()
+	return err
+}
+
+// Prune deletes read notifications older than the given age
+func (c *DatabaseChannel) Prune(olderThan time.Duration) (int64, error) {
+	res, err := c.query().
+		WhereNotNull("read_at").
+		Where("created_at", "<", time.Now().UTC().Add(-olderThan)).
+		Delete()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (c *DatabaseChannel) table() string {
+	if c.Table == "" {
+		return DefaultTable
+	}
+	return c.Table
+}
+
+func (c *DatabaseChannel) query() *database.QueryBuilder {
+	return database.NewQueryBuilder(c.DB).Table(c.table())
+}
+
+func (c *DatabaseChannel) scan(qb *database.QueryBuilder) ([]*StoredNotification, error) {
+	rows, err := qb.Select("id", "user_id", "type", "data", "read_at", "created_at").Get()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*StoredNotification
+	for rows.Next() {
+		var n StoredNotification
+		var data string
+		var readAt sql.NullTime
+
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &data, &readAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &n.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode notification %s: %w", n.ID, err)
+		}
+		if readAt.Valid {
+			t := readAt.Time
+			n.ReadAt = &t
+		}
+
+		list = append(list, &n)
+	}
+
+	return list, rows.Err()
+}
+
+func newNotificationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("ntf_%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDatabaseChannel(t *testing.T) *DatabaseChannel {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile("../cmd/tjo/templates/migrations/notifications.sqlite.up.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(schema))
+	require.NoError(t, err)
+
+	return NewDatabaseChannel(db)
+}
+
+func TestDatabaseChannel_ReadState(t *testing.T) {
+	ch := newTestDatabaseChannel(t)
+	m := NewModule(WithDatabase(ch.DB))
+	require.NoError(t, m.Initialize(nil))
+
+	to := Recipient{UserID: "42"}
+	n := invoicePaid{Amount: "$10", channels: []string{ChannelDatabase}}
+	require.NoError(t, m.SendNow(context.Background(), to, n))
+	require.NoError(t, m.SendNow(context.Background(), to, n))
+	require.NoError(t, m.SendNow(context.Background(), Recipient{UserID: "7"}, n))
+
+	list, err := m.Database.List("42", 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "invoice_paid", list[0].Type)
+	assert.Equal(t, "$10", list[0].Data["amount"])
+	assert.False(t, list[0].IsRead())
+
+	count, err := m.Database.UnreadCount("42")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Users cannot mark each other's notifications
+	assert.ErrorIs(t, m.Database.MarkAsRead("7", list[0].ID), ErrNotFound)
+
+	require.NoError(t, m.Database.MarkAsRead("42", list[0].ID))
+	found, err := m.Database.Find(list[0].ID)
+	require.NoError(t, err)
+	assert.True(t, found.IsRead())
+
+	unread, err := m.Database.Unread("42")
+	require.NoError(t, err)
+	require.Len(t, unread, 1)
+	assert.Equal(t, list[1].ID, unread[0].ID)
+
+	require.NoError(t, m.Database.MarkAsUnread("42", list[0].ID))
+	count, _ = m.Database.UnreadCount("42")
+	assert.Equal(t, int64(2), count)
+
+	require.NoError(t, m.Database.MarkAllAsRead("42"))
+	count, _ = m.Database.UnreadCount("42")
+	assert.Equal(t, int64(0), count)
+
+	count, _ = m.Database.UnreadCount("7")
+	assert.Equal(t, int64(1), count)
+}
+
+func TestDatabaseChannel_SkipsRecipientsWithoutUserID(t *testing.T) {
+	ch := newTestDatabaseChannel(t)
+
+	_, err := ch.Render(invoicePaid{}, Recipient{Email: "ada@example.com"})
+	assert.ErrorIs(t, err, ErrSkip)
+}
+
+func TestDatabaseChannel_DeleteAndPrune(t *testing.T) {
+	ch := newTestDatabaseChannel(t)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ch.Send(context.Background(), &Delivery{
+			Type:   "welcome",
+			UserID: "42",
+			Data:   map[string]interface{}{"n": i},
+		}))
+	}
+
+	list, err := ch.List("42", 2)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	require.NoError(t, ch.Delete("42", list[0].ID))
+	_, err = ch.Find(list[0].ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, ch.MarkAsRead("42", list[1].ID))
+
+	// Only read notifications older than the cutoff are pruned
+	pruned, err := ch.Prune(-time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	count, err := ch.UnreadCount("42")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
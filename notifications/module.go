@@ -0,0 +1,277 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jimmitjoo/tjo"
+	"github.com/jimmitjoo/tjo/jobs"
+)
+
+// JobType is the job type deliveries are queued as
+const JobType = "notifications.deliver"
+
+// ErrUnknownChannel is returned when a notification names a channel that is not registered
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
+// Module implements the tjo.Module interface for notifications.
+// Each channel of a notification is queued as a separate job, so a failing
+// SMS provider does not block the email, and each channel is retried on its own.
+//
+// Example:
+//
+//	app := tjo.Tjo{}
+//	app.New(rootPath, notifications.NewModule(
+//	    notifications.WithMail(emailModule),
+//	    notifications.WithSMS(smsModule),
+//	))
+//
+//	// Later:
+//	n := app.GetModule("notifications").(*notifications.Module)
+//	n.Send(user, InvoicePaid{Amount: "$10"})
+type Module struct {
+	Database *DatabaseChannel
+
+	channels map[string]Channel
+	jobs     *jobs.JobManager
+	queue    string
+	useDB    bool
+	mu       sync.RWMutex
+}
+
+// Option is a function that configures the notifications module
+type Option func(*Module)
+
+// NewModule creates a notifications module
+func NewModule(opts ...Option) *Module {
+	m := &Module{
+		channels: make(map[string]Channel),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// WithChannel registers a custom channel
+func WithChannel(ch Channel) Option {
+	return func(m *Module) {
+		m.channels[ch.Name()] = ch
+	}
+}
+
+// WithMail enables the mail channel
+func WithMail(mailer Mailer) Option {
+	return WithChannel(&MailChannel{Mailer: mailer})
+}
+
+// WithSMS enables the SMS channel
+func WithSMS(sender SMSSender) Option {
+	return WithChannel(&SMSChannel{Sender: sender})
+}
+
+// WithBroadcast enables the websocket broadcast channel
+func WithBroadcast(broadcast BroadcastFunc) Option {
+	return WithChannel(&BroadcastChannel{Broadcast: broadcast})
+}
+
+// WithDatabase enables the database channel using the given connection.
+// Pass nil to use the application's database.
+func WithDatabase(db *sql.DB) Option {
+	return func(m *Module) {
+		m.useDB = true
+		if db != nil {
+			m.Database = NewDatabaseChannel(db)
+			m.channels[ChannelDatabase] = m.Database
+		}
+	}
+}
+
+// WithJobManager sets the job manager deliveries are queued on.
+// Defaults to the application's job manager.
+func WithJobManager(jm *jobs.JobManager) Option {
+	return func(m *Module) {
+		m.jobs = jm
+	}
+}
+
+// WithQueue sets the job queue deliveries are pushed to.
+// The queue must have workers; the default is the manager's default queue.
+func WithQueue(queue string) Option {
+	return func(m *Module) {
+		m.queue = queue
+	}
+}
+
+// Name returns the module identifier
+func (m *Module) Name() string {
+	return "notifications"
+}
+
+// Initialize wires the module to the application's job manager, mailer,
+// SMS provider and database and registers the delivery job handler.
+// Channels configured with options take precedence.
+func (m *Module) Initialize(g *tjo.Tjo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if g != nil && g.Background != nil {
+		if m.jobs == nil {
+			m.jobs = g.Background.Jobs
+		}
+		if _, ok := m.channels[ChannelMail]; !ok {
+			m.channels[ChannelMail] = &MailChannel{Mailer: &g.Background.Mail}
+		}
+		if _, ok := m.channels[ChannelSMS]; !ok && g.Background.SMS != nil {
+			m.channels[ChannelSMS] = &SMSChannel{Sender: g.Background.SMS}
+		}
+	}
+
+	if m.useDB && m.Database == nil {
+		if g == nil || g.Data == nil || g.Data.DB.Pool == nil {
+			return errors.New("notifications: database channel requires a database connection")
+		}
+		m.Database = NewDatabaseChannel(g.Data.DB.Pool)
+		m.channels[ChannelDatabase] = m.Database
+	}
+
+	if m.jobs != nil {
+		m.jobs.RegisterHandlerFunc(JobType, m.handleJob)
+	}
+
+	return nil
+}
+
+// Shutdown is a no-op; queued deliveries are drained by the job manager
+func (m *Module) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Channel returns a registered channel by name
+func (m *Module) Channel(name string) (Channel, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ch, ok := m.channels[name]
+	return ch, ok
+}
+
+// Send renders the notification for each of its channels and queues the
+// deliveries. Without a job manager the deliveries are sent immediately.
+func (m *Module) Send(to Notifiable, n Notification) error {
+	return m.SendContext(context.Background(), to, n)
+}
+
+// SendContext is like Send, but the queued deliveries run as part of the
+// trace in ctx
+func (m *Module) SendContext(ctx context.Context, to Notifiable, n Notification) error {
+	deliveries, err := m.render(to, n)
+	if err != nil {
+		return err
+	}
+
+	if m.jobs == nil {
+		return m.deliverAll(ctx, deliveries)
+	}
+
+	var errs []error
+	for _, d := range deliveries {
+		job := jobs.NewJob(JobType, m.queue, map[string]interface{}{"delivery": d})
+		if err := m.jobs.EnqueueContext(ctx, job); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendNow renders and delivers the notification synchronously, bypassing the queue
+func (m *Module) SendNow(ctx context.Context, to Notifiable, n Notification) error {
+	deliveries, err := m.render(to, n)
+	if err != nil {
+		return err
+	}
+	return m.deliverAll(ctx, deliveries)
+}
+
+// render builds one delivery per channel. Channels the recipient cannot
+// be reached on are skipped.
+func (m *Module) render(to Notifiable, n Notification) ([]*Delivery, error) {
+	var deliveries []*Delivery
+
+	for _, name := range n.Via(to) {
+		ch, ok := m.Channel(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownChannel, name)
+		}
+
+		d, err := ch.Render(n, to)
+		if errors.Is(err, ErrSkip) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		d.Channel = name
+		d.Type = n.Type()
+		d.UserID = to.NotificationUserID()
+		if d.Email == "" {
+			d.Email = to.NotificationEmail()
+		}
+		if d.Phone == "" {
+			d.Phone = to.NotificationPhone()
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+func (m *Module) deliverAll(ctx context.Context, deliveries []*Delivery) error {
+	var errs []error
+	for _, d := range deliveries {
+		if err := m.deliver(ctx, d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Module) deliver(ctx context.Context, d *Delivery) error {
+	ch, ok := m.Channel(d.Channel)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownChannel, d.Channel)
+	}
+
+	if err := ch.Send(ctx, d); err != nil {
+		return fmt.Errorf("%s: %w", d.Channel, err)
+	}
+	return nil
+}
+
+// handleJob delivers a queued notification. The payload is decoded through
+// JSON so jobs restored from persistence are handled the same way.
+func (m *Module) handleJob(ctx context.Context, job *jobs.Job) error {
+	raw, ok := job.GetPayloadValue("delivery")
+	if !ok {
+		return errors.New("notification job has no delivery")
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	var d Delivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return fmt.Errorf("invalid notification delivery: %w", err)
+	}
+
+	return m.deliver(ctx, &d)
+}
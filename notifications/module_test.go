@@ -0,0 +1,210 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jimmitjoo/tjo/email"
+	"github.com/jimmitjoo/tjo/jobs"
+	"github.com/jimmitjoo/tjo/sms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type invoicePaid struct {
+	Amount   string
+	channels []string
+}
+
+func (n invoicePaid) Type() string { return "invoice_paid" }
+
+func (n invoicePaid) Via(to Notifiable) []string { return n.channels }
+
+func (n invoicePaid) ToMail(to Notifiable) email.Message {
+	return email.Message{Subject: "Invoice paid", Data: n.Amount}
+}
+
+func (n invoicePaid) ToSMS(to Notifiable) string {
+	return "Invoice paid: " + n.Amount
+}
+
+func (n invoicePaid) ToBroadcast(to Notifiable) BroadcastMessage {
+	return BroadcastMessage{Data: map[string]interface{}{"amount": n.Amount}}
+}
+
+func (n invoicePaid) ToDatabase(to Notifiable) map[string]interface{} {
+	return map[string]interface{}{"amount": n.Amount}
+}
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []email.Message
+	err  error
+}
+
+func (f *fakeMailer) Send(msg email.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+type fakeSMS struct {
+	mu   sync.Mutex
+	sent map[string]string
+}
+
+func (f *fakeSMS) Send(to, message string) (*sms.SendResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sent == nil {
+		f.sent = make(map[string]string)
+	}
+	f.sent[to] = message
+	return &sms.SendResult{To: to, Status: sms.StatusSent}, nil
+}
+
+func TestModule_SendNow(t *testing.T) {
+	mailer := &fakeMailer{}
+	texter := &fakeSMS{}
+	var room string
+	var payload []byte
+
+	m := NewModule(
+		WithMail(mailer),
+		WithSMS(texter),
+		WithBroadcast(func(r string, msg []byte) { room, payload = r, msg }),
+	)
+
+	to := Recipient{Email: "ada@example.com", Phone: "+46701234567", UserID: "42"}
+	n := invoicePaid{Amount: "$10", channels: []string{ChannelMail, ChannelSMS, ChannelBroadcast}}
+
+	require.NoError(t, m.SendNow(context.Background(), to, n))
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "ada@example.com", mailer.sent[0].To)
+	assert.Equal(t, "Invoice paid", mailer.sent[0].Subject)
+	assert.Equal(t, "Invoice paid: $10", texter.sent["+46701234567"])
+
+	assert.Equal(t, "user:42", room)
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &msg))
+	assert.Equal(t, "notification", msg["type"])
+	assert.Equal(t, "$10", msg["data"].(map[string]interface{})["amount"])
+	assert.Equal(t, "invoice_paid", msg["metadata"].(map[string]interface{})["notification_type"])
+}
+
+func TestModule_SkipsUnreachableChannels(t *testing.T) {
+	mailer := &fakeMailer{}
+	texter := &fakeSMS{}
+	m := NewModule(WithMail(mailer), WithSMS(texter))
+
+	to := Recipient{Email: "ada@example.com"}
+	n := invoicePaid{Amount: "$10", channels: []string{ChannelMail, ChannelSMS}}
+
+	require.NoError(t, m.SendNow(context.Background(), to, n))
+	assert.Len(t, mailer.sent, 1)
+	assert.Empty(t, texter.sent)
+}
+
+func TestModule_UnknownChannel(t *testing.T) {
+	m := NewModule()
+	n := invoicePaid{channels: []string{"pigeon"}}
+
+	err := m.Send(Recipient{Email: "ada@example.com"}, n)
+	assert.ErrorIs(t, err, ErrUnknownChannel)
+}
+
+func TestModule_SendNowReturnsChannelErrors(t *testing.T) {
+	mailer := &fakeMailer{err: errors.New("smtp down")}
+	texter := &fakeSMS{}
+	m := NewModule(WithMail(mailer), WithSMS(texter))
+
+	to := Recipient{Email: "ada@example.com", Phone: "+46701234567"}
+	n := invoicePaid{Amount: "$10", channels: []string{ChannelMail, ChannelSMS}}
+
+	err := m.SendNow(context.Background(), to, n)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "smtp down")
+	assert.Len(t, texter.sent, 1, "a failing channel must not block the others")
+}
+
+// ctxChannel records the context deliveries are sent with
+type ctxChannel struct {
+	ctx context.Context
+}
+
+func (c *ctxChannel) Name() string { return "ctx" }
+
+func (c *ctxChannel) Render(n Notification, to Notifiable) (*Delivery, error) {
+	return &Delivery{}, nil
+}
+
+func (c *ctxChannel) Send(ctx context.Context, d *Delivery) error {
+	c.ctx = ctx
+	return nil
+}
+
+func TestModule_SendContextPassesContext(t *testing.T) {
+	ch := &ctxChannel{}
+	m := NewModule(WithChannel(ch))
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request")
+	require.NoError(t, m.SendContext(ctx, Recipient{}, invoicePaid{channels: []string{"ctx"}}))
+
+	require.NotNil(t, ch.ctx)
+	assert.Equal(t, "request", ch.ctx.Value(key{}))
+}
+
+func TestModule_SendQueuesDeliveries(t *testing.T) {
+	jm := jobs.NewJobManager(nil)
+	require.NoError(t, jm.Start())
+	defer jm.Stop()
+
+	mailer := &fakeMailer{}
+	texter := &fakeSMS{}
+	m := NewModule(WithJobManager(jm), WithMail(mailer), WithSMS(texter))
+	require.NoError(t, m.Initialize(nil))
+
+	to := Recipient{Email: "ada@example.com", Phone: "+46701234567"}
+	n := invoicePaid{Amount: "$10", channels: []string{ChannelMail, ChannelSMS}}
+	require.NoError(t, m.Send(to, n))
+
+	assert.Eventually(t, func() bool {
+		mailer.mu.Lock()
+		defer mailer.mu.Unlock()
+		texter.mu.Lock()
+		defer texter.mu.Unlock()
+		return len(mailer.sent) == 1 && len(texter.sent) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	assert.Equal(t, "ada@example.com", mailer.sent[0].To)
+	assert.Equal(t, "$10", mailer.sent[0].Data)
+}
+
+func TestModule_HandleJobDecodesPersistedPayload(t *testing.T) {
+	mailer := &fakeMailer{}
+	m := NewModule(WithMail(mailer))
+
+	// Simulate a job restored from persistence: the payload is plain JSON maps
+	raw := `{"delivery":{"channel":"mail","type":"invoice_paid","mail":{"to":"ada@example.com","subject":"Hi"}}}`
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &payload))
+
+	job := jobs.NewJob(JobType, "", payload)
+	require.NoError(t, m.handleJob(context.Background(), job))
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "ada@example.com", mailer.sent[0].To)
+	assert.Equal(t, "Hi", mailer.sent[0].Subject)
+}
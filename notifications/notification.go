@@ -0,0 +1,110 @@
+// Package notifications delivers a single notification to a recipient
+// over several channels: mail, SMS, websocket broadcasts and an in-app
+// database inbox.
+//
+// A Notification declares its channels with Via and renders itself for
+// each channel by implementing the matching To* interface:
+//
+//	type InvoicePaid struct{ Amount string }
+//
+//	func (n InvoicePaid) Type() string                          { return "invoice_paid" }
+//	func (n InvoicePaid) Via(to notifications.Notifiable) []string {
+//	    return []string{notifications.ChannelMail, notifications.ChannelDatabase}
+//	}
+//	func (n InvoicePaid) ToMail(to notifications.Notifiable) email.Message {
+//	    return email.Message{Subject: "Invoice paid", Template: "invoice-paid", Data: n}
+//	}
+//	func (n InvoicePaid) ToDatabase(to notifications.Notifiable) map[string]interface{} {
+//	    return map[string]interface{}{"amount": n.Amount}
+//	}
+//
+// Deliveries are queued through jobs.JobManager and retried on failure.
+package notifications
+
+import (
+	"github.com/jimmitjoo/tjo/email"
+)
+
+// Built-in channel names
+const (
+	ChannelMail      = "mail"
+	ChannelSMS       = "sms"
+	ChannelBroadcast = "broadcast"
+	ChannelDatabase  = "database"
+)
+
+// Notifiable identifies a recipient. Return an empty string for any
+// address the recipient does not have; channels needing it are skipped.
+type Notifiable interface {
+	NotificationEmail() string
+	NotificationPhone() string
+	NotificationUserID() string
+}
+
+// Notification is an event that can be delivered to a Notifiable
+type Notification interface {
+	// Type identifies the kind of notification, e.g. "invoice_paid".
+	// It is stored with database notifications.
+	Type() string
+
+	// Via returns the channels the notification is sent on for this recipient
+	Via(to Notifiable) []string
+}
+
+// MailNotification renders a notification as an email.
+// The To field is filled from the recipient when empty.
+type MailNotification interface {
+	ToMail(to Notifiable) email.Message
+}
+
+// SMSNotification renders a notification as an SMS text
+type SMSNotification interface {
+	ToSMS(to Notifiable) string
+}
+
+// BroadcastNotification renders a notification as a websocket message
+type BroadcastNotification interface {
+	ToBroadcast(to Notifiable) BroadcastMessage
+}
+
+// DatabaseNotification renders the data stored for an in-app notification
+type DatabaseNotification interface {
+	ToDatabase(to Notifiable) map[string]interface{}
+}
+
+// BroadcastMessage is a message pushed to a websocket room.
+// Room defaults to "user:<user ID>" when empty.
+type BroadcastMessage struct {
+	Room string                 `json:"room,omitempty"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// Recipient is a simple Notifiable for ad-hoc notifications
+type Recipient struct {
+	Email  string
+	Phone  string
+	UserID string
+}
+
+// NotificationEmail returns the recipient's email address
+func (r Recipient) NotificationEmail() string { return r.Email }
+
+// NotificationPhone returns the recipient's phone number
+func (r Recipient) NotificationPhone() string { return r.Phone }
+
+// NotificationUserID returns the recipient's user ID
+func (r Recipient) NotificationUserID() string { return r.UserID }
+
+// Delivery is a notification rendered for one channel and recipient.
+// It is what gets queued, so every field must survive JSON encoding.
+type Delivery struct {
+	Channel   string                 `json:"channel"`
+	Type      string                 `json:"type"`
+	UserID    string                 `json:"user_id,omitempty"`
+	Email     string                 `json:"email,omitempty"`
+	Phone     string                 `json:"phone,omitempty"`
+	Mail      *email.Message         `json:"mail,omitempty"`
+	SMS       string                 `json:"sms,omitempty"`
+	Broadcast *BroadcastMessage      `json:"broadcast,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
@@ -0,0 +1,218 @@
+package otel
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jimmitjoo/tjo/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// bridgeScope is the instrumentation scope of bridged registry metrics.
+const bridgeScope = "github.com/jimmitjoo/tjo/logging"
+
+// registryProducer converts the metrics of Tjo metric registries to
+// OpenTelemetry metric data on every collection, so they are exported
+// through the same pipeline as the OpenTelemetry instruments.
+type registryProducer struct {
+	mu         sync.RWMutex
+	registries []*logging.MetricRegistry
+	start      time.Time
+}
+
+func newRegistryProducer() *registryProducer {
+	return &registryProducer{start: time.Now()}
+}
+
+// add bridges a registry, ignoring registries already bridged.
+func (p *registryProducer) add(registry *logging.MetricRegistry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, r := range p.registries {
+		if r == registry {
+			return
+		}
+	}
+	p.registries = append(p.registries, registry)
+}
+
+// Produce implements sdkmetric.Producer.
+func (p *registryProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	p.mu.RLock()
+	registries := append([]*logging.MetricRegistry(nil), p.registries...)
+	p.mu.RUnlock()
+
+	now := time.Now()
+	var metrics []metricdata.Metrics
+	for _, registry := range registries {
+		all := registry.GetAll()
+
+		names := make([]string, 0, len(all))
+		for name := range all {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if m, ok := p.convert(all[name], now); ok {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: bridgeScope},
+		Metrics: metrics,
+	}}, nil
+}
+
+// convert returns the OpenTelemetry data of a registry metric.
+func (p *registryProducer) convert(m logging.Metric, now time.Time) (metricdata.Metrics, bool) {
+	attrs := labelAttributes(m.Labels())
+
+	switch m := m.(type) {
+	case *logging.Counter:
+		return metricdata.Metrics{
+			Name: m.Name(),
+			Data: metricdata.Sum[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{{
+					Attributes: attrs,
+					StartTime:  p.start,
+					Time:       now,
+					Value:      m.Get(),
+				}},
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			},
+		}, true
+	case *logging.Gauge:
+		return metricdata.Metrics{
+			Name: m.Name(),
+			Data: metricdata.Gauge[int64]{
+				DataPoints: []metricdata.DataPoint[int64]{{
+					Attributes: attrs,
+					Time:       now,
+					Value:      m.Get(),
+				}},
+			},
+		}, true
+	case *logging.Histogram:
+		point, ok := histogramPoint(m)
+		if !ok {
+			return metricdata.Metrics{}, false
+		}
+		point.Attributes = attrs
+		point.StartTime = p.start
+		point.Time = now
+		return metricdata.Metrics{
+			Name: m.Name(),
+			Data: metricdata.Histogram[float64]{
+				DataPoints:  []metricdata.HistogramDataPoint[float64]{point},
+				Temporality: metricdata.CumulativeTemporality,
+			},
+		}, true
+	case logging.Collector:
+		return p.convertCollector(m, now)
+	default:
+		return metricdata.Metrics{}, false
+	}
+}
+
+// convertCollector returns the OpenTelemetry data of a metric vector, with
+// a data point per label set.
+func (p *registryProducer) convertCollector(c logging.Collector, now time.Time) (metricdata.Metrics, bool) {
+	var merged metricdata.Metrics
+	var ok bool
+	for _, child := range c.Collect() {
+		m, converted := p.convert(child, now)
+		if !converted {
+			continue
+		}
+		if !ok {
+			merged, ok = m, true
+			continue
+		}
+
+		switch data := merged.Data.(type) {
+		case metricdata.Sum[int64]:
+			data.DataPoints = append(data.DataPoints, m.Data.(metricdata.Sum[int64]).DataPoints...)
+			merged.Data = data
+		case metricdata.Gauge[int64]:
+			data.DataPoints = append(data.DataPoints, m.Data.(metricdata.Gauge[int64]).DataPoints...)
+			merged.Data = data
+		case metricdata.Histogram[float64]:
+			data.DataPoints = append(data.DataPoints, m.Data.(metricdata.Histogram[float64]).DataPoints...)
+			merged.Data = data
+		}
+	}
+	merged.Name = c.Name()
+	return merged, ok
+}
+
+// histogramPoint converts the cumulative buckets of a Tjo histogram to the
+// per-bucket counts of an OpenTelemetry histogram.
+func histogramPoint(h *logging.Histogram) (metricdata.HistogramDataPoint[float64], bool) {
+	value, ok := h.Value().(map[string]interface{})
+	if !ok {
+		return metricdata.HistogramDataPoint[float64]{}, false
+	}
+	count, _ := value["count"].(int64)
+	sumMillis, _ := value["sum"].(int64)
+	buckets, _ := value["buckets"].(map[string]int64)
+
+	bounds := make([]float64, 0, len(buckets))
+	cumulative := make(map[float64]int64, len(buckets))
+	for key, n := range buckets {
+		bound, err := strconv.ParseFloat(key, 64)
+		if err != nil {
+			continue
+		}
+		bounds = append(bounds, bound)
+		cumulative[bound] = n
+	}
+	sort.Float64s(bounds)
+
+	// Observations are counted before their buckets, so concurrent
+	// observations can leave a bucket ahead of the next; clamp at zero
+	counts := make([]uint64, len(bounds)+1)
+	var previous int64
+	for i, bound := range bounds {
+		counts[i] = nonNegative(cumulative[bound] - previous)
+		if cumulative[bound] > previous {
+			previous = cumulative[bound]
+		}
+	}
+	counts[len(bounds)] = nonNegative(count - previous)
+
+	return metricdata.HistogramDataPoint[float64]{
+		Count:        uint64(count),
+		Bounds:       bounds,
+		BucketCounts: counts,
+		Sum:          float64(sumMillis) / 1000, // Stored as milliseconds
+	}, true
+}
+
+func nonNegative(n int64) uint64 {
+	if n < 0 {
+		return 0
+	}
+	return uint64(n)
+}
+
+// labelAttributes converts metric labels to an attribute set.
+func labelAttributes(labels map[string]string) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(labels))
+	for k, v := range labels {
+		kvs = append(kvs, attribute.String(k, v))
+	}
+	return attribute.NewSet(kvs...)
+}
@@ -0,0 +1,113 @@
+package otel
+
+import (
+	"testing"
+
+	"github.com/jimmitjoo/tjo/logging"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestBridgeRegistry(t *testing.T) {
+	provider, exporter := newMetricsProvider(t, false)
+
+	registry := logging.NewMetricRegistry()
+	counter := logging.NewCounter("jobs_processed_total", map[string]string{"queue": "mail"})
+	gauge := logging.NewGauge("jobs_waiting", nil)
+	histogram := logging.NewHistogramWithBuckets("job_duration_seconds", nil, []float64{0.1, 1})
+	registry.Register(counter)
+	registry.Register(gauge)
+	registry.Register(histogram)
+
+	counter.Add(5)
+	gauge.Set(7)
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(0.5)
+	histogram.Observe(2)
+
+	provider.BridgeRegistry(registry)
+	provider.BridgeRegistry(registry) // Bridged once
+
+	metrics := exporter.collect(t, provider)
+
+	sum, ok := metrics["jobs_processed_total"].Data.(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 1 {
+		t.Fatalf("jobs_processed_total = %#v, want one sum", metrics["jobs_processed_total"].Data)
+	}
+	if !sum.IsMonotonic || sum.DataPoints[0].Value != 5 {
+		t.Errorf("jobs_processed_total = %d, want monotonic 5", sum.DataPoints[0].Value)
+	}
+	if queue, _ := sum.DataPoints[0].Attributes.Value("queue"); queue.AsString() != "mail" {
+		t.Errorf("queue = %q, want mail", queue.AsString())
+	}
+
+	if g := metrics["jobs_waiting"].Data.(metricdata.Gauge[int64]); g.DataPoints[0].Value != 7 {
+		t.Errorf("jobs_waiting = %d, want 7", g.DataPoints[0].Value)
+	}
+
+	point := metrics["job_duration_seconds"].Data.(metricdata.Histogram[float64]).DataPoints[0]
+	if point.Count != 4 {
+		t.Errorf("Count = %d, want 4", point.Count)
+	}
+	if point.Sum != 3.05 {
+		t.Errorf("Sum = %v, want 3.05", point.Sum)
+	}
+	wantCounts := []uint64{1, 2, 1}
+	for i, want := range wantCounts {
+		if point.BucketCounts[i] != want {
+			t.Errorf("BucketCounts = %v, want %v", point.BucketCounts, wantCounts)
+			break
+		}
+	}
+}
+
+func TestBridgeRegistryVectors(t *testing.T) {
+	provider, exporter := newMetricsProvider(t, false)
+
+	registry := logging.NewMetricRegistry()
+	requests := logging.NewCounterVec("http_server_requests_total", "route", "status")
+	durations := logging.NewHistogramVecWithBuckets("http_server_request_duration_seconds", []float64{0.1, 1}, "route")
+	registry.Register(requests)
+	registry.Register(durations)
+
+	requests.WithLabelValues("/users", "2xx").Add(3)
+	requests.WithLabelValues("/users/{id}", "4xx").Inc()
+	durations.WithLabelValues("/users").Observe(0.05)
+	durations.WithLabelValues("/users/{id}").Observe(0.5)
+
+	provider.BridgeRegistry(registry)
+	metrics := exporter.collect(t, provider)
+
+	sum, ok := metrics["http_server_requests_total"].Data.(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 2 {
+		t.Fatalf("http_server_requests_total = %#v, want two data points", metrics["http_server_requests_total"].Data)
+	}
+	for _, point := range sum.DataPoints {
+		route, _ := point.Attributes.Value("route")
+		status, _ := point.Attributes.Value("status")
+		switch {
+		case route.AsString() == "/users" && status.AsString() == "2xx" && point.Value == 3:
+		case route.AsString() == "/users/{id}" && status.AsString() == "4xx" && point.Value == 1:
+		default:
+			t.Errorf("unexpected data point %s %s = %d", route.AsString(), status.AsString(), point.Value)
+		}
+	}
+
+	histogram := metrics["http_server_request_duration_seconds"].Data.(metricdata.Histogram[float64])
+	if len(histogram.DataPoints) != 2 {
+		t.Errorf("http_server_request_duration_seconds has %d data points, want 2", len(histogram.DataPoints))
+	}
+}
+
+func TestBridgeDefaultRegistry(t *testing.T) {
+	provider, exporter := newMetricsProvider(t, false)
+
+	counter := logging.NewCounter("otel_bridge_test_total", nil)
+	logging.GetDefaultRegistry().Register(counter)
+	defer logging.GetDefaultRegistry().Unregister(counter.Name())
+	counter.Inc()
+
+	if _, ok := exporter.collect(t, provider)["otel_bridge_test_total"]; !ok {
+		t.Error("metrics of the default registry should be bridged")
+	}
+}
@@ -19,6 +19,9 @@ package otel
 import (
 	"errors"
 	"strings"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 )
 
 // ExporterType defines the telemetry exporter to use.
@@ -82,6 +85,13 @@ type Config struct {
 	// Metrics are exported alongside traces.
 	EnableMetrics bool
 
+	// MetricInterval is how often metrics are exported. Defaults to 60 seconds.
+	MetricInterval time.Duration
+
+	// MetricExporter overrides the metric exporter selected by Exporter,
+	// e.g. to print metrics to stdout. Optional.
+	MetricExporter sdkmetric.Exporter
+
 	// EnableTracing enables distributed tracing. Defaults to true.
 	EnableTracing bool
 
@@ -143,6 +153,16 @@ func (c *Config) Validate() error {
 		return errors.New("otel: SampleRatio must be between 0.0 and 1.0")
 	}
 
+	// Zipkin only accepts spans
+	if c.EnableMetrics && c.Exporter == ExporterZipkin && c.MetricExporter == nil {
+		return errors.New("otel: the Zipkin exporter does not support metrics")
+	}
+
+	// Default metric export interval to 60 seconds
+	if c.MetricInterval == 0 {
+		c.MetricInterval = 60 * time.Second
+	}
+
 	// Default EnableTracing to true
 	if !c.EnableMetrics && !c.EnableTracing {
 		c.EnableTracing = true
@@ -107,6 +107,17 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "zipkin exporter with metrics",
+			config: Config{
+				ServiceName:   "test-service",
+				Endpoint:      "http://localhost:9411/api/v2/spans",
+				Exporter:      ExporterZipkin,
+				EnableMetrics: true,
+			},
+			wantErr: true,
+			errMsg:  "does not support metrics",
+		},
 		{
 			name: "valid jaeger exporter (uses OTLP)",
 			config: Config{
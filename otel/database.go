@@ -3,40 +3,43 @@ package otel
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // TracedDB wraps a sql.DB to add tracing to all database operations.
+// Operation durations and connection pool usage are recorded with the
+// global meter provider, which Provider sets when metrics are enabled.
 type TracedDB struct {
-	db     *sql.DB
-	tracer trace.Tracer
-	dbName string
-	dbType string
+	db      *sql.DB
+	tracer  trace.Tracer
+	metrics *dbMetrics
+	dbName  string
+	dbType  string
 }
 
 // WrapDB wraps a sql.DB with tracing capabilities.
 func WrapDB(db *sql.DB, dbType, dbName string) *TracedDB {
-	return &TracedDB{
-		db:     db,
-		tracer: otel.Tracer("tjo/database"),
-		dbName: dbName,
-		dbType: dbType,
-	}
+	return WrapDBWithTracer(db, otel.Tracer("tjo/database"), dbType, dbName)
 }
 
 // WrapDBWithTracer wraps a sql.DB with a specific tracer.
 func WrapDBWithTracer(db *sql.DB, tracer trace.Tracer, dbType, dbName string) *TracedDB {
-	return &TracedDB{
+	t := &TracedDB{
 		db:     db,
 		tracer: tracer,
 		dbName: dbName,
 		dbType: dbType,
 	}
+	t.metrics = newDBMetrics(otel.Meter("tjo/database"), t)
+	return t
 }
 
 // DB returns the underlying sql.DB.
@@ -67,7 +70,9 @@ func (t *TracedDB) Query(ctx context.Context, query string, args ...interface{})
 	)
 	defer span.End()
 
+	start := time.Now()
 	rows, err := t.db.QueryContext(ctx, query, args...)
+	t.metrics.record(ctx, "query", start, err)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -87,7 +92,10 @@ func (t *TracedDB) QueryRow(ctx context.Context, query string, args ...interface
 	)
 	defer span.End()
 
-	return t.db.QueryRowContext(ctx, query, args...)
+	start := time.Now()
+	row := t.db.QueryRowContext(ctx, query, args...)
+	t.metrics.record(ctx, "query_row", start, row.Err())
+	return row
 }
 
 // Exec executes a statement and traces it.
@@ -104,7 +112,9 @@ func (t *TracedDB) Exec(ctx context.Context, query string, args ...interface{})
 	)
 	defer span.End()
 
+	start := time.Now()
 	result, err := t.db.ExecContext(ctx, query, args...)
+	t.metrics.record(ctx, operation, start, err)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -136,10 +146,12 @@ func (t *TracedDB) Prepare(ctx context.Context, query string) (*TracedStmt, erro
 	}
 
 	return &TracedStmt{
-		stmt:   stmt,
-		tracer: t.tracer,
-		query:  query,
-		attrs:  t.baseAttributes(),
+		stmt:      stmt,
+		tracer:    t.tracer,
+		metrics:   t.metrics,
+		query:     query,
+		operation: detectOperation(query),
+		attrs:     t.baseAttributes(),
 	}, nil
 }
 
@@ -160,10 +172,11 @@ func (t *TracedDB) Begin(ctx context.Context) (*TracedTx, error) {
 	}
 
 	return &TracedTx{
-		tx:     tx,
-		tracer: t.tracer,
-		span:   span,
-		attrs:  t.baseAttributes(),
+		tx:      tx,
+		tracer:  t.tracer,
+		metrics: t.metrics,
+		span:    span,
+		attrs:   t.baseAttributes(),
 	}, nil
 }
 
@@ -185,15 +198,18 @@ func (t *TracedDB) Ping(ctx context.Context) error {
 
 // Close closes the database connection.
 func (t *TracedDB) Close() error {
+	t.metrics.unregister()
 	return t.db.Close()
 }
 
 // TracedStmt is a traced prepared statement.
 type TracedStmt struct {
-	stmt   *sql.Stmt
-	tracer trace.Tracer
-	query  string
-	attrs  []attribute.KeyValue
+	stmt      *sql.Stmt
+	tracer    trace.Tracer
+	metrics   *dbMetrics
+	query     string
+	operation string
+	attrs     []attribute.KeyValue
 }
 
 // Query executes the prepared statement with tracing.
@@ -205,7 +221,9 @@ func (s *TracedStmt) Query(ctx context.Context, args ...interface{}) (*sql.Rows,
 	)
 	defer span.End()
 
+	start := time.Now()
 	rows, err := s.stmt.QueryContext(ctx, args...)
+	s.metrics.record(ctx, s.operation, start, err)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -222,7 +240,9 @@ func (s *TracedStmt) Exec(ctx context.Context, args ...interface{}) (sql.Result,
 	)
 	defer span.End()
 
+	start := time.Now()
 	result, err := s.stmt.ExecContext(ctx, args...)
+	s.metrics.record(ctx, s.operation, start, err)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -237,10 +257,11 @@ func (s *TracedStmt) Close() error {
 
 // TracedTx is a traced transaction.
 type TracedTx struct {
-	tx     *sql.Tx
-	tracer trace.Tracer
-	span   trace.Span
-	attrs  []attribute.KeyValue
+	tx      *sql.Tx
+	tracer  trace.Tracer
+	metrics *dbMetrics
+	span    trace.Span
+	attrs   []attribute.KeyValue
 }
 
 // Query executes a query within the transaction.
@@ -252,7 +273,9 @@ func (t *TracedTx) Query(ctx context.Context, query string, args ...interface{})
 	)
 	defer span.End()
 
+	start := time.Now()
 	rows, err := t.tx.QueryContext(ctx, query, args...)
+	t.metrics.record(ctx, detectOperation(query), start, err)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -274,7 +297,9 @@ func (t *TracedTx) Exec(ctx context.Context, query string, args ...interface{})
 	)
 	defer span.End()
 
+	start := time.Now()
 	result, err := t.tx.ExecContext(ctx, query, args...)
+	t.metrics.record(ctx, operation, start, err)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -306,6 +331,98 @@ func (t *TracedTx) Rollback() error {
 	return err
 }
 
+// dbMetrics are the database client metrics defined by the OpenTelemetry
+// semantic conventions.
+type dbMetrics struct {
+	duration     metric.Float64Histogram
+	attrs        []attribute.KeyValue
+	registration metric.Registration
+}
+
+func newDBMetrics(meter metric.Meter, t *TracedDB) *dbMetrics {
+	m := &dbMetrics{attrs: t.baseAttributes()}
+
+	var err error
+	m.duration, err = meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of database client operations."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	usage, err := meter.Int64ObservableUpDownCounter("db.client.connections.usage",
+		metric.WithDescription("Number of connections that are currently in the state described by the state attribute."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		return m
+	}
+	maxOpen, err := meter.Int64ObservableUpDownCounter("db.client.connections.max",
+		metric.WithDescription("The maximum number of open connections allowed."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		return m
+	}
+	waits, err := meter.Int64ObservableCounter("db.client.connections.waits",
+		metric.WithDescription("Number of times a connection was waited for."),
+		metric.WithUnit("{wait}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		return m
+	}
+
+	base := m.attrs[:len(m.attrs):len(m.attrs)]
+	pool := attribute.String("db.client.connections.pool.name", t.dbName)
+	idle := metric.WithAttributes(append(base, pool, attribute.String("state", "idle"))...)
+	used := metric.WithAttributes(append(base, pool, attribute.String("state", "used"))...)
+	poolAttrs := metric.WithAttributes(append(base, pool)...)
+
+	m.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := t.db.Stats()
+		o.ObserveInt64(usage, int64(stats.Idle), idle)
+		o.ObserveInt64(usage, int64(stats.InUse), used)
+		o.ObserveInt64(maxOpen, int64(stats.MaxOpenConnections), poolAttrs)
+		o.ObserveInt64(waits, stats.WaitCount, poolAttrs)
+		return nil
+	}, usage, maxOpen, waits)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return m
+}
+
+// record records the duration of an operation started at start.
+func (m *dbMetrics) record(ctx context.Context, operation string, start time.Time, err error) {
+	if m == nil || m.duration == nil {
+		return
+	}
+
+	attrs := append(m.attrs[:len(m.attrs):len(m.attrs)], semconv.DBOperationKey.String(operation))
+	if err != nil && err != sql.ErrNoRows {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(errorType(err)))
+	}
+	m.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+}
+
+// unregister stops observing the connection pool.
+func (m *dbMetrics) unregister() {
+	if m != nil && m.registration != nil {
+		m.registration.Unregister()
+	}
+}
+
+// errorType returns a low-cardinality description of an error for the
+// error.type attribute.
+func errorType(err error) string {
+	return fmt.Sprintf("%T", err)
+}
+
 // truncateQuery limits query length to avoid huge spans.
 const maxQueryLength = 2048
 
@@ -63,6 +63,31 @@
 //	tracedDB := otel.WrapDB(db, "postgres", "myapp")
 //	rows, err := tracedDB.Query(ctx, "SELECT * FROM users WHERE id = ?", userID)
 //
+// # Outgoing HTTP Requests
+//
+// NewHTTPClient traces the requests of a client as child spans of the
+// request context and propagates the trace to the called service:
+//
+//	client := otel.NewHTTPClient(&http.Client{Timeout: 10 * time.Second})
+//	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+//	resp, err := client.Do(req)
+//
+// # Metrics
+//
+// With EnableMetrics, metrics are exported over OTLP alongside traces. The
+// middleware records the HTTP server metrics of the semantic conventions
+// (http.server.request.duration, http.server.active_requests and body
+// sizes) and TracedDB records db.client.operation.duration and connection
+// pool usage. Counters, gauges and histograms of the default Tjo metric
+// registry are exported too; bridge other registries with BridgeRegistry:
+//
+//	provider.BridgeRegistry(g.Logging.Metrics)
+//
+// Create your own instruments with the provider's meter:
+//
+//	orders, _ := provider.Meter().Int64Counter("orders.created")
+//	orders.Add(ctx, 1)
+//
 // # Log Correlation
 //
 // Add trace context to your logs:
@@ -117,6 +142,7 @@
 //	OTEL_INSECURE=true
 //	OTEL_SAMPLER=ratio
 //	OTEL_SAMPLE_RATIO=0.1
+//	OTEL_METRICS_ENABLED=true
 //
 // # Local Development with Jaeger
 //
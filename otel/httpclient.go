@@ -0,0 +1,91 @@
+package otel
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport is an http.RoundTripper that traces outgoing requests.
+// Each request gets a client span that is a child of the span in the
+// request context, and the trace context is injected into the request
+// headers so the called service continues the trace.
+type Transport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+}
+
+// NewTransport wraps base, or http.DefaultTransport when base is nil.
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		base:   base,
+		tracer: otel.Tracer("tjo/http"),
+	}
+}
+
+// NewHTTPClient returns a copy of client whose requests are traced.
+// A nil client gives a traced client with the default settings.
+//
+// Usage:
+//
+//	client := otel.NewHTTPClient(&http.Client{Timeout: 10 * time.Second})
+//	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+//	resp, err := client.Do(req)
+func NewHTTPClient(client *http.Client) *http.Client {
+	var c http.Client
+	if client != nil {
+		c = *client
+	}
+	c.Transport = NewTransport(c.Transport)
+	return &c
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(httpClientAttributes(r)...),
+	)
+	defer span.End()
+
+	// A RoundTripper must not modify the request it was given
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+
+	return resp, nil
+}
+
+// httpClientAttributes returns standard HTTP client span attributes.
+// The query string is left out since API credentials are often passed in it.
+func httpClientAttributes(r *http.Request) []attribute.KeyValue {
+	u := *r.URL
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return []attribute.KeyValue{
+		semconv.HTTPMethod(r.Method),
+		semconv.HTTPURL(u.String()),
+		semconv.NetPeerName(r.URL.Hostname()),
+	}
+}
@@ -0,0 +1,125 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newSpanRecorder installs a global tracer provider recording ended spans
+func newSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	return recorder
+}
+
+func TestHTTPClient_CreatesChildSpan(t *testing.T) {
+	recorder := newSpanRecorder(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "handler")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/messages?api_key=secret", nil)
+
+	resp, err := NewHTTPClient(nil).Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if req.Header.Get("traceparent") != "" {
+		t.Error("The original request should not be modified")
+	}
+	if traceparent == "" {
+		t.Fatal("The trace context should be injected into the request")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	client := spans[0]
+	if client.Name() != "POST" {
+		t.Errorf("Span name = %q, want POST", client.Name())
+	}
+	if client.SpanKind() != trace.SpanKindClient {
+		t.Errorf("Span kind = %v, want client", client.SpanKind())
+	}
+	if client.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("The client span should be a child of the request span")
+	}
+
+	attrs := make(map[string]string)
+	for _, kv := range client.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["http.url"] != server.URL+"/messages" {
+		t.Errorf("http.url = %q, want it without the query string", attrs["http.url"])
+	}
+	if attrs["http.status_code"] != "202" {
+		t.Errorf("http.status_code = %q, want 202", attrs["http.status_code"])
+	}
+}
+
+func TestHTTPClient_RecordsErrors(t *testing.T) {
+	recorder := newSpanRecorder(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := NewHTTPClient(nil).Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Status = %v, want error", spans[0].Status().Code)
+	}
+}
+
+func TestNewHTTPClient_KeepsSettings(t *testing.T) {
+	base := &http.Client{Timeout: 5}
+	client := NewHTTPClient(base)
+
+	if client == base {
+		t.Error("NewHTTPClient should return a copy")
+	}
+	if client.Timeout != base.Timeout {
+		t.Errorf("Timeout = %v, want %v", client.Timeout, base.Timeout)
+	}
+	if base.Transport != nil {
+		t.Error("The original client should not be modified")
+	}
+	if _, ok := client.Transport.(*Transport); !ok {
+		t.Errorf("Transport = %T, want *Transport", client.Transport)
+	}
+}
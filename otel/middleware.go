@@ -1,13 +1,17 @@
 package otel
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"go.opentelemetry.io/otel/trace"
@@ -53,16 +57,37 @@ func (rw *responseWriter) Unwrap() http.ResponseWriter {
 
 // Middleware returns an HTTP middleware that traces requests.
 // It extracts trace context from incoming requests and creates spans.
+// With metrics enabled it also records the HTTP server metrics.
 func (p *Provider) Middleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !p.IsEnabled() {
+			tracing := p.IsEnabled()
+			var metrics *httpServerMetrics
+			if p.MetricsEnabled() {
+				metrics = p.httpMetrics
+			}
+			if !tracing && metrics == nil {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			ctx := r.Context()
+
+			// Wrap response writer to capture status
+			rw := newResponseWriter(w)
+
+			if metrics != nil {
+				done := metrics.start(ctx, r)
+				defer func() { done(ctx, rw) }()
+			}
+
+			if !tracing {
+				next.ServeHTTP(rw, r)
+				return
+			}
+
 			// Extract trace context from incoming request
-			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
 
 			// Start span
 			spanName := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
@@ -72,9 +97,6 @@ func (p *Provider) Middleware() func(http.Handler) http.Handler {
 			)
 			defer span.End()
 
-			// Wrap response writer to capture status
-			rw := newResponseWriter(w)
-
 			// Add trace ID to response headers for debugging
 			if span.SpanContext().HasTraceID() {
 				rw.Header().Set("X-Trace-ID", span.SpanContext().TraceID().String())
@@ -105,6 +127,88 @@ func (p *Provider) Middleware() func(http.Handler) http.Handler {
 	}
 }
 
+// httpServerMetrics are the HTTP server metrics defined by the OpenTelemetry
+// semantic conventions.
+type httpServerMetrics struct {
+	duration       metric.Float64Histogram
+	activeRequests metric.Int64UpDownCounter
+	requestSize    metric.Int64Histogram
+	responseSize   metric.Int64Histogram
+}
+
+func newHTTPServerMetrics(meter metric.Meter) (*httpServerMetrics, error) {
+	m := &httpServerMetrics{}
+	var err error
+
+	if m.duration, err = meter.Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10),
+	); err != nil {
+		return nil, err
+	}
+
+	if m.activeRequests, err = meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("Number of active HTTP server requests."),
+		metric.WithUnit("{request}"),
+	); err != nil {
+		return nil, err
+	}
+
+	if m.requestSize, err = meter.Int64Histogram("http.server.request.body.size",
+		metric.WithDescription("Size of HTTP server request bodies."),
+		metric.WithUnit("By"),
+	); err != nil {
+		return nil, err
+	}
+
+	if m.responseSize, err = meter.Int64Histogram("http.server.response.body.size",
+		metric.WithDescription("Size of HTTP server response bodies."),
+		metric.WithUnit("By"),
+	); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// start records an active request and returns a function recording the
+// completed request. Recording it with the span context links exemplars to
+// the trace.
+func (m *httpServerMetrics) start(ctx context.Context, r *http.Request) func(context.Context, *responseWriter) {
+	base := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLScheme(scheme(r)),
+	}
+	active := metric.WithAttributes(base...)
+
+	m.activeRequests.Add(ctx, 1, active)
+	start := time.Now()
+
+	return func(ctx context.Context, rw *responseWriter) {
+		duration := time.Since(start)
+		m.activeRequests.Add(ctx, -1, active)
+
+		attrs := append(base, semconv.HTTPResponseStatusCode(rw.statusCode))
+		// The route pattern is known once the router has matched the request
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if route := rctx.RoutePattern(); route != "" {
+				attrs = append(attrs, semconv.HTTPRoute(route))
+			}
+		}
+		if rw.statusCode >= 500 {
+			attrs = append(attrs, semconv.ErrorTypeKey.String(strconv.Itoa(rw.statusCode)))
+		}
+		set := metric.WithAttributeSet(attribute.NewSet(attrs...))
+
+		m.duration.Record(ctx, duration.Seconds(), set)
+		if r.ContentLength >= 0 {
+			m.requestSize.Record(ctx, r.ContentLength, set)
+		}
+		m.responseSize.Record(ctx, rw.bytesWritten, set)
+	}
+}
+
 // httpServerAttributes returns standard HTTP server span attributes.
 func httpServerAttributes(r *http.Request) []attribute.KeyValue {
 	attrs := []attribute.KeyValue{
@@ -5,6 +5,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 func TestMiddleware_CreatesSpan(t *testing.T) {
@@ -139,6 +143,73 @@ func TestMiddleware_RecordsStatusCode(t *testing.T) {
 	}
 }
 
+func TestMiddleware_RecordsMetrics(t *testing.T) {
+	provider, exporter := newMetricsProvider(t, true)
+
+	router := chi.NewRouter()
+	router.Use(provider.Middleware())
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("failed"))
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users/42", nil))
+
+	metrics := exporter.collect(t, provider)
+
+	duration, ok := metrics["http.server.request.duration"]
+	if !ok {
+		t.Fatal("http.server.request.duration was not exported")
+	}
+	point := duration.Data.(metricdata.Histogram[float64]).DataPoints[0]
+	if point.Count != 1 {
+		t.Errorf("Count = %d, want 1", point.Count)
+	}
+
+	want := map[attribute.Key]string{
+		"http.request.method":       "GET",
+		"http.route":                "/users/{id}",
+		"http.response.status_code": "500",
+		"url.scheme":                "http",
+		"error.type":                "500",
+	}
+	for key, value := range want {
+		got, ok := point.Attributes.Value(key)
+		if !ok || got.Emit() != value {
+			t.Errorf("%s = %q, want %q", key, got.Emit(), value)
+		}
+	}
+
+	active := metrics["http.server.active_requests"].Data.(metricdata.Sum[int64])
+	if got := active.DataPoints[0].Value; got != 0 {
+		t.Errorf("http.server.active_requests = %d, want 0", got)
+	}
+
+	size := metrics["http.server.response.body.size"].Data.(metricdata.Histogram[int64])
+	if got := size.DataPoints[0].Sum; got != int64(len("failed")) {
+		t.Errorf("http.server.response.body.size = %d, want %d", got, len("failed"))
+	}
+}
+
+func TestMiddleware_MetricsWithoutTracing(t *testing.T) {
+	provider, exporter := newMetricsProvider(t, false)
+
+	handler := provider.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/", nil))
+
+	if rr.Header().Get("X-Trace-ID") != "" {
+		t.Error("X-Trace-ID header should not be set without tracing")
+	}
+	if _, ok := exporter.collect(t, provider)["http.server.request.duration"]; !ok {
+		t.Error("http.server.request.duration was not exported")
+	}
+}
+
 func TestMiddleware_GlobalFunction(t *testing.T) {
 	// Test the global Middleware function (uses global tracer)
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -123,6 +123,13 @@ func WithZipkinExporter(endpoint string) ModuleOption {
 	}
 }
 
+// WithMetrics enables metrics export alongside traces
+func WithMetrics() ModuleOption {
+	return func(m *Module) {
+		m.config.EnableMetrics = true
+	}
+}
+
 // WithAlwaysSample configures the always-sample strategy
 func WithAlwaysSample() ModuleOption {
 	return func(m *Module) {
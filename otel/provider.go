@@ -6,12 +6,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jimmitjoo/tjo/logging"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
@@ -23,6 +27,10 @@ type Provider struct {
 	config         Config
 	tracerProvider *sdktrace.TracerProvider
 	tracer         trace.Tracer
+	meterProvider  *sdkmetric.MeterProvider
+	meter          metric.Meter
+	httpMetrics    *httpServerMetrics
+	registries     *registryProducer
 	propagator     propagation.TextMapPropagator
 	shutdownOnce   sync.Once
 	shutdown       bool
@@ -45,6 +53,12 @@ func New(cfg Config) (*Provider, error) {
 		}
 	}
 
+	if cfg.EnableMetrics {
+		if err := p.initMetrics(); err != nil {
+			return nil, fmt.Errorf("otel: failed to initialize metrics: %w", err)
+		}
+	}
+
 	return p, nil
 }
 
@@ -94,6 +108,77 @@ func (p *Provider) initTracing() error {
 	return nil
 }
 
+// initMetrics initializes the meter provider. The framework's default
+// metric registry is exported along with the OpenTelemetry instruments.
+func (p *Provider) initMetrics() error {
+	ctx := context.Background()
+
+	exporter, err := p.createMetricExporter(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := p.createResource(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.registries = newRegistryProducer()
+	p.registries.add(logging.GetDefaultRegistry())
+
+	opts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+	}
+
+	if exporter != nil {
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
+			sdkmetric.WithInterval(p.config.MetricInterval),
+			sdkmetric.WithProducer(p.registries),
+		)))
+	}
+
+	p.meterProvider = sdkmetric.NewMeterProvider(opts...)
+
+	// Register as global provider
+	otel.SetMeterProvider(p.meterProvider)
+
+	p.meter = p.meterProvider.Meter(
+		p.config.ServiceName,
+		metric.WithInstrumentationVersion(p.config.ServiceVersion),
+	)
+
+	p.httpMetrics, err = newHTTPServerMetrics(p.meter)
+	return err
+}
+
+// createMetricExporter creates the appropriate metric exporter.
+func (p *Provider) createMetricExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	if p.config.MetricExporter != nil {
+		return p.config.MetricExporter, nil
+	}
+
+	switch p.config.Exporter {
+	case ExporterOTLP, ExporterJaeger:
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(p.config.Endpoint),
+		}
+
+		if p.config.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+
+		if len(p.config.Headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(p.config.Headers))
+		}
+
+		return otlpmetricgrpc.New(ctx, opts...)
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("exporter %s does not support metrics", p.config.Exporter)
+	}
+}
+
 // createExporter creates the appropriate span exporter.
 func (p *Provider) createExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
 	switch p.config.Exporter {
@@ -206,6 +291,39 @@ func (p *Provider) TracerProvider() *sdktrace.TracerProvider {
 	return p.tracerProvider
 }
 
+// Meter returns the meter for creating instruments, or nil if metrics are disabled.
+func (p *Provider) Meter() metric.Meter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.meter
+}
+
+// MeterProvider returns the underlying meter provider.
+func (p *Provider) MeterProvider() *sdkmetric.MeterProvider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.meterProvider
+}
+
+// MetricsEnabled returns true if metrics are enabled and the provider is active.
+func (p *Provider) MetricsEnabled() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config.EnableMetrics && !p.shutdown && p.meterProvider != nil
+}
+
+// BridgeRegistry exports the counters, gauges and histograms of a Tjo
+// metric registry with the OpenTelemetry metrics. The default registry is
+// bridged automatically. It does nothing if metrics are disabled.
+func (p *Provider) BridgeRegistry(registry *logging.MetricRegistry) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.registries != nil && registry != nil {
+		p.registries.add(registry)
+	}
+}
+
 // Propagator returns the text map propagator for context injection/extraction.
 func (p *Provider) Propagator() propagation.TextMapPropagator {
 	p.mu.RLock()
@@ -234,23 +352,34 @@ func (p *Provider) Shutdown(ctx context.Context) error {
 		p.shutdown = true
 		p.mu.Unlock()
 
+		// Give pending spans and metrics time to export
+		shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
 		if p.tracerProvider != nil {
-			// Give pending spans time to export
-			shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-			defer cancel()
 			err = p.tracerProvider.Shutdown(shutdownCtx)
 		}
+		if p.meterProvider != nil {
+			if merr := p.meterProvider.Shutdown(shutdownCtx); err == nil {
+				err = merr
+			}
+		}
 	})
 	return err
 }
 
-// ForceFlush immediately exports all pending spans.
+// ForceFlush immediately exports all pending spans and metrics.
 func (p *Provider) ForceFlush(ctx context.Context) error {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	if p.tracerProvider == nil {
-		return nil
+	if p.tracerProvider != nil {
+		if err := p.tracerProvider.ForceFlush(ctx); err != nil {
+			return err
+		}
 	}
-	return p.tracerProvider.ForceFlush(ctx)
+	if p.meterProvider != nil {
+		return p.meterProvider.ForceFlush(ctx)
+	}
+	return nil
 }
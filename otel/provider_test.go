@@ -2,8 +2,13 @@ package otel
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/jimmitjoo/tjo/logging"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 func TestNew_ValidConfig(t *testing.T) {
@@ -209,3 +214,117 @@ func TestProvider_TracingDisabled(t *testing.T) {
 		t.Error("EnableTracing should be set to true when both are disabled")
 	}
 }
+
+// memoryExporter keeps the metrics of the last export.
+type memoryExporter struct {
+	mu      sync.Mutex
+	metrics map[string]metricdata.Metrics
+}
+
+func (e *memoryExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (e *memoryExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (e *memoryExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.metrics = make(map[string]metricdata.Metrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			e.metrics[m.Name] = m
+		}
+	}
+	return nil
+}
+
+func (e *memoryExporter) ForceFlush(context.Context) error { return nil }
+func (e *memoryExporter) Shutdown(context.Context) error   { return nil }
+
+// collect exports the provider's metrics and returns them by name.
+func (e *memoryExporter) collect(t *testing.T, provider *Provider) map[string]metricdata.Metrics {
+	t.Helper()
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.metrics
+}
+
+// newMetricsProvider creates a provider exporting metrics to memory.
+func newMetricsProvider(t *testing.T, tracing bool) (*Provider, *memoryExporter) {
+	t.Helper()
+
+	exporter := &memoryExporter{}
+	provider, err := New(Config{
+		ServiceName:    "test-service",
+		Exporter:       ExporterNone,
+		EnableTracing:  tracing,
+		EnableMetrics:  true,
+		MetricExporter: exporter,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	return provider, exporter
+}
+
+func TestProvider_Metrics(t *testing.T) {
+	provider, exporter := newMetricsProvider(t, false)
+
+	if !provider.MetricsEnabled() {
+		t.Error("Metrics should be enabled")
+	}
+	if provider.IsEnabled() {
+		t.Error("Tracing should be disabled")
+	}
+	if provider.Meter() == nil || provider.MeterProvider() == nil {
+		t.Fatal("Meter should be set when metrics are enabled")
+	}
+
+	counter, err := provider.Meter().Int64Counter("orders.created")
+	if err != nil {
+		t.Fatalf("Int64Counter() error = %v", err)
+	}
+	counter.Add(context.Background(), 3)
+
+	m, ok := exporter.collect(t, provider)["orders.created"]
+	if !ok {
+		t.Fatal("orders.created was not exported")
+	}
+	sum := m.Data.(metricdata.Sum[int64])
+	if got := sum.DataPoints[0].Value; got != 3 {
+		t.Errorf("orders.created = %d, want 3", got)
+	}
+}
+
+func TestProvider_MetricsDisabled(t *testing.T) {
+	provider, err := New(Config{
+		ServiceName:   "test-service",
+		Exporter:      ExporterNone,
+		EnableTracing: true,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer provider.Shutdown(context.Background())
+
+	if provider.MetricsEnabled() {
+		t.Error("Metrics should be disabled")
+	}
+	if provider.Meter() != nil {
+		t.Error("Meter should be nil when metrics are disabled")
+	}
+
+	// Does nothing without metrics
+	provider.BridgeRegistry(logging.NewMetricRegistry())
+}
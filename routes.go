@@ -53,9 +53,10 @@ func (g *Tjo) AddMonitoringRoutes(mux *chi.Mux) {
 
 	// Health endpoints
 	mux.Get("/health", logging.HealthHandler(g.Logging.Health))
-	mux.Get("/health/ready", logging.ReadinessHandler())
+	mux.Get("/health/ready", logging.ReadinessHandlerFor(g.Logging.Health))
 	mux.Get("/health/live", logging.LivenessHandler())
 
-	// Metrics endpoint
+	// Metrics endpoints
 	mux.Get("/metrics", logging.MetricsHandler(g.Logging.Metrics))
+	mux.Get("/metrics/prometheus", logging.PrometheusHandler(g.Logging.Metrics))
 }
@@ -63,7 +63,7 @@ func DefaultRateLimitConfig() RateLimitConfig {
 		BurstSize:         10,
 		WindowSize:        time.Minute,
 		SkipSuccessful:    false,
-		SkipPaths:         []string{"/health", "/metrics", "/health/ready", "/health/live"},
+		SkipPaths:         []string{"/health", "/metrics", "/metrics/prometheus", "/health/ready", "/health/live"},
 	}
 }
 
@@ -110,7 +110,7 @@ func loadRateLimitConfig() RateLimitConfig {
 		BurstSize:         getEnvInt("RATE_LIMIT_BURST", 10),
 		WindowSize:        time.Duration(getEnvInt("RATE_LIMIT_WINDOW", 60)) * time.Second,
 		SkipSuccessful:    getEnvBool("RATE_LIMIT_SKIP_SUCCESSFUL", false),
-		SkipPaths:         getEnvStringSlice("RATE_LIMIT_SKIP_PATHS", []string{"/health", "/metrics", "/health/ready", "/health/live"}),
+		SkipPaths:         getEnvStringSlice("RATE_LIMIT_SKIP_PATHS", []string{"/health", "/metrics", "/metrics/prometheus", "/health/ready", "/health/live"}),
 	}
 }
 
@@ -53,7 +53,7 @@ func DefaultCSRFConfig() CSRFConfig {
 		CookieMaxAge:   3600, // 1 hour
 		RequestHeader:  "X-CSRF-Token",
 		FormField:      "csrf_token",
-		ExemptPaths:    []string{"/health", "/metrics", "/health/ready", "/health/live"},
+		ExemptPaths:    []string{"/health", "/metrics", "/metrics/prometheus", "/health/ready", "/health/live"},
 		ExemptGlobs:    []string{"/api/*", "/webhook/*"}, // Secure wildcard matching
 		ExemptMethods:  []string{"GET", "HEAD", "OPTIONS"},
 	}
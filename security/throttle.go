@@ -8,6 +8,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/jimmitjoo/tjo/api"
 )
 
 // ThrottleConfig holds IP-based throttling configuration
@@ -38,6 +40,12 @@ type ThrottleConfig struct {
 	// Custom headers to check for real IP
 	TrustedProxyHeaders []string
 	TrustedProxies     []string
+
+	// SharedLimit replaces the in-memory per-IP token bucket so the limit is
+	// shared between instances, e.g. with api.NewRedisSlidingWindow. It is
+	// called with the client IP as key. Penalties, blacklists and subnet
+	// limits remain per instance.
+	SharedLimit api.RateLimiter
 }
 
 // DefaultThrottleConfig returns sensible defaults
@@ -118,6 +126,10 @@ func (t *IPThrottler) Allow(r *http.Request) (bool, string) {
 	// Get or create IP statistics
 	stats := t.getIPStats(clientIP)
 	
+	if t.config.SharedLimit != nil {
+		return t.allowShared(clientIP, stats)
+	}
+	
 	stats.mu.Lock()
 	defer stats.mu.Unlock()
 	
@@ -166,6 +178,33 @@ func (t *IPThrottler) Allow(r *http.Request) (bool, string) {
 	return false, "Rate limit exceeded"
 }
 
+// allowShared checks the per-instance penalties and then asks the shared
+// limiter, which may be a network round trip, without holding stats.mu
+func (t *IPThrottler) allowShared(clientIP string, stats *ipStatistics) (bool, string) {
+	stats.mu.Lock()
+	penalized := time.Now().Before(stats.penaltyUntil)
+	blacklisted := stats.blacklisted
+	stats.mu.Unlock()
+	
+	if penalized {
+		return false, "IP under penalty"
+	}
+	if blacklisted {
+		return false, "IP temporarily blacklisted"
+	}
+	if t.config.EnableSubnetLimiting && !t.allowSubnet(clientIP) {
+		return false, "Subnet rate limit exceeded"
+	}
+	if allowed, _ := t.config.SharedLimit.Allow(clientIP); !allowed {
+		return false, "Rate limit exceeded"
+	}
+	
+	stats.mu.Lock()
+	stats.totalRequests++
+	stats.mu.Unlock()
+	return true, "Request allowed"
+}
+
 // RecordFailure records a failed request for an IP
 func (t *IPThrottler) RecordFailure(r *http.Request, statusCode int) {
 	if statusCode < 400 {
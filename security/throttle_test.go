@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jimmitjoo/tjo/api"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,6 +36,51 @@ func TestIPThrottler(t *testing.T) {
 	assert.Equal(t, "Rate limit exceeded", reason)
 }
 
+// sharedLimiter allows the first request and records the keys it is asked
+// about and whether the throttler held the IP's lock at the time
+type sharedLimiter struct {
+	throttler *IPThrottler
+	keys      []string
+	locked    bool
+}
+
+func (l *sharedLimiter) Allow(key string) (bool, *api.RateLimitInfo) {
+	l.keys = append(l.keys, key)
+	stats := l.throttler.getIPStats(key)
+	if stats.mu.TryLock() {
+		stats.mu.Unlock()
+	} else {
+		l.locked = true
+	}
+	return len(l.keys) <= 1, &api.RateLimitInfo{}
+}
+
+func (l *sharedLimiter) Reset(key string) {}
+
+func TestIPThrottlerSharedLimit(t *testing.T) {
+	config := DefaultThrottleConfig()
+	config.BurstSize = 100
+
+	shared := &sharedLimiter{}
+	config.SharedLimit = shared
+
+	throttler := NewIPThrottler(config)
+	shared.throttler = throttler
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+
+	allowed, _ := throttler.Allow(req)
+	assert.True(t, allowed)
+
+	// The shared limit decides even though the local burst is not exhausted
+	allowed, reason := throttler.Allow(req)
+	assert.False(t, allowed)
+	assert.Equal(t, "Rate limit exceeded", reason)
+	assert.Equal(t, []string{"192.168.1.1", "192.168.1.1"}, shared.keys)
+	assert.False(t, shared.locked, "SharedLimit should be called without holding the IP's lock")
+}
+
 func TestThrottlerWithWhitelist(t *testing.T) {
 	config := DefaultThrottleConfig()
 	config.WhitelistedIPs = []string{"192.168.1.100"}
@@ -58,6 +58,11 @@ func NewDataService() *DataService {
 	}
 }
 
+// RedisPool returns the Redis connection pool, or nil when Redis is not configured
+func (d *DataService) RedisPool() *redis.Pool {
+	return d.redisPool
+}
+
 // BackgroundService handles background jobs, scheduling, mail, and SMS
 type BackgroundService struct {
 	Jobs        *jobs.JobManager
@@ -124,8 +129,9 @@ func (b *BackgroundService) ListCronJobs() []string {
 // FileSystemRegistry provides thread-safe access to registered file systems.
 // It uses the filesystems.FS interface for type safety instead of map[string]interface{}.
 type FileSystemRegistry struct {
-	systems map[string]filesystems.FS
-	mu      sync.RWMutex
+	systems    map[string]filesystems.FS
+	onRegister func(name string, fs filesystems.FS)
+	mu         sync.RWMutex
 }
 
 // NewFileSystemRegistry creates a new file system registry
@@ -138,8 +144,29 @@ func NewFileSystemRegistry() *FileSystemRegistry {
 // Register adds a file system to the registry
 func (r *FileSystemRegistry) Register(name string, fs filesystems.FS) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 	r.systems[name] = fs
+	onRegister := r.onRegister
+	r.mu.Unlock()
+
+	if onRegister != nil {
+		onRegister(name, fs)
+	}
+}
+
+// forEach calls fn for every registered file system, and for those
+// registered later
+func (r *FileSystemRegistry) forEach(fn func(name string, fs filesystems.FS)) {
+	r.mu.Lock()
+	r.onRegister = fn
+	systems := make(map[string]filesystems.FS, len(r.systems))
+	for name, fs := range r.systems {
+		systems[name] = fs
+	}
+	r.mu.Unlock()
+
+	for name, fs := range systems {
+		fn(name, fs)
+	}
 }
 
 // Get retrieves a file system by name
@@ -0,0 +1,128 @@
+package sms
+
+import "unicode/utf16"
+
+// Encoding identifies the character set a message is transmitted in
+type Encoding string
+
+const (
+	// EncodingGSM7 is the default 7-bit GSM alphabet (160 characters per single SMS)
+	EncodingGSM7 Encoding = "gsm7"
+	// EncodingUCS2 is used when the message contains characters outside GSM-7 (70 characters per single SMS)
+	EncodingUCS2 Encoding = "ucs2"
+)
+
+// Segment limits as defined by GSM 03.38. Concatenated messages lose
+// part of each segment to the user data header.
+const (
+	gsm7SingleLimit = 160
+	gsm7MultiLimit  = 153
+	ucs2SingleLimit = 70
+	ucs2MultiLimit  = 67
+)
+
+// gsm7Basic is the GSM 03.38 basic character set. Each character costs one septet.
+var gsm7Basic = makeCharset("@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà")
+
+// gsm7Extended holds characters reached through the escape code. Each costs two septets.
+var gsm7Extended = makeCharset("\f^{}\\[~]|€")
+
+func makeCharset(chars string) map[rune]struct{} {
+	set := make(map[rune]struct{}, len(chars))
+	for _, r := range chars {
+		set[r] = struct{}{}
+	}
+	return set
+}
+
+// SegmentInfo describes how a message will be split when sent
+type SegmentInfo struct {
+	Encoding   Encoding
+	Length     int // Septets for GSM-7, UTF-16 code units for UCS-2
+	Segments   int
+	PerSegment int // Capacity of each segment for this message
+	Remaining  int // Units left in the last segment before another is needed
+}
+
+// DetectEncoding returns EncodingGSM7 if every character in the message
+// can be represented in the GSM-7 alphabet, and EncodingUCS2 otherwise.
+func DetectEncoding(message string) Encoding {
+	for _, r := range message {
+		if _, ok := gsm7Basic[r]; ok {
+			continue
+		}
+		if _, ok := gsm7Extended[r]; ok {
+			continue
+		}
+		return EncodingUCS2
+	}
+	return EncodingGSM7
+}
+
+// IsUnicode reports whether the message has to be sent as UCS-2
+func IsUnicode(message string) bool {
+	return DetectEncoding(message) == EncodingUCS2
+}
+
+// CountSegments calculates the encoding and number of SMS segments needed
+// to deliver the message. Escaped GSM-7 characters and UTF-16 surrogate
+// pairs are never split across segments, matching carrier behaviour.
+func CountSegments(message string) SegmentInfo {
+	if DetectEncoding(message) == EncodingGSM7 {
+		return countUnits(EncodingGSM7, gsm7Units(message), gsm7SingleLimit, gsm7MultiLimit)
+	}
+	return countUnits(EncodingUCS2, ucs2Units(message), ucs2SingleLimit, ucs2MultiLimit)
+}
+
+// gsm7Units returns the septet cost of each character
+func gsm7Units(message string) []int {
+	units := make([]int, 0, len(message))
+	for _, r := range message {
+		if _, ok := gsm7Extended[r]; ok {
+			units = append(units, 2)
+		} else {
+			units = append(units, 1)
+		}
+	}
+	return units
+}
+
+// ucs2Units returns the UTF-16 code unit cost of each character
+func ucs2Units(message string) []int {
+	units := make([]int, 0, len(message))
+	for _, r := range message {
+		units = append(units, len(utf16.Encode([]rune{r})))
+	}
+	return units
+}
+
+func countUnits(enc Encoding, units []int, single, multi int) SegmentInfo {
+	total := 0
+	for _, u := range units {
+		total += u
+	}
+
+	info := SegmentInfo{Encoding: enc, Length: total}
+
+	if total <= single {
+		info.Segments = 1
+		info.PerSegment = single
+		info.Remaining = single - total
+		return info
+	}
+
+	segments, used := 1, 0
+	for _, u := range units {
+		if used+u > multi {
+			segments++
+			used = 0
+		}
+		used += u
+	}
+
+	info.Segments = segments
+	info.PerSegment = multi
+	info.Remaining = multi - used
+	return info
+}
@@ -0,0 +1,70 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected Encoding
+	}{
+		{"Plain ASCII", "Hello world!", EncodingGSM7},
+		{"GSM-7 accents", "Hej på dig, åäö ÅÄÖ é", EncodingGSM7},
+		{"GSM-7 extension", "Price: 10€ [net]", EncodingGSM7},
+		{"Emoji", "Hello 😀", EncodingUCS2},
+		{"Cyrillic", "Привет", EncodingUCS2},
+		{"Lowercase c cedilla", "ç", EncodingUCS2},
+		{"Empty", "", EncodingGSM7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectEncoding(tt.message))
+		})
+	}
+}
+
+func TestCountSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		encoding Encoding
+		length   int
+		segments int
+	}{
+		{"Single GSM-7", strings.Repeat("a", 160), EncodingGSM7, 160, 1},
+		{"Two GSM-7", strings.Repeat("a", 161), EncodingGSM7, 161, 2},
+		{"Exactly two GSM-7", strings.Repeat("a", 306), EncodingGSM7, 306, 2},
+		{"Three GSM-7", strings.Repeat("a", 307), EncodingGSM7, 307, 3},
+		{"Extension chars count double", strings.Repeat("€", 80), EncodingGSM7, 160, 1},
+		{"Extension char not split", strings.Repeat("a", 152) + "€" + strings.Repeat("a", 10), EncodingGSM7, 164, 2},
+		{"Single UCS-2", strings.Repeat("ж", 70), EncodingUCS2, 70, 1},
+		{"Two UCS-2", strings.Repeat("ж", 71), EncodingUCS2, 71, 2},
+		{"Surrogate pairs", strings.Repeat("😀", 35), EncodingUCS2, 70, 1},
+		{"Surrogate pair not split", strings.Repeat("ж", 66) + "😀" + "жжж", EncodingUCS2, 71, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := CountSegments(tt.message)
+			assert.Equal(t, tt.encoding, info.Encoding)
+			assert.Equal(t, tt.length, info.Length)
+			assert.Equal(t, tt.segments, info.Segments)
+		})
+	}
+}
+
+func TestCountSegments_Remaining(t *testing.T) {
+	info := CountSegments("Hello")
+	assert.Equal(t, 160, info.PerSegment)
+	assert.Equal(t, 155, info.Remaining)
+
+	info = CountSegments(strings.Repeat("a", 200))
+	assert.Equal(t, 153, info.PerSegment)
+	assert.Equal(t, 106, info.Remaining)
+}
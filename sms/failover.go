@@ -0,0 +1,229 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrAllProvidersFailed is returned by Failover when no provider accepted the message
+var ErrAllProvidersFailed = errors.New("all SMS providers failed")
+
+// Default circuit breaker settings for Failover
+const (
+	DefaultFailureThreshold = 3
+	DefaultCooldown         = 30 * time.Second
+)
+
+// namedProvider is implemented by providers that can identify themselves
+type namedProvider interface {
+	Name() string
+}
+
+// ProviderName returns the name of a provider, used in logs and stats
+func ProviderName(p SMSProvider) string {
+	if n, ok := p.(namedProvider); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// Failover is an SMSProvider that tries its providers in order until one
+// accepts the message. Each provider has a circuit breaker: after
+// FailureThreshold consecutive failures it is skipped for Cooldown, then
+// a single message is let through to probe whether it has recovered.
+//
+// The returned SendResult.Provider records which provider delivered.
+type Failover struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	providers []SMSProvider
+	breakers  []*breaker
+	now       func() time.Time // For testing
+}
+
+// ProviderStats describes the circuit state of one provider in a Failover
+type ProviderStats struct {
+	Name                string
+	Open                bool
+	ConsecutiveFailures int
+	Sent                int64
+	Failed              int64
+	LastError           string
+	OpenedAt            time.Time
+}
+
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	sent      int64
+	failed    int64
+	lastError string
+}
+
+// NewFailover creates a failover provider trying providers in the given order
+func NewFailover(providers ...SMSProvider) *Failover {
+	breakers := make([]*breaker, len(providers))
+	for i := range breakers {
+		breakers[i] = &breaker{}
+	}
+
+	return &Failover{
+		FailureThreshold: DefaultFailureThreshold,
+		Cooldown:         DefaultCooldown,
+		providers:        providers,
+		breakers:         breakers,
+		now:              time.Now,
+	}
+}
+
+// Name returns the provider identifier
+func (f *Failover) Name() string {
+	return "failover"
+}
+
+// Providers returns the wrapped providers in failover order
+func (f *Failover) Providers() []SMSProvider {
+	providers := make([]SMSProvider, len(f.providers))
+	copy(providers, f.providers)
+	return providers
+}
+
+// Send tries each provider whose circuit is closed. If every circuit is
+// open the providers are tried anyway, since a late message is better
+// than none.
+func (f *Failover) Send(to string, message string) (*SendResult, error) {
+	return f.SendContext(context.Background(), to, message)
+}
+
+// SendContext is Send passing ctx on to the providers
+func (f *Failover) SendContext(ctx context.Context, to string, message string) (*SendResult, error) {
+	if len(f.providers) == 0 {
+		return nil, ErrNoProvider
+	}
+
+	var errs []error
+	var skipped []int
+
+	for i, p := range f.providers {
+		if !f.allow(i) {
+			skipped = append(skipped, i)
+			continue
+		}
+
+		result, err := f.try(ctx, i, p, to, message)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, err)
+	}
+
+	if len(skipped) == len(f.providers) {
+		for _, i := range skipped {
+			result, err := f.try(ctx, i, f.providers[i], to, message)
+			if err == nil {
+				return result, nil
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrAllProvidersFailed, errors.Join(errs...))
+}
+
+// Stats returns the circuit state of every provider
+func (f *Failover) Stats() []ProviderStats {
+	stats := make([]ProviderStats, len(f.providers))
+	for i, p := range f.providers {
+		b := f.breakers[i]
+		b.mu.Lock()
+		stats[i] = ProviderStats{
+			Name:                ProviderName(p),
+			Open:                f.isOpen(b),
+			ConsecutiveFailures: b.failures,
+			Sent:                b.sent,
+			Failed:              b.failed,
+			LastError:           b.lastError,
+			OpenedAt:            b.openedAt,
+		}
+		b.mu.Unlock()
+	}
+	return stats
+}
+
+func (f *Failover) try(ctx context.Context, i int, p SMSProvider, to, message string) (*SendResult, error) {
+	result, err := sendContext(ctx, p, to, message)
+	if err != nil {
+		f.recordFailure(i, err)
+		return nil, fmt.Errorf("%s: %w", ProviderName(p), err)
+	}
+
+	f.recordSuccess(i)
+	if result.Provider == "" {
+		result.Provider = ProviderName(p)
+	}
+	return result, nil
+}
+
+// allow reports whether provider i may be used. Once the cooldown has
+// passed only one concurrent probe is allowed through.
+func (f *Failover) allow(i int) bool {
+	b := f.breakers[i]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if f.isOpen(b) || b.probing {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// isOpen must be called with b.mu held
+func (f *Failover) isOpen(b *breaker) bool {
+	if b.openedAt.IsZero() {
+		return false
+	}
+	return f.now().Sub(b.openedAt) < f.Cooldown
+}
+
+func (f *Failover) recordSuccess(i int) {
+	b := f.breakers[i]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openedAt = time.Time{}
+	b.probing = false
+	b.sent++
+}
+
+func (f *Failover) recordFailure(i int, err error) {
+	b := f.breakers[i]
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.failed++
+	b.lastError = err.Error()
+
+	threshold := f.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+
+	// A failed probe reopens the circuit for another cooldown
+	if b.probing || b.failures >= threshold {
+		b.openedAt = f.now()
+	}
+	b.probing = false
+}
@@ -0,0 +1,167 @@
+package sms
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider fails while err is set
+type fakeProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Send(to, message string) (*SendResult, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &SendResult{MessageID: p.name + "-id", To: to, Status: StatusSent}, nil
+}
+
+func TestFailover_UsesFirstHealthyProvider(t *testing.T) {
+	primary := &fakeProvider{name: "primary"}
+	secondary := &fakeProvider{name: "secondary"}
+	f := NewFailover(primary, secondary)
+
+	result, err := f.Send("+46701234567", "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "primary", result.Provider)
+	assert.Equal(t, 0, secondary.calls)
+}
+
+func TestFailover_FallsBackOnError(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: errors.New("outage")}
+	secondary := &fakeProvider{name: "secondary"}
+	f := NewFailover(primary, secondary)
+
+	result, err := f.Send("+46701234567", "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", result.Provider)
+	assert.Equal(t, "secondary-id", result.MessageID)
+}
+
+func TestFailover_AllFail(t *testing.T) {
+	f := NewFailover(
+		&fakeProvider{name: "a", err: errors.New("down a")},
+		&fakeProvider{name: "b", err: errors.New("down b")},
+	)
+
+	_, err := f.Send("+46701234567", "Hello")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrAllProvidersFailed)
+	assert.Contains(t, err.Error(), "down a")
+	assert.Contains(t, err.Error(), "down b")
+}
+
+func TestFailover_CircuitOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	primary := &fakeProvider{name: "primary", err: errors.New("outage")}
+	secondary := &fakeProvider{name: "secondary"}
+
+	f := NewFailover(primary, secondary)
+	f.FailureThreshold = 2
+	f.Cooldown = time.Minute
+	f.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := f.Send("+46701234567", "Hello")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, primary.calls)
+	assert.True(t, f.Stats()[0].Open)
+
+	// Circuit is open: primary is skipped
+	_, err := f.Send("+46701234567", "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, 2, primary.calls)
+
+	// After the cooldown a probe goes through and closes the circuit
+	now = now.Add(2 * time.Minute)
+	primary.err = nil
+
+	result, err := f.Send("+46701234567", "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "primary", result.Provider)
+
+	stats := f.Stats()
+	assert.False(t, stats[0].Open)
+	assert.Equal(t, 0, stats[0].ConsecutiveFailures)
+	assert.Equal(t, int64(1), stats[0].Sent)
+	assert.Equal(t, int64(2), stats[0].Failed)
+}
+
+func TestFailover_FailedProbeReopens(t *testing.T) {
+	now := time.Now()
+	primary := &fakeProvider{name: "primary", err: errors.New("outage")}
+	secondary := &fakeProvider{name: "secondary"}
+
+	f := NewFailover(primary, secondary)
+	f.FailureThreshold = 1
+	f.Cooldown = time.Minute
+	f.now = func() time.Time { return now }
+
+	_, _ = f.Send("+46701234567", "Hello")
+	now = now.Add(2 * time.Minute)
+	_, _ = f.Send("+46701234567", "Hello")
+
+	assert.Equal(t, 2, primary.calls)
+	assert.True(t, f.Stats()[0].Open)
+}
+
+func TestFailover_AllOpenStillTries(t *testing.T) {
+	only := &fakeProvider{name: "only", err: errors.New("outage")}
+	f := NewFailover(only)
+	f.FailureThreshold = 1
+
+	_, _ = f.Send("+46701234567", "Hello")
+	only.err = nil
+
+	result, err := f.Send("+46701234567", "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "only", result.Provider)
+}
+
+func TestFailover_NoProviders(t *testing.T) {
+	_, err := NewFailover().Send("+46701234567", "Hello")
+	assert.ErrorIs(t, err, ErrNoProvider)
+}
+
+// recordingLogger captures log calls
+type recordingLogger struct {
+	messages []string
+	fields   []map[string]interface{}
+}
+
+func (l *recordingLogger) Info(message string, fields ...map[string]interface{}) {
+	l.messages = append(l.messages, message)
+	if len(fields) > 0 {
+		l.fields = append(l.fields, fields[0])
+	}
+}
+
+func TestLogProvider_Send(t *testing.T) {
+	logger := &recordingLogger{}
+	p := &LogProvider{Logger: logger}
+
+	result, err := p.Send("+46701234567", "Your code is 123456")
+	require.NoError(t, err)
+
+	assert.Equal(t, "log", result.Provider)
+	assert.NotEmpty(t, result.MessageID)
+	assert.Equal(t, StatusDelivered, result.Status)
+
+	require.Len(t, logger.fields, 1)
+	assert.Equal(t, "+46701234567", logger.fields[0]["to"])
+	assert.Equal(t, "Your code is 123456", logger.fields[0]["message"])
+	assert.Equal(t, result.MessageID, logger.fields[0]["message_id"])
+}
@@ -0,0 +1,97 @@
+package sms
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+	"sort"
+)
+
+// Logger is the subset of the framework's structured logger used by the
+// SMS module. *logging.Logger satisfies it.
+type Logger interface {
+	Info(message string, fields ...map[string]interface{})
+}
+
+// appLogger is implemented by applications that share their structured
+// logger with modules. *tjo.Tjo implements it.
+type appLogger interface {
+	AppLogger() *slog.Logger
+}
+
+// slogLogger adapts a slog.Logger, writing fields as attributes
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Info(message string, fields ...map[string]interface{}) {
+	var attrs []any
+	for _, f := range fields {
+		keys := make([]string, 0, len(f))
+		for k := range f {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			attrs = append(attrs, slog.Any(k, f[k]))
+		}
+	}
+	l.logger.Info(message, attrs...)
+}
+
+// stdLogger adapts the standard library logger when no structured logger is set
+type stdLogger struct{}
+
+func (stdLogger) Info(message string, fields ...map[string]interface{}) {
+	if len(fields) > 0 {
+		log.Printf("%s %v", message, fields[0])
+		return
+	}
+	log.Print(message)
+}
+
+// LogProvider writes messages to a logger instead of sending them.
+// Use it for local development by setting SMS_PROVIDER=log.
+type LogProvider struct {
+	Logger Logger
+}
+
+// Name returns the provider identifier
+func (l *LogProvider) Name() string {
+	return "log"
+}
+
+// Send logs the message and reports it as delivered
+func (l *LogProvider) Send(to string, message string) (*SendResult, error) {
+	segments := CountSegments(message)
+	id := newLogMessageID()
+
+	logger := l.Logger
+	if logger == nil {
+		logger = stdLogger{}
+	}
+
+	logger.Info("SMS message (not sent)", map[string]interface{}{
+		"message_id": id,
+		"to":         to,
+		"message":    message,
+		"encoding":   string(segments.Encoding),
+		"segments":   segments.Segments,
+	})
+
+	return &SendResult{
+		Provider:  "log",
+		MessageID: id,
+		To:        to,
+		Status:    StatusDelivered,
+		Encoding:  segments.Encoding,
+		Segments:  segments.Segments,
+	}, nil
+}
+
+func newLogMessageID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "log-" + hex.EncodeToString(b)
+}
@@ -2,7 +2,14 @@ package sms
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimmitjoo/tjo/sms/phone"
 )
 
 // Module implements the tjo.Module interface for SMS functionality.
@@ -16,33 +23,58 @@ import (
 //	// Later, send SMS:
 //	if smsModule := app.Modules.Get("sms"); smsModule != nil {
 //	    provider := smsModule.(*sms.Module).Provider
-//	    provider.Send("+1234567890", "Hello!", false)
+//	    result, err := provider.Send("+1234567890", "Hello!")
 //	}
 type Module struct {
-	Provider SMSProvider
-	config   *Config
+	Provider  SMSProvider
+	Statuses  StatusStore
+	Templates *TemplateSet
+	config    *Config
+	onReport  ReportHandler
+	logger    Logger
 }
 
 // Config holds SMS module configuration
 type Config struct {
-	Provider   string
-	Vonage     VonageConfig
-	Twilio     TwilioConfig
+	// Provider is "vonage", "twilio" or "log", or a comma separated
+	// list such as "twilio,vonage" to fail over between providers.
+	Provider  string
+	Templates string // Directory containing <name>.sms.tmpl files
+	// DefaultRegion is the ISO 3166-1 alpha-2 region (e.g. "SE") used to
+	// interpret numbers in national format. Empty requires international numbers.
+	DefaultRegion string
+	Vonage        VonageConfig
+	Twilio        TwilioConfig
+	Failover      FailoverConfig
+	// HTTPClient makes Vonage and Twilio call their REST APIs through it
+	// instead of their SDKs
+	HTTPClient HTTPClient
+}
+
+// FailoverConfig holds circuit breaker settings used when several providers are configured
+type FailoverConfig struct {
+	FailureThreshold int           // Consecutive failures before a provider is skipped
+	Cooldown         time.Duration // How long a failing provider is skipped
 }
 
 // VonageConfig holds Vonage-specific configuration
 type VonageConfig struct {
-	APIKey     string
-	APISecret  string
-	FromNumber string
+	APIKey          string
+	APISecret       string
+	FromNumber      string
+	CallbackURL     string // Delivery receipt URL, overrides the dashboard setting
+	SignatureSecret string // Used to verify signed delivery receipts
+	SignatureMethod string // md5hash, md5, sha1, sha256 or sha512
 }
 
 // TwilioConfig holds Twilio-specific configuration
 type TwilioConfig struct {
-	AccountSid string
-	APIKey     string
-	APISecret  string
-	FromNumber string
+	AccountSid     string
+	APIKey         string
+	APISecret      string
+	FromNumber     string
+	AuthToken      string // Used to verify X-Twilio-Signature on status callbacks
+	StatusCallback string // Public URL of the status callback webhook
 }
 
 // Option is a function that configures the SMS module
@@ -54,21 +86,39 @@ type Option func(*Module)
 func NewModule(opts ...Option) *Module {
 	m := &Module{
 		config: &Config{
-			Provider: os.Getenv("SMS_PROVIDER"),
+			Provider:      os.Getenv("SMS_PROVIDER"),
+			Templates:     os.Getenv("SMS_TEMPLATES"),
+			DefaultRegion: os.Getenv("SMS_DEFAULT_REGION"),
 			Vonage: VonageConfig{
-				APIKey:     os.Getenv("VONAGE_API_KEY"),
-				APISecret:  os.Getenv("VONAGE_API_SECRET"),
-				FromNumber: os.Getenv("VONAGE_FROM_NUMBER"),
+				APIKey:          os.Getenv("VONAGE_API_KEY"),
+				APISecret:       os.Getenv("VONAGE_API_SECRET"),
+				FromNumber:      os.Getenv("VONAGE_FROM_NUMBER"),
+				CallbackURL:     os.Getenv("VONAGE_CALLBACK_URL"),
+				SignatureSecret: os.Getenv("VONAGE_SIGNATURE_SECRET"),
+				SignatureMethod: os.Getenv("VONAGE_SIGNATURE_METHOD"),
 			},
 			Twilio: TwilioConfig{
-				AccountSid: os.Getenv("TWILIO_ACCOUNT_SID"),
-				APIKey:     os.Getenv("TWILIO_API_KEY"),
-				APISecret:  os.Getenv("TWILIO_API_SECRET"),
-				FromNumber: os.Getenv("TWILIO_FROM_NUMBER"),
+				AccountSid:     os.Getenv("TWILIO_ACCOUNT_SID"),
+				APIKey:         os.Getenv("TWILIO_API_KEY"),
+				APISecret:      os.Getenv("TWILIO_API_SECRET"),
+				FromNumber:     os.Getenv("TWILIO_FROM_NUMBER"),
+				AuthToken:      os.Getenv("TWILIO_AUTH_TOKEN"),
+				StatusCallback: os.Getenv("TWILIO_STATUS_CALLBACK"),
+			},
+			Failover: FailoverConfig{
+				FailureThreshold: DefaultFailureThreshold,
+				Cooldown:         DefaultCooldown,
 			},
 		},
 	}
 
+	if v, err := strconv.Atoi(os.Getenv("SMS_FAILOVER_THRESHOLD")); err == nil && v > 0 {
+		m.config.Failover.FailureThreshold = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("SMS_FAILOVER_COOLDOWN")); err == nil && v > 0 {
+		m.config.Failover.Cooldown = v
+	}
+
 	for _, opt := range opts {
 		opt(m)
 	}
@@ -83,7 +133,8 @@ func WithConfig(cfg *Config) Option {
 	}
 }
 
-// WithProvider sets a specific SMS provider
+// WithProvider sets a specific SMS provider ("vonage", "twilio" or "log").
+// Pass a comma separated list, e.g. "twilio,vonage", to fail over between providers.
 func WithProvider(provider string) Option {
 	return func(m *Module) {
 		m.config.Provider = provider
@@ -94,11 +145,9 @@ func WithProvider(provider string) Option {
 func WithVonage(apiKey, apiSecret, fromNumber string) Option {
 	return func(m *Module) {
 		m.config.Provider = "vonage"
-		m.config.Vonage = VonageConfig{
-			APIKey:     apiKey,
-			APISecret:  apiSecret,
-			FromNumber: fromNumber,
-		}
+		m.config.Vonage.APIKey = apiKey
+		m.config.Vonage.APISecret = apiSecret
+		m.config.Vonage.FromNumber = fromNumber
 	}
 }
 
@@ -106,43 +155,146 @@ func WithVonage(apiKey, apiSecret, fromNumber string) Option {
 func WithTwilio(accountSid, apiKey, apiSecret, fromNumber string) Option {
 	return func(m *Module) {
 		m.config.Provider = "twilio"
-		m.config.Twilio = TwilioConfig{
-			AccountSid: accountSid,
-			APIKey:     apiKey,
-			APISecret:  apiSecret,
-			FromNumber: fromNumber,
+		m.config.Twilio.AccountSid = accountSid
+		m.config.Twilio.APIKey = apiKey
+		m.config.Twilio.APISecret = apiSecret
+		m.config.Twilio.FromNumber = fromNumber
+	}
+}
+
+// WithDefaultRegion sets the region used to interpret numbers in national format
+func WithDefaultRegion(region string) Option {
+	return func(m *Module) {
+		m.config.DefaultRegion = region
+	}
+}
+
+// WithFailover sets the circuit breaker settings used when failing over between providers
+func WithFailover(failureThreshold int, cooldown time.Duration) Option {
+	return func(m *Module) {
+		m.config.Failover = FailoverConfig{
+			FailureThreshold: failureThreshold,
+			Cooldown:         cooldown,
 		}
 	}
 }
 
+// WithHTTPClient sends Vonage and Twilio API requests through client.
+// Pass otel.NewHTTPClient(nil) to trace them as child spans of SendContext.
+func WithHTTPClient(client HTTPClient) Option {
+	return func(m *Module) {
+		m.config.HTTPClient = client
+	}
+}
+
+// WithLogger sets the logger used by the log provider.
+// Any *logging.Logger works, e.g. logging.NewDefault().
+func WithLogger(logger Logger) Option {
+	return func(m *Module) {
+		m.logger = logger
+	}
+}
+
+// WithStatusStore sets where delivery statuses are stored.
+// Defaults to an in-memory store.
+func WithStatusStore(store StatusStore) Option {
+	return func(m *Module) {
+		m.Statuses = store
+	}
+}
+
+// WithTemplates sets the directory SMS templates are loaded from
+func WithTemplates(dir string) Option {
+	return func(m *Module) {
+		m.config.Templates = dir
+	}
+}
+
+// WithReportHandler registers a callback for verified delivery reports
+func WithReportHandler(fn ReportHandler) Option {
+	return func(m *Module) {
+		m.onReport = fn
+	}
+}
+
 // Name returns the module identifier
 func (m *Module) Name() string {
 	return "sms"
 }
 
 // Initialize sets up the SMS provider based on configuration.
-// This is called automatically during app.New().
+// This is called automatically during app.New(). The log provider uses
+// the application's structured logger unless one was set with WithLogger.
 func (m *Module) Initialize(g interface{}) error {
-	switch m.config.Provider {
+	if app, ok := g.(appLogger); ok && m.logger == nil {
+		if logger := app.AppLogger(); logger != nil {
+			m.logger = slogLogger{logger: logger}
+		}
+	}
+
+	// No provider configured is OK, SMS is optional
+	m.Provider = m.config.buildProvider(m.logger)
+
+	if m.Statuses == nil {
+		m.Statuses = NewMemoryStatusStore()
+	}
+
+	m.Templates = NewTemplateSet(m.config.Templates)
+
+	return nil
+}
+
+// buildProvider creates the provider(s) named in c.Provider.
+// Unknown names are ignored; nil is returned if none remain.
+func (c *Config) buildProvider(logger Logger) SMSProvider {
+	var providers []SMSProvider
+	for _, name := range strings.Split(c.Provider, ",") {
+		if p := c.newProvider(strings.TrimSpace(name), logger); p != nil {
+			providers = append(providers, p)
+		}
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil
+	case 1:
+		return providers[0]
+	}
+
+	failover := NewFailover(providers...)
+	if c.Failover.FailureThreshold > 0 {
+		failover.FailureThreshold = c.Failover.FailureThreshold
+	}
+	if c.Failover.Cooldown > 0 {
+		failover.Cooldown = c.Failover.Cooldown
+	}
+	return failover
+}
+
+func (c *Config) newProvider(name string, logger Logger) SMSProvider {
+	switch name {
 	case "vonage":
-		m.Provider = &Vonage{
-			APIKey:     m.config.Vonage.APIKey,
-			APISecret:  m.config.Vonage.APISecret,
-			FromNumber: m.config.Vonage.FromNumber,
+		return &Vonage{
+			APIKey:      c.Vonage.APIKey,
+			APISecret:   c.Vonage.APISecret,
+			FromNumber:  c.Vonage.FromNumber,
+			CallbackURL: c.Vonage.CallbackURL,
+			httpClient:  c.HTTPClient,
 		}
 	case "twilio":
-		m.Provider = &Twilio{
-			AccountSid: m.config.Twilio.AccountSid,
-			APIKey:     m.config.Twilio.APIKey,
-			APISecret:  m.config.Twilio.APISecret,
-			FromNumber: m.config.Twilio.FromNumber,
+		return &Twilio{
+			AccountSid:     c.Twilio.AccountSid,
+			APIKey:         c.Twilio.APIKey,
+			APISecret:      c.Twilio.APISecret,
+			FromNumber:     c.Twilio.FromNumber,
+			StatusCallback: c.Twilio.StatusCallback,
+			httpClient:     c.HTTPClient,
 		}
+	case "log":
+		return &LogProvider{Logger: logger}
 	default:
-		// No provider configured - that's OK, SMS is optional
-		m.Provider = nil
+		return nil
 	}
-
-	return nil
 }
 
 // Shutdown gracefully stops the SMS module.
@@ -152,12 +304,93 @@ func (m *Module) Shutdown(ctx context.Context) error {
 }
 
 // Send is a convenience method that delegates to the configured provider.
+// The recipient is validated and normalized to E.164 first, so numbers in
+// national format are accepted when a default region is configured.
+// The initial status of the message is recorded so it can be looked up
+// with Status until delivery receipts arrive.
 // Returns an error if no provider is configured.
-func (m *Module) Send(to, message string, unicode bool) error {
+func (m *Module) Send(to, message string) (*SendResult, error) {
+	return m.SendContext(context.Background(), to, message)
+}
+
+// SendContext is Send as part of the request in ctx, so with WithHTTPClient
+// the provider API call joins its trace.
+func (m *Module) SendContext(ctx context.Context, to, message string) (*SendResult, error) {
 	if m.Provider == nil {
-		return ErrNoProvider
+		return nil, ErrNoProvider
+	}
+
+	normalized, err := phone.Normalize(to, m.config.DefaultRegion)
+	if err != nil {
+		return nil, fmt.Errorf("sms recipient %q: %w", to, err)
+	}
+
+	result, err := sendContext(ctx, m.Provider, normalized, message)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Statuses != nil && result.MessageID != "" {
+		_ = m.Statuses.Save(&DeliveryReport{
+			Provider:  result.Provider,
+			MessageID: result.MessageID,
+			To:        result.To,
+			Status:    result.Status,
+			Price:     result.Price,
+			Currency:  result.Currency,
+		})
+	}
+
+	return result, nil
+}
+
+// SendTemplate renders the named template with data and sends the result
+func (m *Module) SendTemplate(to, name string, data interface{}) (*SendResult, error) {
+	return m.SendTemplateContext(context.Background(), to, name, data)
+}
+
+// SendTemplateContext is SendTemplate as part of the request in ctx
+func (m *Module) SendTemplateContext(ctx context.Context, to, name string, data interface{}) (*SendResult, error) {
+	if m.Templates == nil {
+		m.Templates = NewTemplateSet(m.config.Templates)
+	}
+
+	message, err := m.Templates.Render(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.SendContext(ctx, to, message)
+}
+
+// Status returns the latest known delivery status for a message ID
+func (m *Module) Status(messageID string) (*DeliveryReport, error) {
+	if m.Statuses == nil {
+		return nil, ErrStatusNotFound
+	}
+	return m.Statuses.Get(messageID)
+}
+
+// VonageWebhook returns a handler for Vonage delivery receipts.
+// Mount it at the URL configured as the delivery receipt webhook.
+func (m *Module) VonageWebhook() http.Handler {
+	return &VonageWebhook{
+		SignatureSecret: m.config.Vonage.SignatureSecret,
+		SignatureMethod: m.config.Vonage.SignatureMethod,
+		Store:           m.Statuses,
+		OnReport:        m.onReport,
+	}
+}
+
+// TwilioWebhook returns a handler for Twilio status callbacks.
+// Mount it at the URL configured as TWILIO_STATUS_CALLBACK.
+func (m *Module) TwilioWebhook() http.Handler {
+	return &TwilioWebhook{
+		AuthToken: m.config.Twilio.AuthToken,
+		URL:       m.config.Twilio.StatusCallback,
+		Store:     m.Statuses,
+		OnReport:  m.onReport,
 	}
-	return m.Provider.Send(to, message, unicode)
 }
 
 // IsConfigured returns true if an SMS provider is configured
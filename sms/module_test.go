@@ -1,8 +1,19 @@
 package sms
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jimmitjoo/tjo/sms/phone"
 )
 
 func TestModule_Name(t *testing.T) {
@@ -89,7 +100,7 @@ func TestModule_Send_NoProvider(t *testing.T) {
 	m := NewModule()
 	_ = m.Initialize(nil)
 
-	err := m.Send("+1234567890", "test", false)
+	_, err := m.Send("+1234567890", "test")
 	if err != ErrNoProvider {
 		t.Errorf("Send() = %v, want ErrNoProvider", err)
 	}
@@ -128,3 +139,252 @@ func TestWithProvider(t *testing.T) {
 		t.Errorf("Provider = %q, want %q", m.config.Provider, "twilio")
 	}
 }
+
+// stubProvider records sends and returns a fixed result
+type stubProvider struct {
+	sent []string
+}
+
+func (p *stubProvider) Send(to, message string) (*SendResult, error) {
+	p.sent = append(p.sent, message)
+	return &SendResult{
+		Provider:  "stub",
+		MessageID: "msg-1",
+		To:        to,
+		Status:    StatusQueued,
+		Encoding:  DetectEncoding(message),
+		Segments:  CountSegments(message).Segments,
+	}, nil
+}
+
+func TestModule_Send_RecordsStatus(t *testing.T) {
+	m := NewModule()
+	_ = m.Initialize(nil)
+	m.Provider = &stubProvider{}
+
+	result, err := m.Send("+46701234567", "Hello")
+	if err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	if result.MessageID != "msg-1" {
+		t.Errorf("MessageID = %q, want %q", result.MessageID, "msg-1")
+	}
+
+	report, err := m.Status("msg-1")
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	if report.Status != StatusQueued {
+		t.Errorf("Status = %q, want %q", report.Status, StatusQueued)
+	}
+}
+
+func TestModule_SendTemplate(t *testing.T) {
+	provider := &stubProvider{}
+	m := NewModule()
+	_ = m.Initialize(nil)
+	m.Provider = provider
+
+	if err := m.Templates.Add("otp", "  Your code is {{.Code}}\n"); err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+
+	if _, err := m.SendTemplate("+46701234567", "otp", map[string]string{"Code": "123456"}); err != nil {
+		t.Fatalf("SendTemplate() = %v", err)
+	}
+
+	if len(provider.sent) != 1 || provider.sent[0] != "Your code is 123456" {
+		t.Errorf("sent = %v, want [Your code is 123456]", provider.sent)
+	}
+
+	if _, err := m.SendTemplate("+46701234567", "missing", nil); err == nil {
+		t.Error("SendTemplate() with unknown template should fail")
+	}
+}
+
+func TestTemplateSet_LoadsFromDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "welcome.sms.tmpl"), []byte("Welcome {{.}}!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set := NewTemplateSet(dir)
+	got, err := set.Render("welcome", "Ada")
+	if err != nil {
+		t.Fatalf("Render() = %v", err)
+	}
+	if got != "Welcome Ada!" {
+		t.Errorf("Render() = %q, want %q", got, "Welcome Ada!")
+	}
+}
+
+func TestTemplateSet_RejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	templates := filepath.Join(dir, "templates")
+	if err := os.Mkdir(templates, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.sms.tmpl"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set := NewTemplateSet(templates)
+	for _, name := range []string{"../secret", "..", "sub/welcome", `sub\welcome`, "/etc/passwd", ""} {
+		if _, err := set.Render(name, nil); err == nil {
+			t.Errorf("Render(%q) should fail", name)
+		}
+	}
+}
+
+func TestModule_Initialize_Log(t *testing.T) {
+	m := NewModule(WithProvider("log"))
+	_ = m.Initialize(nil)
+
+	if _, ok := m.Provider.(*LogProvider); !ok {
+		t.Fatalf("Provider = %T, want *LogProvider", m.Provider)
+	}
+}
+
+// loggingApp shares its logger with modules like *tjo.Tjo
+type loggingApp struct {
+	logger *slog.Logger
+}
+
+func (a *loggingApp) AppLogger() *slog.Logger { return a.logger }
+
+func TestModule_Initialize_AppLogger(t *testing.T) {
+	var buf bytes.Buffer
+	app := &loggingApp{logger: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	m := NewModule(WithProvider("log"))
+	if err := m.Initialize(app); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Provider.Send("+46701234567", "Hello"); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "SMS message (not sent)") || !strings.Contains(out, "to=+46701234567") {
+		t.Errorf("application log = %q, want the message", out)
+	}
+
+	explicit := &recordingLogger{}
+	m = NewModule(WithProvider("log"), WithLogger(explicit))
+	if err := m.Initialize(app); err != nil {
+		t.Fatal(err)
+	}
+	if p := m.Provider.(*LogProvider); p.Logger != explicit {
+		t.Errorf("Logger = %v, want the WithLogger logger", p.Logger)
+	}
+
+	for _, g := range []interface{}{nil, &loggingApp{}, "app"} {
+		m = NewModule(WithProvider("log"))
+		if err := m.Initialize(g); err != nil {
+			t.Fatal(err)
+		}
+		if p := m.Provider.(*LogProvider); p.Logger != nil {
+			t.Errorf("Initialize(%#v): Logger = %v, want nil", g, p.Logger)
+		}
+	}
+}
+
+func TestModule_Initialize_Failover(t *testing.T) {
+	m := NewModule(
+		WithTwilio("acc", "key", "secret", "+1111111111"),
+		WithVonage("key", "secret", "+2222222222"),
+		WithProvider("twilio, vonage"),
+		WithFailover(5, time.Minute),
+	)
+	_ = m.Initialize(nil)
+
+	f, ok := m.Provider.(*Failover)
+	if !ok {
+		t.Fatalf("Provider = %T, want *Failover", m.Provider)
+	}
+	if f.FailureThreshold != 5 || f.Cooldown != time.Minute {
+		t.Errorf("breaker = %d/%v, want 5/1m", f.FailureThreshold, f.Cooldown)
+	}
+
+	providers := f.Providers()
+	if len(providers) != 2 {
+		t.Fatalf("len(Providers()) = %d, want 2", len(providers))
+	}
+	if ProviderName(providers[0]) != "twilio" || ProviderName(providers[1]) != "vonage" {
+		t.Errorf("order = %s,%s, want twilio,vonage", ProviderName(providers[0]), ProviderName(providers[1]))
+	}
+}
+
+func TestModule_Send_NormalizesNumber(t *testing.T) {
+	provider := &recipientProvider{}
+	m := NewModule(WithDefaultRegion("SE"))
+	_ = m.Initialize(nil)
+	m.Provider = provider
+
+	if _, err := m.Send("070-123 45 67", "Hello"); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	if provider.to != "+46701234567" {
+		t.Errorf("to = %q, want %q", provider.to, "+46701234567")
+	}
+}
+
+func TestModule_Send_InvalidNumber(t *testing.T) {
+	provider := &recipientProvider{}
+	m := NewModule(WithDefaultRegion(""))
+	_ = m.Initialize(nil)
+	m.Provider = provider
+
+	_, err := m.Send("070-123 45 67", "Hello")
+	if !errors.Is(err, phone.ErrInvalidNumber) {
+		t.Errorf("Send() = %v, want phone.ErrInvalidNumber", err)
+	}
+	if provider.to != "" {
+		t.Error("provider should not be called for an invalid number")
+	}
+}
+
+// recipientProvider records the recipient it was asked to send to
+type recipientProvider struct {
+	to string
+}
+
+func (p *recipientProvider) Send(to, message string) (*SendResult, error) {
+	p.to = to
+	return &SendResult{Provider: "recipient", To: to}, nil
+}
+
+type contextKey struct{}
+
+func TestModule_SendContext_UsesHTTPClient(t *testing.T) {
+	var got interface{}
+	client := newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+		got = req.Context().Value(contextKey{})
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       io.NopCloser(strings.NewReader(`{"sid":"SM1","status":"queued"}`)),
+		}, nil
+	})
+
+	for _, provider := range []string{"twilio", "twilio,vonage"} {
+		t.Run(provider, func(t *testing.T) {
+			got = nil
+			m := NewModule(
+				WithTwilio("AC1", "key", "secret", "+15005550006"),
+				WithProvider(provider),
+				WithHTTPClient(client),
+			)
+			_ = m.Initialize(nil)
+
+			ctx := context.WithValue(context.Background(), contextKey{}, "request")
+			result, err := m.SendContext(ctx, "+46701234567", "Hello")
+			if err != nil {
+				t.Fatalf("SendContext() = %v", err)
+			}
+			if result.MessageID != "SM1" {
+				t.Errorf("MessageID = %q, want %q", result.MessageID, "SM1")
+			}
+			if got != "request" {
+				t.Error("The API request should carry the SendContext context")
+			}
+		})
+	}
+}
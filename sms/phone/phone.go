@@ -0,0 +1,96 @@
+// Package phone parses, validates and normalizes phone numbers to E.164.
+//
+// Numbers in national format are interpreted relative to a default region
+// (an ISO 3166-1 alpha-2 code such as "SE" or "US"). Numbers that start
+// with "+" or an international dialling prefix need no region.
+//
+//	e164, err := phone.Normalize("070-123 45 67", "SE")
+//	// e164 == "+46701234567"
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// ErrInvalidNumber is returned when a phone number cannot be parsed or is not a valid number
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// maxInputLength guards the parser against very long input
+const maxInputLength = 64
+
+// e164Pattern matches the E.164 format: "+", country code and up to 15 digits in total
+var e164Pattern = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+// Number is a parsed and validated phone number
+type Number struct {
+	E164        string // +46701234567
+	CountryCode int    // 46
+	Region      string // SE
+	National    string // National significant number, e.g. 701234567
+}
+
+// String returns the number in E.164 format
+func (n *Number) String() string {
+	return n.E164
+}
+
+// International formats the number for display, e.g. "+46 70 123 45 67"
+func (n *Number) International() string {
+	num, err := phonenumbers.Parse(n.E164, "")
+	if err != nil {
+		return n.E164
+	}
+	return phonenumbers.Format(num, phonenumbers.INTERNATIONAL)
+}
+
+// Parse parses and validates a phone number. defaultRegion is used for
+// numbers written in national format and may be empty if only
+// international numbers are accepted.
+func Parse(number, defaultRegion string) (*Number, error) {
+	number = strings.TrimSpace(number)
+	if number == "" || len(number) > maxInputLength {
+		return nil, ErrInvalidNumber
+	}
+
+	num, err := phonenumbers.Parse(number, strings.ToUpper(defaultRegion))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNumber, err)
+	}
+
+	if !phonenumbers.IsValidNumber(num) {
+		return nil, ErrInvalidNumber
+	}
+
+	return &Number{
+		E164:        phonenumbers.Format(num, phonenumbers.E164),
+		CountryCode: int(num.GetCountryCode()),
+		Region:      phonenumbers.GetRegionCodeForNumber(num),
+		National:    phonenumbers.GetNationalSignificantNumber(num),
+	}, nil
+}
+
+// Normalize returns the number in E.164 format
+func Normalize(number, defaultRegion string) (string, error) {
+	n, err := Parse(number, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	return n.E164, nil
+}
+
+// IsValid reports whether the number is a valid phone number
+func IsValid(number, defaultRegion string) bool {
+	_, err := Parse(number, defaultRegion)
+	return err == nil
+}
+
+// IsE164 reports whether s is syntactically in E.164 format.
+// It does not check that the number is assigned; use IsValid for that.
+func IsE164(s string) bool {
+	return e164Pattern.MatchString(s)
+}
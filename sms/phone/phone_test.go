@@ -0,0 +1,84 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		region  string
+		want    string
+		wantErr bool
+	}{
+		{"Swedish national", "070-123 45 67", "SE", "+46701234567", false},
+		{"Swedish international", "+46 70 123 45 67", "", "+46701234567", false},
+		{"International with other default", "+46701234567", "US", "+46701234567", false},
+		{"IDD prefix", "0046701234567", "SE", "+46701234567", false},
+		{"US national", "(201) 555-0123", "US", "+12015550123", false},
+		{"UK national", "07911 123456", "gb", "+447911123456", false},
+		{"National without region", "0701234567", "", "", true},
+		{"Too short", "+4670", "", "", true},
+		{"Letters", "not a number", "SE", "", true},
+		{"Empty", "", "SE", "", true},
+		{"Too long", strings.Repeat("1", 100), "SE", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.number, tt.region)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidNumber) {
+					t.Errorf("Normalize(%q) error = %v, want ErrInvalidNumber", tt.number, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%q) error = %v", tt.number, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	n, err := Parse("070-123 45 67", "SE")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if n.CountryCode != 46 {
+		t.Errorf("CountryCode = %d, want 46", n.CountryCode)
+	}
+	if n.Region != "SE" {
+		t.Errorf("Region = %q, want SE", n.Region)
+	}
+	if n.National != "701234567" {
+		t.Errorf("National = %q, want 701234567", n.National)
+	}
+	if n.International() != "+46 70 123 45 67" {
+		t.Errorf("International() = %q, want %q", n.International(), "+46 70 123 45 67")
+	}
+}
+
+func TestIsE164(t *testing.T) {
+	tests := map[string]bool{
+		"+46701234567":      true,
+		"+12015550123":      true,
+		"46701234567":       false,
+		"+046701234567":     false,
+		"+46 70 123 45 67":  false,
+		"+1234567890123456": false,
+	}
+
+	for input, want := range tests {
+		if got := IsE164(input); got != want {
+			t.Errorf("IsE164(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
@@ -2,13 +2,14 @@ package sms
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,120 +21,228 @@ import (
 // ErrNoProvider is returned when attempting to send SMS without a configured provider
 var ErrNoProvider = errors.New("no SMS provider configured")
 
-// SMSProvider defines the interface for SMS providers
+// SMSProvider defines the interface for SMS providers.
+// The encoding is detected from the message content, so callers never
+// have to decide between GSM-7 and unicode themselves.
 type SMSProvider interface {
-	Send(to string, message string, unicode bool) error
+	Send(to string, message string) (*SendResult, error)
 }
 
-// HTTPClient interface for dependency injection in tests
+// ContextProvider is implemented by providers that send as part of the
+// request in a context, so the API call joins its trace and deadline
+type ContextProvider interface {
+	SendContext(ctx context.Context, to string, message string) (*SendResult, error)
+}
+
+// sendContext sends with ctx when the provider supports it
+func sendContext(ctx context.Context, p SMSProvider, to, message string) (*SendResult, error) {
+	if cp, ok := p.(ContextProvider); ok {
+		return cp.SendContext(ctx, to, message)
+	}
+	return p.Send(to, message)
+}
+
+// HTTPClient sends provider API requests. Set with WithHTTPClient, e.g. to
+// trace them with otel.NewHTTPClient, or inject a mock in tests.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
 // Vonage provider implementation
 type Vonage struct {
-	APIKey     string
-	APISecret  string
-	FromNumber string
-	httpClient HTTPClient // For testing
+	APIKey      string
+	APISecret   string
+	FromNumber  string
+	CallbackURL string     // Delivery receipt webhook, overrides the account default
+	httpClient  HTTPClient // Replaces the Vonage SDK when set
+}
+
+// Name returns the provider identifier
+func (v *Vonage) Name() string {
+	return "vonage"
 }
 
 // Send sends an SMS via Vonage
-func (v *Vonage) Send(to string, msg string, unicode bool) error {
-	// Use injected client for testing if available
+func (v *Vonage) Send(to string, msg string) (*SendResult, error) {
+	return v.SendContext(context.Background(), to, msg)
+}
+
+// SendContext sends an SMS via Vonage. The context is only used with an
+// HTTP client, since the Vonage SDK does not accept one.
+func (v *Vonage) SendContext(ctx context.Context, to string, msg string) (*SendResult, error) {
+	segments := CountSegments(msg)
+
 	if v.httpClient != nil {
-		return v.sendWithHTTPClient(to, msg, unicode)
+		return v.sendWithHTTPClient(ctx, to, msg, segments)
 	}
-	
+
 	// Production implementation using Vonage SDK
 	auth := vonage.CreateAuthFromKeySecret(v.APIKey, v.APISecret)
 	client := vonage.NewSMSClient(auth)
 
 	smsOpts := vonage.SMSOpts{}
-	if unicode {
+	if segments.Encoding == EncodingUCS2 {
 		smsOpts.Type = "unicode"
 	}
+	if v.CallbackURL != "" {
+		smsOpts.Callback = v.CallbackURL
+		smsOpts.StatusReportReq = true
+	}
 
 	response, _, err := client.Send(v.FromNumber, to, msg, smsOpts)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if len(response.Messages) == 0 {
+		return nil, errors.New("SMS send failed: empty response")
+	}
+
+	first := response.Messages[0]
+	if first.Status != "0" {
+		return nil, fmt.Errorf("SMS send failed with status: %s", first.Status)
 	}
-	
-	if len(response.Messages) > 0 && response.Messages[0].Status != "0" {
-		return fmt.Errorf("SMS send failed with status: %s", response.Messages[0].Status)
+
+	// Long messages are billed per part
+	prices := make([]string, 0, len(response.Messages))
+	for _, m := range response.Messages {
+		prices = append(prices, m.MessagePrice)
 	}
 
-	return nil
+	return &SendResult{
+		Provider:  "vonage",
+		MessageID: first.MessageId,
+		To:        to,
+		Status:    StatusSent,
+		Encoding:  segments.Encoding,
+		Segments:  segments.Segments,
+		Price:     addPrices(prices),
+	}, nil
 }
 
-// sendWithHTTPClient is used for testing with mocked HTTP client
-func (v *Vonage) sendWithHTTPClient(to string, msg string, unicode bool) error {
+// sendWithHTTPClient calls the Vonage REST API through the HTTP client
+func (v *Vonage) sendWithHTTPClient(ctx context.Context, to string, msg string, segments SegmentInfo) (*SendResult, error) {
 	data := url.Values{}
 	data.Set("api_key", v.APIKey)
 	data.Set("api_secret", v.APISecret)
 	data.Set("from", v.FromNumber)
 	data.Set("to", to)
 	data.Set("text", msg)
-	
-	if unicode {
+
+	if segments.Encoding == EncodingUCS2 {
 		data.Set("type", "unicode")
 	}
-	
-	req, err := http.NewRequest("POST", "https://rest.nexmo.com/sms/json", strings.NewReader(data.Encode()))
+	if v.CallbackURL != "" {
+		data.Set("callback", v.CallbackURL)
+		data.Set("status-report-req", "true")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://rest.nexmo.com/sms/json", strings.NewReader(data.Encode()))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	
+
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	
+
 	resp, err := v.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	
+
 	var response struct {
 		Messages []struct {
-			Status    string `json:"status"`
-			ErrorText string `json:"error-text"`
+			MessageID    string `json:"message-id"`
+			Status       string `json:"status"`
+			ErrorText    string `json:"error-text"`
+			MessagePrice string `json:"message-price"`
 		} `json:"messages"`
 	}
-	
+
 	if err := json.Unmarshal(body, &response); err != nil {
-		return err
+		return nil, err
+	}
+
+	if len(response.Messages) == 0 {
+		return nil, errors.New("SMS send failed: empty response")
 	}
-	
-	if len(response.Messages) > 0 && response.Messages[0].Status != "0" {
-		if response.Messages[0].ErrorText != "" {
-			return errors.New(response.Messages[0].ErrorText)
+
+	first := response.Messages[0]
+	if first.Status != "0" {
+		if first.ErrorText != "" {
+			return nil, errors.New(first.ErrorText)
 		}
-		return fmt.Errorf("SMS send failed with status: %s", response.Messages[0].Status)
+		return nil, fmt.Errorf("SMS send failed with status: %s", first.Status)
 	}
-	
-	return nil
+
+	prices := make([]string, 0, len(response.Messages))
+	for _, m := range response.Messages {
+		prices = append(prices, m.MessagePrice)
+	}
+
+	return &SendResult{
+		Provider:  "vonage",
+		MessageID: first.MessageID,
+		To:        to,
+		Status:    StatusSent,
+		Encoding:  segments.Encoding,
+		Segments:  segments.Segments,
+		Price:     addPrices(prices),
+	}, nil
+}
+
+// addPrices sums decimal price strings, returning "" if none are known
+func addPrices(prices []string) string {
+	var total float64
+	var found bool
+	for _, p := range prices {
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			continue
+		}
+		total += f
+		found = true
+	}
+	if !found {
+		return ""
+	}
+	return strconv.FormatFloat(total, 'f', -1, 64)
 }
 
 // Twilio provider implementation
 type Twilio struct {
-	AccountSid string
-	APIKey     string
-	APISecret  string
-	FromNumber string
-	httpClient HTTPClient // For testing
+	AccountSid     string
+	APIKey         string
+	APISecret      string
+	FromNumber     string
+	StatusCallback string     // URL Twilio posts delivery status updates to
+	httpClient     HTTPClient // Replaces the Twilio SDK when set
+}
+
+// Name returns the provider identifier
+func (t *Twilio) Name() string {
+	return "twilio"
 }
 
 // Send sends an SMS via Twilio
-func (t *Twilio) Send(to string, msg string, unicode bool) error {
-	// Use injected client for testing if available
+func (t *Twilio) Send(to string, msg string) (*SendResult, error) {
+	return t.SendContext(context.Background(), to, msg)
+}
+
+// SendContext sends an SMS via Twilio. The context is only used with an
+// HTTP client, since the Twilio SDK does not accept one.
+func (t *Twilio) SendContext(ctx context.Context, to string, msg string) (*SendResult, error) {
+	segments := CountSegments(msg)
+
 	if t.httpClient != nil {
-		return t.sendWithHTTPClient(to, msg, unicode)
+		return t.sendWithHTTPClient(ctx, to, msg, segments)
 	}
-	
+
 	// Production implementation using Twilio SDK
 	client := twilio.NewRestClientWithParams(twilio.ClientParams{
 		Username:   t.APIKey,
@@ -145,64 +254,113 @@ func (t *Twilio) Send(to string, msg string, unicode bool) error {
 	params.SetTo(to)
 	params.SetFrom(t.FromNumber)
 	params.SetBody(msg)
+	if t.StatusCallback != "" {
+		params.SetStatusCallback(t.StatusCallback)
+	}
 
-	_, err := client.Api.CreateMessage(params)
+	resp, err := client.Api.CreateMessage(params)
 	if err != nil {
-		return fmt.Errorf("failed to send SMS: %w", err)
+		return nil, fmt.Errorf("failed to send SMS: %w", err)
 	}
 
-	return nil
+	result := &SendResult{
+		Provider: "twilio",
+		To:       to,
+		Status:   StatusQueued,
+		Encoding: segments.Encoding,
+		Segments: segments.Segments,
+	}
+	if resp.Sid != nil {
+		result.MessageID = *resp.Sid
+	}
+	if resp.Status != nil {
+		result.Status = normalizeTwilioStatus(*resp.Status)
+	}
+	if resp.NumSegments != nil {
+		if n, err := strconv.Atoi(*resp.NumSegments); err == nil && n > 0 {
+			result.Segments = n
+		}
+	}
+	if resp.Price != nil {
+		result.Price = *resp.Price
+	}
+	if resp.PriceUnit != nil {
+		result.Currency = *resp.PriceUnit
+	}
+
+	return result, nil
 }
 
-// sendWithHTTPClient is used for testing with mocked HTTP client
-func (t *Twilio) sendWithHTTPClient(to string, msg string, unicode bool) error {
+// sendWithHTTPClient calls the Twilio REST API through the HTTP client
+func (t *Twilio) sendWithHTTPClient(ctx context.Context, to string, msg string, segments SegmentInfo) (*SendResult, error) {
 	data := url.Values{}
 	data.Set("To", to)
 	data.Set("From", t.FromNumber)
 	data.Set("Body", msg)
-	
+	if t.StatusCallback != "" {
+		data.Set("StatusCallback", t.StatusCallback)
+	}
+
 	url := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", t.AccountSid)
-	req, err := http.NewRequest("POST", url, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(data.Encode()))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	
+
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.SetBasicAuth(t.APIKey, t.APISecret)
-	
+
 	resp, err := t.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("SMS send failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("SMS send failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Sid         string  `json:"sid"`
+		Status      string  `json:"status"`
+		NumSegments string  `json:"num_segments"`
+		Price       *string `json:"price"`
+		PriceUnit   string  `json:"price_unit"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
 	}
-	
-	return nil
+
+	result := &SendResult{
+		Provider:  "twilio",
+		MessageID: response.Sid,
+		To:        to,
+		Status:    normalizeTwilioStatus(response.Status),
+		Encoding:  segments.Encoding,
+		Segments:  segments.Segments,
+		Currency:  response.PriceUnit,
+	}
+	if n, err := strconv.Atoi(response.NumSegments); err == nil && n > 0 {
+		result.Segments = n
+	}
+	if response.Price != nil {
+		result.Price = *response.Price
+	}
+
+	return result, nil
 }
 
-// CreateSMSProvider creates an SMS provider based on environment configuration
+// CreateSMSProvider creates an SMS provider based on environment configuration.
+// A comma separated list such as "twilio,vonage" creates a Failover
+// trying the providers in that order.
 func CreateSMSProvider(provider string) SMSProvider {
-	switch provider {
-	case "vonage":
-		return &Vonage{
-			APIKey:     os.Getenv("VONAGE_API_KEY"),
-			APISecret:  os.Getenv("VONAGE_API_SECRET"),
-			FromNumber: os.Getenv("VONAGE_FROM_NUMBER"),
-		}
-	case "twilio":
-		return &Twilio{
-			AccountSid: os.Getenv("TWILIO_ACCOUNT_SID"),
-			APIKey:     os.Getenv("TWILIO_API_KEY"),
-			APISecret:  os.Getenv("TWILIO_API_SECRET"),
-			FromNumber: os.Getenv("TWILIO_FROM_NUMBER"),
-		}
-	default:
-		return nil
-	}
+	return NewModule(WithProvider(provider)).config.buildProvider(nil)
 }
 
 // mockHTTPClient is a helper for testing
@@ -230,4 +388,4 @@ func defaultHTTPClient() *http.Client {
 	return &http.Client{
 		Timeout: 10 * time.Second,
 	}
-}
\ No newline at end of file
+}
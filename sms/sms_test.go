@@ -20,7 +20,6 @@ func TestVonage_Send(t *testing.T) {
 		mockResponse  func(req *http.Request) (*http.Response, error)
 		to            string
 		message       string
-		unicode       bool
 		expectError   bool
 		errorContains string
 	}{
@@ -54,7 +53,6 @@ func TestVonage_Send(t *testing.T) {
 			},
 			to:          "+0987654321",
 			message:     "Test message",
-			unicode:     false,
 			expectError: false,
 		},
 		{
@@ -77,7 +75,6 @@ func TestVonage_Send(t *testing.T) {
 			},
 			to:          "+0987654321",
 			message:     "Test message 😀",
-			unicode:     true,
 			expectError: false,
 		},
 		{
@@ -98,7 +95,6 @@ func TestVonage_Send(t *testing.T) {
 			},
 			to:            "+0987654321",
 			message:       "Test message",
-			unicode:       false,
 			expectError:   true,
 			errorContains: "Invalid credentials",
 		},
@@ -109,7 +105,6 @@ func TestVonage_Send(t *testing.T) {
 			},
 			to:            "+0987654321",
 			message:       "Test message",
-			unicode:       false,
 			expectError:   true,
 			errorContains: "network error",
 		},
@@ -124,7 +119,7 @@ func TestVonage_Send(t *testing.T) {
 				httpClient: newMockHTTPClient(tt.mockResponse),
 			}
 			
-			err := client.Send(tt.to, tt.message, tt.unicode)
+			_, err := client.Send(tt.to, tt.message)
 			
 			if tt.expectError {
 				require.Error(t, err)
@@ -144,7 +139,6 @@ func TestTwilio_Send(t *testing.T) {
 		mockResponse  func(req *http.Request) (*http.Response, error)
 		to            string
 		message       string
-		unicode       bool
 		expectError   bool
 		errorContains string
 	}{
@@ -182,7 +176,6 @@ func TestTwilio_Send(t *testing.T) {
 			},
 			to:          "+0987654321",
 			message:     "Test message",
-			unicode:     false,
 			expectError: false,
 		},
 		{
@@ -195,7 +188,6 @@ func TestTwilio_Send(t *testing.T) {
 			},
 			to:            "+0987654321",
 			message:       "Test message",
-			unicode:       false,
 			expectError:   true,
 			errorContains: "status 401",
 		},
@@ -206,7 +198,6 @@ func TestTwilio_Send(t *testing.T) {
 			},
 			to:            "+0987654321",
 			message:       "Test message",
-			unicode:       false,
 			expectError:   true,
 			errorContains: "connection refused",
 		},
@@ -222,7 +213,7 @@ func TestTwilio_Send(t *testing.T) {
 				httpClient: newMockHTTPClient(tt.mockResponse),
 			}
 			
-			err := client.Send(tt.to, tt.message, tt.unicode)
+			_, err := client.Send(tt.to, tt.message)
 			
 			if tt.expectError {
 				require.Error(t, err)
@@ -280,6 +271,25 @@ func TestCreateSMSProvider(t *testing.T) {
 				assert.Equal(t, "+456", tw.FromNumber)
 			},
 		},
+		{
+			name:      "Log provider",
+			provider:  "log",
+			expectNil: false,
+			verify: func(t *testing.T, provider SMSProvider) {
+				_, ok := provider.(*LogProvider)
+				assert.True(t, ok)
+			},
+		},
+		{
+			name:      "Failover provider",
+			provider:  "twilio,vonage,unknown",
+			expectNil: false,
+			verify: func(t *testing.T, provider SMSProvider) {
+				f, ok := provider.(*Failover)
+				require.True(t, ok)
+				assert.Len(t, f.Providers(), 2)
+			},
+		},
 		{
 			name:      "Unknown provider",
 			provider:  "unknown",
@@ -380,7 +390,7 @@ func TestVonage_ProductionPath(t *testing.T) {
 	
 	// This will use the production SDK path which will fail with test credentials
 	// We're just testing that it attempts to use the SDK
-	err := v.Send("+456", "test", false)
+	_, err := v.Send("+456", "test")
 	assert.Error(t, err) // Expected to fail with test credentials
 }
 
@@ -396,7 +406,7 @@ func TestTwilio_ProductionPath(t *testing.T) {
 	
 	// This will use the production SDK path which will fail with test credentials
 	// We're just testing that it attempts to use the SDK
-	err := tw.Send("+456", "test", false)
+	_, err := tw.Send("+456", "test")
 	assert.Error(t, err) // Expected to fail with test credentials
 }
 
@@ -416,7 +426,7 @@ func BenchmarkVonage_Send(b *testing.B) {
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = client.Send("+123", "test", false)
+		_, _ = client.Send("+123", "test")
 	}
 }
 
@@ -436,7 +446,7 @@ func BenchmarkTwilio_Send(b *testing.B) {
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = client.Send("+123", "test", false)
+		_, _ = client.Send("+123", "test")
 	}
 }
 
@@ -454,4 +464,64 @@ func BenchmarkCreateSMSProvider(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = CreateSMSProvider("vonage")
 	}
-}
\ No newline at end of file
+}
+func TestVonage_SendResult(t *testing.T) {
+	client := &Vonage{
+		APIKey:      "key",
+		APISecret:   "secret",
+		FromNumber:  "+123",
+		CallbackURL: "https://example.com/sms/dlr",
+		httpClient: newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			values, _ := url.ParseQuery(string(body))
+			assert.Equal(t, "https://example.com/sms/dlr", values.Get("callback"))
+			assert.Equal(t, "", values.Get("type"))
+
+			return &http.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(`{"message-count":"2","messages":[
+					{"message-id":"id-1","status":"0","message-price":"0.0333"},
+					{"message-id":"id-2","status":"0","message-price":"0.0333"}]}`)),
+			}, nil
+		}),
+	}
+
+	result, err := client.Send("+456", strings.Repeat("a", 200))
+	require.NoError(t, err)
+	assert.Equal(t, "vonage", result.Provider)
+	assert.Equal(t, "id-1", result.MessageID)
+	assert.Equal(t, StatusSent, result.Status)
+	assert.Equal(t, EncodingGSM7, result.Encoding)
+	assert.Equal(t, 2, result.Segments)
+	assert.Equal(t, "0.0666", result.Price)
+}
+
+func TestTwilio_SendResult(t *testing.T) {
+	client := &Twilio{
+		AccountSid:     "AC123",
+		APIKey:         "key",
+		APISecret:      "secret",
+		FromNumber:     "+123",
+		StatusCallback: "https://example.com/sms/status",
+		httpClient: newMockHTTPClient(func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			values, _ := url.ParseQuery(string(body))
+			assert.Equal(t, "https://example.com/sms/status", values.Get("StatusCallback"))
+
+			return &http.Response{
+				StatusCode: 201,
+				Body: io.NopCloser(strings.NewReader(
+					`{"sid":"SM1","status":"queued","num_segments":"1","price":null,"price_unit":"USD"}`)),
+			}, nil
+		}),
+	}
+
+	result, err := client.Send("+456", "Привет")
+	require.NoError(t, err)
+	assert.Equal(t, "SM1", result.MessageID)
+	assert.Equal(t, StatusQueued, result.Status)
+	assert.Equal(t, EncodingUCS2, result.Encoding)
+	assert.Equal(t, 1, result.Segments)
+	assert.Equal(t, "", result.Price)
+	assert.Equal(t, "USD", result.Currency)
+}
@@ -0,0 +1,141 @@
+package sms
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStatusNotFound is returned when no delivery status is known for a message ID
+var ErrStatusNotFound = errors.New("sms status not found")
+
+// DeliveryStatus is a provider-independent message delivery state
+type DeliveryStatus string
+
+const (
+	StatusQueued    DeliveryStatus = "queued"
+	StatusSent      DeliveryStatus = "sent"
+	StatusDelivered DeliveryStatus = "delivered"
+	StatusFailed    DeliveryStatus = "failed"
+	StatusUnknown   DeliveryStatus = "unknown"
+)
+
+// IsFinal reports whether the status will not change any more
+func (s DeliveryStatus) IsFinal() bool {
+	return s == StatusDelivered || s == StatusFailed
+}
+
+// SendResult describes a message accepted by a provider
+type SendResult struct {
+	Provider  string
+	MessageID string
+	To        string
+	Status    DeliveryStatus
+	Encoding  Encoding
+	Segments  int
+	Price     string // Cost as reported by the provider, empty if unknown
+	Currency  string
+}
+
+// DeliveryReport is a delivery status update for a sent message,
+// either recorded at send time or received through a provider webhook.
+type DeliveryReport struct {
+	Provider    string
+	MessageID   string
+	To          string
+	Status      DeliveryStatus
+	ErrorCode   string
+	Price       string
+	Currency    string
+	ProviderRaw string // Status string as reported by the provider
+	UpdatedAt   time.Time
+}
+
+// StatusStore persists delivery reports so they can be looked up by message ID
+type StatusStore interface {
+	Save(report *DeliveryReport) error
+	Get(messageID string) (*DeliveryReport, error)
+}
+
+// MemoryStatusStore is an in-process StatusStore.
+// Reports are lost on restart; use a persistent store in production
+// if statuses must survive deploys.
+type MemoryStatusStore struct {
+	mu      sync.RWMutex
+	reports map[string]*DeliveryReport
+}
+
+// NewMemoryStatusStore creates an empty in-memory status store
+func NewMemoryStatusStore() *MemoryStatusStore {
+	return &MemoryStatusStore{
+		reports: make(map[string]*DeliveryReport),
+	}
+}
+
+// Save stores a report. A report never replaces a final status with
+// a non-final one, since webhooks may arrive out of order.
+func (s *MemoryStatusStore) Save(report *DeliveryReport) error {
+	if report == nil || report.MessageID == "" {
+		return errors.New("delivery report requires a message ID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.reports[report.MessageID]; ok {
+		if existing.Status.IsFinal() && !report.Status.IsFinal() {
+			return nil
+		}
+	}
+
+	r := *report
+	if r.UpdatedAt.IsZero() {
+		r.UpdatedAt = time.Now()
+	}
+	s.reports[report.MessageID] = &r
+	return nil
+}
+
+// Get returns the latest report for a message ID
+func (s *MemoryStatusStore) Get(messageID string) (*DeliveryReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.reports[messageID]
+	if !ok {
+		return nil, ErrStatusNotFound
+	}
+	report := *r
+	return &report, nil
+}
+
+// normalizeVonageStatus maps Vonage DLR statuses to DeliveryStatus
+func normalizeVonageStatus(status string) DeliveryStatus {
+	switch strings.ToLower(status) {
+	case "delivered":
+		return StatusDelivered
+	case "accepted", "buffered":
+		return StatusSent
+	case "expired", "failed", "rejected":
+		return StatusFailed
+	default:
+		return StatusUnknown
+	}
+}
+
+// normalizeTwilioStatus maps Twilio message statuses to DeliveryStatus
+func normalizeTwilioStatus(status string) DeliveryStatus {
+	switch strings.ToLower(status) {
+	case "accepted", "scheduled", "queued", "sending":
+		return StatusQueued
+	case "sent":
+		return StatusSent
+	case "delivered", "read":
+		return StatusDelivered
+	case "undelivered", "failed", "canceled":
+		return StatusFailed
+	default:
+		return StatusUnknown
+	}
+}
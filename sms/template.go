@@ -0,0 +1,93 @@
+package sms
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// TemplateSet renders named SMS templates using text/template.
+// Templates are registered with Add or loaded lazily from
+// "<dir>/<name>.sms.tmpl".
+type TemplateSet struct {
+	dir       string
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+}
+
+// NewTemplateSet creates a template set that loads files from dir.
+// An empty dir disables loading from disk.
+func NewTemplateSet(dir string) *TemplateSet {
+	return &TemplateSet{
+		dir:       dir,
+		templates: make(map[string]*template.Template),
+	}
+}
+
+// Add parses and registers a template under the given name
+func (s *TemplateSet) Add(name, text string) error {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return fmt.Errorf("sms template %q: %w", name, err)
+	}
+
+	s.mu.Lock()
+	s.templates[name] = tmpl
+	s.mu.Unlock()
+	return nil
+}
+
+// Render executes the named template with data.
+// Surrounding whitespace is trimmed since it would cost segments.
+func (s *TemplateSet) Render(name string, data interface{}) (string, error) {
+	tmpl, err := s.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("sms template %q: %w", name, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func (s *TemplateSet) lookup(name string) (*template.Template, error) {
+	s.mu.RLock()
+	tmpl, ok := s.templates[name]
+	s.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	if s.dir == "" {
+		return nil, fmt.Errorf("sms template %q not found", name)
+	}
+	if !validTemplateName(name) {
+		return nil, fmt.Errorf("invalid sms template name %q", name)
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.dir, name+".sms.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("sms template %q not found: %w", name, err)
+	}
+
+	if err := s.Add(name, string(content)); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.templates[name], nil
+}
+
+// validTemplateName reports whether name refers to a file directly in the
+// template directory, so names can't read files outside it
+func validTemplateName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.Contains(name, "..")
+}
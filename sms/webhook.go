@@ -0,0 +1,313 @@
+package sms
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	twilioClient "github.com/twilio/twilio-go/client"
+)
+
+// maxWebhookBody limits the size of delivery receipt payloads
+const maxWebhookBody = 64 << 10
+
+// ReportHandler is called for every verified delivery report
+type ReportHandler func(report *DeliveryReport)
+
+// VonageWebhook handles Vonage SMS delivery receipts.
+// Configure the URL it is mounted at as the delivery receipt webhook
+// in the Vonage dashboard, or set Vonage.CallbackURL.
+//
+// Requests are rejected unless their "sig" parameter matches the
+// account signature secret.
+type VonageWebhook struct {
+	SignatureSecret string
+	// SignatureMethod is one of "md5hash" (default), "md5", "sha1", "sha256" or "sha512".
+	// The hash variants other than md5hash are HMAC based.
+	SignatureMethod string
+	Store           StatusStore
+	OnReport        ReportHandler
+}
+
+// ServeHTTP verifies and records a Vonage delivery receipt
+func (h *VonageWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params, err := webhookParams(w, r)
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verify(params) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	messageID := params.Get("messageId")
+	if messageID == "" {
+		http.Error(w, "missing messageId", http.StatusBadRequest)
+		return
+	}
+
+	report := &DeliveryReport{
+		Provider:    "vonage",
+		MessageID:   messageID,
+		To:          params.Get("msisdn"),
+		Status:      normalizeVonageStatus(params.Get("status")),
+		ErrorCode:   params.Get("err-code"),
+		Price:       params.Get("price"),
+		ProviderRaw: params.Get("status"),
+		UpdatedAt:   time.Now(),
+	}
+	if report.ErrorCode == "0" {
+		report.ErrorCode = ""
+	}
+
+	if !recordReport(w, h.Store, h.OnReport, report) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify checks the signature of a Vonage signed webhook
+func (h *VonageWebhook) verify(params url.Values) bool {
+	if h.SignatureSecret == "" {
+		return false
+	}
+
+	sig := params.Get("sig")
+	if sig == "" {
+		return false
+	}
+
+	expected := vonageSignature(params, h.SignatureSecret, h.SignatureMethod)
+	if expected == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(strings.ToLower(sig)), []byte(expected)) == 1
+}
+
+// vonageSignature computes the signature Vonage attaches to signed requests.
+// Parameters are sorted by name and joined as "&key=value", with "&" and "="
+// in values replaced by "_".
+func vonageSignature(params url.Values, secret, method string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "sig" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		value := strings.NewReplacer("&", "_", "=", "_").Replace(params.Get(k))
+		b.WriteString("&")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(value)
+	}
+	payload := b.String()
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "", "md5hash":
+		sum := md5.Sum([]byte(payload + secret))
+		return hex.EncodeToString(sum[:])
+	case "md5":
+		newHash = md5.New
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return ""
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// TwilioWebhook handles Twilio message status callbacks.
+// Requests are rejected unless their X-Twilio-Signature header is valid
+// for the account auth token.
+type TwilioWebhook struct {
+	AuthToken string
+	// URL is the public URL Twilio calls. Set it when the app runs behind a
+	// proxy that rewrites the host or path; otherwise it is derived from the request.
+	URL      string
+	Store    StatusStore
+	OnReport ReportHandler
+}
+
+// ServeHTTP verifies and records a Twilio status callback
+func (h *TwilioWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params, err := webhookParams(w, r)
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verify(r) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	messageID := params.Get("MessageSid")
+	if messageID == "" {
+		messageID = params.Get("SmsSid")
+	}
+	if messageID == "" {
+		http.Error(w, "missing MessageSid", http.StatusBadRequest)
+		return
+	}
+
+	status := params.Get("MessageStatus")
+	if status == "" {
+		status = params.Get("SmsStatus")
+	}
+
+	report := &DeliveryReport{
+		Provider:    "twilio",
+		MessageID:   messageID,
+		To:          params.Get("To"),
+		Status:      normalizeTwilioStatus(status),
+		ErrorCode:   params.Get("ErrorCode"),
+		ProviderRaw: status,
+		UpdatedAt:   time.Now(),
+	}
+
+	if !recordReport(w, h.Store, h.OnReport, report) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verify validates the X-Twilio-Signature header
+func (h *TwilioWebhook) verify(r *http.Request) bool {
+	if h.AuthToken == "" {
+		return false
+	}
+
+	sig := r.Header.Get("X-Twilio-Signature")
+	if sig == "" {
+		return false
+	}
+
+	// Twilio signs the URL including its query string plus the POST body
+	flat := make(map[string]string, len(r.PostForm))
+	for k := range r.PostForm {
+		flat[k] = r.PostForm.Get(k)
+	}
+
+	validator := twilioClient.NewRequestValidator(h.AuthToken)
+	return validator.Validate(h.requestURL(r), flat, sig)
+}
+
+// requestURL returns the URL Twilio signed
+func (h *TwilioWebhook) requestURL(r *http.Request) string {
+	if h.URL != "" {
+		return h.URL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// webhookParams reads form parameters from the query string and, for
+// POST requests, the url-encoded or JSON body. Only the body is used when
+// present, matching how providers sign their requests.
+func webhookParams(w http.ResponseWriter, r *http.Request) (url.Values, error) {
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBody)
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+			params, err := jsonParams(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			if len(params) > 0 {
+				return params, nil
+			}
+			return r.URL.Query(), nil
+		}
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		if len(r.PostForm) > 0 {
+			return r.PostForm, nil
+		}
+	}
+	return r.URL.Query(), nil
+}
+
+// jsonParams reads the top-level fields of a JSON object as parameters,
+// as Vonage sends delivery receipts configured to use JSON
+func jsonParams(body io.Reader) (url.Values, error) {
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	params := make(url.Values, len(fields))
+	for k, v := range fields {
+		switch v := v.(type) {
+		case string:
+			params.Set(k, v)
+		case json.Number:
+			params.Set(k, v.String())
+		case bool:
+			params.Set(k, strconv.FormatBool(v))
+		}
+	}
+	return params, nil
+}
+
+// recordReport stores the report and notifies the callback.
+// Returns false if an error response has been written.
+func recordReport(w http.ResponseWriter, store StatusStore, onReport ReportHandler, report *DeliveryReport) bool {
+	if store != nil {
+		if err := store.Save(report); err != nil {
+			http.Error(w, "failed to store status", http.StatusInternalServerError)
+			return false
+		}
+	}
+
+	if onReport != nil {
+		onReport(report)
+	}
+
+	return true
+}
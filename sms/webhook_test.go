@@ -0,0 +1,230 @@
+package sms
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedVonageParams(secret, method string) url.Values {
+	params := url.Values{}
+	params.Set("messageId", "0A0000000123ABCD1")
+	params.Set("msisdn", "447700900000")
+	params.Set("status", "delivered")
+	params.Set("err-code", "0")
+	params.Set("price", "0.03330000")
+	params.Set("message-timestamp", "2020-01-01 12:00:00")
+	params.Set("timestamp", "1577880000")
+	params.Set("sig", vonageSignature(params, secret, method))
+	return params
+}
+
+// twilioSignature mirrors Twilio's documented signing algorithm
+func twilioSignature(authToken, requestURL string, params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	payload := requestURL
+	for _, k := range keys {
+		payload += k + params.Get(k)
+	}
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVonageWebhook(t *testing.T) {
+	for _, method := range []string{"md5hash", "sha256"} {
+		t.Run(method, func(t *testing.T) {
+			store := NewMemoryStatusStore()
+			var received *DeliveryReport
+
+			h := &VonageWebhook{
+				SignatureSecret: "secret",
+				SignatureMethod: method,
+				Store:           store,
+				OnReport:        func(r *DeliveryReport) { received = r },
+			}
+
+			params := signedVonageParams("secret", method)
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/vonage", strings.NewReader(params.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			require.NotNil(t, received)
+
+			report, err := store.Get("0A0000000123ABCD1")
+			require.NoError(t, err)
+			assert.Equal(t, StatusDelivered, report.Status)
+			assert.Equal(t, "vonage", report.Provider)
+			assert.Equal(t, "447700900000", report.To)
+			assert.Equal(t, "", report.ErrorCode)
+		})
+	}
+}
+
+func TestVonageWebhook_JSON(t *testing.T) {
+	store := NewMemoryStatusStore()
+	h := &VonageWebhook{SignatureSecret: "secret", SignatureMethod: "sha256", Store: store}
+
+	params := signedVonageParams("secret", "sha256")
+	fields := make(map[string]string, len(params))
+	for k := range params {
+		fields[k] = params.Get(k)
+	}
+	body, err := json.Marshal(fields)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/vonage", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	report, err := store.Get("0A0000000123ABCD1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusDelivered, report.Status)
+	assert.Equal(t, "447700900000", report.To)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/vonage", strings.NewReader(`{"messageId":`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestVonageWebhook_GET(t *testing.T) {
+	store := NewMemoryStatusStore()
+	h := &VonageWebhook{SignatureSecret: "secret", Store: store}
+
+	params := signedVonageParams("secret", "")
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/vonage?"+params.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestVonageWebhook_InvalidSignature(t *testing.T) {
+	store := NewMemoryStatusStore()
+	h := &VonageWebhook{SignatureSecret: "secret", Store: store}
+
+	params := signedVonageParams("wrong-secret", "")
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/vonage", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	_, err := store.Get("0A0000000123ABCD1")
+	assert.ErrorIs(t, err, ErrStatusNotFound)
+}
+
+func TestVonageWebhook_NoSecret(t *testing.T) {
+	h := &VonageWebhook{Store: NewMemoryStatusStore()}
+
+	params := signedVonageParams("", "")
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/vonage?"+params.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestTwilioWebhook(t *testing.T) {
+	store := NewMemoryStatusStore()
+	h := &TwilioWebhook{AuthToken: "token", Store: store}
+
+	params := url.Values{}
+	params.Set("MessageSid", "SM123")
+	params.Set("MessageStatus", "undelivered")
+	params.Set("To", "+46701234567")
+	params.Set("ErrorCode", "30003")
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/webhooks/twilio", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", twilioSignature("token", "https://example.com/webhooks/twilio", params))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	report, err := store.Get("SM123")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, "30003", report.ErrorCode)
+	assert.Equal(t, "undelivered", report.ProviderRaw)
+}
+
+func TestTwilioWebhook_ConfiguredURL(t *testing.T) {
+	h := &TwilioWebhook{
+		AuthToken: "token",
+		URL:       "https://public.example.com/sms/status",
+		Store:     NewMemoryStatusStore(),
+	}
+
+	params := url.Values{}
+	params.Set("MessageSid", "SM123")
+	params.Set("MessageStatus", "delivered")
+
+	// Request arrives on an internal host behind a proxy
+	req := httptest.NewRequest(http.MethodPost, "http://10.0.0.5:8080/sms/status", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", twilioSignature("token", "https://public.example.com/sms/status", params))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestTwilioWebhook_InvalidSignature(t *testing.T) {
+	h := &TwilioWebhook{AuthToken: "token", Store: NewMemoryStatusStore()}
+
+	params := url.Values{}
+	params.Set("MessageSid", "SM123")
+	params.Set("MessageStatus", "delivered")
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/webhooks/twilio", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", twilioSignature("other", "https://example.com/webhooks/twilio", params))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestMemoryStatusStore_KeepsFinalStatus(t *testing.T) {
+	store := NewMemoryStatusStore()
+
+	require.NoError(t, store.Save(&DeliveryReport{MessageID: "m1", Status: StatusDelivered}))
+	require.NoError(t, store.Save(&DeliveryReport{MessageID: "m1", Status: StatusSent}))
+
+	report, err := store.Get("m1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusDelivered, report.Status)
+}
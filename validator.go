@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/jimmitjoo/tjo/sms/phone"
 	"github.com/microcosm-cc/bluemonday"
 )
 
@@ -62,6 +63,16 @@ func (v *Validation) IsEmail(field, value string) {
 	}
 }
 
+// IsPhone validates that a field contains a valid phone number.
+// defaultRegion (e.g. "SE") is used for numbers in national format;
+// pass "" to require international format. Use phone.Normalize to
+// convert the value to E.164 before storing it.
+func (v *Validation) IsPhone(field, value, defaultRegion string) {
+	if !phone.IsValid(value, defaultRegion) {
+		v.AddError(field, "This field must be a valid phone number")
+	}
+}
+
 func (v *Validation) Equals(eq bool, field, verified string) {
 	if !eq {
 		v.AddError(field, "This field must equal: "+verified)
@@ -122,6 +133,13 @@ func (v *Validation) IsAlphanumeric(field, value string) {
 	}
 }
 
+// IsUUID validates that a field contains a valid UUID
+func (v *Validation) IsUUID(field, value string) {
+	if !govalidator.IsUUID(value) {
+		v.AddError(field, "This field must be a valid UUID")
+	}
+}
+
 // IsURL validates that a field contains a valid URL
 func (v *Validation) IsURL(field, value string) {
 	if !govalidator.IsURL(value) {
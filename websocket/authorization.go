@@ -0,0 +1,161 @@
+package websocket
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RoomAuthorizer decides whether a client may act on a room. params holds
+// the placeholder values of the matched pattern, e.g. {"id": "42"} for the
+// room "private-user.42" and the pattern "private-user.{id}".
+type RoomAuthorizer func(c *Client, room string, params map[string]string) bool
+
+// RoomPolicy authorizes what clients may do in the rooms matching a pattern.
+// It applies to join_room and room_message messages sent by clients; the
+// server can always join clients to rooms with Hub.JoinRoom.
+type RoomPolicy struct {
+	// Join decides whether a client may join. If nil, every client may.
+	Join RoomAuthorizer
+
+	// Publish decides whether a client may send room messages.
+	// If nil, clients that may join may publish.
+	Publish RoomAuthorizer
+}
+
+// roomRule is a room policy with its compiled pattern
+type roomRule struct {
+	pattern string
+	re      *regexp.Regexp
+	names   []string
+	policy  RoomPolicy
+}
+
+// placeholder matches {name} in room patterns
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// newRoomRule compiles a room pattern. {name} matches one segment of a room
+// name, i.e. anything but '.', ':' and '/', and * matches anything.
+func newRoomRule(pattern string, policy RoomPolicy) *roomRule {
+	var expr strings.Builder
+	var names []string
+
+	expr.WriteString("^")
+	rest := pattern
+	for rest != "" {
+		loc := placeholder.FindStringSubmatchIndex(rest)
+		literal := rest
+		if loc != nil {
+			literal = rest[:loc[0]]
+		}
+
+		parts := strings.Split(literal, "*")
+		for i, part := range parts {
+			if i > 0 {
+				expr.WriteString(".*")
+			}
+			expr.WriteString(regexp.QuoteMeta(part))
+		}
+
+		if loc == nil {
+			break
+		}
+		names = append(names, rest[loc[2]:loc[3]])
+		expr.WriteString(`([^.:/]+)`)
+		rest = rest[loc[1]:]
+	}
+	expr.WriteString("$")
+
+	return &roomRule{
+		pattern: pattern,
+		re:      regexp.MustCompile(expr.String()),
+		names:   names,
+		policy:  policy,
+	}
+}
+
+// match returns the placeholder values if the room matches the rule
+func (r *roomRule) match(room string) (map[string]string, bool) {
+	values := r.re.FindStringSubmatch(room)
+	if values == nil {
+		return nil, false
+	}
+	params := make(map[string]string, len(r.names))
+	for i, name := range r.names {
+		params[name] = values[i+1]
+	}
+	return params, true
+}
+
+// roomPolicies holds the hub's room policies in the order they were added
+type roomPolicies struct {
+	mu    sync.RWMutex
+	rules []*roomRule
+}
+
+func (p *roomPolicies) add(rule *roomRule) {
+	p.mu.Lock()
+	p.rules = append(p.rules, rule)
+	p.mu.Unlock()
+}
+
+// find returns the first rule matching the room
+func (p *roomPolicies) find(room string) (*roomRule, map[string]string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, rule := range p.rules {
+		if params, ok := rule.match(room); ok {
+			return rule, params
+		}
+	}
+	return nil, nil
+}
+
+// AuthorizeRoom adds a policy for the rooms matching pattern. Patterns may
+// contain placeholders such as {id}, which match one segment of the room
+// name (up to a '.', ':' or '/'), and * wildcards. The first matching
+// pattern decides; rooms matching no pattern are open unless StrictRooms
+// is set.
+//
+//	hub.AuthorizeRoom("private-user.{id}", websocket.RoomPolicy{
+//	    Join: func(c *websocket.Client, room string, params map[string]string) bool {
+//	        return c.GetUserID() == params["id"]
+//	    },
+//	})
+func (h *Hub) AuthorizeRoom(pattern string, policy RoomPolicy) {
+	h.policies.add(newRoomRule(pattern, policy))
+}
+
+// CanJoinRoom reports whether a client may join a room
+func (h *Hub) CanJoinRoom(c *Client, room string) bool {
+	rule, params := h.policies.find(room)
+	if rule == nil {
+		return !h.config.StrictRooms
+	}
+	if rule.policy.Join == nil {
+		return true
+	}
+	return rule.policy.Join(c, room, params)
+}
+
+// CanPublishToRoom reports whether a client may send messages to a room
+func (h *Hub) CanPublishToRoom(c *Client, room string) bool {
+	rule, params := h.policies.find(room)
+	if rule == nil {
+		return !h.config.StrictRooms
+	}
+	if rule.policy.Publish == nil {
+		return h.CanJoinRoom(c, room)
+	}
+	return rule.policy.Publish(c, room, params)
+}
+
+// CanBroadcast reports whether a client may broadcast to every client.
+// Only clients allowed by AuthorizeBroadcast may.
+func (h *Hub) CanBroadcast(c *Client) bool {
+	if h.config.AuthorizeBroadcast == nil {
+		return false
+	}
+	return h.config.AuthorizeBroadcast(c)
+}
@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoomRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		room    string
+		match   bool
+		params  map[string]string
+	}{
+		{"private-user.{id}", "private-user.42", true, map[string]string{"id": "42"}},
+		{"private-user.{id}", "private-user.42.extra", false, nil},
+		{"private-user.{id}", "private-user.", false, nil},
+		{"team.{team}.doc.{doc}", "team.7.doc.9", true, map[string]string{"team": "7", "doc": "9"}},
+		{"chat:*", "chat:general:random", true, map[string]string{}},
+		{"chat:*", "chatroom", false, nil},
+		{"a+b.{x}", "a+b.1", true, map[string]string{"x": "1"}},
+		{"a+b.{x}", "aab.1", false, nil},
+		{"lobby", "lobby", true, map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.room, func(t *testing.T) {
+			params, ok := newRoomRule(tt.pattern, RoomPolicy{}).match(tt.room)
+			assert.Equal(t, tt.match, ok)
+			if tt.match {
+				assert.Equal(t, tt.params, params)
+			}
+		})
+	}
+}
+
+func TestRoomPolicies(t *testing.T) {
+	onlyOwner := func(c *Client, room string, params map[string]string) bool {
+		return c.GetUserID() == params["id"]
+	}
+	hub := NewHub(NewConfig(
+		WithRoomPolicy("private-user.{id}", RoomPolicy{Join: onlyOwner}),
+		WithRoomPolicy("announcements", RoomPolicy{
+			Publish: func(c *Client, room string, params map[string]string) bool {
+				return c.GetUserID() == "admin"
+			},
+		}),
+	))
+	alice := newTestClient(hub, "c1", "alice")
+	admin := newTestClient(hub, "c2", "admin")
+
+	assert.True(t, hub.CanJoinRoom(alice, "private-user.alice"))
+	assert.False(t, hub.CanJoinRoom(alice, "private-user.bob"))
+	assert.False(t, hub.CanPublishToRoom(alice, "private-user.bob"), "publish falls back to join")
+
+	assert.True(t, hub.CanJoinRoom(alice, "announcements"))
+	assert.False(t, hub.CanPublishToRoom(alice, "announcements"))
+	assert.True(t, hub.CanPublishToRoom(admin, "announcements"))
+
+	// Rooms without a policy are open, but broadcasts are not
+	assert.True(t, hub.CanJoinRoom(alice, "lobby"))
+	assert.False(t, hub.CanBroadcast(alice))
+
+	// The first matching pattern decides
+	hub.AuthorizeRoom("private-*", RoomPolicy{})
+	assert.False(t, hub.CanJoinRoom(alice, "private-user.bob"))
+	assert.True(t, hub.CanJoinRoom(alice, "private-team.1"))
+}
+
+func TestStrictRooms(t *testing.T) {
+	hub := NewHub(NewConfig(
+		WithStrictRooms(),
+		WithRoomPolicy("public.*", RoomPolicy{}),
+		WithAuthorizeBroadcast(func(c *Client) bool { return c.GetUserID() == "admin" }),
+	))
+	client := newTestClient(hub, "c1", "u1")
+
+	assert.True(t, hub.CanJoinRoom(client, "public.lobby"))
+	assert.False(t, hub.CanJoinRoom(client, "lobby"))
+	assert.False(t, hub.CanPublishToRoom(client, "lobby"))
+	assert.False(t, hub.CanBroadcast(client))
+	assert.True(t, hub.CanBroadcast(newTestClient(hub, "c2", "admin")))
+
+	require.False(t, NewHub(NewConfig(WithStrictRooms())).CanBroadcast(client))
+}
@@ -0,0 +1,427 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backplane relays hub events between application instances, so room and
+// broadcast messages reach clients connected to other instances and
+// client counts cover the whole cluster.
+//
+// Hubs sharing a backplane publish every broadcast, room message, join
+// and leave, and periodically a snapshot of their clients and rooms.
+type Backplane interface {
+	// Publish sends data to every subscribed hub, including the sender
+	Publish(ctx context.Context, data []byte) error
+
+	// Subscribe calls handler for every published message until ctx is
+	// done or the subscription fails
+	Subscribe(ctx context.Context, handler func(data []byte)) error
+}
+
+// Backplane event kinds
+const (
+	eventBroadcast  = "broadcast"
+	eventRoom       = "room"
+	eventUser       = "user" // A message for all connections of a user
+	eventConnect    = "connect"
+	eventDisconnect = "disconnect"
+	eventJoin       = "join"
+	eventLeave      = "leave"
+	eventKick       = "kick"     // Close all connections of a user
+	eventPresence   = "presence" // A client's presence state changed
+	eventSync       = "sync"     // Snapshot of a node's clients and rooms
+	eventHello      = "hello"    // A node started and asks the others to sync
+	eventBye        = "bye"      // A node shut down
+)
+
+// backplaneEvent is a hub event as published on the backplane
+type backplaneEvent struct {
+	Node     string                 `json:"node"`
+	Kind     string                 `json:"kind"`
+	Room     string                 `json:"room,omitempty"`
+	Client   string                 `json:"client,omitempty"`
+	User     string                 `json:"user,omitempty"`
+	State    string                 `json:"state,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Message  []byte                 `json:"message,omitempty"`
+	Clients  map[string]*clientInfo `json:"clients,omitempty"` // By client ID
+	Rooms    map[string][]string    `json:"rooms,omitempty"`   // Room to client IDs
+}
+
+// clientInfo describes a client on another instance
+type clientInfo struct {
+	User     string                 `json:"user"`
+	State    string                 `json:"state,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	at       time.Time              // When the presence state was received
+}
+
+// remoteNode is what a hub knows about another instance's clients
+type remoteNode struct {
+	clients map[string]*clientInfo     // By client ID
+	rooms   map[string]map[string]bool // Room to client IDs
+	seen    time.Time
+}
+
+// clusterState tracks the clients and rooms of the other instances
+type clusterState struct {
+	mu    sync.RWMutex
+	nodes map[string]*remoteNode
+}
+
+func newClusterState() *clusterState {
+	return &clusterState{nodes: make(map[string]*remoteNode)}
+}
+
+// apply updates the state of the event's node
+func (cs *clusterState) apply(ev *backplaneEvent) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if ev.Kind == eventBye {
+		delete(cs.nodes, ev.Node)
+		return
+	}
+
+	node, ok := cs.nodes[ev.Node]
+	if !ok || ev.Kind == eventSync {
+		node = &remoteNode{
+			clients: make(map[string]*clientInfo),
+			rooms:   make(map[string]map[string]bool),
+		}
+		cs.nodes[ev.Node] = node
+	}
+	node.seen = time.Now()
+
+	switch ev.Kind {
+	case eventSync:
+		for id, info := range ev.Clients {
+			if info != nil {
+				info.at = node.seen
+				node.clients[id] = info
+			}
+		}
+		for room, ids := range ev.Rooms {
+			members := make(map[string]bool, len(ids))
+			for _, id := range ids {
+				members[id] = true
+			}
+			node.rooms[room] = members
+		}
+	case eventConnect, eventPresence:
+		node.clients[ev.Client] = &clientInfo{
+			User:     ev.User,
+			State:    ev.State,
+			Metadata: ev.Metadata,
+			at:       node.seen,
+		}
+	case eventDisconnect:
+		delete(node.clients, ev.Client)
+		for room, members := range node.rooms {
+			delete(members, ev.Client)
+			if len(members) == 0 {
+				delete(node.rooms, room)
+			}
+		}
+	case eventJoin:
+		node.clients[ev.Client] = &clientInfo{
+			User:     ev.User,
+			State:    ev.State,
+			Metadata: ev.Metadata,
+			at:       node.seen,
+		}
+		if node.rooms[ev.Room] == nil {
+			node.rooms[ev.Room] = make(map[string]bool)
+		}
+		node.rooms[ev.Room][ev.Client] = true
+	case eventLeave:
+		delete(node.rooms[ev.Room], ev.Client)
+		if len(node.rooms[ev.Room]) == 0 {
+			delete(node.rooms, ev.Room)
+		}
+	}
+}
+
+// prune forgets nodes that have not been heard from within maxAge,
+// e.g. because they crashed without saying bye
+func (cs *clusterState) prune(maxAge time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	for id, node := range cs.nodes {
+		if time.Since(node.seen) > maxAge {
+			delete(cs.nodes, id)
+		}
+	}
+}
+
+func (cs *clusterState) clientCount() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	n := 0
+	for _, node := range cs.nodes {
+		n += len(node.clients)
+	}
+	return n
+}
+
+// roomMembers returns the clients in a room on other instances
+func (cs *clusterState) roomMembers(room string) []clientInfo {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	var members []clientInfo
+	for _, node := range cs.nodes {
+		for id := range node.rooms[room] {
+			if info, ok := node.clients[id]; ok {
+				members = append(members, *info)
+			}
+		}
+	}
+	return members
+}
+
+// userInRoom reports whether a user has a client in a room on another instance
+func (cs *clusterState) userInRoom(room, user string) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, node := range cs.nodes {
+		for id := range node.rooms[room] {
+			if info, ok := node.clients[id]; ok && info.User == user {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (cs *clusterState) roomCount(room string) int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	n := 0
+	for _, node := range cs.nodes {
+		n += len(node.rooms[room])
+	}
+	return n
+}
+
+// publish sends an event to the other instances. Events are queued so
+// callers never wait for the network; they are dropped if the queue is full.
+func (h *Hub) publish(ev *backplaneEvent) {
+	if h.backplane == nil {
+		return
+	}
+	ev.Node = h.nodeID
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("WebSocket backplane: failed to encode %s event: %v", ev.Kind, err)
+		return
+	}
+
+	select {
+	case h.outbox <- data:
+	default:
+		log.Printf("WebSocket backplane: outbox is full, dropping %s event", ev.Kind)
+	}
+}
+
+// runBackplane publishes queued events, subscribes to other instances'
+// events and sends periodic snapshots until ctx is done
+func (h *Hub) runBackplane(ctx context.Context) {
+	subscribed := make(chan struct{})
+	go h.subscribe(ctx, subscribed)
+
+	interval := h.config.SyncInterval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Say hello until our own hello comes back, so the other instances'
+	// snapshots are not sent before we are subscribed
+	hello := time.NewTicker(100 * time.Millisecond)
+	defer hello.Stop()
+	h.publish(&backplaneEvent{Kind: eventHello})
+	h.publish(h.snapshot())
+
+	for {
+		select {
+		case <-hello.C:
+			h.publish(&backplaneEvent{Kind: eventHello})
+		case <-subscribed:
+			hello.Stop()
+			subscribed = nil
+		case <-ctx.Done():
+			h.sayBye()
+			return
+		case data := <-h.outbox:
+			if err := h.backplane.Publish(ctx, data); err != nil && ctx.Err() == nil {
+				log.Printf("WebSocket backplane: publish failed: %v", err)
+			}
+		case <-ticker.C:
+			h.publish(h.snapshot())
+			h.cluster.prune(3 * interval)
+		}
+	}
+}
+
+// subscribe delivers other instances' events to the run loop,
+// resubscribing when the subscription fails. subscribed is closed when
+// the hub's own hello is received.
+func (h *Hub) subscribe(ctx context.Context, subscribed chan struct{}) {
+	var once sync.Once
+	handler := func(data []byte) {
+		var ev backplaneEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			log.Printf("WebSocket backplane: invalid event: %v", err)
+			return
+		}
+		if ev.Node == h.nodeID {
+			if ev.Kind == eventHello {
+				once.Do(func() { close(subscribed) })
+			}
+			return
+		}
+		select {
+		case h.remote <- &ev:
+		case <-ctx.Done():
+		}
+	}
+
+	for {
+		err := h.backplane.Subscribe(ctx, handler)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("WebSocket backplane: subscription failed, retrying: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+		// Events may have been missed, so ask the others for their state
+		h.publish(&backplaneEvent{Kind: eventHello})
+	}
+}
+
+// handleRemote applies another instance's event. It runs on the run loop
+// like local events.
+func (h *Hub) handleRemote(ev *backplaneEvent) {
+	switch ev.Kind {
+	case eventBroadcast:
+		h.broadcastToAll(ev.Message)
+	case eventRoom:
+		h.broadcastToRoom(&RoomMessage{Room: ev.Room, Message: ev.Message})
+	case eventUser:
+		h.sendToUser(&userMessage{UserID: ev.User, Message: ev.Message})
+	case eventKick:
+		h.disconnectUser(ev.User)
+	case eventHello:
+		h.cluster.apply(ev)
+		h.publish(h.snapshot())
+	default:
+		h.cluster.apply(ev)
+	}
+}
+
+// snapshot returns a sync event with the hub's clients and rooms
+func (h *Hub) snapshot() *backplaneEvent {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ev := &backplaneEvent{
+		Kind:    eventSync,
+		Clients: make(map[string]*clientInfo, len(h.clients)),
+		Rooms:   make(map[string][]string, len(h.rooms)),
+	}
+	for client := range h.clients {
+		ev.Clients[client.id] = client.info()
+	}
+	for name, room := range h.rooms {
+		room.mu.RLock()
+		ids := make([]string, 0, len(room.clients))
+		for client := range room.clients {
+			ids = append(ids, client.id)
+			if _, ok := ev.Clients[client.id]; !ok {
+				ev.Clients[client.id] = client.info()
+			}
+		}
+		room.mu.RUnlock()
+		ev.Rooms[name] = ids
+	}
+	return ev
+}
+
+// sayBye tells the other instances to forget this one
+func (h *Hub) sayBye() {
+	data, err := json.Marshal(&backplaneEvent{Node: h.nodeID, Kind: eventBye})
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	h.backplane.Publish(ctx, data)
+}
+
+// MemoryBackplane connects hubs in the same process, e.g. in tests
+type MemoryBackplane struct {
+	mu          sync.RWMutex
+	subscribers map[int]func([]byte)
+	next        int
+}
+
+// NewMemoryBackplane creates an in-process backplane
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{subscribers: make(map[int]func([]byte))}
+}
+
+// Publish calls every subscriber's handler
+func (b *MemoryBackplane) Publish(ctx context.Context, data []byte) error {
+	b.mu.RLock()
+	handlers := make([]func([]byte), 0, len(b.subscribers))
+	for _, handler := range b.subscribers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(append([]byte(nil), data...))
+	}
+	return nil
+}
+
+// Subscribe registers handler until ctx is done
+func (b *MemoryBackplane) Subscribe(ctx context.Context, handler func([]byte)) error {
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subscribers[id] = handler
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	delete(b.subscribers, id)
+	b.mu.Unlock()
+	return ctx.Err()
+}
+
+// backplaneChannel returns the channel name for a prefix
+func backplaneChannel(prefix string) string {
+	if prefix == "" {
+		prefix = "tjo"
+	}
+	return strings.TrimSuffix(prefix, ":") + ":websocket"
+}
@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisBackplane relays hub events over Redis pub/sub. Every instance
+// using the same pool and prefix forms one cluster.
+//
+//	hub := websocket.NewHub(websocket.NewConfig(
+//	    websocket.WithBackplane(websocket.NewRedisBackplane(app.Data.RedisPool(), "myapp")),
+//	))
+type RedisBackplane struct {
+	pool    *redis.Pool
+	channel string
+}
+
+// NewRedisBackplane creates a backplane publishing on the channel
+// <prefix>:websocket
+func NewRedisBackplane(pool *redis.Pool, prefix string) *RedisBackplane {
+	return &RedisBackplane{
+		pool:    pool,
+		channel: backplaneChannel(prefix),
+	}
+}
+
+// Publish publishes data on the backplane channel
+func (b *RedisBackplane) Publish(ctx context.Context, data []byte) error {
+	conn, err := b.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Do("PUBLISH", b.channel, data)
+	return err
+}
+
+// Subscribe subscribes to the backplane channel on a dedicated connection
+func (b *RedisBackplane) Subscribe(ctx context.Context, handler func([]byte)) error {
+	conn, err := b.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+
+	if err := psc.Subscribe(b.channel); err != nil {
+		return err
+	}
+
+	for {
+		switch v := psc.ReceiveContext(ctx).(type) {
+		case redis.Message:
+			handler(v.Data)
+		case redis.Subscription:
+			if v.Count == 0 {
+				return fmt.Errorf("unsubscribed from %s", b.channel)
+			}
+		case error:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return v
+		}
+	}
+}
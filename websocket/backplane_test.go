@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(hub *Hub, id, userID string) *Client {
+	return &Client{
+		hub:      hub,
+		send:     make(chan []byte, 256),
+		id:       id,
+		userID:   userID,
+		rooms:    make(map[string]bool),
+		metadata: make(map[string]interface{}),
+	}
+}
+
+// startClusterHub runs a hub on the backplane until the test ends
+func startClusterHub(t *testing.T, b Backplane) (*Hub, context.CancelFunc) {
+	hub := NewHub(NewConfig(WithBackplane(b)))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx)
+	return hub, cancel
+}
+
+func receive(t *testing.T, c *Client) string {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		return string(msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+		return ""
+	}
+}
+
+func testCluster(t *testing.T, a, b Backplane) {
+	hubA, _ := startClusterHub(t, a)
+	hubB, stopB := startClusterHub(t, b)
+
+	client := newTestClient(hubB, "c1", "u1")
+	hubB.register <- client
+	hubB.JoinRoom(client, "lobby")
+
+	require.Eventually(t, func() bool {
+		return hubA.GetConnectedClients() == 1 && hubA.GetRoomClients("lobby") == 1
+	}, 2*time.Second, 10*time.Millisecond, "counts include the other instance")
+	assert.Equal(t, 0, hubA.GetLocalClients())
+	assert.Equal(t, 0, hubA.GetLocalRoomClients("lobby"))
+
+	hubA.BroadcastToRoom("lobby", []byte("to room"), nil)
+	assert.Equal(t, "to room", receive(t, client))
+
+	hubA.BroadcastToAll([]byte("to all"))
+	assert.Equal(t, "to all", receive(t, client))
+
+	hubB.LeaveRoom(client, "lobby")
+	require.Eventually(t, func() bool {
+		return hubA.GetRoomClients("lobby") == 0
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, hubA.GetConnectedClients())
+
+	stopB()
+	require.Eventually(t, func() bool {
+		return hubA.GetConnectedClients() == 0
+	}, 2*time.Second, 10*time.Millisecond, "stopped instances are forgotten")
+}
+
+func TestMemoryBackplane(t *testing.T) {
+	b := NewMemoryBackplane()
+	testCluster(t, b, b)
+}
+
+func TestRedisBackplane(t *testing.T) {
+	addr := miniredis.RunT(t).Addr()
+	newPool := func() *redis.Pool {
+		return &redis.Pool{Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		}}
+	}
+
+	testCluster(t, NewRedisBackplane(newPool(), "app"), NewRedisBackplane(newPool(), "app:"))
+}
+
+func TestBackplaneLateJoiner(t *testing.T) {
+	b := NewMemoryBackplane()
+	hubA, _ := startClusterHub(t, b)
+
+	client := newTestClient(hubA, "c1", "u1")
+	hubA.register <- client
+	hubA.JoinRoom(client, "lobby")
+
+	// A hub started later learns the existing clients from a snapshot
+	hubB, _ := startClusterHub(t, b)
+	require.Eventually(t, func() bool {
+		return hubB.GetConnectedClients() == 1 && hubB.GetRoomClients("lobby") == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestClusterStatePrune(t *testing.T) {
+	cs := newClusterState()
+	cs.apply(&backplaneEvent{Node: "n1", Kind: eventConnect, Client: "c1", User: "u1"})
+	cs.apply(&backplaneEvent{Node: "n1", Kind: eventJoin, Room: "r", Client: "c1"})
+	cs.apply(&backplaneEvent{Node: "n2", Kind: eventConnect, Client: "c2", User: "u2"})
+	assert.Equal(t, 2, cs.clientCount())
+	assert.Equal(t, 1, cs.roomCount("r"))
+
+	cs.apply(&backplaneEvent{Node: "n1", Kind: eventDisconnect, Client: "c1"})
+	assert.Equal(t, 1, cs.clientCount())
+	assert.Equal(t, 0, cs.roomCount("r"))
+
+	cs.nodes["n2"].seen = time.Now().Add(-time.Hour)
+	cs.prune(time.Minute)
+	assert.Equal(t, 0, cs.clientCount())
+	assert.Equal(t, "tjo:websocket", backplaneChannel(""))
+	assert.Equal(t, "app:websocket", backplaneChannel("app:"))
+}
@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jimmitjoo/tjo/api"
+	"github.com/jimmitjoo/tjo/logging"
+)
+
+// SlowConsumerPolicy decides what happens to a message for a client whose
+// send buffer is full, e.g. because its network is slower than the rate
+// of messages
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerDisconnect closes the connection. The client can
+	// reconnect and resume. This is the default.
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect"
+
+	// SlowConsumerDropNewest drops the message
+	SlowConsumerDropNewest SlowConsumerPolicy = "drop_newest"
+
+	// SlowConsumerDropOldest drops the oldest queued message to make room
+	SlowConsumerDropOldest SlowConsumerPolicy = "drop_oldest"
+)
+
+// Metric names registered with WithMetrics
+const (
+	MetricMessagesDropped = "websocket_messages_dropped_total"
+	MetricSlowConsumers   = "websocket_slow_consumer_disconnects_total"
+	MetricRateLimited     = "websocket_rate_limited_total"
+	MetricQueueDepth      = "websocket_send_queue_depth"
+	MetricQueueDepthMax   = "websocket_send_queue_depth_max"
+	MetricConnections     = "websocket_connections"
+)
+
+// hubMetrics are the hub's delivery metrics
+type hubMetrics struct {
+	dropped       *logging.Counter
+	slowConsumers *logging.Counter
+	rateLimited   *logging.Counter
+	queueDepth    *logging.Gauge // Messages queued for all clients
+	queueDepthMax *logging.Gauge // Messages queued for the most behind client
+	connections   *logging.Gauge
+}
+
+func newHubMetrics(registry *logging.MetricRegistry) *hubMetrics {
+	m := &hubMetrics{
+		dropped:       logging.NewCounter(MetricMessagesDropped, nil),
+		slowConsumers: logging.NewCounter(MetricSlowConsumers, nil),
+		rateLimited:   logging.NewCounter(MetricRateLimited, nil),
+		queueDepth:    logging.NewGauge(MetricQueueDepth, nil),
+		queueDepthMax: logging.NewGauge(MetricQueueDepthMax, nil),
+		connections:   logging.NewGauge(MetricConnections, nil),
+	}
+
+	if registry != nil {
+		registry.Register(m.dropped)
+		registry.Register(m.slowConsumers)
+		registry.Register(m.rateLimited)
+		registry.Register(m.queueDepth)
+		registry.Register(m.queueDepthMax)
+		registry.Register(m.connections)
+	}
+	return m
+}
+
+// updateQueueMetrics samples the send buffers of the local clients
+func (h *Hub) updateQueueMetrics() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var total, deepest int
+	for client := range h.clients {
+		n := len(client.send)
+		total += n
+		if n > deepest {
+			deepest = n
+		}
+	}
+	h.metrics.queueDepth.Set(int64(total))
+	h.metrics.queueDepthMax.Set(int64(deepest))
+	h.metrics.connections.Set(int64(len(h.clients)))
+}
+
+// enqueue queues a message for the client, applying the slow consumer
+// policy when its buffer is full. It reports whether the message was
+// queued. The caller must hold h.mu, so the send channel is not closed
+// meanwhile.
+func (c *Client) enqueue(message []byte) bool {
+	if c.closed {
+		return false
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+	}
+
+	metrics := c.hub.metrics
+	switch c.hub.config.SlowConsumer {
+	case SlowConsumerDropNewest:
+		metrics.dropped.Inc()
+		return false
+	case SlowConsumerDropOldest:
+		select {
+		case <-c.send:
+			metrics.dropped.Inc()
+		default:
+		}
+		select {
+		case c.send <- message:
+			return true
+		default:
+			metrics.dropped.Inc()
+			return false
+		}
+	default:
+		metrics.dropped.Inc()
+		if c.closing.CompareAndSwap(false, true) {
+			metrics.slowConsumers.Inc()
+			log.Printf("Client %s: send buffer full, disconnecting slow consumer", c.id)
+			go c.close(websocket.CloseTryAgainLater, "slow consumer")
+		}
+		return false
+	}
+}
+
+// allowMessage applies the inbound rate limits to a message from the client
+func (c *Client) allowMessage() (bool, *api.RateLimitInfo) {
+	limits := []struct {
+		limiter api.RateLimiter
+		key     string
+	}{
+		{c.hub.config.ClientRateLimiter, c.id},
+		{c.hub.config.UserRateLimiter, c.userID},
+	}
+
+	for _, limit := range limits {
+		if limit.limiter == nil {
+			continue
+		}
+		if allowed, info := limit.limiter.Allow(limit.key); !allowed {
+			c.hub.metrics.rateLimited.Inc()
+			return false, info
+		}
+	}
+	return true, nil
+}
+
+// rateLimitError is sent to clients exceeding a rate limit
+func rateLimitError(info *api.RateLimitInfo) *ClientError {
+	retryAfter := 1
+	if info != nil && info.RetryAfter > 0 {
+		retryAfter = info.RetryAfter
+	}
+	return NewClientError("rate_limited", fmt.Sprintf("too many messages, retry after %ds", retryAfter))
+}
+
+// runMetrics samples the queue metrics until done is closed
+func (h *Hub) runMetrics(done <-chan struct{}) {
+	ticker := time.NewTicker(h.config.MetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			h.updateQueueMetrics()
+		}
+	}
+}
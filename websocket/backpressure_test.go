@@ -0,0 +1,168 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jimmitjoo/tjo/api"
+	"github.com/jimmitjoo/tjo/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBufferedClient creates a registered test client with a small buffer
+func newBufferedClient(t *testing.T, hub *Hub, id string, size int) *Client {
+	client := newTestClient(hub, id, id)
+	client.send = make(chan []byte, size)
+	hub.register <- client
+	return client
+}
+
+func TestSlowConsumerDropNewest(t *testing.T) {
+	registry := logging.NewMetricRegistry()
+	hub := startHub(t, WithSlowConsumerPolicy(SlowConsumerDropNewest), WithMetrics(registry))
+	client := newBufferedClient(t, hub, "c1", 2)
+
+	require.NoError(t, client.Send([]byte("1")))
+	require.NoError(t, client.Send([]byte("2")))
+	assert.Error(t, client.Send([]byte("3")))
+	assert.Equal(t, "1", receive(t, client))
+	assert.Equal(t, "2", receive(t, client))
+	assertNoMessage(t, client)
+
+	dropped, ok := registry.Get(MetricMessagesDropped)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), dropped.Value())
+	assert.Equal(t, 1, hub.GetLocalClients())
+}
+
+func TestSlowConsumerDropOldest(t *testing.T) {
+	hub := startHub(t, WithSlowConsumerPolicy(SlowConsumerDropOldest))
+	client := newBufferedClient(t, hub, "c1", 2)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, client.Send([]byte(fmt.Sprint(i))))
+	}
+	assert.Equal(t, "2", receive(t, client))
+	assert.Equal(t, "3", receive(t, client))
+	assert.Equal(t, int64(1), hub.metrics.dropped.Get())
+}
+
+func TestSlowConsumerDisconnect(t *testing.T) {
+	hub := startHub(t)
+	slow := newBufferedClient(t, hub, "c1", 1)
+	fast := newBufferedClient(t, hub, "c2", 10)
+	hub.JoinRoom(slow, "lobby")
+	hub.JoinRoom(fast, "lobby")
+
+	for i := 0; i < 3; i++ {
+		hub.BroadcastToRoom("lobby", []byte("hi"), nil)
+	}
+
+	require.Eventually(t, func() bool {
+		return hub.GetLocalClients() == 1 && hub.GetRoomClients("lobby") == 1
+	}, time.Second, 10*time.Millisecond, "the slow client leaves its rooms")
+	assert.Equal(t, int64(1), hub.metrics.slowConsumers.Get())
+	assert.Error(t, slow.Send([]byte("late")))
+
+	// Later messages to the room still reach the others
+	hub.BroadcastToRoom("lobby", []byte("after"), nil)
+	hub.BroadcastToAll([]byte("all"))
+	for _, want := range []string{"hi", "hi", "hi", "after", "all"} {
+		assert.Equal(t, want, receive(t, fast))
+	}
+}
+
+func TestQueueMetrics(t *testing.T) {
+	registry := logging.NewMetricRegistry()
+	hub := startHub(t, WithMetrics(registry))
+	a := newBufferedClient(t, hub, "c1", 10)
+	newBufferedClient(t, hub, "c2", 10)
+
+	require.NoError(t, a.Send([]byte("1")))
+	require.NoError(t, a.Send([]byte("2")))
+	hub.SendToUser("c2", []byte("3"))
+	require.Eventually(t, func() bool {
+		hub.updateQueueMetrics()
+		return hub.metrics.queueDepth.Get() == 3
+	}, time.Second, 10*time.Millisecond)
+
+	for name, want := range map[string]int64{
+		MetricQueueDepth:    3,
+		MetricQueueDepthMax: 2,
+		MetricConnections:   2,
+	} {
+		metric, ok := registry.Get(name)
+		require.True(t, ok, name)
+		assert.Equal(t, want, metric.Value(), name)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	limiter := api.NewTokenBucket(1, 2, time.Hour)
+	defer limiter.Stop()
+	hub := startHub(t, WithClientRateLimit(limiter))
+	client := newTestClient(hub, "c1", "u1")
+
+	for i := 0; i < 3; i++ {
+		allowed, info := client.allowMessage()
+		if i < 2 {
+			assert.True(t, allowed)
+			continue
+		}
+		assert.False(t, allowed)
+		assert.Contains(t, rateLimitError(info).Message, "retry after")
+	}
+	assert.Equal(t, int64(1), hub.metrics.rateLimited.Get())
+
+	// Other connections have their own limit
+	allowed, _ := newTestClient(hub, "c2", "u1").allowMessage()
+	assert.True(t, allowed)
+}
+
+func TestUserRateLimit(t *testing.T) {
+	limiter := api.NewTokenBucket(1, 2, time.Hour)
+	defer limiter.Stop()
+	hub := startHub(t, WithUserRateLimit(limiter))
+	tab1 := newTestClient(hub, "c1", "u1")
+	tab2 := newTestClient(hub, "c2", "u1")
+
+	allowed, _ := tab1.allowMessage()
+	assert.True(t, allowed)
+	allowed, _ = tab2.allowMessage()
+	assert.True(t, allowed)
+	allowed, _ = tab1.allowMessage()
+	assert.False(t, allowed, "connections share the user's limit")
+
+	allowed, _ = newTestClient(hub, "c3", "u2").allowMessage()
+	assert.True(t, allowed)
+}
+
+func TestSSEPublishRateLimit(t *testing.T) {
+	limiter := api.NewTokenBucket(1, 1, time.Hour)
+	defer limiter.Stop()
+	hub := startHub(t, WithClientRateLimit(limiter))
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeSSEPublish))
+	t.Cleanup(server.Close)
+
+	client := newBufferedClient(t, hub, "c1", 10)
+	client.publishToken = "token"
+	publish := func() *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"type":"ping"}`))
+		req.Header.Set(SSEClientIDHeader, client.id)
+		req.Header.Set(SSEPublishTokenHeader, client.publishToken)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusAccepted, publish().StatusCode)
+	resp := publish()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+}
@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"compress/flate"
+	"log"
+
+	"github.com/gorilla/websocket"
+)
+
+// Codec is a wire format for messages, negotiated per connection through
+// the Sec-WebSocket-Protocol header. The hub passes messages around as
+// JSON, so codecs convert from and to JSON at the connection and
+// application code doesn't depend on the format clients use.
+type Codec interface {
+	// Name is the subprotocol clients request, e.g. "msgpack"
+	Name() string
+
+	// Binary reports whether messages are sent in binary frames
+	Binary() bool
+
+	// Encode converts a JSON message to the wire format
+	Encode(message []byte) ([]byte, error)
+
+	// Decode converts a received message to JSON
+	Decode(data []byte) ([]byte, error)
+}
+
+// Built-in codecs
+var (
+	// JSONCodec sends messages as JSON in text frames. It is used when a
+	// client requests no subprotocol.
+	JSONCodec Codec = jsonCodec{}
+
+	// MessagePackCodec sends messages as MessagePack in binary frames
+	MessagePackCodec Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                          { return "json" }
+func (jsonCodec) Binary() bool                          { return false }
+func (jsonCodec) Encode(message []byte) ([]byte, error) { return message, nil }
+func (jsonCodec) Decode(data []byte) ([]byte, error)    { return data, nil }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string                          { return "msgpack" }
+func (msgpackCodec) Binary() bool                          { return true }
+func (msgpackCodec) Encode(message []byte) ([]byte, error) { return jsonToMsgpack(message) }
+func (msgpackCodec) Decode(data []byte) ([]byte, error)    { return msgpackToJSON(data) }
+
+// subprotocols returns the names of the configured codecs for the upgrader
+func (h *Hub) subprotocols() []string {
+	names := make([]string, 0, len(h.config.Codecs))
+	for _, codec := range h.config.Codecs {
+		names = append(names, codec.Name())
+	}
+	return names
+}
+
+// codecFor returns the codec of a negotiated subprotocol
+func (h *Hub) codecFor(subprotocol string) Codec {
+	for _, codec := range h.config.Codecs {
+		if codec.Name() == subprotocol {
+			return codec
+		}
+	}
+	return JSONCodec
+}
+
+// configureCompression applies the compression settings to a new connection
+func (h *Hub) configureCompression(conn *websocket.Conn) {
+	if !h.config.Compression {
+		return
+	}
+	conn.EnableWriteCompression(true)
+	if err := conn.SetCompressionLevel(h.config.CompressionLevel); err != nil {
+		conn.SetCompressionLevel(flate.DefaultCompression)
+	}
+}
+
+// Codec returns the client's codec. SSE clients always use JSON.
+func (c *Client) Codec() Codec {
+	if c.codec == nil {
+		return JSONCodec
+	}
+	return c.codec
+}
+
+// frameType returns the websocket frame type for the client's messages
+func (c *Client) frameType() int {
+	if c.Codec().Binary() || c.hub.config.BinaryFrames {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// writeFrame encodes a message with the client's codec and writes it as
+// one frame, compressing it if it is large enough
+func (c *Client) writeFrame(message []byte) error {
+	data, err := c.Codec().Encode(message)
+	if err != nil {
+		// Skip messages the codec can't represent, e.g. plain text for
+		// MessagePack, rather than dropping the connection
+		log.Printf("Client %s: failed to encode message as %s: %v", c.id, c.Codec().Name(), err)
+		c.hub.metrics.dropped.Inc()
+		return nil
+	}
+
+	if c.hub.config.Compression {
+		c.conn.EnableWriteCompression(len(data) >= c.hub.config.CompressionThreshold)
+	}
+	return c.conn.WriteMessage(c.frameType(), data)
+}
@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialCodecHub starts a hub with the options and connects a client with
+// the dialer
+func dialCodecHub(t *testing.T, dialer *websocket.Dialer, options ...Option) (*Hub, *websocket.Conn) {
+	t.Helper()
+
+	hub := NewHub(NewConfig(append([]Option{WithAllowedOrigins([]string{"*"})}, options...)...))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx)
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeWS))
+	t.Cleanup(server.Close)
+
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.Eventually(t, func() bool { return hub.GetConnectedClients() == 1 }, time.Second, 10*time.Millisecond)
+	return hub, conn
+}
+
+// broadcastMessage sends a message of the type to every client
+func broadcastMessage(t *testing.T, hub *Hub, msgType string, data interface{}) {
+	t.Helper()
+
+	message, err := json.Marshal(Message{Type: msgType, Data: data})
+	require.NoError(t, err)
+	hub.BroadcastToAll(message)
+}
+
+func TestCodecDefaultsToJSON(t *testing.T) {
+	dialer := &websocket.Dialer{Subprotocols: []string{"msgpack"}}
+	hub, conn := dialCodecHub(t, dialer)
+
+	assert.Equal(t, "", conn.Subprotocol())
+
+	broadcastMessage(t, hub, "news", "hello")
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	frameType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, frameType)
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, "news", msg.Type)
+}
+
+func TestCodecMessagePack(t *testing.T) {
+	dialer := &websocket.Dialer{Subprotocols: []string{"msgpack"}}
+	hub, conn := dialCodecHub(t, dialer, WithCodecs(JSONCodec, MessagePackCodec))
+
+	assert.Equal(t, "msgpack", conn.Subprotocol())
+
+	t.Run("sends binary MessagePack frames", func(t *testing.T) {
+		broadcastMessage(t, hub, "news", map[string]interface{}{"title": "hello"})
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		frameType, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.BinaryMessage, frameType)
+
+		decoded, err := msgpackToJSON(data)
+		require.NoError(t, err)
+
+		var msg Message
+		require.NoError(t, json.Unmarshal(decoded, &msg))
+		assert.Equal(t, "news", msg.Type)
+		assert.Equal(t, map[string]interface{}{"title": "hello"}, msg.Data)
+	})
+
+	t.Run("decodes MessagePack from the client", func(t *testing.T) {
+		packed, err := jsonToMsgpack([]byte(`{"type":"join_room","data":"lobby"}`))
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, packed))
+
+		assert.Eventually(t, func() bool { return hub.GetRoomClients("lobby") == 1 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestCodecJSONSubprotocol(t *testing.T) {
+	dialer := &websocket.Dialer{Subprotocols: []string{"json"}}
+	_, conn := dialCodecHub(t, dialer, WithCodecs(JSONCodec, MessagePackCodec))
+
+	assert.Equal(t, "json", conn.Subprotocol())
+}
+
+func TestBinaryFrames(t *testing.T) {
+	hub, conn := dialCodecHub(t, websocket.DefaultDialer, WithBinaryFrames())
+
+	broadcastMessage(t, hub, "news", "hello")
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	frameType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, frameType)
+	assert.Contains(t, string(data), `"type":"news"`)
+}
+
+func TestCompression(t *testing.T) {
+	dialer := &websocket.Dialer{EnableCompression: true}
+	hub, conn := dialCodecHub(t, dialer, WithCompression(9, 16))
+
+	text := strings.Repeat("compressible ", 100)
+	broadcastMessage(t, hub, "news", text)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	var msg Message
+	require.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, text, msg.Data)
+}
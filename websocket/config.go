@@ -3,6 +3,9 @@ package websocket
 import (
 	"net/http"
 	"time"
+
+	"github.com/jimmitjoo/tjo/api"
+	"github.com/jimmitjoo/tjo/logging"
 )
 
 type Config struct {
@@ -21,22 +24,115 @@ type Config struct {
 	// using session cookies, JWT tokens, or other secure authentication mechanisms.
 	AuthenticateConnection func(r *http.Request) (userID string, err error)
 
+	// Backplane relays messages and client counts between instances.
+	// If nil, the hub only reaches clients connected to this instance.
+	Backplane Backplane
+
+	// SyncInterval is how often the hub publishes a snapshot of its clients
+	// and rooms on the backplane. Instances not heard from for three
+	// intervals are dropped from the counts.
+	SyncInterval time.Duration
+
+	// Presence sends presence_join, presence_leave and presence_update
+	// messages to room members and the member list to joining clients
+	Presence bool
+
+	// RoomPolicies authorize client joins and room messages by room
+	// pattern. See Hub.AuthorizeRoom.
+	RoomPolicies []RoomPattern
+
+	// StrictRooms denies clients rooms that match no policy
+	StrictRooms bool
+
+	// AuthorizeBroadcast decides whether a client may send broadcast
+	// messages to every client. If nil, no client may.
+	AuthorizeBroadcast func(*Client) bool
+
+	// History retains room messages, so clients can replay what they
+	// missed with a resume message. If nil, nothing is retained.
+	History History
+
+	// HistoryRooms are the room patterns to retain messages for, as for
+	// room policies. If empty, all rooms are retained.
+	HistoryRooms []string
+
+	// SSEHeartbeat is how often idle Server-Sent Events streams get a
+	// comment, so proxies don't close them
+	SSEHeartbeat time.Duration
+
+	// ClientRateLimiter limits the messages each connection may send,
+	// keyed by client ID. Messages over the limit are answered with a
+	// rate_limited error. If nil, connections are not limited.
+	ClientRateLimiter api.RateLimiter
+
+	// UserRateLimiter limits the messages each user may send across all
+	// their connections, keyed by user ID
+	UserRateLimiter api.RateLimiter
+
+	// SlowConsumer is what happens when a client's send buffer is full.
+	// Defaults to SlowConsumerDisconnect.
+	SlowConsumer SlowConsumerPolicy
+
+	// Metrics registers the hub's delivery metrics, such as dropped
+	// messages and send queue depth. If nil, metrics are not exported.
+	Metrics *logging.MetricRegistry
+
+	// MetricsInterval is how often the queue depth metrics are sampled
+	MetricsInterval time.Duration
+
+	// Codecs are the wire formats clients may request as subprotocols, in
+	// order of preference. Clients requesting none get JSON.
+	Codecs []Codec
+
+	// BinaryFrames sends JSON messages in binary frames instead of text
+	BinaryFrames bool
+
+	// Compression negotiates permessage-deflate with clients that support it
+	Compression bool
+
+	// CompressionLevel is the flate level, from 1 (fastest) to 9 (smallest)
+	CompressionLevel int
+
+	// CompressionThreshold is the size in bytes below which messages are
+	// sent uncompressed, as compressing them costs more than it saves
+	CompressionThreshold int
+
 	OnConnect    func(*Client)
 	OnDisconnect func(*Client)
-	OnMessage    func(*Client, *Message)
-	OnJoinRoom   func(*Client, string)
-	OnLeaveRoom  func(*Client, string)
+
+	// OnMessage is called for every message received from a client,
+	// before its handler.
+	//
+	// Deprecated: register handlers for message types with Hub.HandleFunc
+	// or Handle instead.
+	OnMessage func(*Client, *Message)
+
+	OnJoinRoom  func(*Client, string)
+	OnLeaveRoom func(*Client, string)
+}
+
+// RoomPattern is a room policy and the rooms it applies to
+type RoomPattern struct {
+	Pattern string
+	Policy  RoomPolicy
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		WriteWait:         10 * time.Second,
-		PongWait:          60 * time.Second,
-		PingPeriod:        (60 * time.Second * 9) / 10,
-		MaxMessageSize:    512,
-		BroadcastBuffer:   256,
-		RoomMessageBuffer: 256,
-		ClientBuffer:      256,
+		WriteWait:            10 * time.Second,
+		PongWait:             60 * time.Second,
+		PingPeriod:           (60 * time.Second * 9) / 10,
+		MaxMessageSize:       512,
+		BroadcastBuffer:      256,
+		RoomMessageBuffer:    256,
+		ClientBuffer:         256,
+		SyncInterval:         15 * time.Second,
+		SSEHeartbeat:         15 * time.Second,
+		SlowConsumer:         SlowConsumerDisconnect,
+		MetricsInterval:      5 * time.Second,
+		Codecs:               []Codec{JSONCodec},
+		CompressionLevel:     1,
+		CompressionThreshold: 256,
 	}
 }
 
@@ -81,6 +177,10 @@ func WithOnDisconnect(handler func(*Client)) Option {
 	}
 }
 
+// WithOnMessage sets a callback for every message received from a client.
+//
+// Deprecated: register handlers for message types with Hub.HandleFunc or
+// Handle instead.
 func WithOnMessage(handler func(*Client, *Message)) Option {
 	return func(c *Config) {
 		c.OnMessage = handler
@@ -139,6 +239,120 @@ func WithAuthenticateConnection(fn func(r *http.Request) (string, error)) Option
 	}
 }
 
+// WithBackplane connects the hub to other instances through a backplane,
+// such as NewRedisBackplane, so broadcasts and room messages reach every
+// client in the cluster.
+func WithBackplane(b Backplane) Option {
+	return func(c *Config) {
+		c.Backplane = b
+	}
+}
+
+// WithPresence enables presence messages in rooms. See Hub.Presence.
+func WithPresence() Option {
+	return func(c *Config) {
+		c.Presence = true
+	}
+}
+
+// WithRoomPolicy authorizes client joins and room messages for the rooms
+// matching pattern, e.g. "private-user.{id}". See Hub.AuthorizeRoom.
+func WithRoomPolicy(pattern string, policy RoomPolicy) Option {
+	return func(c *Config) {
+		c.RoomPolicies = append(c.RoomPolicies, RoomPattern{Pattern: pattern, Policy: policy})
+	}
+}
+
+// WithStrictRooms denies clients every room no policy matches
+func WithStrictRooms() Option {
+	return func(c *Config) {
+		c.StrictRooms = true
+	}
+}
+
+// WithAuthorizeBroadcast sets who may send broadcast messages to every
+// client. Without it, client broadcasts are denied.
+func WithAuthorizeBroadcast(fn func(*Client) bool) Option {
+	return func(c *Config) {
+		c.AuthorizeBroadcast = fn
+	}
+}
+
+// WithHistory retains room messages, adding a sequence ID to each, so
+// reconnecting clients can resume where they left off. Pass room patterns
+// to retain only some rooms, e.g. "chat:*".
+func WithHistory(history History, rooms ...string) Option {
+	return func(c *Config) {
+		c.History = history
+		c.HistoryRooms = rooms
+	}
+}
+
+// WithSSEHeartbeat sets how often idle Server-Sent Events streams get a
+// heartbeat comment
+func WithSSEHeartbeat(interval time.Duration) Option {
+	return func(c *Config) {
+		c.SSEHeartbeat = interval
+	}
+}
+
+// WithClientRateLimit limits the messages each connection may send, e.g.
+// api.NewTokenBucket(10, 20, time.Second) for 10 per second with bursts of 20
+func WithClientRateLimit(limiter api.RateLimiter) Option {
+	return func(c *Config) {
+		c.ClientRateLimiter = limiter
+	}
+}
+
+// WithUserRateLimit limits the messages each user may send across all
+// their connections. Use api.NewRedisTokenBucket to limit across instances.
+func WithUserRateLimit(limiter api.RateLimiter) Option {
+	return func(c *Config) {
+		c.UserRateLimiter = limiter
+	}
+}
+
+// WithSlowConsumerPolicy sets what happens when a client's send buffer is full
+func WithSlowConsumerPolicy(policy SlowConsumerPolicy) Option {
+	return func(c *Config) {
+		c.SlowConsumer = policy
+	}
+}
+
+// WithMetrics registers the hub's delivery metrics with a registry, e.g.
+// logging.GetDefaultRegistry()
+func WithMetrics(registry *logging.MetricRegistry) Option {
+	return func(c *Config) {
+		c.Metrics = registry
+	}
+}
+
+// WithCodecs sets the wire formats clients may request through the
+// Sec-WebSocket-Protocol header, e.g. WithCodecs(JSONCodec, MessagePackCodec).
+// Clients requesting no subprotocol get JSON.
+func WithCodecs(codecs ...Codec) Option {
+	return func(c *Config) {
+		c.Codecs = codecs
+	}
+}
+
+// WithBinaryFrames sends JSON messages in binary instead of text frames
+func WithBinaryFrames() Option {
+	return func(c *Config) {
+		c.BinaryFrames = true
+	}
+}
+
+// WithCompression enables permessage-deflate compression at the given flate
+// level (1-9) for messages of at least threshold bytes
+func WithCompression(level, threshold int) Option {
+	return func(c *Config) {
+		c.Compression = true
+		c.CompressionLevel = level
+		c.CompressionThreshold = threshold
+	}
+}
+
 func NewConfig(options ...Option) *Config {
 	config := DefaultConfig()
 	for _, option := range options {
@@ -27,33 +27,6 @@ func ExampleChatServer() {
 		WithOnDisconnect(func(client *Client) {
 			log.Printf("User %s disconnected", client.GetUserID())
 		}),
-		WithOnMessage(func(client *Client, msg *Message) {
-			log.Printf("Message from %s: %s", client.GetUserID(), msg.Type)
-			
-			if msg.Type == "chat_message" {
-				chatMsg := ChatMessage{
-					Username: client.GetUserID(),
-					Message:  msg.Data.(string),
-					Room:     msg.Room,
-					Time:     time.Now(),
-				}
-				
-				response := Message{
-					Type:      "chat_message",
-					Data:      chatMsg,
-					Room:      msg.Room,
-					Timestamp: time.Now(),
-				}
-				
-				responseBytes, _ := json.Marshal(response)
-				
-				if msg.Room != "" {
-					client.hub.BroadcastToRoom(msg.Room, responseBytes, nil)
-				} else {
-					client.hub.BroadcastToAll(responseBytes)
-				}
-			}
-		}),
 		WithOnJoinRoom(func(client *Client, room string) {
 			log.Printf("User %s joined room %s", client.GetUserID(), room)
 			
@@ -91,6 +64,34 @@ func ExampleChatServer() {
 	hub := NewHub(config)
 	SetDefaultHub(hub)
 	
+	Handle(hub, "chat_message", func(client *Client, msg *Message, text string) error {
+		if msg.Room == "" {
+			return NewClientError("invalid_data", "chat_message requires room field")
+		}
+		if !hub.CanPublishToRoom(client, msg.Room) {
+			return NewClientError("forbidden", "not allowed to chat in "+msg.Room)
+		}
+		
+		response := Message{
+			Type: "chat_message",
+			Data: ChatMessage{
+				Username: client.GetUserID(),
+				Message:  text,
+				Room:     msg.Room,
+				Time:     time.Now(),
+			},
+			Room:      msg.Room,
+			Timestamp: time.Now(),
+		}
+		
+		responseBytes, err := json.Marshal(response)
+		if err != nil {
+			return err
+		}
+		hub.BroadcastToRoom(msg.Room, responseBytes, nil)
+		return nil
+	})
+	
 	ctx := context.Background()
 	go hub.Run(ctx)
 	
@@ -174,6 +175,12 @@ func ExampleNotificationServer() {
 			userID := client.GetUserID()
 			client.hub.JoinRoom(client, "notifications_"+userID)
 		}),
+		// Clients may not join other users' notification rooms
+		WithRoomPolicy("notifications_{id}", RoomPolicy{
+			Join: func(client *Client, room string, params map[string]string) bool {
+				return client.GetUserID() == params["id"]
+			},
+		}),
 	)
 	
 	hub := NewHub(config)
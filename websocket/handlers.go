@@ -0,0 +1,229 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// HandlerFunc handles a message type sent by clients. Returning a
+// ClientError sends it to the client as an error message; other errors are
+// logged and the client gets an internal_error.
+type HandlerFunc func(c *Client, msg *Message) error
+
+// ClientError is an error reported to the client that sent a message.
+// It is sent as {"type": "error", "data": {"code": ..., "message": ...}}.
+type ClientError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewClientError creates an error for the client, e.g.
+// NewClientError("not_found", "document does not exist")
+func NewClientError(code, message string) *ClientError {
+	return &ClientError{Code: code, Message: message}
+}
+
+func (e *ClientError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Message types handled by the hub unless replaced or removed
+const (
+	MessageJoinRoom    = "join_room"
+	MessageLeaveRoom   = "leave_room"
+	MessageSetPresence = "set_presence"
+	MessageRoom        = "room_message"
+	MessageBroadcast   = "broadcast"
+	MessageError       = "error"
+)
+
+// HandleFunc registers the handler for a message type, replacing any
+// handler registered before, including the built-in ones.
+func (h *Hub) HandleFunc(msgType string, handler HandlerFunc) {
+	h.handlersMu.Lock()
+	h.handlers[msgType] = handler
+	h.handlersMu.Unlock()
+}
+
+// RemoveHandler unregisters the handler for a message type, e.g. to stop
+// clients from sending broadcast messages
+func (h *Hub) RemoveHandler(msgType string) {
+	h.handlersMu.Lock()
+	delete(h.handlers, msgType)
+	h.handlersMu.Unlock()
+}
+
+// Handle registers a handler for a message type whose data is decoded into T.
+// Data that does not decode is answered with an invalid_data error.
+//
+//	type ChatData struct {
+//	    Text string `json:"text"`
+//	}
+//
+//	websocket.Handle(hub, "chat", func(c *websocket.Client, msg *websocket.Message, data ChatData) error {
+//	    if data.Text == "" {
+//	        return websocket.NewClientError("empty_message", "text is required")
+//	    }
+//	    ...
+//	})
+func Handle[T any](h *Hub, msgType string, handler func(c *Client, msg *Message, data T) error) {
+	h.HandleFunc(msgType, func(c *Client, msg *Message) error {
+		var data T
+		if err := msg.Decode(&data); err != nil {
+			return NewClientError("invalid_data", fmt.Sprintf("invalid data for %s: %v", msgType, err))
+		}
+		return handler(c, msg, data)
+	})
+}
+
+func (h *Hub) handler(msgType string) HandlerFunc {
+	h.handlersMu.RLock()
+	defer h.handlersMu.RUnlock()
+	return h.handlers[msgType]
+}
+
+// Decode decodes the message data into v. Messages received from clients
+// are decoded from the JSON as sent.
+func (m *Message) Decode(v interface{}) error {
+	raw := m.raw
+	if raw == nil {
+		var err error
+		if raw, err = json.Marshal(m.Data); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// parseMessage decodes a message received from a client, keeping its raw data
+func parseMessage(data []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	msg.raw = raw.Data
+	return &msg, nil
+}
+
+// dispatch handles a message received from the client
+func (c *Client) dispatch(msg *Message) {
+	msg.UserID = c.userID
+	msg.Timestamp = time.Now()
+
+	if c.hub.config.OnMessage != nil {
+		c.hub.config.OnMessage(c, msg)
+	}
+	c.handleMessage(msg)
+}
+
+// handleMessage passes a client's message to the handler for its type
+func (c *Client) handleMessage(msg *Message) {
+	handler := c.hub.handler(msg.Type)
+	if handler == nil {
+		log.Printf("Client %s: unknown message type: %s", c.id, msg.Type)
+		// Types handled by the deprecated OnMessage callback are not errors
+		if c.hub.config.OnMessage == nil {
+			c.sendError(msg, NewClientError("unknown_type", "unknown message type "+msg.Type))
+		}
+		return
+	}
+
+	if err := handler(c, msg); err != nil {
+		var clientErr *ClientError
+		if !errors.As(err, &clientErr) {
+			log.Printf("Client %s: %s handler failed: %v", c.id, msg.Type, err)
+			clientErr = NewClientError("internal_error", "the message could not be handled")
+		}
+		c.sendError(msg, clientErr)
+	}
+}
+
+// sendError tells the client its message failed
+func (c *Client) sendError(msg *Message, clientErr *ClientError) {
+	messageBytes, err := json.Marshal(Message{
+		Type: MessageError,
+		Data: map[string]string{
+			"code":    clientErr.Code,
+			"message": clientErr.Message,
+			"type":    msg.Type,
+		},
+		Room:      msg.Room,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	if err := c.Send(messageBytes); err != nil {
+		log.Printf("Client %s: failed to send error: %v", c.id, err)
+	}
+}
+
+// registerBuiltinHandlers registers the handlers for the hub's own
+// message types
+func (h *Hub) registerBuiltinHandlers() {
+	Handle(h, MessageJoinRoom, func(c *Client, msg *Message, roomName string) error {
+		if !h.CanJoinRoom(c, roomName) {
+			return NewClientError("forbidden", "not allowed to join room "+roomName)
+		}
+		h.JoinRoom(c, roomName)
+		return nil
+	})
+
+	Handle(h, MessageLeaveRoom, func(c *Client, msg *Message, roomName string) error {
+		h.LeaveRoom(c, roomName)
+		return nil
+	})
+
+	Handle(h, MessageSetPresence, func(c *Client, msg *Message, state string) error {
+		c.SetPresence(state)
+		return nil
+	})
+
+	Handle(h, MessageResume, func(c *Client, msg *Message, after uint64) error {
+		if msg.Room == "" {
+			return NewClientError("invalid_data", "resume requires room field")
+		}
+		if !h.CanJoinRoom(c, msg.Room) {
+			return NewClientError("forbidden", "not allowed to join room "+msg.Room)
+		}
+		_, err := h.Resume(c, msg.Room, after)
+		return err
+	})
+
+	h.HandleFunc(MessageRoom, func(c *Client, msg *Message) error {
+		if msg.Room == "" {
+			return NewClientError("invalid_data", "room_message requires room field")
+		}
+		if !h.CanPublishToRoom(c, msg.Room) {
+			return NewClientError("forbidden", "not allowed to publish to room "+msg.Room)
+		}
+		msg.Seq = 0
+		messageBytes, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		h.BroadcastToRoom(msg.Room, messageBytes, c)
+		return nil
+	})
+
+	h.HandleFunc(MessageBroadcast, func(c *Client, msg *Message) error {
+		if !h.CanBroadcast(c) {
+			return NewClientError("forbidden", "not allowed to broadcast")
+		}
+		messageBytes, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		h.BroadcastToAll(messageBytes)
+		return nil
+	})
+}
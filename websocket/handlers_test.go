@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendFromClient(t *testing.T, c *Client, data string) {
+	t.Helper()
+	msg, err := parseMessage([]byte(data))
+	require.NoError(t, err)
+	msg.UserID = c.userID
+	c.handleMessage(msg)
+}
+
+func errorData(t *testing.T, msg Message) map[string]interface{} {
+	t.Helper()
+	require.Equal(t, MessageError, msg.Type)
+	return msg.Data.(map[string]interface{})
+}
+
+func TestHandleTyped(t *testing.T) {
+	type move struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+
+	hub := startHub(t)
+	client := newTestClient(hub, "c1", "u1")
+
+	var got move
+	Handle(hub, "move", func(c *Client, msg *Message, data move) error {
+		got = data
+		return c.SendMessage("moved", data)
+	})
+
+	sendFromClient(t, client, `{"type":"move","data":{"x":3,"y":4}}`)
+	assert.Equal(t, move{X: 3, Y: 4}, got)
+	assert.Equal(t, "moved", receiveMessage(t, client).Type)
+
+	sendFromClient(t, client, `{"type":"move","data":"north"}`)
+	assert.Equal(t, "invalid_data", errorData(t, receiveMessage(t, client))["code"])
+}
+
+func TestHandlerErrors(t *testing.T) {
+	hub := startHub(t)
+	client := newTestClient(hub, "c1", "u1")
+
+	hub.HandleFunc("save", func(c *Client, msg *Message) error {
+		return NewClientError("read_only", "document is read-only")
+	})
+	hub.HandleFunc("crash", func(c *Client, msg *Message) error {
+		return errors.New("database is down")
+	})
+
+	sendFromClient(t, client, `{"type":"save","room":"doc"}`)
+	msg := receiveMessage(t, client)
+	data := errorData(t, msg)
+	assert.Equal(t, "read_only", data["code"])
+	assert.Equal(t, "document is read-only", data["message"])
+	assert.Equal(t, "save", data["type"])
+	assert.Equal(t, "doc", msg.Room)
+
+	sendFromClient(t, client, `{"type":"crash"}`)
+	data = errorData(t, receiveMessage(t, client))
+	assert.Equal(t, "internal_error", data["code"])
+	assert.NotContains(t, data["message"], "database")
+
+	sendFromClient(t, client, `{"type":"nope"}`)
+	assert.Equal(t, "unknown_type", errorData(t, receiveMessage(t, client))["code"])
+}
+
+func TestBuiltinHandlersAuthorize(t *testing.T) {
+	hub := startHub(t, WithRoomPolicy("private-user.{id}", RoomPolicy{
+		Join: func(c *Client, room string, params map[string]string) bool {
+			return c.GetUserID() == params["id"]
+		},
+	}))
+	alice := newTestClient(hub, "c1", "alice")
+	bob := newTestClient(hub, "c2", "bob")
+
+	sendFromClient(t, alice, `{"type":"join_room","data":"private-user.alice"}`)
+	assert.Contains(t, alice.GetRooms(), "private-user.alice")
+
+	sendFromClient(t, bob, `{"type":"join_room","data":"private-user.alice"}`)
+	msg := receiveMessage(t, bob)
+	assert.Equal(t, "forbidden", errorData(t, msg)["code"])
+	assert.Empty(t, bob.GetRooms())
+
+	sendFromClient(t, bob, `{"type":"room_message","room":"private-user.alice","data":"hi"}`)
+	assert.Equal(t, "forbidden", errorData(t, receiveMessage(t, bob))["code"])
+	assertNoMessage(t, alice)
+
+	sendFromClient(t, bob, `{"type":"join_room","data":42}`)
+	assert.Equal(t, "invalid_data", errorData(t, receiveMessage(t, bob))["code"])
+}
+
+func TestReplaceAndRemoveHandlers(t *testing.T) {
+	hub := startHub(t)
+	client := newTestClient(hub, "c1", "u1")
+
+	var joined string
+	Handle(hub, MessageJoinRoom, func(c *Client, msg *Message, room string) error {
+		joined = room
+		return nil
+	})
+	sendFromClient(t, client, `{"type":"join_room","data":"lobby"}`)
+	assert.Equal(t, "lobby", joined)
+	assert.Empty(t, client.GetRooms())
+
+	hub.RemoveHandler(MessageBroadcast)
+	sendFromClient(t, client, `{"type":"broadcast","data":"hi"}`)
+	assert.Equal(t, "unknown_type", errorData(t, receiveMessage(t, client))["code"])
+}
+
+func TestClientBroadcast(t *testing.T) {
+	hub := startHub(t)
+	client := newTestClient(hub, "c1", "u1")
+	other := newTestClient(hub, "c2", "u2")
+	hub.register <- other
+
+	// Denied unless AuthorizeBroadcast allows it
+	sendFromClient(t, client, `{"type":"broadcast","data":"hi"}`)
+	assert.Equal(t, "forbidden", errorData(t, receiveMessage(t, client))["code"])
+	assertNoMessage(t, other)
+
+	hub = startHub(t, WithAuthorizeBroadcast(func(c *Client) bool { return c.GetUserID() == "u1" }))
+	client = newTestClient(hub, "c1", "u1")
+	other = newTestClient(hub, "c2", "u2")
+	hub.register <- other
+
+	sendFromClient(t, client, `{"type":"broadcast","data":"hi"}`)
+	assert.Equal(t, MessageBroadcast, receiveMessage(t, other).Type)
+}
+
+func TestMessageDecode(t *testing.T) {
+	msg := &Message{Data: map[string]interface{}{"text": "hi"}}
+	var data struct {
+		Text string `json:"text"`
+	}
+	require.NoError(t, msg.Decode(&data))
+	assert.Equal(t, "hi", data.Text)
+}
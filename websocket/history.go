@@ -0,0 +1,315 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults of the history implementations
+const (
+	// DefaultHistoryTTL is how long a room's history is kept after its
+	// last message
+	DefaultHistoryTTL = 24 * time.Hour
+
+	// DefaultHistoryMaxRooms is the number of rooms MemoryHistory keeps
+	DefaultHistoryMaxRooms = 10000
+)
+
+// ErrHistoryReset is returned by History.Since when the sequence ID is past
+// the room's last one: the room's history expired and its sequence IDs
+// started over
+var ErrHistoryReset = errors.New("room history was reset")
+
+// History retains recent room messages so reconnecting clients can replay
+// what they missed. Each room has its own sequence of IDs, starting at 1.
+type History interface {
+	// Append stores a room message and returns its sequence ID
+	Append(ctx context.Context, room string, message []byte) (uint64, error)
+
+	// Since returns the retained messages of a room with a sequence ID
+	// greater than after, oldest first, or ErrHistoryReset if after is
+	// greater than the room's last sequence ID
+	Since(ctx context.Context, room string, after uint64) ([]HistoryEntry, error)
+}
+
+// HistoryEntry is a retained room message
+type HistoryEntry struct {
+	Seq     uint64
+	Message []byte
+}
+
+// Message types of the resume protocol
+const (
+	// MessageResume asks to join a room and replay the messages after a
+	// sequence ID: {"type": "resume", "room": "chat", "data": 41}
+	MessageResume = "resume"
+
+	// MessageResumed follows the replayed messages
+	MessageResumed = "resumed"
+)
+
+// ResumeResult is the data of a resumed message
+type ResumeResult struct {
+	// LastSeq is the sequence ID of the last replayed message, or the
+	// requested one if nothing was missed
+	LastSeq uint64 `json:"last_seq"`
+
+	// Replayed is the number of messages replayed
+	Replayed int `json:"replayed"`
+
+	// Complete is false when messages were missed that are no longer
+	// retained, so the client should reload the room's state
+	Complete bool `json:"complete"`
+}
+
+// retainsRoom reports whether the hub keeps history for a room
+func (h *Hub) retainsRoom(roomName string) bool {
+	if h.config.History == nil {
+		return false
+	}
+	if len(h.historyRooms) == 0 {
+		return true
+	}
+	for _, rule := range h.historyRooms {
+		if _, ok := rule.match(roomName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// record appends a room message to the history and returns it with its
+// sequence ID. The message is returned unchanged if it can't be stored.
+func (h *Hub) record(roomName string, message []byte) []byte {
+	seq, err := h.config.History.Append(context.Background(), roomName, message)
+	if err != nil {
+		log.Printf("WebSocket history: failed to store message for room %s: %v", roomName, err)
+		return message
+	}
+	return withSeq(message, seq)
+}
+
+// RoomHistory returns a room's retained messages after a sequence ID, with
+// their sequence IDs set. It returns nothing for rooms without history.
+func (h *Hub) RoomHistory(ctx context.Context, roomName string, after uint64) ([]HistoryEntry, error) {
+	if !h.retainsRoom(roomName) {
+		return nil, nil
+	}
+
+	entries, err := h.config.History.Since(ctx, roomName, after)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Message = withSeq(entries[i].Message, entries[i].Seq)
+	}
+	return entries, nil
+}
+
+// Resume joins a client to a room, if it isn't in it, and replays the
+// messages after the sequence ID it saw last, followed by a resumed
+// message. Messages sent while replaying may arrive twice and out of
+// order, so clients should skip sequence IDs they have seen.
+func (h *Hub) Resume(c *Client, roomName string, after uint64) (ResumeResult, error) {
+	c.mu.RLock()
+	member := c.rooms[roomName]
+	c.mu.RUnlock()
+	if !member {
+		h.JoinRoom(c, roomName)
+	}
+
+	result := ResumeResult{LastSeq: after, Complete: true}
+	entries, err := h.RoomHistory(context.Background(), roomName, after)
+	if errors.Is(err, ErrHistoryReset) {
+		// The client's sequence IDs are from before the room expired, so
+		// it starts over with what is retained now
+		result = ResumeResult{}
+		entries, err = h.RoomHistory(context.Background(), roomName, 0)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if len(entries) > 0 && entries[0].Seq > result.LastSeq+1 {
+		// The messages in between have been dropped from the history
+		result.Complete = false
+	}
+	for _, entry := range entries {
+		if err := c.Send(entry.Message); err != nil {
+			result.Complete = false
+			break
+		}
+		result.LastSeq = entry.Seq
+		result.Replayed++
+	}
+
+	return result, c.sendRoomMessage(MessageResumed, roomName, result)
+}
+
+// withSeq adds a seq field to a JSON object message. Other messages are
+// returned unchanged.
+func withSeq(message []byte, seq uint64) []byte {
+	trimmed := bytes.TrimSpace(message)
+	if len(trimmed) < 2 || trimmed[0] != '{' || trimmed[len(trimmed)-1] != '}' {
+		return message
+	}
+
+	body := bytes.TrimSpace(trimmed[1 : len(trimmed)-1])
+	out := make([]byte, 0, len(trimmed)+32)
+	out = append(out, '{')
+	if len(body) > 0 {
+		out = append(out, body...)
+		out = append(out, ',')
+	}
+	// Added last, so it wins over a seq field sent by a client
+	out = append(out, `"seq":`...)
+	out = strconv.AppendUint(out, seq, 10)
+	return append(out, '}')
+}
+
+// MemoryHistory keeps the last messages of every room in memory. Each
+// instance has its own history, so use RedisHistory when running several.
+// Rooms are dropped DefaultHistoryTTL after their last message, and the
+// least recently active room is dropped when DefaultHistoryMaxRooms are
+// kept; see WithTTL and WithMaxRooms.
+type MemoryHistory struct {
+	mu       sync.Mutex
+	size     int
+	ttl      time.Duration
+	maxRooms int
+	rooms    map[string]*ringBuffer
+}
+
+// ringBuffer holds the last messages of a room
+type ringBuffer struct {
+	seq     uint64 // Sequence ID of the last message
+	entries []HistoryEntry
+	next    int       // Where the next message goes once full
+	updated time.Time // When the last message was added
+}
+
+// NewMemoryHistory creates a history keeping the last size messages per room
+func NewMemoryHistory(size int) *MemoryHistory {
+	if size <= 0 {
+		size = 100
+	}
+	return &MemoryHistory{
+		size:     size,
+		ttl:      DefaultHistoryTTL,
+		maxRooms: DefaultHistoryMaxRooms,
+		rooms:    make(map[string]*ringBuffer),
+	}
+}
+
+// WithTTL sets how long a room is kept after its last message. Zero keeps
+// rooms until they are dropped for new ones.
+func (m *MemoryHistory) WithTTL(ttl time.Duration) *MemoryHistory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ttl = ttl
+	return m
+}
+
+// WithMaxRooms sets the number of rooms kept. Zero keeps every room.
+func (m *MemoryHistory) WithMaxRooms(n int) *MemoryHistory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxRooms = n
+	return m
+}
+
+// Append stores a room message, dropping the room's oldest if it is full
+func (m *MemoryHistory) Append(ctx context.Context, room string, message []byte) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	buf := m.room(room, now)
+	if buf == nil {
+		m.makeRoom(now)
+		buf = &ringBuffer{entries: make([]HistoryEntry, 0, m.size)}
+		m.rooms[room] = buf
+	}
+
+	buf.updated = now
+	buf.seq++
+	entry := HistoryEntry{Seq: buf.seq, Message: append([]byte(nil), message...)}
+	if len(buf.entries) < m.size {
+		buf.entries = append(buf.entries, entry)
+	} else {
+		buf.entries[buf.next] = entry
+		buf.next = (buf.next + 1) % m.size
+	}
+	return buf.seq, nil
+}
+
+// Since returns the room's messages after a sequence ID
+func (m *MemoryHistory) Since(ctx context.Context, room string, after uint64) ([]HistoryEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buf := m.room(room, time.Now())
+	if buf == nil {
+		if after > 0 {
+			return nil, ErrHistoryReset
+		}
+		return nil, nil
+	}
+	if after > buf.seq {
+		return nil, ErrHistoryReset
+	}
+
+	var entries []HistoryEntry
+	for i := range buf.entries {
+		entry := buf.entries[(buf.next+i)%len(buf.entries)]
+		if entry.Seq > after {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// room returns a room's messages, dropping them if they have expired
+func (m *MemoryHistory) room(room string, now time.Time) *ringBuffer {
+	buf, ok := m.rooms[room]
+	if !ok {
+		return nil
+	}
+	if m.expired(buf, now) {
+		delete(m.rooms, room)
+		return nil
+	}
+	return buf
+}
+
+func (m *MemoryHistory) expired(buf *ringBuffer, now time.Time) bool {
+	return m.ttl > 0 && now.Sub(buf.updated) > m.ttl
+}
+
+// makeRoom drops expired rooms when the history is full and, if it still
+// is, the least recently active room
+func (m *MemoryHistory) makeRoom(now time.Time) {
+	if m.maxRooms <= 0 || len(m.rooms) < m.maxRooms {
+		return
+	}
+
+	var oldest string
+	var oldestAt time.Time
+	for name, buf := range m.rooms {
+		if m.expired(buf, now) {
+			delete(m.rooms, name)
+			continue
+		}
+		if oldestAt.IsZero() || buf.updated.Before(oldestAt) {
+			oldest, oldestAt = name, buf.updated
+		}
+	}
+	if len(m.rooms) >= m.maxRooms {
+		delete(m.rooms, oldest)
+	}
+}
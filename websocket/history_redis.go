@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// appendScript assigns the next sequence ID of a room and adds the message
+// to the room's stream under the ID 0-<seq>, trimming it to the size. Both
+// keys expire ARGV[3] milliseconds after the last message, if set.
+var appendScript = redis.NewScript(2, `
+local seq = redis.call('INCR', KEYS[2])
+redis.call('XADD', KEYS[1], 'MAXLEN', ARGV[2], '0-' .. seq, 'm', ARGV[1])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+	redis.call('PEXPIRE', KEYS[2], ARGV[3])
+end
+return seq
+`)
+
+// sinceScript reads a room's stream after a sequence ID, or returns nil if
+// the ID is past the room's last one
+var sinceScript = redis.NewScript(2, `
+local seq = tonumber(redis.call('GET', KEYS[2]) or '0')
+if tonumber(ARGV[1]) > seq then
+	return false
+end
+return redis.call('XRANGE', KEYS[1], '(0-' .. ARGV[1], '+')
+`)
+
+// RedisHistory keeps the last messages of every room in Redis streams,
+// shared by every instance using the same pool and prefix. A room's keys
+// expire DefaultHistoryTTL after its last message; see WithTTL. Exclusive
+// XRANGE starts are used, which need Redis 6.2 or later.
+//
+//	websocket.WithHistory(websocket.NewRedisHistory(app.Data.RedisPool(), "myapp", 100))
+type RedisHistory struct {
+	pool   *redis.Pool
+	prefix string
+	size   int
+	ttl    time.Duration
+}
+
+// NewRedisHistory creates a history keeping the last size messages per
+// room in the streams <prefix>:websocket:history:<room>
+func NewRedisHistory(pool *redis.Pool, prefix string, size int) *RedisHistory {
+	if size <= 0 {
+		size = 100
+	}
+	return &RedisHistory{
+		pool:   pool,
+		prefix: backplaneChannel(prefix) + ":history:",
+		size:   size,
+		ttl:    DefaultHistoryTTL,
+	}
+}
+
+// WithTTL sets how long a room's keys are kept after its last message.
+// Zero keeps them forever.
+func (r *RedisHistory) WithTTL(ttl time.Duration) *RedisHistory {
+	r.ttl = ttl
+	return r
+}
+
+// Append adds a message to the room's stream
+func (r *RedisHistory) Append(ctx context.Context, room string, message []byte) (uint64, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	key := r.prefix + room
+	return redis.Uint64(appendScript.Do(conn, key, key+":seq", message, r.size, r.ttl.Milliseconds()))
+}
+
+// Since reads the room's stream after a sequence ID
+func (r *RedisHistory) Since(ctx context.Context, room string, after uint64) ([]HistoryEntry, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	key := r.prefix + room
+	values, err := redis.Values(sinceScript.Do(conn, key, key+":seq", after))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, ErrHistoryReset
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0, len(values))
+	for _, value := range values {
+		// Each entry is [id, [field, value, ...]]
+		parts, err := redis.Values(value, nil)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected stream entry: %v", value)
+		}
+		id, err := redis.String(parts[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		seq, err := strconv.ParseUint(strings.TrimPrefix(id, "0-"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected stream ID %q", id)
+		}
+		fields, err := redis.ByteSlices(parts[1], nil)
+		if err != nil || len(fields) < 2 {
+			return nil, fmt.Errorf("unexpected stream entry %s", id)
+		}
+		entries = append(entries, HistoryEntry{Seq: seq, Message: fields[1]})
+	}
+	return entries, nil
+}
@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSeq(t *testing.T) {
+	assert.Equal(t, `{"type":"chat","seq":7}`, string(withSeq([]byte(`{"type":"chat"}`), 7)))
+	assert.Equal(t, `{"seq":1}`, string(withSeq([]byte(` { } `), 1)))
+	assert.Equal(t, `plain text`, string(withSeq([]byte(`plain text`), 1)))
+
+	// A seq sent by a client is overridden
+	var msg Message
+	require.NoError(t, json.Unmarshal(withSeq([]byte(`{"seq":99}`), 3), &msg))
+	assert.Equal(t, uint64(3), msg.Seq)
+}
+
+func testHistory(t *testing.T, history History) {
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		seq, err := history.Append(ctx, "chat", []byte(fmt.Sprintf("m%d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), seq)
+	}
+	seq, err := history.Append(ctx, "other", []byte("x"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), seq, "rooms have their own sequence")
+
+	// Only the last three are retained
+	entries, err := history.Since(ctx, "chat", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, HistoryEntry{Seq: 3, Message: []byte("m3")}, entries[0])
+	assert.Equal(t, HistoryEntry{Seq: 5, Message: []byte("m5")}, entries[2])
+
+	entries, err = history.Since(ctx, "chat", 4)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, uint64(5), entries[0].Seq)
+
+	entries, err = history.Since(ctx, "chat", 5)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	entries, err = history.Since(ctx, "unknown", 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Sequence IDs past the last one are from before a reset
+	_, err = history.Since(ctx, "chat", 6)
+	assert.ErrorIs(t, err, ErrHistoryReset)
+	_, err = history.Since(ctx, "unknown", 1)
+	assert.ErrorIs(t, err, ErrHistoryReset)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	testHistory(t, NewMemoryHistory(3))
+}
+
+func TestMemoryHistoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	history := NewMemoryHistory(3).WithTTL(time.Minute).WithMaxRooms(2)
+
+	for _, room := range []string{"a", "b", "a", "c"} {
+		_, err := history.Append(ctx, room, []byte("m"))
+		require.NoError(t, err)
+	}
+
+	// b was the least recently active room when c was added
+	assert.Len(t, history.rooms, 2)
+	assert.NotContains(t, history.rooms, "b")
+
+	// Idle rooms expire and start over
+	history.rooms["a"].updated = time.Now().Add(-2 * time.Minute)
+	_, err := history.Since(ctx, "a", 2)
+	assert.ErrorIs(t, err, ErrHistoryReset)
+
+	seq, err := history.Append(ctx, "a", []byte("m"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+}
+
+func TestRedisHistory(t *testing.T) {
+	addr := miniredis.RunT(t).Addr()
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", addr) }}
+	defer pool.Close()
+
+	testHistory(t, NewRedisHistory(pool, "test", 3))
+}
+
+func TestRedisHistoryExpiry(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", mr.Addr()) }}
+	defer pool.Close()
+
+	ctx := context.Background()
+	history := NewRedisHistory(pool, "test", 3).WithTTL(time.Hour)
+	for i := 0; i < 2; i++ {
+		_, err := history.Append(ctx, "chat", []byte("m"))
+		require.NoError(t, err)
+	}
+
+	key := history.prefix + "chat"
+	assert.Equal(t, time.Hour, mr.TTL(key))
+	assert.Equal(t, time.Hour, mr.TTL(key+":seq"))
+
+	mr.FastForward(2 * time.Hour)
+	_, err := history.Since(ctx, "chat", 2)
+	assert.ErrorIs(t, err, ErrHistoryReset)
+
+	seq, err := history.Append(ctx, "chat", []byte("m"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+}
+
+func TestRoomHistoryPatterns(t *testing.T) {
+	hub := startHub(t, WithHistory(NewMemoryHistory(10), "chat:*"))
+	member := newTestClient(hub, "c1", "u1")
+	hub.JoinRoom(member, "chat:1")
+	hub.JoinRoom(member, "lobby")
+
+	hub.BroadcastToRoom("chat:1", []byte(`{"type":"chat"}`), nil)
+	assert.Equal(t, uint64(1), receiveMessage(t, member).Seq)
+
+	hub.BroadcastToRoom("lobby", []byte(`{"type":"chat"}`), nil)
+	assert.Zero(t, receiveMessage(t, member).Seq)
+
+	entries, err := hub.RoomHistory(context.Background(), "lobby", 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestResume(t *testing.T) {
+	hub := startHub(t, WithHistory(NewMemoryHistory(3)))
+	sender := newTestClient(hub, "c1", "u1")
+	hub.JoinRoom(sender, "chat")
+
+	for i := 1; i <= 2; i++ {
+		hub.BroadcastToRoom("chat", []byte(fmt.Sprintf(`{"type":"chat","data":%d}`, i)), sender)
+	}
+
+	// A client that saw message 1 comes back
+	client := newTestClient(hub, "c2", "u2")
+	sendFromClient(t, client, `{"type":"resume","room":"chat","data":1}`)
+	assert.Contains(t, client.GetRooms(), "chat")
+
+	replayed := receiveMessage(t, client)
+	assert.Equal(t, "chat", replayed.Type)
+	assert.Equal(t, uint64(2), replayed.Seq)
+
+	resumed := receiveMessage(t, client)
+	assert.Equal(t, MessageResumed, resumed.Type)
+	assert.Equal(t, "chat", resumed.Room)
+	assert.Equal(t, map[string]interface{}{"last_seq": 2.0, "replayed": 1.0, "complete": true}, resumed.Data)
+
+	// Messages 1 and 2 have been dropped by the time this one resumes
+	for i := 3; i <= 5; i++ {
+		hub.BroadcastToRoom("chat", []byte(`{"type":"chat"}`), nil)
+	}
+	late := newTestClient(hub, "c3", "u3")
+	result, err := hub.Resume(late, "chat", 0)
+	require.NoError(t, err)
+	assert.Equal(t, ResumeResult{LastSeq: 5, Replayed: 3, Complete: false}, result)
+
+	// The room expires and its sequence starts over
+	history := hub.config.History.(*MemoryHistory)
+	history.mu.Lock()
+	history.rooms["chat"].updated = time.Now().Add(-2 * DefaultHistoryTTL)
+	history.mu.Unlock()
+	hub.BroadcastToRoom("chat", []byte(`{"type":"chat"}`), nil)
+
+	result, err = hub.Resume(late, "chat", 5)
+	require.NoError(t, err)
+	assert.Equal(t, ResumeResult{LastSeq: 1, Replayed: 1, Complete: false}, result)
+}
+
+func TestResumeAuthorization(t *testing.T) {
+	hub := startHub(t,
+		WithHistory(NewMemoryHistory(3)),
+		WithRoomPolicy("private", RoomPolicy{
+			Join: func(c *Client, room string, params map[string]string) bool { return false },
+		}),
+	)
+	client := newTestClient(hub, "c1", "u1")
+	hub.BroadcastToRoom("private", []byte(`{"type":"secret"}`), nil)
+
+	sendFromClient(t, client, `{"type":"resume","room":"private","data":0}`)
+	assert.Equal(t, "forbidden", errorData(t, receiveMessage(t, client))["code"])
+	assertNoMessage(t, client)
+}
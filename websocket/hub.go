@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,14 +15,35 @@ import (
 
 type Hub struct {
 	clients      map[*Client]bool
+	users        map[string]map[*Client]bool // Local clients by user ID
 	rooms        map[string]*Room
 	broadcast    chan []byte
 	register     chan *Client
 	unregister   chan *Client
 	roomMessages chan *RoomMessage
+	userMessages chan *userMessage
 	mu           sync.RWMutex
+
+	// stopped is set when Run returns and closes the channels above
+	stopped bool
+	stopMu  sync.RWMutex
 	config       *Config
 	upgrader     websocket.Upgrader
+
+	// Cluster support, see Backplane
+	nodeID    string
+	backplane Backplane
+	outbox    chan []byte
+	remote    chan *backplaneEvent
+	cluster   *clusterState
+
+	handlers   map[string]HandlerFunc
+	handlersMu sync.RWMutex
+	policies   *roomPolicies
+
+	historyRooms []*roomRule
+
+	metrics *hubMetrics
 }
 
 type Client struct {
@@ -33,6 +55,16 @@ type Client struct {
 	rooms    map[string]bool
 	metadata map[string]interface{}
 	mu       sync.RWMutex
+
+	presence   string
+	presenceAt time.Time
+
+	codec Codec // Negotiated wire format, nil for JSON
+
+	publishToken string // Authorizes SSE publishes, empty for websockets
+
+	closed  bool        // send is closed; guarded by hub.mu
+	closing atomic.Bool // The connection is being closed
 }
 
 type Room struct {
@@ -52,8 +84,11 @@ type Message struct {
 	Data      interface{}            `json:"data,omitempty"`
 	Room      string                 `json:"room,omitempty"`
 	UserID    string                 `json:"user_id,omitempty"`
+	Seq       uint64                 `json:"seq,omitempty"` // Set on retained room messages, see History
 	Timestamp time.Time              `json:"timestamp"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+
+	raw json.RawMessage // Data as received from the client, see Decode
 }
 
 func NewHub(config *Config) *Hub {
@@ -63,19 +98,39 @@ func NewHub(config *Config) *Hub {
 
 	hub := &Hub{
 		clients:      make(map[*Client]bool),
+		users:        make(map[string]map[*Client]bool),
 		rooms:        make(map[string]*Room),
 		broadcast:    make(chan []byte, config.BroadcastBuffer),
 		register:     make(chan *Client),
 		unregister:   make(chan *Client),
 		roomMessages: make(chan *RoomMessage, config.RoomMessageBuffer),
+		userMessages: make(chan *userMessage, config.RoomMessageBuffer),
 		config:       config,
+		nodeID:       generateClientID(),
+		backplane:    config.Backplane,
+		outbox:       make(chan []byte, config.BroadcastBuffer),
+		remote:       make(chan *backplaneEvent, config.BroadcastBuffer),
+		cluster:      newClusterState(),
+		handlers:     make(map[string]HandlerFunc),
+		policies:     &roomPolicies{},
+		metrics:      newHubMetrics(config.Metrics),
+	}
+
+	hub.registerBuiltinHandlers()
+	for _, p := range config.RoomPolicies {
+		hub.AuthorizeRoom(p.Pattern, p.Policy)
+	}
+	for _, pattern := range config.HistoryRooms {
+		hub.historyRooms = append(hub.historyRooms, newRoomRule(pattern, RoomPolicy{}))
 	}
 
 	// Configure upgrader with origin checking
 	hub.upgrader = websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		CheckOrigin:     hub.checkOrigin,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		CheckOrigin:       hub.checkOrigin,
+		Subprotocols:      hub.subprotocols(),
+		EnableCompression: config.Compression,
 	}
 
 	return hub
@@ -111,6 +166,13 @@ func (h *Hub) checkOrigin(r *http.Request) bool {
 }
 
 func (h *Hub) Run(ctx context.Context) {
+	if h.backplane != nil {
+		go h.runBackplane(ctx)
+	}
+	if h.config.Metrics != nil && h.config.MetricsInterval > 0 {
+		go h.runMetrics(ctx.Done())
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -124,6 +186,10 @@ func (h *Hub) Run(ctx context.Context) {
 			h.broadcastToAll(message)
 		case roomMsg := <-h.roomMessages:
 			h.broadcastToRoom(roomMsg)
+		case userMsg := <-h.userMessages:
+			h.sendToUser(userMsg)
+		case ev := <-h.remote:
+			h.handleRemote(ev)
 		}
 	}
 }
@@ -131,8 +197,11 @@ func (h *Hub) Run(ctx context.Context) {
 func (h *Hub) registerClient(client *Client) {
 	h.mu.Lock()
 	h.clients[client] = true
+	h.addUserClient(client)
 	h.mu.Unlock()
 
+	h.publish(&backplaneEvent{Kind: eventConnect, Client: client.id, User: client.userID})
+
 	if h.config.OnConnect != nil {
 		h.config.OnConnect(client)
 	}
@@ -142,15 +211,27 @@ func (h *Hub) registerClient(client *Client) {
 
 func (h *Hub) unregisterClient(client *Client) {
 	h.mu.Lock()
-	if _, ok := h.clients[client]; ok {
+	_, ok := h.clients[client]
+	if ok {
 		delete(h.clients, client)
+		h.removeUserClient(client)
+		client.closed = true
 		close(client.send)
+	}
+	h.mu.Unlock()
+
+	if ok && h.config.ClientRateLimiter != nil {
+		h.config.ClientRateLimiter.Reset(client.id)
+	}
 
-		for roomName := range client.rooms {
+	// Leave rooms without holding h.mu, which leaveRoom locks
+	if ok {
+		for _, roomName := range client.GetRooms() {
 			h.leaveRoom(client, roomName)
 		}
 	}
-	h.mu.Unlock()
+
+	h.publish(&backplaneEvent{Kind: eventDisconnect, Client: client.id})
 
 	if h.config.OnDisconnect != nil {
 		h.config.OnDisconnect(client)
@@ -159,64 +240,36 @@ func (h *Hub) unregisterClient(client *Client) {
 	log.Printf("Client %s disconnected. Total clients: %d", client.id, len(h.clients))
 }
 
+// broadcastToAll queues a message for every client. Clients whose buffer
+// is full are handled by the slow consumer policy.
 func (h *Hub) broadcastToAll(message []byte) {
 	h.mu.RLock()
-	var deadClients []*Client
-	for client := range h.clients {
-		select {
-		case client.send <- message:
-		default:
-			// Collect dead clients - don't modify map while holding read lock
-			deadClients = append(deadClients, client)
-		}
-	}
-	h.mu.RUnlock()
+	defer h.mu.RUnlock()
 
-	// Clean up dead clients with write lock
-	if len(deadClients) > 0 {
-		h.mu.Lock()
-		for _, client := range deadClients {
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-			}
-		}
-		h.mu.Unlock()
+	for client := range h.clients {
+		client.enqueue(message)
 	}
 }
 
+// broadcastToRoom queues a message for the clients in a room. Clients
+// whose buffer is full are handled by the slow consumer policy.
 func (h *Hub) broadcastToRoom(roomMsg *RoomMessage) {
+	// Hold h.mu so send channels are not closed while queueing
 	h.mu.RLock()
-	room, exists := h.rooms[roomMsg.Room]
-	h.mu.RUnlock()
+	defer h.mu.RUnlock()
 
+	room, exists := h.rooms[roomMsg.Room]
 	if !exists {
 		return
 	}
 
 	room.mu.RLock()
-	var deadClients []*Client
+	defer room.mu.RUnlock()
 	for client := range room.clients {
-		if client == roomMsg.Exclude {
-			continue
-		}
-		select {
-		case client.send <- roomMsg.Message:
-		default:
-			// Collect dead clients - don't modify map while holding read lock
-			deadClients = append(deadClients, client)
+		if client != roomMsg.Exclude {
+			client.enqueue(roomMsg.Message)
 		}
 	}
-	room.mu.RUnlock()
-
-	// Clean up dead clients with write lock
-	if len(deadClients) > 0 {
-		room.mu.Lock()
-		for _, client := range deadClients {
-			delete(room.clients, client)
-		}
-		room.mu.Unlock()
-	}
 }
 
 func (h *Hub) JoinRoom(client *Client, roomName string) {
@@ -231,6 +284,8 @@ func (h *Hub) JoinRoom(client *Client, roomName string) {
 	}
 	h.mu.Unlock()
 
+	alreadyPresent := h.userInRoom(roomName, client.userID, client)
+
 	room.mu.Lock()
 	room.clients[client] = true
 	room.mu.Unlock()
@@ -240,8 +295,22 @@ func (h *Hub) JoinRoom(client *Client, roomName string) {
 		client.rooms = make(map[string]bool)
 	}
 	client.rooms[roomName] = true
+	if client.presenceAt.IsZero() {
+		client.presenceAt = time.Now()
+	}
 	client.mu.Unlock()
 
+	info := client.info()
+	h.publish(&backplaneEvent{
+		Kind:     eventJoin,
+		Room:     roomName,
+		Client:   client.id,
+		User:     client.userID,
+		State:    info.State,
+		Metadata: info.Metadata,
+	})
+	h.presenceJoined(client, roomName, alreadyPresent)
+
 	if h.config.OnJoinRoom != nil {
 		h.config.OnJoinRoom(client, roomName)
 	}
@@ -263,6 +332,7 @@ func (h *Hub) leaveRoom(client *Client, roomName string) {
 	}
 
 	room.mu.Lock()
+	_, wasMember := room.clients[client]
 	delete(room.clients, client)
 	isEmpty := len(room.clients) == 0
 	room.mu.Unlock()
@@ -277,6 +347,11 @@ func (h *Hub) leaveRoom(client *Client, roomName string) {
 	}
 	h.mu.Unlock()
 
+	if wasMember {
+		h.publish(&backplaneEvent{Kind: eventLeave, Room: roomName, Client: client.id})
+		h.presenceLeft(client, roomName)
+	}
+
 	if h.config.OnLeaveRoom != nil {
 		h.config.OnLeaveRoom(client, roomName)
 	}
@@ -284,35 +359,86 @@ func (h *Hub) leaveRoom(client *Client, roomName string) {
 	log.Printf("Client %s left room %s", client.id, roomName)
 }
 
+// BroadcastToAll sends a message to every client, on all instances when
+// a backplane is configured
 func (h *Hub) BroadcastToAll(message []byte) {
-	select {
-	case h.broadcast <- message:
-	default:
-		log.Printf("Broadcast channel is full, dropping message")
+	h.publish(&backplaneEvent{Kind: eventBroadcast, Message: message})
+
+	h.whileRunning(func() {
+		select {
+		case h.broadcast <- message:
+		default:
+			log.Printf("Broadcast channel is full, dropping message")
+		}
+	})
+}
+
+// whileRunning calls send unless the hub has stopped. Run closes the
+// hub's channels when its context is cancelled, and sending on them
+// afterwards would panic, e.g. in a background job during shutdown.
+func (h *Hub) whileRunning(send func()) {
+	h.stopMu.RLock()
+	defer h.stopMu.RUnlock()
+
+	if h.stopped {
+		log.Printf("Hub has stopped, dropping message")
+		return
 	}
+	send()
 }
 
+// BroadcastToRoom sends a message to the clients in a room, on all
+// instances when a backplane is configured. exclude is a local client to
+// skip, e.g. the sender, or nil. Messages to rooms with history are
+// retained and get a seq field.
 func (h *Hub) BroadcastToRoom(roomName string, message []byte, exclude *Client) {
+	if h.retainsRoom(roomName) {
+		message = h.record(roomName, message)
+	}
+	h.sendToRoom(roomName, message, exclude)
+}
+
+// sendToRoom sends a message to a room without retaining it
+func (h *Hub) sendToRoom(roomName string, message []byte, exclude *Client) {
+	h.publish(&backplaneEvent{Kind: eventRoom, Room: roomName, Message: message})
+
 	roomMsg := &RoomMessage{
 		Room:    roomName,
 		Message: message,
 		Exclude: exclude,
 	}
 
-	select {
-	case h.roomMessages <- roomMsg:
-	default:
-		log.Printf("Room message channel is full, dropping message for room %s", roomName)
-	}
+	h.whileRunning(func() {
+		select {
+		case h.roomMessages <- roomMsg:
+		default:
+			log.Printf("Room message channel is full, dropping message for room %s", roomName)
+		}
+	})
 }
 
+// GetConnectedClients returns the number of connected clients, including
+// those on other instances when a backplane is configured
 func (h *Hub) GetConnectedClients() int {
+	return h.GetLocalClients() + h.cluster.clientCount()
+}
+
+// GetLocalClients returns the number of clients connected to this instance
+func (h *Hub) GetLocalClients() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return len(h.clients)
 }
 
+// GetRoomClients returns the number of clients in a room, including those
+// on other instances when a backplane is configured
 func (h *Hub) GetRoomClients(roomName string) int {
+	return h.GetLocalRoomClients(roomName) + h.cluster.roomCount(roomName)
+}
+
+// GetLocalRoomClients returns the number of clients in a room connected
+// to this instance
+func (h *Hub) GetLocalRoomClients(roomName string) int {
 	h.mu.RLock()
 	room, exists := h.rooms[roomName]
 	h.mu.RUnlock()
@@ -338,6 +464,10 @@ func (h *Hub) GetRooms() []string {
 }
 
 func (h *Hub) shutdown() {
+	h.stopMu.Lock()
+	defer h.stopMu.Unlock()
+	h.stopped = true
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -345,6 +475,7 @@ func (h *Hub) shutdown() {
 		if client.conn != nil {
 			client.conn.Close()
 		}
+		client.closed = true
 		close(client.send)
 	}
 
@@ -352,6 +483,7 @@ func (h *Hub) shutdown() {
 	close(h.register)
 	close(h.unregister)
 	close(h.roomMessages)
+	close(h.userMessages)
 }
 
 func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
@@ -362,24 +494,18 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	clientID := generateClientID()
-	var userID string
 
-	// Use authentication callback if configured
-	if h.config.AuthenticateConnection != nil {
-		userID, err = h.config.AuthenticateConnection(r)
-		if err != nil {
-			log.Printf("WebSocket authentication failed for client %s: %v", clientID, err)
-			conn.WriteMessage(websocket.CloseMessage,
-				websocket.FormatCloseMessage(4001, "authentication failed"))
-			conn.Close()
-			return
-		}
+	// Use authentication callback if configured, or the client ID
+	userID, err := h.authenticate(r, clientID)
+	if err != nil {
+		log.Printf("WebSocket authentication failed for client %s: %v", clientID, err)
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(4001, "authentication failed"))
+		conn.Close()
+		return
 	}
 
-	// If no auth callback or user not authenticated, use client ID
-	if userID == "" {
-		userID = clientID
-	}
+	h.configureCompression(conn)
 
 	client := &Client{
 		hub:      h,
@@ -389,6 +515,7 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
 		userID:   userID,
 		rooms:    make(map[string]bool),
 		metadata: make(map[string]interface{}),
+		codec:    h.codecFor(conn.Subprotocol()),
 	}
 
 	// Safely register - recover if hub is shutting down
@@ -428,20 +555,24 @@ func (c *Client) readPump() {
 			break
 		}
 
-		var msg Message
-		if err := json.Unmarshal(messageBytes, &msg); err != nil {
-			log.Printf("Error unmarshaling message: %v", err)
+		if allowed, info := c.allowMessage(); !allowed {
+			c.sendError(&Message{}, rateLimitError(info))
 			continue
 		}
 
-		msg.UserID = c.userID
-		msg.Timestamp = time.Now()
+		data, err := c.Codec().Decode(messageBytes)
+		if err != nil {
+			log.Printf("Client %s: failed to decode %s message: %v", c.id, c.Codec().Name(), err)
+			continue
+		}
 
-		if c.hub.config.OnMessage != nil {
-			c.hub.config.OnMessage(c, &msg)
+		msg, err := parseMessage(data)
+		if err != nil {
+			log.Printf("Error unmarshaling message: %v", err)
+			continue
 		}
 
-		c.handleMessage(&msg)
+		c.dispatch(msg)
 	}
 }
 
@@ -461,6 +592,29 @@ func (c *Client) writePump() {
 				return
 			}
 
+			if c.Codec() != JSONCodec || c.hub.config.BinaryFrames {
+				// Binary messages can't be separated by newlines, so each
+				// queued message gets its own frame
+				if err := c.writeFrame(message); err != nil {
+					return
+				}
+				n := len(c.send)
+				for i := 0; i < n; i++ {
+					next, ok := <-c.send
+					if !ok {
+						c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+						return
+					}
+					if err := c.writeFrame(next); err != nil {
+						return
+					}
+				}
+				continue
+			}
+
+			if c.hub.config.Compression {
+				c.conn.EnableWriteCompression(len(message) >= c.hub.config.CompressionThreshold)
+			}
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				return
@@ -485,52 +639,21 @@ func (c *Client) writePump() {
 	}
 }
 
-func (c *Client) handleMessage(msg *Message) {
-	switch msg.Type {
-	case "join_room":
-		if roomName, ok := msg.Data.(string); ok {
-			c.hub.JoinRoom(c, roomName)
-		} else {
-			log.Printf("Client %s: join_room requires string room name", c.id)
-		}
-	case "leave_room":
-		if roomName, ok := msg.Data.(string); ok {
-			c.hub.LeaveRoom(c, roomName)
-		} else {
-			log.Printf("Client %s: leave_room requires string room name", c.id)
-		}
-	case "room_message":
-		if msg.Room == "" {
-			log.Printf("Client %s: room_message requires room field", c.id)
-			return
-		}
-		messageBytes, err := json.Marshal(msg)
-		if err != nil {
-			log.Printf("Client %s: failed to marshal room message: %v", c.id, err)
-			return
-		}
-		c.hub.BroadcastToRoom(msg.Room, messageBytes, c)
-	case "broadcast":
-		messageBytes, err := json.Marshal(msg)
-		if err != nil {
-			log.Printf("Client %s: failed to marshal broadcast message: %v", c.id, err)
-			return
-		}
-		c.hub.BroadcastToAll(messageBytes)
-	default:
-		log.Printf("Client %s: unknown message type: %s", c.id, msg.Type)
-	}
-}
-
+// Send queues a message for the client. If its buffer is full, the slow
+// consumer policy applies and an error is returned unless the message was
+// queued by dropping an older one.
 func (c *Client) Send(message []byte) error {
-	select {
-	case c.send <- message:
-		return nil
-	default:
-		// Channel full - let the writePump/unregister handle cleanup
-		// Do NOT modify hub.clients here - causes race condition
+	// Hold h.mu so send is not closed while queueing
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+
+	if c.closed {
+		return fmt.Errorf("client %s is disconnected", c.id)
+	}
+	if !c.enqueue(message) {
 		return fmt.Errorf("send buffer full for client %s", c.id)
 	}
+	return nil
 }
 
 func (c *Client) SendMessage(msgType string, data interface{}) error {
@@ -30,6 +30,16 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 	hub.ServeWS(w, r)
 }
 
+func SSEHandler(w http.ResponseWriter, r *http.Request) {
+	hub := GetDefaultHub()
+	hub.ServeSSE(w, r)
+}
+
+func SSEPublishHandler(w http.ResponseWriter, r *http.Request) {
+	hub := GetDefaultHub()
+	hub.ServeSSEPublish(w, r)
+}
+
 func RegisterRoutes(router chi.Router, path string) {
 	if path == "" {
 		path = "/ws"
@@ -37,6 +47,16 @@ func RegisterRoutes(router chi.Router, path string) {
 	router.Get(path, WSHandler)
 }
 
+// RegisterSSERoutes mounts the Server-Sent Events stream at GET path and
+// the publish endpoint at POST path
+func RegisterSSERoutes(router chi.Router, path string) {
+	if path == "" {
+		path = "/events"
+	}
+	router.Get(path, SSEHandler)
+	router.Post(path, SSEPublishHandler)
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		next.ServeHTTP(w, r)
@@ -78,6 +98,16 @@ func BroadcastToRoom(roomName string, message []byte, exclude *Client) {
 	hub.BroadcastToRoom(roomName, message, exclude)
 }
 
+func SendToUser(userID string, message []byte) {
+	hub := GetDefaultHub()
+	hub.SendToUser(userID, message)
+}
+
+func DisconnectUser(userID string) {
+	hub := GetDefaultHub()
+	hub.DisconnectUser(userID)
+}
+
 func GetConnectedClients() int {
 	hub := GetDefaultHub()
 	return hub.GetConnectedClients()
@@ -88,6 +88,18 @@ func (m *Module) Handler() http.HandlerFunc {
 	return m.Hub.ServeWS
 }
 
+// SSEHandler returns an HTTP handler streaming messages as Server-Sent
+// Events, for clients that can't use websockets. See Hub.ServeSSE.
+func (m *Module) SSEHandler() http.HandlerFunc {
+	return m.Hub.ServeSSE
+}
+
+// SSEPublishHandler returns an HTTP handler accepting messages from SSE
+// clients. See Hub.ServeSSEPublish.
+func (m *Module) SSEPublishHandler() http.HandlerFunc {
+	return m.Hub.ServeSSEPublish
+}
+
 // Broadcast sends a message to all connected clients
 func (m *Module) Broadcast(message []byte) {
 	if m.Hub != nil {
@@ -110,3 +122,35 @@ func (m *Module) GetClientCount() int {
 	}
 	return 0
 }
+
+// SendToUser sends a message to every connection of a user, e.g. from a
+// background job once it has finished
+func (m *Module) SendToUser(userID string, message []byte) {
+	if m.Hub != nil {
+		m.Hub.SendToUser(userID, message)
+	}
+}
+
+// SendMessageToUser encodes a message of the given type and sends it to
+// every connection of a user
+func (m *Module) SendMessageToUser(userID, msgType string, data interface{}) error {
+	if m.Hub == nil {
+		return nil
+	}
+	return m.Hub.SendMessageToUser(userID, msgType, data)
+}
+
+// DisconnectUser closes every connection of a user
+func (m *Module) DisconnectUser(userID string) {
+	if m.Hub != nil {
+		m.Hub.DisconnectUser(userID)
+	}
+}
+
+// ClientsForUser returns the user's connections to this instance
+func (m *Module) ClientsForUser(userID string) []*Client {
+	if m.Hub != nil {
+		return m.Hub.ClientsForUser(userID)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	docs := []string{
+		`null`,
+		`true`,
+		`false`,
+		`0`,
+		`127`,
+		`128`,
+		`-1`,
+		`-32`,
+		`-33`,
+		`-129`,
+		`70000`,
+		`-70000`,
+		`5000000000`,
+		`18446744073709551615`,
+		`1.5`,
+		`-0.25`,
+		`""`,
+		`"hello"`,
+		`"` + strings.Repeat("x", 40) + `"`,
+		`"` + strings.Repeat("y", 300) + `"`,
+		`[]`,
+		`[1,"two",[3],{"four":4}]`,
+		`{}`,
+		`{"type":"room_message","room":"lobby","data":{"text":"hi","tags":["a","b"]},"seq":42}`,
+	}
+
+	for _, doc := range docs {
+		t.Run(doc, func(t *testing.T) {
+			packed, err := jsonToMsgpack([]byte(doc))
+			require.NoError(t, err)
+
+			unpacked, err := msgpackToJSON(packed)
+			require.NoError(t, err)
+			assert.JSONEq(t, doc, string(unpacked))
+		})
+	}
+}
+
+func TestMsgpackEncoding(t *testing.T) {
+	packed, err := jsonToMsgpack([]byte(`{"a":1,"b":[true,null]}`))
+	require.NoError(t, err)
+
+	// fixmap(2) "a" 1 "b" fixarray(2) true nil
+	assert.Equal(t, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x92, 0xc3, 0xc0}, packed)
+}
+
+func TestMsgpackDecodeBinary(t *testing.T) {
+	// bin8 with 3 bytes becomes a base64 string
+	unpacked, err := msgpackToJSON([]byte{0xc4, 0x03, 'a', 'b', 'c'})
+	require.NoError(t, err)
+	assert.Equal(t, `"YWJj"`, string(unpacked))
+}
+
+func TestMsgpackDecodeInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":             {},
+		"truncated string":  {0xa5, 'a', 'b'},
+		"truncated integer": {0xcd, 0x01},
+		"truncated map":     {0x81, 0xa1, 'a'},
+		"huge array":        {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"unsupported type":  {0xc1},
+		"trailing data":     {0x01, 0x02},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := msgpackToJSON(data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMsgpackDecodeDepth(t *testing.T) {
+	data := make([]byte, maxMsgpackDepth+2)
+	for i := range data {
+		data[i] = 0x91 // fixarray(1)
+	}
+	data[len(data)-1] = 0xc0
+
+	_, err := msgpackToJSON(data)
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+)
+
+// Presence message types sent to room members when presence is enabled
+const (
+	// PresenceState is sent to a client joining a room, with the members
+	PresenceState = "presence_state"
+	// PresenceJoin is sent when a user's first connection joins a room
+	PresenceJoin = "presence_join"
+	// PresenceLeave is sent when a user's last connection leaves a room
+	PresenceLeave = "presence_leave"
+	// PresenceUpdate is sent when a member's presence state changes
+	PresenceUpdate = "presence_update"
+)
+
+// PresenceMember is a user in a room. Users with several connections,
+// e.g. browser tabs, are listed once.
+type PresenceMember struct {
+	UserID string `json:"user_id"`
+
+	// State is the member's presence state, e.g. "typing" or "away"
+	State string `json:"state,omitempty"`
+
+	// Metadata is the client metadata of the member's connection
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
+	// Connections is the number of the user's connections in the room
+	Connections int `json:"connections"`
+
+	at time.Time
+}
+
+// Presence returns the members of a room by user ID, sorted by user ID.
+// The state and metadata of a user with several connections are those of
+// the connection that changed its state last. Members on other instances
+// are included when a backplane is configured.
+func (h *Hub) Presence(roomName string) []PresenceMember {
+	members := make(map[string]*PresenceMember)
+	add := func(info *clientInfo) {
+		m, ok := members[info.User]
+		if !ok {
+			m = &PresenceMember{UserID: info.User}
+			members[info.User] = m
+		}
+		m.Connections++
+		if m.Connections == 1 || info.at.After(m.at) {
+			m.State = info.State
+			m.Metadata = info.Metadata
+			m.at = info.at
+		}
+	}
+
+	h.mu.RLock()
+	room, exists := h.rooms[roomName]
+	h.mu.RUnlock()
+	if exists {
+		room.mu.RLock()
+		for client := range room.clients {
+			add(client.info())
+		}
+		room.mu.RUnlock()
+	}
+
+	for _, info := range h.cluster.roomMembers(roomName) {
+		info := info
+		add(&info)
+	}
+
+	result := make([]PresenceMember, 0, len(members))
+	for _, m := range members {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result
+}
+
+// userInRoom reports whether a user has a connection other than exclude
+// in a room, on any instance
+func (h *Hub) userInRoom(roomName, userID string, exclude *Client) bool {
+	h.mu.RLock()
+	room, exists := h.rooms[roomName]
+	h.mu.RUnlock()
+
+	if exists {
+		room.mu.RLock()
+		for client := range room.clients {
+			if client != exclude && client.userID == userID {
+				room.mu.RUnlock()
+				return true
+			}
+		}
+		room.mu.RUnlock()
+	}
+
+	return h.cluster.userInRoom(roomName, userID)
+}
+
+// presenceJoined tells the room a user arrived, unless they were already
+// there with another connection, and sends the joining client the members
+func (h *Hub) presenceJoined(client *Client, roomName string, alreadyPresent bool) {
+	if !h.config.Presence {
+		return
+	}
+
+	if !alreadyPresent {
+		if member, ok := h.presenceMember(roomName, client.userID); ok {
+			h.sendToRoom(roomName, presenceMessage(PresenceJoin, roomName, member), client)
+		}
+	}
+
+	if err := client.sendRoomMessage(PresenceState, roomName, h.Presence(roomName)); err != nil {
+		log.Printf("Client %s: failed to send presence state: %v", client.id, err)
+	}
+}
+
+// presenceLeft tells the room a user left once their last connection left
+func (h *Hub) presenceLeft(client *Client, roomName string) {
+	if !h.config.Presence || h.userInRoom(roomName, client.userID, nil) {
+		return
+	}
+
+	member := PresenceMember{UserID: client.userID}
+	h.sendToRoom(roomName, presenceMessage(PresenceLeave, roomName, member), nil)
+}
+
+// presenceMember returns a user's membership of a room
+func (h *Hub) presenceMember(roomName, userID string) (PresenceMember, bool) {
+	for _, m := range h.Presence(roomName) {
+		if m.UserID == userID {
+			return m, true
+		}
+	}
+	return PresenceMember{}, false
+}
+
+func presenceMessage(msgType, roomName string, member PresenceMember) []byte {
+	data, _ := json.Marshal(Message{
+		Type:      msgType,
+		Room:      roomName,
+		UserID:    member.UserID,
+		Data:      member,
+		Timestamp: time.Now(),
+	})
+	return data
+}
+
+// SetPresence sets the client's presence state, e.g. "typing" or "away".
+// With presence enabled, the rooms the client is in get a presence_update.
+func (c *Client) SetPresence(state string) {
+	c.mu.Lock()
+	c.presence = state
+	c.presenceAt = time.Now()
+	c.mu.Unlock()
+
+	info := c.info()
+	c.hub.publish(&backplaneEvent{
+		Kind:     eventPresence,
+		Client:   c.id,
+		User:     c.userID,
+		State:    info.State,
+		Metadata: info.Metadata,
+	})
+
+	if !c.hub.config.Presence {
+		return
+	}
+	for _, roomName := range c.GetRooms() {
+		if member, ok := c.hub.presenceMember(roomName, c.userID); ok {
+			c.hub.sendToRoom(roomName, presenceMessage(PresenceUpdate, roomName, member), nil)
+		}
+	}
+}
+
+// GetPresence returns the client's presence state
+func (c *Client) GetPresence() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.presence
+}
+
+// info returns the client's presence information
+func (c *Client) info() *clientInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var metadata map[string]interface{}
+	if len(c.metadata) > 0 {
+		metadata = make(map[string]interface{}, len(c.metadata))
+		for k, v := range c.metadata {
+			metadata[k] = v
+		}
+	}
+	return &clientInfo{
+		User:     c.userID,
+		State:    c.presence,
+		Metadata: metadata,
+		at:       c.presenceAt,
+	}
+}
+
+// sendRoomMessage sends the client a message about a room
+func (c *Client) sendRoomMessage(msgType, roomName string, data interface{}) error {
+	messageBytes, err := json.Marshal(Message{
+		Type:      msgType,
+		Data:      data,
+		Room:      roomName,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return c.Send(messageBytes)
+}
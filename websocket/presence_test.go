@@ -0,0 +1,157 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveMessage(t *testing.T, c *Client) Message {
+	t.Helper()
+	var msg Message
+	require.NoError(t, json.Unmarshal([]byte(receive(t, c)), &msg))
+	return msg
+}
+
+func assertNoMessage(t *testing.T, c *Client) {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		t.Fatalf("unexpected message: %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// settle lets the hub deliver queued room messages
+func settle() {
+	time.Sleep(20 * time.Millisecond)
+}
+
+func startHub(t *testing.T, opts ...Option) *Hub {
+	hub := NewHub(NewConfig(opts...))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx)
+	return hub
+}
+
+func TestPresenceMembers(t *testing.T) {
+	hub := startHub(t)
+
+	tab1 := newTestClient(hub, "c1", "u1")
+	tab2 := newTestClient(hub, "c2", "u1")
+	other := newTestClient(hub, "c3", "u2")
+	tab2.SetMetadata("name", "Ada")
+
+	hub.JoinRoom(tab1, "doc")
+	hub.JoinRoom(other, "doc")
+	hub.JoinRoom(tab2, "doc")
+	tab2.SetPresence("typing")
+
+	members := hub.Presence("doc")
+	require.Len(t, members, 2)
+	assert.Equal(t, "u1", members[0].UserID)
+	assert.Equal(t, 2, members[0].Connections)
+	assert.Equal(t, "typing", members[0].State)
+	assert.Equal(t, "Ada", members[0].Metadata["name"])
+	assert.Equal(t, "u2", members[1].UserID)
+	assert.Equal(t, 1, members[1].Connections)
+	assert.Equal(t, "typing", tab2.GetPresence())
+
+	assert.Empty(t, hub.Presence("empty"))
+}
+
+func TestPresenceEvents(t *testing.T) {
+	hub := startHub(t, WithPresence())
+
+	alice := newTestClient(hub, "c1", "alice")
+	bob := newTestClient(hub, "c2", "bob")
+	aliceTab := newTestClient(hub, "c3", "alice")
+
+	hub.JoinRoom(alice, "doc")
+	settle()
+	state := receiveMessage(t, alice)
+	assert.Equal(t, PresenceState, state.Type)
+	assert.Equal(t, "doc", state.Room)
+
+	hub.JoinRoom(bob, "doc")
+	settle()
+	assert.Equal(t, PresenceState, receiveMessage(t, bob).Type)
+	joined := receiveMessage(t, alice)
+	assert.Equal(t, PresenceJoin, joined.Type)
+	assert.Equal(t, "bob", joined.UserID)
+
+	// A second tab is not a new member
+	hub.JoinRoom(aliceTab, "doc")
+	assert.Equal(t, PresenceState, receiveMessage(t, aliceTab).Type)
+	assertNoMessage(t, bob)
+
+	aliceTab.SetPresence("away")
+	update := receiveMessage(t, bob)
+	assert.Equal(t, PresenceUpdate, update.Type)
+	assert.Equal(t, "away", update.Data.(map[string]interface{})["state"])
+	receiveMessage(t, alice)
+	receiveMessage(t, aliceTab)
+
+	// Alice is still there with the other tab
+	hub.LeaveRoom(aliceTab, "doc")
+	assertNoMessage(t, bob)
+
+	hub.LeaveRoom(alice, "doc")
+	left := receiveMessage(t, bob)
+	assert.Equal(t, PresenceLeave, left.Type)
+	assert.Equal(t, "alice", left.UserID)
+}
+
+func TestPresenceLeaveOnDisconnect(t *testing.T) {
+	hub := startHub(t, WithPresence())
+
+	alice := newTestClient(hub, "c1", "alice")
+	bob := newTestClient(hub, "c2", "bob")
+	hub.register <- alice
+	hub.register <- bob
+	hub.JoinRoom(alice, "doc")
+	settle()
+	hub.JoinRoom(bob, "doc")
+	receiveMessage(t, alice)
+	receiveMessage(t, alice)
+	receiveMessage(t, bob)
+
+	hub.unregister <- alice
+	left := receiveMessage(t, bob)
+	assert.Equal(t, PresenceLeave, left.Type)
+	assert.Equal(t, 1, hub.GetRoomClients("doc"))
+}
+
+func TestPresenceAcrossInstances(t *testing.T) {
+	b := NewMemoryBackplane()
+	hubA := startHub(t, WithBackplane(b), WithPresence())
+	hubB := startHub(t, WithBackplane(b), WithPresence())
+
+	alice := newTestClient(hubA, "c1", "alice")
+	hubA.JoinRoom(alice, "doc")
+	settle()
+	receiveMessage(t, alice)
+
+	bob := newTestClient(hubB, "c2", "bob")
+	bob.SetMetadata("name", "Bob")
+	require.Eventually(t, func() bool {
+		return len(hubB.Presence("doc")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	hubB.JoinRoom(bob, "doc")
+
+	joined := receiveMessage(t, alice)
+	assert.Equal(t, PresenceJoin, joined.Type)
+	assert.Equal(t, "bob", joined.UserID)
+
+	require.Eventually(t, func() bool {
+		return len(hubA.Presence("doc")) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	members := hubA.Presence("doc")
+	assert.Equal(t, "bob", members[1].UserID)
+	assert.Equal(t, "Bob", members[1].Metadata["name"])
+}
@@ -0,0 +1,333 @@
+package websocket
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SSE message types and headers
+const (
+	// MessageConnected is the first message on an SSE stream. Its data
+	// holds the client_id and publish_token to send with published messages.
+	MessageConnected = "connected"
+
+	// SSEClientIDHeader identifies the SSE client publishing a message
+	SSEClientIDHeader = "X-Client-ID"
+
+	// SSEPublishTokenHeader carries the publish token of the SSE client
+	SSEPublishTokenHeader = "X-Publish-Token"
+)
+
+// ServeSSE streams messages to a client as Server-Sent Events, for clients
+// that can't use websockets, e.g. behind proxies that block upgrades. The
+// client is registered with the hub like a websocket client, so broadcasts,
+// room and user messages reach it the same way.
+//
+// Rooms to join are passed as room query parameters and checked against
+// the room policies. The event IDs record the sequence IDs of retained
+// room messages, so a reconnecting EventSource resumes where it left off.
+// Clients send messages with ServeSSEPublish.
+func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	if !h.checkSSEOrigin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	clientID := generateClientID()
+	userID, err := h.authenticate(r, clientID)
+	if err != nil {
+		log.Printf("SSE authentication failed for client %s: %v", clientID, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	client := &Client{
+		hub:      h,
+		send:     make(chan []byte, h.config.ClientBuffer),
+		id:       clientID,
+		userID:   userID,
+		rooms:    make(map[string]bool),
+		metadata: make(map[string]interface{}),
+
+		publishToken: generatePublishToken(),
+	}
+
+	rooms := r.URL.Query()["room"]
+	for _, roomName := range rooms {
+		if !h.CanJoinRoom(client, roomName) {
+			http.Error(w, "Forbidden: room "+roomName, http.StatusForbidden)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering in nginx
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			log.Printf("SSE client %s: response writer does not support streaming", clientID)
+		}
+		return
+	}
+
+	// Safely register - recover if hub is shutting down
+	registered := func() (ok bool) {
+		defer func() {
+			if recover() != nil {
+				ok = false
+			}
+		}()
+		h.register <- client
+		return true
+	}()
+	if !registered {
+		return
+	}
+
+	unregistered := false
+	defer func() {
+		if unregistered {
+			return
+		}
+		// Safely unregister - recover if hub is shutting down
+		defer func() { recover() }()
+		h.unregister <- client
+	}()
+
+	client.SendMessage(MessageConnected, map[string]string{
+		"client_id":     clientID,
+		"publish_token": client.publishToken,
+	})
+
+	lastSeqs := parseLastEventID(r.Header.Get("Last-Event-ID"))
+	for _, roomName := range rooms {
+		if seq, ok := lastSeqs[roomName]; ok {
+			if _, err := h.Resume(client, roomName, seq); err != nil {
+				log.Printf("SSE client %s: failed to resume room %s: %v", clientID, roomName, err)
+			}
+		} else {
+			h.JoinRoom(client, roomName)
+		}
+	}
+
+	stream := &sseStream{w: w, rc: rc, writeWait: h.config.WriteWait, seqs: lastSeqs}
+	heartbeat := time.NewTicker(h.config.SSEHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			if !ok {
+				// Unregistered by the hub, e.g. DisconnectUser
+				unregistered = true
+				return
+			}
+			if err := stream.event(message); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := stream.comment("heartbeat"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ServeSSEPublish accepts a message from an SSE client and handles it like
+// a message received over a websocket, so join_room, room_message and
+// handlers registered with HandleFunc work the same. The client is
+// identified by the X-Client-ID header, or the client_id query parameter,
+// and must belong to the authenticated user. The X-Publish-Token header, or
+// the publish_token query parameter, must match the token sent in the
+// connected message: anonymous clients use their client ID as user ID, so
+// the client ID alone can't prove who is publishing. Errors returned by
+// handlers are sent to the client's stream.
+func (h *Hub) ServeSSEPublish(w http.ResponseWriter, r *http.Request) {
+	if !h.checkSSEOrigin(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	clientID := r.Header.Get(SSEClientIDHeader)
+	if clientID == "" {
+		clientID = r.URL.Query().Get("client_id")
+	}
+	if clientID == "" {
+		http.Error(w, "Missing client ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authenticate(r, clientID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	client := h.findClient(userID, clientID)
+	if client == nil {
+		http.Error(w, "Unknown client", http.StatusNotFound)
+		return
+	}
+
+	token := r.Header.Get(SSEPublishTokenHeader)
+	if token == "" {
+		token = r.URL.Query().Get("publish_token")
+	}
+	if client.publishToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(client.publishToken)) != 1 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if allowed, info := client.allowMessage(); !allowed {
+		if info != nil && info.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(info.RetryAfter))
+		}
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.config.MaxMessageSize+1))
+	if err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > h.config.MaxMessageSize {
+		http.Error(w, "Message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	msg, err := parseMessage(body)
+	if err != nil {
+		http.Error(w, "Invalid message", http.StatusBadRequest)
+		return
+	}
+
+	client.dispatch(msg)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authenticate returns the user ID of a request, or the client ID for
+// anonymous connections
+func (h *Hub) authenticate(r *http.Request, clientID string) (string, error) {
+	var userID string
+	if h.config.AuthenticateConnection != nil {
+		var err error
+		if userID, err = h.config.AuthenticateConnection(r); err != nil {
+			return "", err
+		}
+	}
+	if userID == "" {
+		userID = clientID
+	}
+	return userID, nil
+}
+
+// findClient returns a user's local client by ID
+func (h *Hub) findClient(userID, clientID string) *Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.users[userID] {
+		if client.id == clientID {
+			return client
+		}
+	}
+	return nil
+}
+
+// checkSSEOrigin checks the origin of cross-origin requests. Unlike
+// websocket upgrades, same-origin EventSource requests carry no Origin.
+func (h *Hub) checkSSEOrigin(r *http.Request) bool {
+	if r.Header.Get("Origin") == "" {
+		return true
+	}
+	return h.checkOrigin(r)
+}
+
+// sseStream writes events to an SSE response
+type sseStream struct {
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	writeWait time.Duration
+	seqs      map[string]uint64 // Last sequence ID by room, sent as event IDs
+}
+
+// event writes a message as an event. Retained room messages update the
+// event ID, which the browser sends back as Last-Event-ID on reconnect.
+func (s *sseStream) event(message []byte) error {
+	var buf bytes.Buffer
+
+	var meta struct {
+		Room string `json:"room"`
+		Seq  uint64 `json:"seq"`
+	}
+	if json.Unmarshal(message, &meta) == nil && meta.Room != "" && meta.Seq > s.seqs[meta.Room] {
+		s.seqs[meta.Room] = meta.Seq
+		buf.WriteString("id: ")
+		buf.WriteString(formatLastEventID(s.seqs))
+		buf.WriteByte('\n')
+	}
+
+	for _, line := range bytes.Split(message, []byte{'\n'}) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	return s.write(buf.Bytes())
+}
+
+// comment writes a comment line, which keeps proxies from closing an
+// idle connection
+func (s *sseStream) comment(text string) error {
+	return s.write([]byte(": " + text + "\n\n"))
+}
+
+func (s *sseStream) write(data []byte) error {
+	s.rc.SetWriteDeadline(time.Now().Add(s.writeWait))
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// formatLastEventID encodes the last sequence ID of each room,
+// e.g. "chat%3A1=42&lobby=7"
+func formatLastEventID(seqs map[string]uint64) string {
+	values := make(url.Values, len(seqs))
+	for room, seq := range seqs {
+		values.Set(room, strconv.FormatUint(seq, 10))
+	}
+	return values.Encode()
+}
+
+// parseLastEventID decodes an event ID written by formatLastEventID
+func parseLastEventID(id string) map[string]uint64 {
+	seqs := make(map[string]uint64)
+	values, err := url.ParseQuery(id)
+	if err != nil {
+		return seqs
+	}
+	for room := range values {
+		if seq, err := strconv.ParseUint(values.Get(room), 10, 64); err == nil {
+			seqs[room] = seq
+		}
+	}
+	return seqs
+}
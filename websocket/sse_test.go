@@ -0,0 +1,275 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent is an event read from a stream
+type sseEvent struct {
+	ID   string
+	Data string
+}
+
+func (e sseEvent) message(t *testing.T) Message {
+	t.Helper()
+	var msg Message
+	require.NoError(t, json.Unmarshal([]byte(e.Data), &msg))
+	return msg
+}
+
+// openSSE connects to the stream and returns a channel of its events
+func openSSE(t *testing.T, url string, header http.Header) (<-chan sseEvent, *http.Response) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan sseEvent, 64)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if ev.Data != "" {
+					events <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				ev.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.Data += strings.TrimPrefix(line, "data: ")
+			case strings.HasPrefix(line, ": "):
+				events <- sseEvent{Data: line}
+			}
+		}
+	}()
+	return events, resp
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "stream closed")
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return sseEvent{}
+	}
+}
+
+func TestSSEStream(t *testing.T) {
+	hub := startHub(t)
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	t.Cleanup(server.Close)
+
+	events, resp := openSSE(t, server.URL+"?room=lobby", nil)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	connected := nextEvent(t, events).message(t)
+	assert.Equal(t, MessageConnected, connected.Type)
+	clientID := connected.Data.(map[string]interface{})["client_id"].(string)
+	assert.NotEmpty(t, clientID)
+
+	require.Eventually(t, func() bool { return hub.GetRoomClients("lobby") == 1 }, time.Second, 10*time.Millisecond)
+
+	hub.BroadcastToRoom("lobby", []byte(`{"type":"chat","room":"lobby"}`), nil)
+	assert.Equal(t, "chat", nextEvent(t, events).message(t).Type)
+
+	hub.BroadcastToAll([]byte(`{"type":"news"}`))
+	assert.Equal(t, "news", nextEvent(t, events).message(t).Type)
+
+	hub.SendToUser(clientID, []byte(`{"type":"direct"}`))
+	assert.Equal(t, "direct", nextEvent(t, events).message(t).Type)
+
+	hub.DisconnectUser(clientID)
+	require.Eventually(t, func() bool { return hub.GetLocalClients() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	hub := startHub(t, WithSSEHeartbeat(20*time.Millisecond))
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	t.Cleanup(server.Close)
+
+	events, _ := openSSE(t, server.URL, nil)
+	nextEvent(t, events)
+	assert.Equal(t, ": heartbeat", nextEvent(t, events).Data)
+}
+
+func TestSSEUnregistersOnClose(t *testing.T) {
+	hub := startHub(t)
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	t.Cleanup(server.Close)
+
+	events, resp := openSSE(t, server.URL+"?room=lobby", nil)
+	nextEvent(t, events)
+	require.Eventually(t, func() bool { return hub.GetLocalClients() == 1 }, time.Second, 10*time.Millisecond)
+
+	resp.Body.Close()
+	require.Eventually(t, func() bool {
+		return hub.GetLocalClients() == 0 && hub.GetRoomClients("lobby") == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSSEAuthorization(t *testing.T) {
+	hub := startHub(t,
+		WithAuthenticateConnection(func(r *http.Request) (string, error) {
+			if user := r.Header.Get("X-User"); user != "" {
+				return user, nil
+			}
+			return "", errors.New("not logged in")
+		}),
+		WithRoomPolicy("private-user.{id}", RoomPolicy{
+			Join: func(c *Client, room string, params map[string]string) bool {
+				return c.GetUserID() == params["id"]
+			},
+		}),
+	)
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?room=private-user.bob", nil)
+	req.Header.Set("X-User", "alice")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	events, _ := openSSE(t, server.URL+"?room=private-user.alice", http.Header{"X-User": {"alice"}})
+	assert.Equal(t, MessageConnected, nextEvent(t, events).message(t).Type)
+}
+
+func TestSSEPublish(t *testing.T) {
+	hub := startHub(t, WithAuthenticateConnection(func(r *http.Request) (string, error) {
+		return r.Header.Get("X-User"), nil
+	}))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events", hub.ServeSSE)
+	mux.HandleFunc("POST /events", hub.ServeSSEPublish)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	events, _ := openSSE(t, server.URL+"/events", http.Header{"X-User": {"alice"}})
+	connected := nextEvent(t, events).message(t).Data.(map[string]interface{})
+	clientID := connected["client_id"].(string)
+	token := connected["publish_token"].(string)
+	require.NotEmpty(t, token)
+
+	wsClient := newTestClient(hub, "c1", "bob")
+	hub.register <- wsClient
+	hub.JoinRoom(wsClient, "lobby")
+
+	publish := func(user, clientID, token, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/events", strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		req.Header.Set(SSEClientIDHeader, clientID)
+		req.Header.Set(SSEPublishTokenHeader, token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusAccepted, publish("alice", clientID, token, `{"type":"join_room","data":"lobby"}`))
+	require.Eventually(t, func() bool { return hub.GetRoomClients("lobby") == 2 }, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, http.StatusAccepted, publish("alice", clientID, token, `{"type":"room_message","room":"lobby","data":"hi"}`))
+	msg := receiveMessage(t, wsClient)
+	assert.Equal(t, "room_message", msg.Type)
+	assert.Equal(t, "alice", msg.UserID)
+
+	// Handler errors go to the stream
+	assert.Equal(t, http.StatusAccepted, publish("alice", clientID, token, `{"type":"nope"}`))
+	assert.Equal(t, MessageError, nextEvent(t, events).message(t).Type)
+
+	assert.Equal(t, http.StatusNotFound, publish("mallory", clientID, token, `{"type":"broadcast"}`))
+	assert.Equal(t, http.StatusNotFound, publish("alice", "client_unknown", token, `{"type":"broadcast"}`))
+	assert.Equal(t, http.StatusForbidden, publish("alice", clientID, "", `{"type":"broadcast"}`))
+	assert.Equal(t, http.StatusForbidden, publish("alice", clientID, "wrong", `{"type":"broadcast"}`))
+	assert.Equal(t, http.StatusBadRequest, publish("alice", clientID, token, `not json`))
+	assert.Equal(t, http.StatusBadRequest, publish("alice", "", token, `{}`))
+}
+
+func TestSSEPublishAnonymous(t *testing.T) {
+	hub := startHub(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events", hub.ServeSSE)
+	mux.HandleFunc("POST /events", hub.ServeSSEPublish)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	events, _ := openSSE(t, server.URL+"/events", nil)
+	connected := nextEvent(t, events).message(t).Data.(map[string]interface{})
+	clientID := connected["client_id"].(string)
+
+	publish := func(query string) int {
+		resp, err := http.Post(server.URL+"/events?"+query, "application/json", strings.NewReader(`{"type":"broadcast","data":"hi"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The client ID is broadcast as user_id, so it must not be enough to
+	// publish as the client
+	assert.Equal(t, http.StatusForbidden, publish("client_id="+clientID))
+	assert.Equal(t, http.StatusAccepted, publish("client_id="+clientID+"&publish_token="+connected["publish_token"].(string)))
+}
+
+func TestSSELastEventID(t *testing.T) {
+	hub := startHub(t, WithHistory(NewMemoryHistory(10)))
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	t.Cleanup(server.Close)
+
+	events, _ := openSSE(t, server.URL+"?room=chat:1", nil)
+	nextEvent(t, events)
+	require.Eventually(t, func() bool { return hub.GetRoomClients("chat:1") == 1 }, time.Second, 10*time.Millisecond)
+
+	hub.BroadcastToRoom("chat:1", []byte(`{"type":"chat","room":"chat:1","data":1}`), nil)
+	first := nextEvent(t, events)
+	assert.Equal(t, "chat%3A1=1", first.ID)
+
+	hub.BroadcastToRoom("chat:1", []byte(`{"type":"chat","room":"chat:1","data":2}`), nil)
+	hub.BroadcastToRoom("chat:1", []byte(`{"type":"chat","room":"chat:1","data":3}`), nil)
+
+	// Reconnect as EventSource does after seeing the first message
+	events, _ = openSSE(t, server.URL+"?room=chat:1", http.Header{"Last-Event-ID": {first.ID}})
+	nextEvent(t, events)
+	second := nextEvent(t, events)
+	assert.Equal(t, uint64(2), second.message(t).Seq)
+	assert.Equal(t, "chat%3A1=2", second.ID)
+	assert.Equal(t, uint64(3), nextEvent(t, events).message(t).Seq)
+	assert.Equal(t, MessageResumed, nextEvent(t, events).message(t).Type)
+}
+
+func TestLastEventID(t *testing.T) {
+	seqs := map[string]uint64{"chat:1": 42, "lobby": 7}
+	assert.Equal(t, seqs, parseLastEventID(formatLastEventID(seqs)))
+	assert.Empty(t, parseLastEventID("garbage;%%"))
+}
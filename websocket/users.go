@@ -0,0 +1,127 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// userMessage is a message for all connections of a user
+type userMessage struct {
+	UserID  string
+	Message []byte
+}
+
+// SendToUser sends a message to every connection of a user, on all
+// instances when a backplane is configured
+func (h *Hub) SendToUser(userID string, message []byte) {
+	h.publish(&backplaneEvent{Kind: eventUser, User: userID, Message: message})
+
+	h.whileRunning(func() {
+		select {
+		case h.userMessages <- &userMessage{UserID: userID, Message: message}:
+		default:
+			log.Printf("User message channel is full, dropping message for user %s", userID)
+		}
+	})
+}
+
+// SendMessageToUser encodes a message of the given type and sends it to
+// every connection of a user, e.g. from a background job:
+//
+//	hub.SendMessageToUser("42", "export_ready", map[string]string{"url": url})
+func (h *Hub) SendMessageToUser(userID, msgType string, data interface{}) error {
+	messageBytes, err := json.Marshal(Message{
+		Type:      msgType,
+		Data:      data,
+		UserID:    userID,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	h.SendToUser(userID, messageBytes)
+	return nil
+}
+
+// DisconnectUser closes every connection of a user, on all instances when
+// a backplane is configured, e.g. after logging the user out or banning them
+func (h *Hub) DisconnectUser(userID string) {
+	h.publish(&backplaneEvent{Kind: eventKick, User: userID})
+	h.disconnectUser(userID)
+}
+
+// ClientsForUser returns the user's connections to this instance
+func (h *Hub) ClientsForUser(userID string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(h.users[userID]))
+	for client := range h.users[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// sendToUser delivers a message to the user's local connections. It runs
+// on the run loop, so connections are not closed while sending.
+func (h *Hub) sendToUser(msg *userMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.users[msg.UserID] {
+		client.enqueue(msg.Message)
+	}
+}
+
+// disconnectUser closes the user's local connections. Their read pumps
+// then unregister them.
+func (h *Hub) disconnectUser(userID string) {
+	for _, client := range h.ClientsForUser(userID) {
+		client.disconnect(websocket.ClosePolicyViolation, "disconnected by server")
+	}
+}
+
+// addUserClient adds a client to the user index. h.mu must be held.
+func (h *Hub) addUserClient(client *Client) {
+	if h.users[client.userID] == nil {
+		h.users[client.userID] = make(map[*Client]bool)
+	}
+	h.users[client.userID][client] = true
+}
+
+// removeUserClient removes a client from the user index. h.mu must be held.
+func (h *Hub) removeUserClient(client *Client) {
+	delete(h.users[client.userID], client)
+	if len(h.users[client.userID]) == 0 {
+		delete(h.users, client.userID)
+	}
+}
+
+// disconnect closes the connection unless it is already being closed
+func (c *Client) disconnect(code int, reason string) {
+	if c.closing.CompareAndSwap(false, true) {
+		c.close(code, reason)
+	}
+}
+
+// close sends a close frame and closes the connection
+func (c *Client) close(code int, reason string) {
+	if c.conn == nil {
+		// Not connected to a socket, e.g. SSE clients, whose stream ends
+		// when they are unregistered
+		go func() {
+			defer func() { recover() }()
+			c.hub.unregister <- c
+		}()
+		return
+	}
+
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(c.hub.config.WriteWait))
+	c.conn.Close()
+}
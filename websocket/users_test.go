@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendToUser(t *testing.T) {
+	hub := startHub(t)
+
+	tab1 := newTestClient(hub, "c1", "u1")
+	tab2 := newTestClient(hub, "c2", "u1")
+	other := newTestClient(hub, "c3", "u2")
+	hub.register <- tab1
+	hub.register <- tab2
+	hub.register <- other
+
+	assert.ElementsMatch(t, []*Client{tab1, tab2}, hub.ClientsForUser("u1"))
+	assert.Empty(t, hub.ClientsForUser("nobody"))
+
+	hub.SendToUser("u1", []byte("hello"))
+	assert.Equal(t, "hello", receive(t, tab1))
+	assert.Equal(t, "hello", receive(t, tab2))
+	assertNoMessage(t, other)
+
+	require.NoError(t, hub.SendMessageToUser("u2", "export_ready", map[string]string{"url": "/x.csv"}))
+	msg := receiveMessage(t, other)
+	assert.Equal(t, "export_ready", msg.Type)
+	assert.Equal(t, "u2", msg.UserID)
+
+	hub.unregister <- tab1
+	require.Eventually(t, func() bool {
+		return len(hub.ClientsForUser("u1")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	hub.unregister <- tab2
+	require.Eventually(t, func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		_, ok := hub.users["u1"]
+		return !ok
+	}, time.Second, 10*time.Millisecond, "empty users are removed from the index")
+}
+
+func TestDisconnectUser(t *testing.T) {
+	hub := startHub(t)
+
+	tab1 := newTestClient(hub, "c1", "u1")
+	tab2 := newTestClient(hub, "c2", "u1")
+	other := newTestClient(hub, "c3", "u2")
+	hub.register <- tab1
+	hub.register <- tab2
+	hub.register <- other
+	hub.JoinRoom(tab1, "lobby")
+
+	hub.DisconnectUser("u1")
+
+	require.Eventually(t, func() bool {
+		return len(hub.ClientsForUser("u1")) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, hub.GetLocalClients())
+	assert.Equal(t, 0, hub.GetRoomClients("lobby"))
+	assert.Len(t, hub.ClientsForUser("u2"), 1)
+}
+
+func TestSendToUserAcrossInstances(t *testing.T) {
+	b := NewMemoryBackplane()
+	hubA, _ := startClusterHub(t, b)
+	hubB, _ := startClusterHub(t, b)
+
+	client := newTestClient(hubB, "c1", "u1")
+	hubB.register <- client
+	require.Eventually(t, func() bool {
+		return hubA.GetConnectedClients() == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	hubA.SendToUser("u1", []byte("from A"))
+	assert.Equal(t, "from A", receive(t, client))
+
+	hubA.DisconnectUser("u1")
+	require.Eventually(t, func() bool {
+		return hubB.GetLocalClients() == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestModuleUserMessaging(t *testing.T) {
+	m := NewModule()
+	m.SendToUser("u1", []byte("ignored before Initialize"))
+	assert.Nil(t, m.ClientsForUser("u1"))
+
+	require.NoError(t, m.Initialize(nil))
+	defer m.Shutdown(context.Background())
+
+	client := newTestClient(m.Hub, "c1", "u1")
+	m.Hub.register <- client
+
+	require.NoError(t, m.SendMessageToUser("u1", "notification", "done"))
+	assert.Equal(t, "notification", receiveMessage(t, client).Type)
+	assert.Len(t, m.ClientsForUser("u1"), 1)
+}
+
+func TestSendToUserAfterShutdown(t *testing.T) {
+	hub := NewHub(NewConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+	<-done
+
+	// A background job sending after shutdown must not panic
+	assert.NotPanics(t, func() {
+		hub.SendToUser("u1", []byte("late"))
+		require.NoError(t, hub.SendMessageToUser("u1", "export_ready", nil))
+		hub.BroadcastToAll([]byte("late"))
+		hub.BroadcastToRoom("lobby", []byte("late"), nil)
+	})
+}
@@ -14,6 +14,16 @@ func generateClientID() string {
 	return fmt.Sprintf("client_%s", hex.EncodeToString(bytes))
 }
 
+// generatePublishToken returns a random token authorizing publishes from an
+// SSE client, or an empty string if none could be generated
+func generatePublishToken() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(bytes)
+}
+
 func randomInt() int64 {
 	b := make([]byte, 8)
 	rand.Read(b)